@@ -4,4 +4,5 @@ type JSONAPIError struct {
 	Code         string `json:"code"`
 	Msg          string `json:"msg"`
 	ErrorDetails string `json:"error_details"`
+	Details      any    `json:"details,omitempty"`
 }
@@ -1,6 +1,7 @@
 package application
 
 import (
+	"context"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -9,6 +10,9 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/veedubyou/chord-paper-be/src/server/google_id"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/gateway"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/storage"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/usecase"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/gateway"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/storage"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/usecase"
@@ -17,9 +21,13 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/server/internal/user/gateway"
 	userstorage "github.com/veedubyou/chord-paper-be/src/server/internal/user/storage"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/user/usecase"
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	filestore "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
 	"github.com/veedubyou/chord-paper-be/src/shared/config"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/storage"
 	"net/http"
 )
@@ -34,18 +42,28 @@ const (
 )
 
 type App struct {
-	echo *echo.Echo
-	port string
+	echo             *echo.Echo
+	port             string
+	startRelay       func()
+	startOutboxRelay func()
+	stopRelay        context.CancelFunc
 }
 
 type Config struct {
 	DynamoConfig       config.Dynamo
+	CloudStorageConfig config.CloudStorage
 	RabbitMQURL        string
 	RabbitMQQueueName  string
 	CORSAllowedOrigins []string
 	UserValidator      google_id.Validator
-	Port               string
-	Log                bool
+	// ShareLinkSigningKey signs share link tokens, rotating it invalidates all existing links
+	ShareLinkSigningKey string
+	// EventsTokenSigningKey signs the short lived tokens that browsers open the track event stream with
+	EventsTokenSigningKey string
+	// OpenUnmixEnabled offers Open-Unmix to split requests, for when the worker has it installed
+	OpenUnmixEnabled bool
+	Port             string
+	Log              bool
 }
 
 func NewApp(config Config) App {
@@ -82,10 +100,14 @@ func NewApp(config Config) App {
 	rabbitmqPublisher := makeRabbitMQPublisher(config)
 	userUsecase := makeUserUsecase(config, dynamoDB)
 	songUsecase := makeSongUsecase(dynamoDB, userUsecase)
+	fileStore := makeFileStore(config.CloudStorageConfig)
+	eventBus := trackevents.NewBus()
+	trackUsecase := makeTrackUsecase(config, dynamoDB, songUsecase, rabbitmqPublisher, fileStore, eventBus)
 
 	userGateway := makeUserGateway(userUsecase)
 	songGateway := makeSongGateway(songUsecase)
-	trackGateway := makeTrackGateway(dynamoDB, songUsecase, rabbitmqPublisher)
+	trackGateway := makeTrackGateway(trackUsecase)
+	shareLinkGateway := makeShareLinkGateway(config, dynamoDB, songUsecase, trackUsecase)
 
 	// health check
 	handleRoute(GET, "/health-check", func(c echo.Context) error {
@@ -109,6 +131,33 @@ func NewApp(config Config) App {
 		songID := c.Param("id")
 		return songGateway.DeleteSong(c, songID)
 	})
+	handleRoute(GET, "/songs/:id/revisions", func(c echo.Context) error {
+		songID := c.Param("id")
+		return songGateway.GetSongRevisions(c, songID)
+	})
+	handleRoute(GET, "/songs/:id/revisions/:rev", func(c echo.Context) error {
+		songID := c.Param("id")
+		revision := c.Param("rev")
+		return songGateway.GetSongRevision(c, songID, revision)
+	})
+	handleRoute(POST, "/songs/:id/revisions/:rev/restore", func(c echo.Context) error {
+		songID := c.Param("id")
+		revision := c.Param("rev")
+		return songGateway.RestoreSongRevision(c, songID, revision)
+	})
+	handleRoute(GET, "/songs/:id/collaborators", func(c echo.Context) error {
+		songID := c.Param("id")
+		return songGateway.GetCollaborators(c, songID)
+	})
+	handleRoute(POST, "/songs/:id/collaborators", func(c echo.Context) error {
+		songID := c.Param("id")
+		return songGateway.InviteCollaborator(c, songID)
+	})
+	handleRoute(DELETE, "/songs/:id/collaborators/:userId", func(c echo.Context) error {
+		songID := c.Param("id")
+		userID := c.Param("userId")
+		return songGateway.RevokeCollaborator(c, songID, userID)
+	})
 	handleRoute(GET, "/users/:id/songs", func(c echo.Context) error {
 		userID := c.Param("id")
 		return songGateway.GetSongSummariesForUser(c, userID)
@@ -123,14 +172,86 @@ func NewApp(config Config) App {
 		songID := c.Param("id")
 		return trackGateway.SetTrackList(c, songID)
 	})
+	handleRoute(POST, "/songs/:id/tracklist/events/token", func(c echo.Context) error {
+		songID := c.Param("id")
+		return trackGateway.CreateEventsToken(c, songID)
+	})
+	handleRoute(GET, "/songs/:id/tracklist/events", func(c echo.Context) error {
+		songID := c.Param("id")
+		return trackGateway.StreamTrackListEvents(c, songID)
+	})
+	handleRoute(POST, "/songs/:id/tracklist/tracks/:trackId/cancel", func(c echo.Context) error {
+		songID := c.Param("id")
+		trackID := c.Param("trackId")
+		return trackGateway.CancelTrack(c, songID, trackID)
+	})
+	handleRoute(POST, "/songs/:id/tracklist/tracks/:trackId/retry", func(c echo.Context) error {
+		songID := c.Param("id")
+		trackID := c.Param("trackId")
+		return trackGateway.RetryTrack(c, songID, trackID)
+	})
+	handleRoute(GET, "/songs/:id/tracklist/tracks/:trackId/jobs", func(c echo.Context) error {
+		songID := c.Param("id")
+		trackID := c.Param("trackId")
+		return trackGateway.GetJobRuns(c, songID, trackID)
+	})
+
+	handleRoute(GET, "/split-engines", trackGateway.GetSplitEngines)
+
+	// share link routes
+	handleRoute(GET, "/songs/:id/share-links", func(c echo.Context) error {
+		songID := c.Param("id")
+		return shareLinkGateway.GetShareLinks(c, songID)
+	})
+	handleRoute(POST, "/songs/:id/share-links", func(c echo.Context) error {
+		songID := c.Param("id")
+		return shareLinkGateway.CreateShareLink(c, songID)
+	})
+	handleRoute(DELETE, "/songs/:id/share-links/:linkId", func(c echo.Context) error {
+		songID := c.Param("id")
+		linkID := c.Param("linkId")
+		return shareLinkGateway.RevokeShareLink(c, songID, linkID)
+	})
+	handleRoute(GET, "/shared/:token", func(c echo.Context) error {
+		token := c.Param("token")
+		return shareLinkGateway.GetSharedSong(c, token)
+	})
+	handleRoute(PUT, "/shared/:token", func(c echo.Context) error {
+		token := c.Param("token")
+		return shareLinkGateway.UpdateSharedSong(c, token)
+	})
+
+	// files on local disk are served from here, the signed URLs point at this route
+	if localDiskFileStore, ok := fileStore.(filestore.LocalDiskFileStore); ok {
+		fileServer := http.StripPrefix("/files", localDiskFileStore.FileServer())
+		handleRoute(GET, "/files/*", echo.WrapHandler(fileServer))
+	}
+
+	// the worker publishes track events, the relay brings them to the subscribers on this server
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	startRelay := func() {
+		exchangeName := trackevents.ExchangeName(config.RabbitMQQueueName)
+		trackevents.Relay(relayCtx, config.RabbitMQURL, exchangeName, eventBus)
+	}
+
+	// jobs that weren't published when their tracklist was saved are sent from the outbox
+	startOutboxRelay := func() {
+		trackUsecase.RelayOutbox(relayCtx, trackusecase.OutboxRelayInterval)
+	}
 
 	return App{
-		echo: e,
-		port: config.Port,
+		echo:             e,
+		port:             config.Port,
+		startRelay:       startRelay,
+		startOutboxRelay: startOutboxRelay,
+		stopRelay:        stopRelay,
 	}
 }
 
 func (a *App) Start() error {
+	go a.startRelay()
+	go a.startOutboxRelay()
+
 	err := a.echo.Start(a.port)
 	if err != nil && err != http.ErrServerClosed {
 		return errors.Wrap(err, "Couldn't start echo server")
@@ -140,6 +261,8 @@ func (a *App) Start() error {
 }
 
 func (a *App) Stop() error {
+	a.stopRelay()
+
 	err := a.echo.Close()
 	if err != nil {
 		return errors.Wrap(err, "Failed to stop echo server")
@@ -194,12 +317,39 @@ func makeSongGateway(songUsecase songusecase.Usecase) songgateway.Gateway {
 	return songgateway.NewGateway(songUsecase)
 }
 
-func makeTrackGateway(dynamoDB dynamolib.DynamoDBWrapper, songUsecase songusecase.Usecase, publisher *rabbitmq.QueuePublisher) trackgateway.Gateway {
+func makeFileStore(cloudStorageConfig config.CloudStorage) cloudstorage.FileStore {
+	fileStore, err := filestore.NewFileStore(cloudStorageConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	return fileStore
+}
+
+func makeTrackUsecase(config Config, dynamoDB dynamolib.DynamoDBWrapper, songUsecase songusecase.Usecase, publisher *rabbitmq.QueuePublisher, urlSigner cloudstorage.URLSigner, eventSubscriber trackentity.EventSubscriber) trackusecase.Usecase {
 	trackDB := trackstorage.NewDB(dynamoDB)
-	trackUsecase := trackusecase.NewUsecase(trackDB, songUsecase, publisher)
+	return trackusecase.NewUsecase(trackDB, trackDB, trackDB, songUsecase, publisher, urlSigner, eventSubscriber, config.EventsTokenSigningKey, makeInstalledSplitEngines(config))
+}
+
+func makeInstalledSplitEngines(config Config) trackentity.InstalledSplitEngines {
+	optionalEngines := []trackentity.SplitEngineType{}
+	if config.OpenUnmixEnabled {
+		optionalEngines = append(optionalEngines, trackentity.OpenUnmixType)
+	}
+
+	return trackentity.NewInstalledSplitEngines(optionalEngines...)
+}
+
+func makeTrackGateway(trackUsecase trackusecase.Usecase) trackgateway.Gateway {
 	return trackgateway.NewGateway(trackUsecase)
 }
 
+func makeShareLinkGateway(config Config, dynamoDB dynamolib.DynamoDBWrapper, songUsecase songusecase.Usecase, trackUsecase trackusecase.Usecase) sharelinkgateway.Gateway {
+	shareLinkDB := sharelinkstorage.NewDB(dynamoDB)
+	shareLinkUsecase := sharelinkusecase.NewUsecase(shareLinkDB, songUsecase, trackUsecase, config.ShareLinkSigningKey)
+	return sharelinkgateway.NewGateway(shareLinkUsecase)
+}
+
 func makeUserUsecase(config Config, dynamoDB dynamolib.DynamoDBWrapper) userusecase.Usecase {
 	userDB := userstorage.NewDB(dynamoDB)
 	return userusecase.NewUsecase(userDB, config.UserValidator)
@@ -11,6 +11,7 @@ func WrapError(err *Error, msg string) *Error {
 		ErrorCode:     err.ErrorCode,
 		UserMessage:   err.UserMessage,
 		InternalError: errors.Wrap(err.InternalError, msg),
+		Details:       err.Details,
 	}
 }
 
@@ -31,6 +32,14 @@ type Error struct {
 	ErrorCode     ErrorCode
 	UserMessage   string
 	InternalError error
+	// Details is optional structured data for the client to act on,
+	// for errors where a message alone isn't enough
+	Details any
+}
+
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
 }
 
 func (e Error) Cause() error {
@@ -9,4 +9,5 @@ const (
 	NoAccountCode              = api.ErrorCode("no_account")
 	WrongOwnerCode             = api.ErrorCode("wrong_owner")
 	BadAuthorizationHeaderCode = api.ErrorCode("bad_header")
+	InsufficientRoleCode       = api.ErrorCode("insufficient_role")
 )
@@ -6,23 +6,38 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/server/api_error"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/auth"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/errors"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/track/errors"
 	"net/http"
 )
 
 var httpStatusCodeMap = map[api.ErrorCode]int{
-	api.DefaultErrorCode:              http.StatusInternalServerError,
-	auth.NotGoogleAuthorizedCode:      http.StatusUnauthorized,
-	auth.NoAccountCode:                http.StatusUnauthorized,
-	auth.BadAuthorizationHeaderCode:   http.StatusBadRequest,
-	auth.WrongOwnerCode:               http.StatusForbidden,
-	songerrors.SongNotFoundCode:       http.StatusNotFound,
-	songerrors.ExistingSongCode:       http.StatusBadRequest,
-	songerrors.BadSongDataCode:        http.StatusBadRequest,
-	songerrors.SongOverwriteCode:      http.StatusBadRequest,
-	trackerrors.TrackListSizeExceeded: http.StatusBadRequest,
-	trackerrors.BadTracklistDataCode:  http.StatusBadRequest,
+	api.DefaultErrorCode:                  http.StatusInternalServerError,
+	auth.NotGoogleAuthorizedCode:          http.StatusUnauthorized,
+	auth.NoAccountCode:                    http.StatusUnauthorized,
+	auth.BadAuthorizationHeaderCode:       http.StatusBadRequest,
+	auth.WrongOwnerCode:                   http.StatusForbidden,
+	auth.InsufficientRoleCode:             http.StatusForbidden,
+	songerrors.SongNotFoundCode:           http.StatusNotFound,
+	songerrors.ExistingSongCode:           http.StatusBadRequest,
+	songerrors.BadSongDataCode:            http.StatusBadRequest,
+	songerrors.SongOverwriteCode:          http.StatusBadRequest,
+	songerrors.SongMergeConflictCode:      http.StatusConflict,
+	songerrors.CollaboratorNotFoundCode:   http.StatusNotFound,
+	songerrors.BadCollaboratorDataCode:    http.StatusBadRequest,
+	songerrors.InviteeNotFoundCode:        http.StatusNotFound,
+	songerrors.RevisionNotFoundCode:       http.StatusNotFound,
+	trackerrors.TrackListSizeExceeded:     http.StatusBadRequest,
+	trackerrors.BadTracklistDataCode:      http.StatusBadRequest,
+	trackerrors.TrackNotFoundCode:         http.StatusNotFound,
+	trackerrors.TrackNotCancellableCode:   http.StatusConflict,
+	trackerrors.TrackNotRetryableCode:     http.StatusConflict,
+	trackerrors.BadEventsTokenCode:        http.StatusUnauthorized,
+	sharelinkerrors.ShareLinkNotFoundCode: http.StatusNotFound,
+	sharelinkerrors.ShareLinkExpiredCode:  http.StatusGone,
+	sharelinkerrors.ShareLinkReadOnlyCode: http.StatusForbidden,
+	sharelinkerrors.BadShareLinkDataCode:  http.StatusBadRequest,
 }
 
 func ErrorResponse(c echo.Context, err *api.Error) error {
@@ -36,5 +51,6 @@ func ErrorResponse(c echo.Context, err *api.Error) error {
 		Code:         string(err.ErrorCode),
 		Msg:          err.UserMessage,
 		ErrorDetails: err.Error(),
+		Details:      err.Details,
 	})
 }
@@ -24,6 +24,12 @@ func Context(c echo.Context) context.Context {
 	}
 }
 
+// OptionalAuthHeader is for endpoints that only need to authenticate some requests,
+// the usecase decides whether an empty header is acceptable
+func OptionalAuthHeader(c echo.Context) string {
+	return c.Request().Header.Get("authorization")
+}
+
 func AuthHeader(c echo.Context) (string, *api.Error) {
 	header := c.Request().Header.Get("authorization")
 	if header == "" {
@@ -0,0 +1,50 @@
+package sharelinkentity
+
+import (
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"time"
+)
+
+type Scope string
+
+const (
+	ReadOnlyScope  Scope = "read_only"
+	ReadWriteScope Scope = "read_write"
+)
+
+func (s Scope) CanWrite() bool {
+	return s == ReadWriteScope
+}
+
+// ShareLink gives anyone holding its token access to a song and its tracklist
+// without logging in, until it expires or the owner revokes it
+type ShareLink struct {
+	ID        string    `json:"id"`
+	SongID    string    `json:"songId"`
+	Scope     Scope     `json:"scope"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Token is derived from the ID and never stored
+	Token string `json:"token"`
+}
+
+func (s ShareLink) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// ShareLinkRequest is what the owner sends to create a link.
+// Both fields are optional: links are read only unless ReadOnly is explicitly false,
+// and a default expiry is applied if ExpiresAt is not set
+type ShareLinkRequest struct {
+	ReadOnly  *bool      `json:"readOnly"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// SharedSong is what a share link resolves to
+type SharedSong struct {
+	Song      songentity.Song       `json:"song"`
+	TrackList trackentity.TrackList `json:"tracklist"`
+	Scope     Scope                 `json:"scope"`
+	ExpiresAt time.Time             `json:"expiresAt"`
+}
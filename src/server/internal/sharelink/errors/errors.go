@@ -0,0 +1,12 @@
+package sharelinkerrors
+
+import (
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+)
+
+const (
+	ShareLinkNotFoundCode = api.ErrorCode("share_link_not_found")
+	ShareLinkExpiredCode  = api.ErrorCode("share_link_expired")
+	ShareLinkReadOnlyCode = api.ErrorCode("share_link_read_only")
+	BadShareLinkDataCode  = api.ErrorCode("bad_share_link_data")
+)
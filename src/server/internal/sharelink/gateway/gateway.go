@@ -0,0 +1,115 @@
+package sharelinkgateway
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/gateway"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/lib/request"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/usecase"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
+	"net/http"
+)
+
+type Gateway struct {
+	usecase sharelinkusecase.Usecase
+}
+
+func NewGateway(usecase sharelinkusecase.Usecase) Gateway {
+	return Gateway{
+		usecase: usecase,
+	}
+}
+
+func (g Gateway) CreateShareLink(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	linkRequest := sharelinkentity.ShareLinkRequest{}
+	err := c.Bind(&linkRequest)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to bind request body to share link request")
+		apiErr := api.CommitError(err,
+			sharelinkerrors.BadShareLinkDataCode,
+			"The share link request received was malformed. Please contact the developer")
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	link, apiErr := g.usecase.CreateShareLink(ctx, authHeader, songID, linkRequest)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, link)
+}
+
+func (g Gateway) GetShareLinks(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	links, apiErr := g.usecase.GetShareLinks(ctx, authHeader, songID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+func (g Gateway) RevokeShareLink(c echo.Context, songID string, linkID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	apiErr = g.usecase.RevokeShareLink(ctx, authHeader, songID, linkID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (g Gateway) GetSharedSong(c echo.Context, token string) error {
+	ctx := request.Context(c)
+
+	sharedSong, apiErr := g.usecase.GetSharedSong(ctx, token)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, sharedSong)
+}
+
+func (g Gateway) UpdateSharedSong(c echo.Context, token string) error {
+	ctx := request.Context(c)
+
+	song := songentity.Song{}
+	err := c.Bind(&song)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to bind request body to song object")
+		apiErr := api.CommitError(err,
+			songerrors.BadSongDataCode,
+			"The song data received was malformed. Please contact the developer")
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	updatedSong, apiErr := g.usecase.UpdateSharedSong(ctx, token, song)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, updatedSong)
+}
@@ -0,0 +1,28 @@
+package sharelink_test
+
+import (
+	dynamolib "github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
+	testing2 "github.com/veedubyou/chord-paper-be/src/shared/testing"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	db dynamolib.DynamoDBWrapper
+)
+
+func TestShareLink(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ShareLink Suite")
+}
+
+var _ = BeforeSuite(func() {
+	testing2.SetTestEnv()
+	db = testing2.BeforeSuiteDB("sharelink_integration_test")
+})
+
+var _ = AfterSuite(func() {
+	testing2.AfterSuiteDB(db)
+})
@@ -0,0 +1,313 @@
+package sharelink_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/shared_tests/auth"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/gateway"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/storage"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/usecase"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/gateway"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/storage"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/usecase"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/track/usecase"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/user/storage"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/user/usecase"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
+	"github.com/veedubyou/chord-paper-be/src/shared/testing"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/storage"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+var _ = Describe("ShareLink", func() {
+	var (
+		shareLinkGateway sharelinkgateway.Gateway
+		songGateway      songgateway.Gateway
+		songID           string
+	)
+
+	BeforeEach(func() {
+		validator := testing.Validator{}
+		userStorage := userstorage.NewDB(db)
+		userUsecase := userusecase.NewUsecase(userStorage, validator)
+
+		songStorage := songstorage.NewDB(db)
+		songUsecase := songusecase.NewUsecase(songStorage, userUsecase)
+		songGateway = songgateway.NewGateway(songUsecase)
+
+		// nothing here splits tracks, so there's no need for a publisher
+		trackStorage := trackstorage.NewDB(db)
+		trackUsecase := trackusecase.NewUsecase(trackStorage, trackStorage, trackStorage, songUsecase, nil, store.FakeURLSigner{}, trackevents.NewBus(), testing.EventsTokenSigningKey, trackentity.NewInstalledSplitEngines())
+
+		shareLinkStorage := sharelinkstorage.NewDB(db)
+		shareLinkUsecase := sharelinkusecase.NewUsecase(shareLinkStorage, songUsecase, trackUsecase, testing.ShareLinkSigningKey)
+		shareLinkGateway = sharelinkgateway.NewGateway(shareLinkUsecase)
+	})
+
+	BeforeEach(func() {
+		testing.ResetDB(db)
+	})
+
+	BeforeEach(func() {
+		By("Creating a private song to share")
+
+		songPayload := testing.LoadDemoSong()
+		songPayload["visibility"] = "private"
+
+		request := testing.RequestFactory{
+			Method:  "POST",
+			Target:  "/songs",
+			JSONObj: songPayload,
+			Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+		}.MakeFake()
+
+		response := httptest.NewRecorder()
+		c := testing.PrepareEchoContext(request, response)
+		Expect(songGateway.CreateSong(c)).To(Succeed())
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		song := testing.DecodeJSON[map[string]any](response.Body)
+		songID = testing.ExpectType[string](song["id"])
+	})
+
+	var createShareLink = func(payload map[string]any) *httptest.ResponseRecorder {
+		request := testing.RequestFactory{
+			Method:  "POST",
+			Target:  fmt.Sprintf("/songs/%s/share-links", songID),
+			JSONObj: payload,
+			Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+		}.MakeFake()
+
+		response := httptest.NewRecorder()
+		c := testing.PrepareEchoContext(request, response)
+		Expect(shareLinkGateway.CreateShareLink(c, songID)).To(Succeed())
+		return response
+	}
+
+	var getShareLinks = func() []map[string]any {
+		request := testing.RequestFactory{
+			Method: "GET",
+			Target: fmt.Sprintf("/songs/%s/share-links", songID),
+			Mods:   testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+		}.MakeFake()
+
+		response := httptest.NewRecorder()
+		c := testing.PrepareEchoContext(request, response)
+		Expect(shareLinkGateway.GetShareLinks(c, songID)).To(Succeed())
+		Expect(response.Code).To(Equal(http.StatusOK))
+		return testing.DecodeJSON[[]map[string]any](response.Body)
+	}
+
+	var getSharedSong = func(token string) *httptest.ResponseRecorder {
+		request := testing.RequestFactory{
+			Method: "GET",
+			Target: fmt.Sprintf("/shared/%s", token),
+		}.MakeFake()
+
+		response := httptest.NewRecorder()
+		c := testing.PrepareEchoContext(request, response)
+		Expect(shareLinkGateway.GetSharedSong(c, token)).To(Succeed())
+		return response
+	}
+
+	var updateSharedSong = func(token string, song map[string]any) *httptest.ResponseRecorder {
+		request := testing.RequestFactory{
+			Method:  "PUT",
+			Target:  fmt.Sprintf("/shared/%s", token),
+			JSONObj: song,
+		}.MakeFake()
+
+		response := httptest.NewRecorder()
+		c := testing.PrepareEchoContext(request, response)
+		Expect(shareLinkGateway.UpdateSharedSong(c, token)).To(Succeed())
+		return response
+	}
+
+	Describe("Create share link", func() {
+		Describe("With the default expiry", func() {
+			var link map[string]any
+
+			BeforeEach(func() {
+				response := createShareLink(map[string]any{})
+				Expect(response.Code).To(Equal(http.StatusOK))
+				link = testing.DecodeJSON[map[string]any](response.Body)
+			})
+
+			It("returns a token", func() {
+				Expect(link["token"]).NotTo(BeEmpty())
+			})
+
+			It("is read only", func() {
+				Expect(link["scope"]).To(Equal("read_only"))
+			})
+
+			It("expires in a week", func() {
+				expiresAt := testing.ExpectSuccess(time.Parse(time.RFC3339, testing.ExpectType[string](link["expiresAt"])))
+				Expect(expiresAt).To(BeTemporally("~", time.Now().Add(7*24*time.Hour), time.Minute))
+			})
+		})
+
+		Describe("That allows writing", func() {
+			It("has a read write scope", func() {
+				response := createShareLink(map[string]any{"readOnly": false})
+				Expect(response.Code).To(Equal(http.StatusOK))
+				link := testing.DecodeJSON[map[string]any](response.Body)
+				Expect(link["scope"]).To(Equal("read_write"))
+			})
+		})
+
+		Describe("With an expiry too far away", func() {
+			var response *httptest.ResponseRecorder
+
+			BeforeEach(func() {
+				response = createShareLink(map[string]any{
+					"expiresAt": time.Now().Add(365 * 24 * time.Hour).Format(time.RFC3339),
+				})
+			})
+
+			It("rejects the request", func() {
+				Expect(response.Code).To(Equal(http.StatusBadRequest))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(sharelinkerrors.BadShareLinkDataCode))
+			})
+		})
+
+		Describe("Unauthorized", func() {
+			BeforeEach(func() {
+				authtest.Endpoint = func(c echo.Context) error {
+					return shareLinkGateway.CreateShareLink(c, songID)
+				}
+				authtest.JSONBody = map[string]any{}
+			})
+
+			authtest.ItRejectsUnpermittedRequests("POST", "/songs/song-id/share-links")
+		})
+	})
+
+	Describe("Get shared song", func() {
+		var token string
+
+		BeforeEach(func() {
+			response := createShareLink(map[string]any{"readOnly": true})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			link := testing.DecodeJSON[map[string]any](response.Body)
+			token = testing.ExpectType[string](link["token"])
+		})
+
+		It("returns the private song and its tracklist without logging in", func() {
+			response := getSharedSong(token)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			shared := testing.DecodeJSON[map[string]any](response.Body)
+			song := testing.ExpectType[map[string]any](shared["song"])
+			Expect(song["id"]).To(Equal(songID))
+
+			tracklist := testing.ExpectType[map[string]any](shared["tracklist"])
+			Expect(tracklist["song_id"]).To(Equal(songID))
+			Expect(shared["scope"]).To(Equal("read_only"))
+		})
+
+		It("rejects a tampered token", func() {
+			response := getSharedSong(token + "x")
+			Expect(response.Code).To(Equal(http.StatusNotFound))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(sharelinkerrors.ShareLinkNotFoundCode))
+		})
+
+		It("rejects updates through a read only link", func() {
+			shared := testing.DecodeJSON[map[string]any](getSharedSong(token).Body)
+			song := testing.ExpectType[map[string]any](shared["song"])
+
+			response := updateSharedSong(token, song)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(sharelinkerrors.ShareLinkReadOnlyCode))
+		})
+
+		Describe("After the link is revoked", func() {
+			BeforeEach(func() {
+				links := getShareLinks()
+				Expect(links).To(HaveLen(1))
+				linkID := testing.ExpectType[string](links[0]["id"])
+
+				request := testing.RequestFactory{
+					Method: "DELETE",
+					Target: fmt.Sprintf("/songs/%s/share-links/%s", songID, linkID),
+					Mods:   testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+				}.MakeFake()
+
+				response := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, response)
+				Expect(shareLinkGateway.RevokeShareLink(c, songID, linkID)).To(Succeed())
+				Expect(response.Code).To(Equal(http.StatusOK))
+			})
+
+			It("no longer lists the link", func() {
+				Expect(getShareLinks()).To(BeEmpty())
+			})
+
+			It("no longer resolves the token", func() {
+				response := getSharedSong(token)
+				Expect(response.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Update shared song", func() {
+		var token string
+
+		BeforeEach(func() {
+			response := createShareLink(map[string]any{"readOnly": false})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			link := testing.DecodeJSON[map[string]any](response.Body)
+			token = testing.ExpectType[string](link["token"])
+		})
+
+		It("saves the song through a read write link", func() {
+			shared := testing.DecodeJSON[map[string]any](getSharedSong(token).Body)
+			song := testing.ExpectType[map[string]any](shared["song"])
+			metadata := testing.ExpectType[map[string]any](song["metadata"])
+			metadata["title"] = "Shared Title"
+
+			response := updateSharedSong(token, song)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			shared = testing.DecodeJSON[map[string]any](getSharedSong(token).Body)
+			song = testing.ExpectType[map[string]any](shared["song"])
+			metadata = testing.ExpectType[map[string]any](song["metadata"])
+			Expect(metadata["title"]).To(Equal("Shared Title"))
+			Expect(song["visibility"]).To(Equal("private"))
+		})
+	})
+
+	Describe("Deleting the song", func() {
+		BeforeEach(func() {
+			Expect(createShareLink(map[string]any{}).Code).To(Equal(http.StatusOK))
+			Expect(createShareLink(map[string]any{"readOnly": false}).Code).To(Equal(http.StatusOK))
+
+			request := testing.RequestFactory{
+				Method: "DELETE",
+				Target: fmt.Sprintf("/songs/%s", songID),
+				Mods:   testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(songGateway.DeleteSong(c, songID)).To(Succeed())
+			Expect(response.Code).To(Equal(http.StatusOK))
+		})
+
+		It("removes the share links of the song", func() {
+			links := testing.ExpectSuccess(sharelinkstorage.NewDB(db).GetShareLinksForSong(context.Background(), songID))
+			Expect(links).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,161 @@
+package sharelinkstorage
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/cockroachdb/errors"
+	"github.com/guregu/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"time"
+)
+
+const (
+	ShareLinksTable = "ShareLinks"
+	idKey           = "id"
+	songIDKey       = "song_id"
+	songIDIndex     = "song_id-index"
+)
+
+type dbShareLink struct {
+	ID        string    `dynamo:"id"`
+	SongID    string    `dynamo:"song_id"`
+	Scope     string    `dynamo:"scope"`
+	CreatedAt time.Time `dynamo:"created_at"`
+	ExpiresAt time.Time `dynamo:"expires_at"`
+}
+
+func (d dbShareLink) toEntity() sharelinkentity.ShareLink {
+	return sharelinkentity.ShareLink{
+		ID:        d.ID,
+		SongID:    d.SongID,
+		Scope:     sharelinkentity.Scope(d.Scope),
+		CreatedAt: d.CreatedAt,
+		ExpiresAt: d.ExpiresAt,
+	}
+}
+
+type DB struct {
+	dynamoDB dynamolib.DynamoDBWrapper
+}
+
+func NewDB(dynamoDB dynamolib.DynamoDBWrapper) DB {
+	return DB{
+		dynamoDB: dynamoDB,
+	}
+}
+
+func (d DB) GetShareLink(ctx context.Context, linkID string) (sharelinkentity.ShareLink, error) {
+	if linkID == "" {
+		err := errors.New("Share link ID is empty")
+		return sharelinkentity.ShareLink{}, mark.Wrap(err, ShareLinkNotFoundMark, "No ID provided to fetch share link")
+	}
+
+	value := dbShareLink{}
+	err := d.dynamoDB.Table(ShareLinksTable).
+		Get(idKey, linkID).
+		OneWithContext(ctx, &value)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, dynamo.ErrNotFound):
+			return sharelinkentity.ShareLink{}, mark.Wrap(err, ShareLinkNotFoundMark, "Share link for this ID couldn't be found")
+		default:
+			return sharelinkentity.ShareLink{}, mark.Wrap(err, DefaultErrorMark, "Failed to fetch share link due to unknown data store error")
+		}
+	}
+
+	return value.toEntity(), nil
+}
+
+func (d DB) GetShareLinksForSong(ctx context.Context, songID string) ([]sharelinkentity.ShareLink, error) {
+	values := []dbShareLink{}
+	err := d.dynamoDB.Table(ShareLinksTable).
+		Get(songIDKey, songID).
+		Index(songIDIndex).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch share links for song ID")
+	}
+
+	links := []sharelinkentity.ShareLink{}
+	for _, value := range values {
+		links = append(links, value.toEntity())
+	}
+
+	return links, nil
+}
+
+func (d DB) CreateShareLink(ctx context.Context, link sharelinkentity.ShareLink) error {
+	if link.ID == "" || link.SongID == "" {
+		err := errors.New("Share link ID or song ID is empty")
+		return mark.Wrap(err, DefaultErrorMark, "Share link is missing its keys")
+	}
+
+	value := dbShareLink{
+		ID:        link.ID,
+		SongID:    link.SongID,
+		Scope:     string(link.Scope),
+		CreatedAt: link.CreatedAt,
+		ExpiresAt: link.ExpiresAt,
+	}
+
+	err := d.dynamoDB.Table(ShareLinksTable).Table.
+		Put(value).
+		If("attribute_not_exists(" + idKey + ")").
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to put share link")
+	}
+
+	return nil
+}
+
+// DeleteShareLinksForSong removes every link to the song, for when the song itself is deleted
+func (d DB) DeleteShareLinksForSong(ctx context.Context, songID string) error {
+	links, err := d.GetShareLinksForSong(ctx, songID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch share links to delete")
+	}
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	keys := []dynamo.Keyed{}
+	for _, link := range links {
+		keys = append(keys, dynamo.Keys{link.ID})
+	}
+
+	_, err = d.dynamoDB.Table(ShareLinksTable).
+		Batch(idKey).
+		Write().
+		Delete(keys...).
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete share links")
+	}
+
+	return nil
+}
+
+func (d DB) DeleteShareLink(ctx context.Context, linkID string) error {
+	err := d.dynamoDB.Table(ShareLinksTable).
+		Delete(idKey, linkID).
+		If("attribute_exists(" + idKey + ")").
+		RunWithContext(ctx)
+
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return mark.Wrap(err, ShareLinkNotFoundMark, "Failed to find share link to delete")
+		}
+
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete share link")
+	}
+
+	return nil
+}
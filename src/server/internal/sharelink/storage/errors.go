@@ -0,0 +1,8 @@
+package sharelinkstorage
+
+import "github.com/cockroachdb/errors/domains"
+
+var (
+	ShareLinkNotFoundMark = domains.New("share_link_not_found")
+	DefaultErrorMark      = domains.New("default_error")
+)
@@ -0,0 +1,36 @@
+package sharelinkusecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/cockroachdb/errors"
+	"strings"
+)
+
+const tokenSeparator = "."
+
+// tokens are the link ID followed by its HMAC, so a link ID on its own
+// can't be used to guess a working token, and the token doesn't need to be stored
+func (u Usecase) signToken(linkID string) string {
+	return linkID + tokenSeparator + u.signature(linkID)
+}
+
+func (u Usecase) verifyToken(token string) (string, error) {
+	linkID, signature, found := strings.Cut(token, tokenSeparator)
+	if !found || linkID == "" {
+		return "", errors.New("Share link token is malformed")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(u.signature(linkID))) {
+		return "", errors.New("Share link token has an invalid signature")
+	}
+
+	return linkID, nil
+}
+
+func (u Usecase) signature(linkID string) string {
+	mac := hmac.New(sha256.New, u.signingKey)
+	mac.Write([]byte(linkID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,233 @@
+package sharelinkusecase
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/markers"
+	"github.com/google/uuid"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/storage"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/usecase"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/track/usecase"
+	"sort"
+	"time"
+)
+
+const (
+	defaultLinkDuration = 7 * 24 * time.Hour
+	maxLinkDuration     = 90 * 24 * time.Hour
+)
+
+type Usecase struct {
+	db           sharelinkstorage.DB
+	songUsecase  songusecase.Usecase
+	trackUsecase trackusecase.Usecase
+	signingKey   []byte
+}
+
+func NewUsecase(db sharelinkstorage.DB, songUsecase songusecase.Usecase, trackUsecase trackusecase.Usecase, signingKey string) Usecase {
+	return Usecase{
+		db:           db,
+		songUsecase:  songUsecase,
+		trackUsecase: trackUsecase,
+		signingKey:   []byte(signingKey),
+	}
+}
+
+func (u Usecase) CreateShareLink(ctx context.Context, authHeader string, songID string, linkRequest sharelinkentity.ShareLinkRequest) (sharelinkentity.ShareLink, *api.Error) {
+	if apiErr := u.songUsecase.VerifySongOwnerBySongID(ctx, authHeader, songID); apiErr != nil {
+		return sharelinkentity.ShareLink{}, api.WrapError(apiErr, "Only the owner can share this song")
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(defaultLinkDuration)
+	if linkRequest.ExpiresAt != nil {
+		expiresAt = linkRequest.ExpiresAt.UTC()
+	}
+
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxLinkDuration)) {
+		err := errors.Newf("Share link expiry %s is out of range", expiresAt)
+		return sharelinkentity.ShareLink{}, api.CommitError(err,
+			sharelinkerrors.BadShareLinkDataCode,
+			"Share links must expire some time within the next 90 days")
+	}
+
+	scope := sharelinkentity.ReadOnlyScope
+	if linkRequest.ReadOnly != nil && !*linkRequest.ReadOnly {
+		scope = sharelinkentity.ReadWriteScope
+	}
+
+	link := sharelinkentity.ShareLink{
+		ID:        uuid.New().String(),
+		SongID:    songID,
+		Scope:     scope,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	err := u.db.CreateShareLink(ctx, link)
+	if err != nil {
+		return sharelinkentity.ShareLink{}, api.CommitError(errors.Wrap(err, "Failed to create share link in DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to create the share link")
+	}
+
+	link.Token = u.signToken(link.ID)
+	return link, nil
+}
+
+// GetShareLinks returns the links for the song that haven't been revoked, newest first.
+// Expired links are included so the owner can see what was shared
+func (u Usecase) GetShareLinks(ctx context.Context, authHeader string, songID string) ([]sharelinkentity.ShareLink, *api.Error) {
+	if apiErr := u.songUsecase.VerifySongOwnerBySongID(ctx, authHeader, songID); apiErr != nil {
+		return nil, api.WrapError(apiErr, "Only the owner can see the share links of this song")
+	}
+
+	links, err := u.db.GetShareLinksForSong(ctx, songID)
+	if err != nil {
+		return nil, api.CommitError(errors.Wrap(err, "Failed to get share links from DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to fetch the share links of this song")
+	}
+
+	for i := range links {
+		links[i].Token = u.signToken(links[i].ID)
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].CreatedAt.After(links[j].CreatedAt)
+	})
+
+	return links, nil
+}
+
+func (u Usecase) RevokeShareLink(ctx context.Context, authHeader string, songID string, linkID string) *api.Error {
+	if apiErr := u.songUsecase.VerifySongOwnerBySongID(ctx, authHeader, songID); apiErr != nil {
+		return api.WrapError(apiErr, "Only the owner can revoke the share links of this song")
+	}
+
+	link, apiErr := u.fetchShareLink(ctx, linkID)
+	if apiErr != nil {
+		return api.WrapError(apiErr, "Failed to fetch the share link to revoke")
+	}
+
+	// don't let the owner of one song revoke the links of another
+	if link.SongID != songID {
+		err := errors.New("Share link belongs to a different song")
+		return api.CommitError(err,
+			sharelinkerrors.ShareLinkNotFoundCode,
+			"The share link can't be found")
+	}
+
+	err := u.db.DeleteShareLink(ctx, linkID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to delete share link from DB")
+		switch {
+		case markers.Is(err, sharelinkstorage.ShareLinkNotFoundMark):
+			return api.CommitError(err,
+				sharelinkerrors.ShareLinkNotFoundCode,
+				"The share link can't be found")
+		default:
+			return api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to revoke the share link")
+		}
+	}
+
+	return nil
+}
+
+// GetSharedSong resolves a share link token to its song and tracklist,
+// the token stands in for the login so the song's visibility isn't checked
+func (u Usecase) GetSharedSong(ctx context.Context, token string) (sharelinkentity.SharedSong, *api.Error) {
+	link, apiErr := u.resolveToken(ctx, token)
+	if apiErr != nil {
+		return sharelinkentity.SharedSong{}, api.WrapError(apiErr, "Failed to resolve share link")
+	}
+
+	song, apiErr := u.songUsecase.GetSongWithoutAuth(ctx, link.SongID)
+	if apiErr != nil {
+		return sharelinkentity.SharedSong{}, api.WrapError(apiErr, "Failed to fetch the shared song")
+	}
+
+	tracklist, apiErr := u.trackUsecase.GetTrackListWithoutAuth(ctx, link.SongID)
+	if apiErr != nil {
+		return sharelinkentity.SharedSong{}, api.WrapError(apiErr, "Failed to fetch the shared tracklist")
+	}
+
+	return sharelinkentity.SharedSong{
+		Song:      song,
+		TrackList: tracklist,
+		Scope:     link.Scope,
+		ExpiresAt: link.ExpiresAt,
+	}, nil
+}
+
+// UpdateSharedSong saves the song with the access of an editor, if the link allows writing
+func (u Usecase) UpdateSharedSong(ctx context.Context, token string, song songentity.Song) (songentity.Song, *api.Error) {
+	link, apiErr := u.resolveToken(ctx, token)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to resolve share link")
+	}
+
+	if !link.Scope.CanWrite() {
+		err := errors.Newf("Share link has scope %s", link.Scope)
+		return songentity.Song{}, api.CommitError(err,
+			sharelinkerrors.ShareLinkReadOnlyCode,
+			"This link only allows viewing the song")
+	}
+
+	updatedSong, apiErr := u.songUsecase.UpdateSongWithoutAuth(ctx, link.SongID, song)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to save the shared song")
+	}
+
+	return updatedSong, nil
+}
+
+func (u Usecase) resolveToken(ctx context.Context, token string) (sharelinkentity.ShareLink, *api.Error) {
+	linkID, err := u.verifyToken(token)
+	if err != nil {
+		return sharelinkentity.ShareLink{}, api.CommitError(errors.Wrap(err, "Failed to verify share link token"),
+			sharelinkerrors.ShareLinkNotFoundCode,
+			"This link is invalid or has been revoked")
+	}
+
+	link, apiErr := u.fetchShareLink(ctx, linkID)
+	if apiErr != nil {
+		return sharelinkentity.ShareLink{}, api.WrapError(apiErr, "Failed to fetch share link")
+	}
+
+	if link.IsExpired(time.Now()) {
+		err := errors.Newf("Share link expired at %s", link.ExpiresAt)
+		return sharelinkentity.ShareLink{}, api.CommitError(err,
+			sharelinkerrors.ShareLinkExpiredCode,
+			"This link has expired. Please ask the owner for a new one")
+	}
+
+	return link, nil
+}
+
+func (u Usecase) fetchShareLink(ctx context.Context, linkID string) (sharelinkentity.ShareLink, *api.Error) {
+	link, err := u.db.GetShareLink(ctx, linkID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to get share link from DB")
+		switch {
+		case markers.Is(err, sharelinkstorage.ShareLinkNotFoundMark):
+			return sharelinkentity.ShareLink{}, api.CommitError(err,
+				sharelinkerrors.ShareLinkNotFoundCode,
+				"This link is invalid or has been revoked")
+		case markers.Is(err, sharelinkstorage.DefaultErrorMark):
+			fallthrough
+		default:
+			return sharelinkentity.ShareLink{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to fetch the share link")
+		}
+	}
+
+	return link, nil
+}
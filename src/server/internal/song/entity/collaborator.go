@@ -0,0 +1,39 @@
+package songentity
+
+type Role string
+
+const (
+	ViewerRole Role = "viewer"
+	EditorRole Role = "editor"
+	// OwnerRole is never given to a collaborator, it's only used
+	// to describe the access of the song's owner
+	OwnerRole Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	ViewerRole: 1,
+	EditorRole: 2,
+	OwnerRole:  3,
+}
+
+// Permits returns whether this role has at least the access of the required role
+func (r Role) Permits(requiredRole Role) bool {
+	return roleRanks[r] >= roleRanks[requiredRole]
+}
+
+func (r Role) IsCollaboratorRole() bool {
+	return r == ViewerRole || r == EditorRole
+}
+
+type Collaborator struct {
+	SongID string `json:"songId"`
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+}
+
+type CollaboratorInvite struct {
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
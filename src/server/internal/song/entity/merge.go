@@ -0,0 +1,50 @@
+package songentity
+
+import (
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/jsonlib"
+	"time"
+)
+
+// MergeConflicts is returned to the client when a stale save can't be merged automatically.
+// The paths point into the song JSON, e.g. ["elements", 3, "elements", 0, "chord"]
+type MergeConflicts struct {
+	BaseSavedAt    *time.Time      `json:"baseSavedAt"`
+	CurrentSavedAt *time.Time      `json:"currentSavedAt"`
+	Conflicts      []MergeConflict `json:"conflicts"`
+}
+
+type MergeConflict struct {
+	Path     []any `json:"path"`
+	Base     any   `json:"base"`
+	Current  any   `json:"current"`
+	Incoming any   `json:"incoming"`
+}
+
+// MergeSongs applies the changes between base and incoming on top of current.
+// Only the song content is merged, the defined fields are left for the caller to decide
+func MergeSongs(base Song, current Song, incoming Song) (Song, *MergeConflicts) {
+	mergedExtra, conflicts := jsonlib.ThreeWayMerge(base.Extra, current.Extra, incoming.Extra)
+
+	if len(conflicts) > 0 {
+		mergeConflicts := MergeConflicts{
+			BaseSavedAt:    base.Defined.LastSavedAt,
+			CurrentSavedAt: current.Defined.LastSavedAt,
+			Conflicts:      []MergeConflict{},
+		}
+
+		for _, conflict := range conflicts {
+			mergeConflicts.Conflicts = append(mergeConflicts.Conflicts, MergeConflict{
+				Path:     conflict.Path,
+				Base:     conflict.Base,
+				Current:  conflict.Ours,
+				Incoming: conflict.Theirs,
+			})
+		}
+
+		return Song{}, &mergeConflicts
+	}
+
+	merged := incoming
+	merged.Extra = mergedExtra
+	return merged, nil
+}
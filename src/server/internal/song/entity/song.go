@@ -1,6 +1,7 @@
 package songentity
 
 import (
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/jsonlib"
 	"time"
@@ -29,16 +30,53 @@ func (s *Song) CreateID() {
 	s.Defined.ID = uuid.New().String()
 }
 
+// SetSavedAtToNow also stamps the song with a new revision, since every save is kept as one
 func (s *Song) SetSavedAtToNow() {
+	now := time.Now().UTC()
+
 	// truncate to seconds because this will be consumed by the browser
 	// and browser dates have only millisecond resolution
+	savedAt := now.Truncate(time.Second)
+	s.Defined.LastSavedAt = &savedAt
 
-	now := time.Now().UTC().Truncate(time.Second)
-	s.Defined.LastSavedAt = &now
+	// lastSavedAt can't tell apart two saves within the same second, the revision can
+	s.Defined.Revision = fmt.Sprintf("%s_%s", now.Format(RevisionTimeFormat), uuid.New().String())
 }
 
+// RevisionTimeFormat is fixed width, so that revisions sort in the order they were saved
+const RevisionTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
 type SongFields struct {
 	ID          string     `json:"id"`
 	Owner       string     `json:"owner"`
 	LastSavedAt *time.Time `json:"lastSavedAt"`
+	// Revision is the key of the revision the song was saved as, sent back on the next save
+	// so that a stale save can be merged against the exact copy it started from
+	Revision   string     `json:"revision,omitempty"`
+	Visibility Visibility `json:"visibility,omitempty"`
+}
+
+type Visibility string
+
+const (
+	// PrivateVisibility songs can only be read by the owner and collaborators
+	PrivateVisibility Visibility = "private"
+	// UnlistedVisibility songs can be read by anyone who has the song ID.
+	// Songs that were saved before visibility existed have no visibility set, which is treated as unlisted
+	UnlistedVisibility Visibility = "unlisted"
+	// PublicVisibility songs can be read by anyone
+	PublicVisibility Visibility = "public"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case "", PrivateVisibility, UnlistedVisibility, PublicVisibility:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v Visibility) RequiresAuth() bool {
+	return v == PrivateVisibility
 }
@@ -5,8 +5,13 @@ import (
 )
 
 const (
-	SongNotFoundCode  = api.ErrorCode("song_not_found")
-	ExistingSongCode  = api.ErrorCode("create_song_exists")
-	BadSongDataCode   = api.ErrorCode("bad_song_data")
-	SongOverwriteCode = api.ErrorCode("update_song_overwrite")
+	SongNotFoundCode         = api.ErrorCode("song_not_found")
+	ExistingSongCode         = api.ErrorCode("create_song_exists")
+	BadSongDataCode          = api.ErrorCode("bad_song_data")
+	SongOverwriteCode        = api.ErrorCode("update_song_overwrite")
+	SongMergeConflictCode    = api.ErrorCode("update_song_merge_conflict")
+	CollaboratorNotFoundCode = api.ErrorCode("song_collaborator_not_found")
+	BadCollaboratorDataCode  = api.ErrorCode("bad_collaborator_data")
+	InviteeNotFoundCode      = api.ErrorCode("invitee_not_found")
+	RevisionNotFoundCode     = api.ErrorCode("song_revision_not_found")
 )
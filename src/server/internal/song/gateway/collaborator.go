@@ -0,0 +1,70 @@
+package songgateway
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/gateway"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/lib/request"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
+	"net/http"
+)
+
+func (g Gateway) GetCollaborators(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	collaborators, apiErr := g.usecase.GetCollaborators(ctx, authHeader, songID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, collaborators)
+}
+
+func (g Gateway) InviteCollaborator(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	invite := songentity.CollaboratorInvite{}
+	err := c.Bind(&invite)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to bind request body to collaborator invite")
+		apiErr := api.CommitError(err,
+			songerrors.BadCollaboratorDataCode,
+			"The collaborator invite received was malformed. Please contact the developer")
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	collaborator, apiErr := g.usecase.InviteCollaborator(ctx, authHeader, songID, invite)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, collaborator)
+}
+
+func (g Gateway) RevokeCollaborator(c echo.Context, songID string, userID string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	apiErr = g.usecase.RevokeCollaborator(ctx, authHeader, songID, userID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
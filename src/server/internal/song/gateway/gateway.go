@@ -25,7 +25,9 @@ func NewGateway(usecase songusecase.Usecase) Gateway {
 func (g Gateway) GetSong(c echo.Context, songID string) error {
 	ctx := request.Context(c)
 
-	song, apiErr := g.usecase.GetSong(ctx, songID)
+	authHeader := request.OptionalAuthHeader(c)
+
+	song, apiErr := g.usecase.GetSong(ctx, authHeader, songID)
 	if apiErr != nil {
 		return gateway.ErrorResponse(c, apiErr)
 	}
@@ -116,3 +118,45 @@ func (g Gateway) DeleteSong(c echo.Context, songID string) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+func (g Gateway) GetSongRevisions(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+
+	authHeader := request.OptionalAuthHeader(c)
+
+	revisions, apiErr := g.usecase.GetSongRevisions(ctx, authHeader, songID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (g Gateway) GetSongRevision(c echo.Context, songID string, revision string) error {
+	ctx := request.Context(c)
+
+	authHeader := request.OptionalAuthHeader(c)
+
+	song, apiErr := g.usecase.GetSongRevision(ctx, authHeader, songID, revision)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, song)
+}
+
+func (g Gateway) RestoreSongRevision(c echo.Context, songID string, revision string) error {
+	ctx := request.Context(c)
+
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	restoredSong, apiErr := g.usecase.RestoreSongRevision(ctx, authHeader, songID, revision)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, restoredSong)
+}
@@ -1,6 +1,7 @@
 package song_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -159,6 +160,121 @@ var _ = Describe("Song", func() {
 		})
 	})
 
+	Describe("Song visibility", func() {
+		var (
+			songID   string
+			songJSON map[string]any
+		)
+
+		var getSongAs = func(user *testing.User) *httptest.ResponseRecorder {
+			requestFactory := testing.RequestFactory{
+				Method:  "GET",
+				Target:  "/songs/:id",
+				JSONObj: nil,
+			}
+
+			if user != nil {
+				requestFactory.Mods.Add(testing.WithUserCred(*user))
+			}
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(requestFactory.MakeFake(), response)
+			Expect(songGateway.GetSong(c, songID)).To(Succeed())
+			return response
+		}
+
+		BeforeEach(func() {
+			songJSON = testing.LoadDemoSong()
+		})
+
+		JustBeforeEach(func() {
+			songID, _ = createSong(songJSON)
+		})
+
+		Describe("For a public song", func() {
+			BeforeEach(func() {
+				songJSON["visibility"] = "public"
+			})
+
+			It("can be fetched anonymously", func() {
+				response := getSongAs(nil)
+				Expect(response.Code).To(Equal(http.StatusOK))
+				song := testing.DecodeJSON[map[string]any](response.Body)
+				Expect(song["visibility"]).To(Equal("public"))
+			})
+		})
+
+		Describe("For a song with no visibility set", func() {
+			It("can be fetched anonymously", func() {
+				Expect(getSongAs(nil).Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Describe("For a private song", func() {
+			BeforeEach(func() {
+				songJSON["visibility"] = "private"
+			})
+
+			It("can be fetched by the owner", func() {
+				Expect(getSongAs(&testing.PrimaryUser).Code).To(Equal(http.StatusOK))
+			})
+
+			It("can't be fetched anonymously", func() {
+				response := getSongAs(nil)
+				Expect(response.Code).To(Equal(http.StatusBadRequest))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(auth.BadAuthorizationHeaderCode))
+			})
+
+			It("can't be fetched by other users", func() {
+				response := getSongAs(&testing.OtherUser)
+				Expect(response.Code).To(Equal(http.StatusForbidden))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(auth.WrongOwnerCode))
+			})
+
+			Describe("For a collaborator", func() {
+				JustBeforeEach(func() {
+					request := testing.RequestFactory{
+						Method:  "POST",
+						Target:  "/songs/:id/collaborators",
+						JSONObj: map[string]any{"email": testing.OtherUser.Email, "role": "editor"},
+						Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+					}.MakeFake()
+
+					response := httptest.NewRecorder()
+					c := testing.PrepareEchoContext(request, response)
+					Expect(songGateway.InviteCollaborator(c, songID)).To(Succeed())
+					Expect(response.Code).To(Equal(http.StatusOK))
+				})
+
+				It("can be fetched", func() {
+					Expect(getSongAs(&testing.OtherUser).Code).To(Equal(http.StatusOK))
+				})
+
+				It("can't have its visibility changed by the collaborator", func() {
+					song := testing.DecodeJSON[map[string]any](getSongAs(&testing.OtherUser).Body)
+					song["visibility"] = "public"
+
+					request := testing.RequestFactory{
+						Method:  "PUT",
+						Target:  "/songs/:id",
+						JSONObj: song,
+						Mods:    testing.RequestModifiers{testing.WithUserCred(testing.OtherUser)},
+					}.MakeFake()
+
+					response := httptest.NewRecorder()
+					c := testing.PrepareEchoContext(request, response)
+					Expect(songGateway.UpdateSong(c, songID)).To(Succeed())
+					Expect(response.Code).To(Equal(http.StatusOK))
+
+					updatedSong := testing.DecodeJSON[map[string]any](response.Body)
+					Expect(updatedSong["visibility"]).To(Equal("private"))
+				})
+			})
+		})
+	})
+
 	Describe("Get Song Summaries for User", func() {
 		Describe("Unauthorized", func() {
 			BeforeEach(func() {
@@ -387,6 +503,21 @@ var _ = Describe("Song", func() {
 					})
 				})
 
+				Describe("For an unrecognized visibility", func() {
+					BeforeEach(func() {
+						createSongPayload["visibility"] = "everyone"
+					})
+
+					It("fails with the right error code", func() {
+						resErr := testing.DecodeJSONError(response.Body)
+						Expect(resErr.Code).To(BeEquivalentTo(songerrors.BadSongDataCode))
+					})
+
+					It("fails with the right status code", func() {
+						Expect(response.Code).To(Equal(http.StatusBadRequest))
+					})
+				})
+
 				Describe("For a malformed song payload", func() {
 					BeforeEach(func() {
 						createSongPayload["id"] = 5
@@ -593,6 +724,140 @@ var _ = Describe("Song", func() {
 				})
 			})
 
+			Describe("For a stale save based on an older revision", func() {
+				var (
+					intermediateTitle string
+				)
+
+				BeforeEach(func() {
+					createdSong := getSong(songID)
+					songUpdate["lastSavedAt"] = createdSong["lastSavedAt"]
+					songUpdate["revision"] = createdSong["revision"]
+				})
+
+				saveIntermediateCopyNow := func(modify func(song map[string]any)) {
+					intermediateSong := getSong(songID)
+					modify(intermediateSong)
+
+					request := testing.RequestFactory{
+						Method:  "PUT",
+						Target:  "/songs/:id",
+						JSONObj: intermediateSong,
+						Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+					}.MakeFake()
+
+					response := httptest.NewRecorder()
+					c := testing.PrepareEchoContext(request, response)
+					err := songGateway.UpdateSong(c, songID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(response.Code).To(Equal(http.StatusOK))
+				}
+
+				saveIntermediateCopy := func(modify func(song map[string]any)) {
+					// make sure the intermediate save lands on a later second than the base
+					time.Sleep(time.Second)
+					saveIntermediateCopyNow(modify)
+				}
+
+				Describe("With changes that don't overlap", func() {
+					BeforeEach(func() {
+						saveIntermediateCopy(func(song map[string]any) {
+							metadata := testing.ExpectType[map[string]any](song["metadata"])
+							metadata["performedBy"] = "Somebody else"
+						})
+					})
+
+					It("succeeds", func() {
+						Expect(response.Code).To(Equal(http.StatusOK))
+					})
+
+					It("saves the changes from both copies", func() {
+						fetchedSong := getSong(songID)
+						metadata := testing.ExpectType[map[string]any](fetchedSong["metadata"])
+						Expect(metadata["title"]).To(Equal("Totally gonna give you up"))
+						Expect(metadata["performedBy"]).To(Equal("Somebody else"))
+						Expect(getFirstBlock(fetchedSong)["chord"]).To(Equal("Dm7"))
+					})
+				})
+
+				Describe("With changes that overlap", func() {
+					BeforeEach(func() {
+						intermediateTitle = "Never gonna give you up, again"
+						saveIntermediateCopy(func(song map[string]any) {
+							metadata := testing.ExpectType[map[string]any](song["metadata"])
+							metadata["title"] = intermediateTitle
+						})
+					})
+
+					It("rejects the save with the right error code", func() {
+						resErr := testing.DecodeJSONError(response.Body)
+						Expect(resErr.Code).To(BeEquivalentTo(songerrors.SongMergeConflictCode))
+					})
+
+					It("rejects the save with the right status code", func() {
+						Expect(response.Code).To(Equal(http.StatusConflict))
+					})
+
+					It("returns the conflicts", func() {
+						resErr := testing.DecodeJSONError(response.Body)
+						details := testing.ExpectType[map[string]any](resErr.Details)
+						conflicts := testing.ExpectType[[]any](details["conflicts"])
+						Expect(conflicts).To(ConsistOf(map[string]any{
+							"path":     []any{"metadata", "title"},
+							"base":     "Never Gonna Give You Up x Plastic Love",
+							"current":  intermediateTitle,
+							"incoming": "Totally gonna give you up",
+						}))
+					})
+
+					It("doesn't save the song", func() {
+						fetchedSong := getSong(songID)
+						metadata := testing.ExpectType[map[string]any](fetchedSong["metadata"])
+						Expect(metadata["title"]).To(Equal(intermediateTitle))
+					})
+				})
+
+				Describe("With a copy saved within the same second", func() {
+					BeforeEach(func() {
+						intermediateTitle = "Never gonna give you up, again"
+						saveIntermediateCopyNow(func(song map[string]any) {
+							metadata := testing.ExpectType[map[string]any](song["metadata"])
+							metadata["title"] = intermediateTitle
+						})
+					})
+
+					It("still catches that the save is stale", func() {
+						resErr := testing.DecodeJSONError(response.Body)
+						Expect(resErr.Code).To(BeEquivalentTo(songerrors.SongMergeConflictCode))
+					})
+
+					It("doesn't save the song", func() {
+						fetchedSong := getSong(songID)
+						metadata := testing.ExpectType[map[string]any](fetchedSong["metadata"])
+						Expect(metadata["title"]).To(Equal(intermediateTitle))
+					})
+				})
+
+				Describe("From a client that only has the save time it loaded", func() {
+					BeforeEach(func() {
+						delete(songUpdate, "revision")
+						saveIntermediateCopy(func(song map[string]any) {
+							metadata := testing.ExpectType[map[string]any](song["metadata"])
+							metadata["performedBy"] = "Somebody else"
+						})
+					})
+
+					It("merges against the revision saved at that time", func() {
+						Expect(response.Code).To(Equal(http.StatusOK))
+
+						fetchedSong := getSong(songID)
+						metadata := testing.ExpectType[map[string]any](fetchedSong["metadata"])
+						Expect(metadata["title"]).To(Equal("Totally gonna give you up"))
+						Expect(metadata["performedBy"]).To(Equal("Somebody else"))
+					})
+				})
+			})
+
 			Describe("For a song that has an old last saved at timestamp", func() {
 				BeforeEach(func() {
 					anHourAgo := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
@@ -611,6 +876,450 @@ var _ = Describe("Song", func() {
 		})
 	})
 
+	Describe("Song Revisions", func() {
+		var (
+			songID          string
+			createdSong     map[string]any
+			updatedSong     map[string]any
+			originalTitle   string
+			updatedTitle    string
+			getRevisionList = func() []map[string]any {
+				request := testing.RequestFactory{
+					Method:  "GET",
+					Target:  fmt.Sprintf("/songs/%s/revisions", songID),
+					JSONObj: nil,
+				}.MakeFake()
+
+				response := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, response)
+				err := songGateway.GetSongRevisions(c, songID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.Code).To(Equal(http.StatusOK))
+
+				return testing.DecodeJSON[[]map[string]any](response.Body)
+			}
+		)
+
+		var saveSong = func(lastSavedAt any, title string) map[string]any {
+			songUpdate := testing.LoadDemoSong()
+			songUpdate["id"] = songID
+			songUpdate["lastSavedAt"] = lastSavedAt
+			testing.ExpectType[map[string]any](songUpdate["metadata"])["title"] = title
+
+			request := testing.RequestFactory{
+				Method:  "PUT",
+				Target:  fmt.Sprintf("/songs/%s", songID),
+				JSONObj: songUpdate,
+				Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			err := songGateway.UpdateSong(c, songID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			return testing.DecodeJSON[map[string]any](response.Body)
+		}
+
+		BeforeEach(func() {
+			songID, createdSong = createSong(testing.LoadDemoSong())
+			originalTitle = testing.ExpectType[string](testing.ExpectType[map[string]any](createdSong["metadata"])["title"])
+
+			// a revision fetched by its save time is the latest save within that second,
+			// so make sure the update lands on a different one
+			time.Sleep(time.Second)
+
+			updatedTitle = "Totally gonna give you up"
+			updatedSong = saveSong(createdSong["lastSavedAt"], updatedTitle)
+		})
+
+		Describe("List revisions", func() {
+			It("returns a revision for each save, newest first", func() {
+				revisions := getRevisionList()
+				Expect(revisions).To(HaveLen(2))
+				Expect(revisions[0]["lastSavedAt"]).To(Equal(updatedSong["lastSavedAt"]))
+				Expect(revisions[1]["lastSavedAt"]).To(Equal(createdSong["lastSavedAt"]))
+			})
+
+			It("doesn't return the body of the revisions", func() {
+				for _, revision := range getRevisionList() {
+					Expect(revision).NotTo(HaveKey("elements"))
+				}
+			})
+
+			It("returns the key of each revision", func() {
+				revisions := getRevisionList()
+				Expect(revisions[0]).To(HaveKey("revision"))
+				Expect(revisions[1]).To(HaveKey("revision"))
+				Expect(revisions[0]["revision"]).NotTo(Equal(revisions[1]["revision"]))
+			})
+
+			Describe("For saves within the same second", func() {
+				BeforeEach(func() {
+					firstSave := saveSong(updatedSong["lastSavedAt"], "Never gonna let you down")
+					saveSong(firstSave["lastSavedAt"], "Never gonna run around")
+				})
+
+				It("keeps a revision for each of them", func() {
+					revisions := getRevisionList()
+					Expect(revisions).To(HaveLen(4))
+
+					revisionKeys := map[any]bool{}
+					for _, revision := range revisions {
+						revisionKeys[revision["revision"]] = true
+					}
+					Expect(revisionKeys).To(HaveLen(4))
+				})
+			})
+		})
+
+		Describe("Get revision", func() {
+			var (
+				revision string
+				response *httptest.ResponseRecorder
+			)
+
+			BeforeEach(func() {
+				revision = testing.ExpectType[string](createdSong["lastSavedAt"])
+			})
+
+			JustBeforeEach(func() {
+				request := testing.RequestFactory{
+					Method:  "GET",
+					Target:  "/songs/:id/revisions/:rev",
+					JSONObj: nil,
+				}.MakeFake()
+
+				response = httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, response)
+				err := songGateway.GetSongRevision(c, songID, revision)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the song as it was saved at that time", func() {
+				Expect(response.Code).To(Equal(http.StatusOK))
+				fetchedRevision := testing.DecodeJSON[map[string]any](response.Body)
+				Expect(fetchedRevision).To(Equal(createdSong))
+			})
+
+			Describe("By the revision key from the list", func() {
+				BeforeEach(func() {
+					revisions := getRevisionList()
+					revision = testing.ExpectType[string](revisions[len(revisions)-1]["revision"])
+				})
+
+				It("returns the song as it was saved in that revision", func() {
+					Expect(response.Code).To(Equal(http.StatusOK))
+					fetchedRevision := testing.DecodeJSON[map[string]any](response.Body)
+					Expect(fetchedRevision).To(Equal(createdSong))
+				})
+			})
+
+			Describe("For a revision that doesn't exist", func() {
+				BeforeEach(func() {
+					revision = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+				})
+
+				It("fails with the right error code", func() {
+					resErr := testing.DecodeJSONError(response.Body)
+					Expect(resErr.Code).To(BeEquivalentTo(songerrors.RevisionNotFoundCode))
+				})
+
+				It("fails with the right status code", func() {
+					Expect(response.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Describe("For a malformed revision", func() {
+				BeforeEach(func() {
+					revision = "boat"
+				})
+
+				It("fails with the right error code", func() {
+					resErr := testing.DecodeJSONError(response.Body)
+					Expect(resErr.Code).To(BeEquivalentTo(songerrors.RevisionNotFoundCode))
+				})
+
+				It("fails with the right status code", func() {
+					Expect(response.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+		})
+
+		Describe("Restore revision", func() {
+			var revision string
+
+			BeforeEach(func() {
+				revision = testing.ExpectType[string](createdSong["lastSavedAt"])
+			})
+
+			Describe("Unpermitted requests", func() {
+				BeforeEach(func() {
+					authtest.Endpoint = func(c echo.Context) error {
+						return songGateway.RestoreSongRevision(c, songID, revision)
+					}
+				})
+
+				authtest.ItRejectsUnpermittedRequests("POST", "/songs/:id/revisions/:rev/restore")
+			})
+
+			Describe("Authorized", func() {
+				var response *httptest.ResponseRecorder
+
+				BeforeEach(func() {
+					time.Sleep(time.Second)
+
+					request := testing.RequestFactory{
+						Method:  "POST",
+						Target:  "/songs/:id/revisions/:rev/restore",
+						JSONObj: nil,
+						Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+					}.MakeFake()
+
+					response = httptest.NewRecorder()
+					c := testing.PrepareEchoContext(request, response)
+					err := songGateway.RestoreSongRevision(c, songID, revision)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("succeeds", func() {
+					Expect(response.Code).To(Equal(http.StatusOK))
+				})
+
+				It("restores the content of the revision", func() {
+					restoredSong := testing.DecodeJSON[map[string]any](response.Body)
+					fetchedSong := getSong(songID)
+					Expect(fetchedSong).To(Equal(restoredSong))
+
+					fetchedTitle := testing.ExpectType[map[string]any](fetchedSong["metadata"])["title"]
+					Expect(fetchedTitle).To(Equal(originalTitle))
+					Expect(fetchedTitle).NotTo(Equal(updatedTitle))
+
+					testing.ExpectJSONEqualExceptLastSavedAt(restoredSong, createdSong)
+				})
+
+				It("records the restore as a new revision", func() {
+					restoredSong := testing.DecodeJSON[map[string]any](response.Body)
+					revisions := getRevisionList()
+					Expect(revisions).To(HaveLen(3))
+					Expect(revisions[0]["lastSavedAt"]).To(Equal(restoredSong["lastSavedAt"]))
+				})
+			})
+		})
+
+		Describe("Deleting the song", func() {
+			BeforeEach(func() {
+				request := testing.RequestFactory{
+					Method:  "DELETE",
+					Target:  "/songs/:id",
+					JSONObj: nil,
+					Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+				}.MakeFake()
+
+				response := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, response)
+				err := songGateway.DeleteSong(c, songID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.Code).To(Equal(http.StatusOK))
+			})
+
+			It("removes the revisions too", func() {
+				revisions := testing.ExpectSuccess(songstorage.NewDB(db).GetSongRevisions(context.Background(), songID))
+				Expect(revisions).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Collaborators", func() {
+		var (
+			songID string
+		)
+
+		var doRequest = func(method string, body any, user *testing.User, endpoint func(c echo.Context) error) *httptest.ResponseRecorder {
+			requestFactory := testing.RequestFactory{
+				Method:  method,
+				Target:  "/songs/:id/collaborators",
+				JSONObj: body,
+			}
+
+			if user != nil {
+				requestFactory.Mods.Add(testing.WithUserCred(*user))
+			}
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(requestFactory.MakeFake(), response)
+			Expect(endpoint(c)).To(Succeed())
+			return response
+		}
+
+		var invite = func(email string, role string) *httptest.ResponseRecorder {
+			body := map[string]any{"email": email, "role": role}
+			return doRequest("POST", body, &testing.PrimaryUser, func(c echo.Context) error {
+				return songGateway.InviteCollaborator(c, songID)
+			})
+		}
+
+		var listCollaborators = func(user testing.User) []map[string]any {
+			response := doRequest("GET", nil, &user, func(c echo.Context) error {
+				return songGateway.GetCollaborators(c, songID)
+			})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			return testing.DecodeJSON[[]map[string]any](response.Body)
+		}
+
+		var updateSongAs = func(user testing.User) *httptest.ResponseRecorder {
+			song := getSong(songID)
+			testing.ExpectType[map[string]any](song["metadata"])["title"] = "Edited by a collaborator"
+			return doRequest("PUT", song, &user, func(c echo.Context) error {
+				return songGateway.UpdateSong(c, songID)
+			})
+		}
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+		})
+
+		Describe("Inviting", func() {
+			Describe("Unpermitted requests", func() {
+				BeforeEach(func() {
+					authtest.Endpoint = func(c echo.Context) error {
+						return songGateway.InviteCollaborator(c, songID)
+					}
+					authtest.JSONBody = map[string]any{"email": testing.OtherUser.Email, "role": "editor"}
+				})
+
+				authtest.ItRejectsUnpermittedRequests("POST", "/songs/:id/collaborators")
+			})
+
+			Describe("For an existing user", func() {
+				var response *httptest.ResponseRecorder
+
+				BeforeEach(func() {
+					response = invite(testing.OtherUser.Email, "viewer")
+				})
+
+				It("succeeds", func() {
+					Expect(response.Code).To(Equal(http.StatusOK))
+				})
+
+				It("adds the user as a collaborator", func() {
+					Expect(listCollaborators(testing.PrimaryUser)).To(ConsistOf(map[string]any{
+						"songId": songID,
+						"userId": testing.OtherUser.ID,
+						"email":  testing.OtherUser.Email,
+						"name":   testing.OtherUser.Name,
+						"role":   "viewer",
+					}))
+				})
+
+				It("lets the collaborator see the collaborators", func() {
+					Expect(listCollaborators(testing.OtherUser)).To(HaveLen(1))
+				})
+
+				It("changes the role when invited again", func() {
+					Expect(invite(testing.OtherUser.Email, "editor").Code).To(Equal(http.StatusOK))
+					collaborators := listCollaborators(testing.PrimaryUser)
+					Expect(collaborators).To(HaveLen(1))
+					Expect(collaborators[0]["role"]).To(Equal("editor"))
+				})
+			})
+
+			Describe("For an email without an account", func() {
+				It("fails with the right error", func() {
+					response := invite(testing.NoAccountUser.Email, "editor")
+					Expect(response.Code).To(Equal(http.StatusNotFound))
+					resErr := testing.DecodeJSONError(response.Body)
+					Expect(resErr.Code).To(BeEquivalentTo(songerrors.InviteeNotFoundCode))
+				})
+			})
+
+			Describe("For an invalid role", func() {
+				It("fails with the right error", func() {
+					response := invite(testing.OtherUser.Email, "owner")
+					Expect(response.Code).To(Equal(http.StatusBadRequest))
+					resErr := testing.DecodeJSONError(response.Body)
+					Expect(resErr.Code).To(BeEquivalentTo(songerrors.BadCollaboratorDataCode))
+				})
+			})
+		})
+
+		Describe("Role based access", func() {
+			Describe("For an editor", func() {
+				BeforeEach(func() {
+					Expect(invite(testing.OtherUser.Email, "editor").Code).To(Equal(http.StatusOK))
+				})
+
+				It("allows updating the song", func() {
+					response := updateSongAs(testing.OtherUser)
+					Expect(response.Code).To(Equal(http.StatusOK))
+				})
+
+				It("doesn't allow deleting the song", func() {
+					response := doRequest("DELETE", nil, &testing.OtherUser, func(c echo.Context) error {
+						return songGateway.DeleteSong(c, songID)
+					})
+					Expect(response.Code).To(Equal(http.StatusForbidden))
+				})
+
+				It("doesn't allow inviting others", func() {
+					body := map[string]any{"email": testing.PrimaryUser.Email, "role": "viewer"}
+					response := doRequest("POST", body, &testing.OtherUser, func(c echo.Context) error {
+						return songGateway.InviteCollaborator(c, songID)
+					})
+					Expect(response.Code).To(Equal(http.StatusForbidden))
+				})
+			})
+
+			Describe("For a viewer", func() {
+				BeforeEach(func() {
+					Expect(invite(testing.OtherUser.Email, "viewer").Code).To(Equal(http.StatusOK))
+				})
+
+				It("doesn't allow updating the song", func() {
+					response := updateSongAs(testing.OtherUser)
+					Expect(response.Code).To(Equal(http.StatusForbidden))
+					resErr := testing.DecodeJSONError(response.Body)
+					Expect(resErr.Code).To(BeEquivalentTo(auth.InsufficientRoleCode))
+				})
+			})
+		})
+
+		Describe("Revoking", func() {
+			var revoke = func(user testing.User, userID string) *httptest.ResponseRecorder {
+				return doRequest("DELETE", nil, &user, func(c echo.Context) error {
+					return songGateway.RevokeCollaborator(c, songID, userID)
+				})
+			}
+
+			BeforeEach(func() {
+				Expect(invite(testing.OtherUser.Email, "editor").Code).To(Equal(http.StatusOK))
+			})
+
+			It("removes the collaborator's access", func() {
+				Expect(revoke(testing.PrimaryUser, testing.OtherUser.ID).Code).To(Equal(http.StatusOK))
+				Expect(listCollaborators(testing.PrimaryUser)).To(BeEmpty())
+
+				response := updateSongAs(testing.OtherUser)
+				Expect(response.Code).To(Equal(http.StatusForbidden))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(auth.WrongOwnerCode))
+			})
+
+			It("lets a collaborator leave on their own", func() {
+				Expect(revoke(testing.OtherUser, testing.OtherUser.ID).Code).To(Equal(http.StatusOK))
+				Expect(listCollaborators(testing.PrimaryUser)).To(BeEmpty())
+			})
+
+			It("fails for a user that isn't a collaborator", func() {
+				response := revoke(testing.PrimaryUser, testing.UnverifiedUserInDB.ID)
+				Expect(response.Code).To(Equal(http.StatusNotFound))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(songerrors.CollaboratorNotFoundCode))
+			})
+		})
+	})
+
 	Describe("Delete Song", func() {
 		var (
 			songID string
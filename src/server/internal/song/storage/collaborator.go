@@ -0,0 +1,138 @@
+package songstorage
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/guregu/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+)
+
+const (
+	SongCollaboratorsTable = "SongCollaborators"
+	songIDKey              = "song_id"
+	userIDKey              = "user_id"
+)
+
+type dbCollaborator struct {
+	SongID string `dynamo:"song_id"`
+	UserID string `dynamo:"user_id"`
+	Email  string `dynamo:"email"`
+	Name   string `dynamo:"name"`
+	Role   string `dynamo:"role"`
+}
+
+func (d dbCollaborator) toEntity() songentity.Collaborator {
+	return songentity.Collaborator{
+		SongID: d.SongID,
+		UserID: d.UserID,
+		Email:  d.Email,
+		Name:   d.Name,
+		Role:   songentity.Role(d.Role),
+	}
+}
+
+func (d DB) GetCollaborators(ctx context.Context, songID string) ([]songentity.Collaborator, error) {
+	values := []dbCollaborator{}
+	err := d.dynamoDB.Table(SongCollaboratorsTable).
+		Get(songIDKey, songID).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch collaborators for song ID")
+	}
+
+	collaborators := []songentity.Collaborator{}
+	for _, value := range values {
+		collaborators = append(collaborators, value.toEntity())
+	}
+
+	return collaborators, nil
+}
+
+func (d DB) GetCollaborator(ctx context.Context, songID string, userID string) (songentity.Collaborator, error) {
+	value := dbCollaborator{}
+	err := d.dynamoDB.Table(SongCollaboratorsTable).
+		Get(songIDKey, songID).
+		Range(userIDKey, dynamo.Equal, userID).
+		OneWithContext(ctx, &value)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, dynamo.ErrNotFound):
+			return songentity.Collaborator{}, mark.Wrap(err, CollaboratorNotFoundMark, "User is not a collaborator of this song")
+		default:
+			return songentity.Collaborator{}, mark.Wrap(err, DefaultErrorMark, "Failed to fetch collaborator")
+		}
+	}
+
+	return value.toEntity(), nil
+}
+
+func (d DB) SetCollaborator(ctx context.Context, collaborator songentity.Collaborator) error {
+	if collaborator.SongID == "" || collaborator.UserID == "" {
+		err := errors.New("Song ID or user ID is empty")
+		return mark.Wrap(err, DefaultErrorMark, "Collaborator is missing its keys")
+	}
+
+	value := dbCollaborator{
+		SongID: collaborator.SongID,
+		UserID: collaborator.UserID,
+		Email:  collaborator.Email,
+		Name:   collaborator.Name,
+		Role:   string(collaborator.Role),
+	}
+
+	err := d.dynamoDB.Table(SongCollaboratorsTable).Table.Put(value).RunWithContext(ctx)
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to put collaborator")
+	}
+
+	return nil
+}
+
+func (d DB) DeleteCollaborator(ctx context.Context, songID string, userID string) error {
+	err := d.dynamoDB.Table(SongCollaboratorsTable).
+		Delete(songIDKey, songID).
+		Range(userIDKey, userID).
+		If("attribute_exists(" + userIDKey + ")").
+		RunWithContext(ctx)
+
+	if err != nil {
+		if conditionalCheckFailed(err) {
+			return mark.Wrap(err, CollaboratorNotFoundMark, "Failed to find collaborator to delete")
+		}
+
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete collaborator")
+	}
+
+	return nil
+}
+
+func (d DB) deleteAllCollaborators(ctx context.Context, songID string) error {
+	collaborators, err := d.GetCollaborators(ctx, songID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch collaborators to delete")
+	}
+
+	if len(collaborators) == 0 {
+		return nil
+	}
+
+	keys := []dynamo.Keyed{}
+	for _, collaborator := range collaborators {
+		keys = append(keys, dynamo.Keys{collaborator.SongID, collaborator.UserID})
+	}
+
+	_, err = d.dynamoDB.Table(SongCollaboratorsTable).
+		Batch(songIDKey, userIDKey).
+		Write().
+		Delete(keys...).
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete collaborators")
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/markers"
 	"github.com/guregu/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/sharelink/storage"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
@@ -17,7 +18,10 @@ const (
 	existingSongCondition = "attribute_exists(" + idKey + ")"
 	lastSavedAtField      = "lastSavedAt"
 	metadataField         = "metadata"
+	visibilityField       = "visibility"
 	ownerIndex            = "owner-index"
+
+	conditionalCheckFailedReason = "ConditionalCheckFailed"
 )
 
 type DB struct {
@@ -66,7 +70,7 @@ func (d DB) GetSongSummariesForUser(ctx context.Context, ownerID string) ([]song
 	err := d.dynamoDB.Table(SongsTable).
 		Get(ownerKey, ownerID).
 		Index(ownerIndex).
-		Project(idKey, ownerKey, lastSavedAtField, metadataField).
+		Project(idKey, ownerKey, lastSavedAtField, metadataField, visibilityField).
 		AllWithContext(ctx, &values)
 
 	if err != nil {
@@ -147,7 +151,19 @@ func (d DB) putSong(ctx context.Context, song songentity.Song, expectSongExists
 		putExpr = putExpr.If(newSongCondition)
 	}
 
-	return putExpr.RunWithContext(ctx)
+	// every accepted save is also kept as an immutable revision, under the key the song
+	// was stamped with, written in the same transaction so that the two can't diverge
+	if song.Defined.Revision == "" {
+		err := errors.New("Song revision is empty")
+		return mark.Wrap(err, DefaultErrorMark, "No revision provided to save the song as")
+	}
+
+	revisionExpr := d.dynamoDB.Table(SongRevisionsTable).Put(dbObject)
+
+	return d.dynamoDB.WriteTx().
+		Put(putExpr).
+		Put(revisionExpr).
+		RunWithContext(ctx)
 }
 
 func (d DB) DeleteSong(ctx context.Context, songID string) error {
@@ -167,10 +183,32 @@ func (d DB) DeleteSong(ctx context.Context, songID string) error {
 		return mark.Wrap(err, DefaultErrorMark, "Failed to delete song")
 	}
 
+	if err := d.deleteAllRevisions(ctx, songID); err != nil {
+		return errors.Wrap(err, "Failed to delete the revisions of the song")
+	}
+
+	if err := d.deleteAllCollaborators(ctx, songID); err != nil {
+		return errors.Wrap(err, "Failed to delete the collaborators of the song")
+	}
+
+	if err := sharelinkstorage.NewDB(d.dynamoDB).DeleteShareLinksForSong(ctx, songID); err != nil {
+		return errors.Wrap(err, "Failed to delete the share links of the song")
+	}
+
 	return nil
 }
 
 func conditionalCheckFailed(err error) bool {
-	_, ok := err.(*dynamodb.ConditionalCheckFailedException)
-	return ok
+	switch typedErr := err.(type) {
+	case *dynamodb.ConditionalCheckFailedException:
+		return true
+	case *dynamodb.TransactionCanceledException:
+		for _, reason := range typedErr.CancellationReasons {
+			if reason.Code != nil && *reason.Code == conditionalCheckFailedReason {
+				return true
+			}
+		}
+	}
+
+	return false
 }
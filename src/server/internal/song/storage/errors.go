@@ -3,8 +3,11 @@ package songstorage
 import "github.com/cockroachdb/errors/domains"
 
 var (
-	SongUnmarshalMark     = domains.New("song_unmarshal_fail")
-	SongNotFoundMark      = domains.New("song_not_found")
-	SongAlreadyExistsMark = domains.New("song_already_exists")
-	DefaultErrorMark      = domains.New("default_error")
+	SongUnmarshalMark        = domains.New("song_unmarshal_fail")
+	SongNotFoundMark         = domains.New("song_not_found")
+	SongAlreadyExistsMark    = domains.New("song_already_exists")
+	RevisionNotFoundMark     = domains.New("song_revision_not_found")
+	AmbiguousRevisionMark    = domains.New("song_revision_ambiguous")
+	CollaboratorNotFoundMark = domains.New("song_collaborator_not_found")
+	DefaultErrorMark         = domains.New("default_error")
 )
@@ -0,0 +1,208 @@
+package songstorage
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/markers"
+	"github.com/guregu/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"time"
+)
+
+const (
+	SongRevisionsTable = "SongRevisions"
+	revisionKey        = "revision"
+
+	// savedAtRevisionWindow is how far after its lastSavedAt a revision's key can be.
+	// Revisions from before the song carried its revision were keyed a little after it was stamped
+	savedAtRevisionWindow = 2 * time.Second
+)
+
+type dbRevisionKey struct {
+	ID          string `dynamo:"id"`
+	Revision    string `dynamo:"revision"`
+	LastSavedAt string `dynamo:"lastSavedAt"`
+}
+
+func (d DB) GetSongRevisions(ctx context.Context, songID string) ([]songentity.SongSummary, error) {
+	values := []dbSong{}
+	err := d.dynamoDB.Table(SongRevisionsTable).
+		Get(idKey, songID).
+		Project(idKey, ownerKey, lastSavedAtField, metadataField, revisionKey).
+		Order(dynamo.Descending).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch revisions for song ID")
+	}
+
+	summaries := []songentity.SongSummary{}
+	for _, value := range values {
+		summary := songentity.SongSummary{}
+		err := summary.FromMap(value)
+		if err != nil {
+			return nil, mark.Wrap(err,
+				SongUnmarshalMark,
+				"Failed to unmarshal song revision into its entity form")
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func (d DB) GetSongRevision(ctx context.Context, songID string, revision string) (songentity.Song, error) {
+	value := dbSong{}
+	err := d.dynamoDB.Table(SongRevisionsTable).
+		Get(idKey, songID).
+		Range(revisionKey, dynamo.Equal, revision).
+		OneWithContext(ctx, &value)
+
+	if err != nil {
+		switch {
+		case markers.Is(err, SongUnmarshalMark):
+			return songentity.Song{}, err
+		case errors.Is(err, dynamo.ErrNotFound):
+			return songentity.Song{}, mark.Wrap(err, RevisionNotFoundMark, "Song revision for this ID couldn't be found")
+		default:
+			return songentity.Song{}, mark.Wrap(err, DefaultErrorMark, "Failed to fetch song revision due to unknown data store error")
+		}
+	}
+
+	return revisionToSong(value)
+}
+
+// GetSongRevisionSavedAt looks up a revision by its lastSavedAt, for clients that don't have its
+// revision key. Since revision keys start with the save time, only the revisions around that second are
+// read. lastSavedAt only has second resolution, so if more than one revision was saved within that
+// second, there's no telling which one is meant and the lookup fails rather than guess
+func (d DB) GetSongRevisionSavedAt(ctx context.Context, songID string, savedAt time.Time) (songentity.Song, error) {
+	savedAt = savedAt.UTC()
+
+	values := []dbSong{}
+	err := d.dynamoDB.Table(SongRevisionsTable).
+		Get(idKey, songID).
+		Range(revisionKey, dynamo.Between,
+			savedAt.Format(songentity.RevisionTimeFormat),
+			savedAt.Add(savedAtRevisionWindow).Format(songentity.RevisionTimeFormat)).
+		Filter("$ = ?", lastSavedAtField, formatSavedAt(savedAt)).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		if markers.Is(err, SongUnmarshalMark) {
+			return songentity.Song{}, err
+		}
+
+		return songentity.Song{}, mark.Wrap(err, DefaultErrorMark, "Failed to fetch song revision due to unknown data store error")
+	}
+
+	switch len(values) {
+	case 0:
+		err := errors.Newf("No revision was saved at %s", formatSavedAt(savedAt))
+		return songentity.Song{}, mark.Wrap(err, RevisionNotFoundMark, "Song revision for this ID and time couldn't be found")
+	case 1:
+		return revisionToSong(values[0])
+	default:
+		err := errors.Newf("%d revisions were saved at %s", len(values), formatSavedAt(savedAt))
+		return songentity.Song{}, mark.Wrap(err, AmbiguousRevisionMark, "Song revision for this ID and time can't be told apart from the others")
+	}
+}
+
+func revisionToSong(value dbSong) (songentity.Song, error) {
+	song := songentity.Song{}
+	err := song.FromMap(value)
+	if err != nil {
+		return songentity.Song{}, mark.Wrap(err, SongUnmarshalMark, "Failed to unmarshal song revision into its entity form")
+	}
+
+	return song, nil
+}
+
+// PruneSongRevisions removes revisions beyond the newest keepCount, as well as
+// revisions saved before keepAfter. The newest revision is never removed so that
+// the current copy of the song always has a matching revision
+func (d DB) PruneSongRevisions(ctx context.Context, songID string, keepCount int, keepAfter time.Time) error {
+	keys, err := d.getRevisionKeys(ctx, songID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch revision keys to prune")
+	}
+
+	toDelete := []dynamo.Keyed{}
+	for i, key := range keys {
+		if i == 0 {
+			continue
+		}
+
+		if i >= keepCount || key.savedBefore(keepAfter) {
+			toDelete = append(toDelete, dynamo.Keys{key.ID, key.Revision})
+		}
+	}
+
+	return d.deleteRevisions(ctx, toDelete)
+}
+
+func (d DB) deleteAllRevisions(ctx context.Context, songID string) error {
+	keys, err := d.getRevisionKeys(ctx, songID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch revision keys to delete")
+	}
+
+	toDelete := []dynamo.Keyed{}
+	for _, key := range keys {
+		toDelete = append(toDelete, dynamo.Keys{key.ID, key.Revision})
+	}
+
+	return d.deleteRevisions(ctx, toDelete)
+}
+
+// getRevisionKeys returns the keys of all revisions of a song, newest first
+func (d DB) getRevisionKeys(ctx context.Context, songID string) ([]dbRevisionKey, error) {
+	keys := []dbRevisionKey{}
+	err := d.dynamoDB.Table(SongRevisionsTable).
+		Get(idKey, songID).
+		Project(idKey, revisionKey, lastSavedAtField).
+		Order(dynamo.Descending).
+		AllWithContext(ctx, &keys)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to query song revision keys")
+	}
+
+	return keys, nil
+}
+
+func (d DB) deleteRevisions(ctx context.Context, keys []dynamo.Keyed) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := d.dynamoDB.Table(SongRevisionsTable).
+		Batch(idKey, revisionKey).
+		Write().
+		Delete(keys...).
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete song revisions")
+	}
+
+	return nil
+}
+
+// savedBefore is false for a revision whose save time can't be read,
+// those are left to be pruned by count instead
+func (k dbRevisionKey) savedBefore(t time.Time) bool {
+	savedAt, err := time.Parse(time.RFC3339Nano, k.LastSavedAt)
+	if err != nil {
+		return false
+	}
+
+	return savedAt.Before(t)
+}
+
+// formatSavedAt formats the time the same way a song's lastSavedAt is serialized
+func formatSavedAt(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,187 @@
+package songusecase
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/markers"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/auth"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/storage"
+	"strings"
+)
+
+func (u Usecase) GetCollaborators(ctx context.Context, authHeader string, songID string) ([]songentity.Collaborator, *api.Error) {
+	if apiErr := u.AuthorizeSongAccess(ctx, authHeader, songID, songentity.ViewerRole); apiErr != nil {
+		return nil, api.WrapError(apiErr, "Cannot verify that this user can view this song")
+	}
+
+	collaborators, err := u.db.GetCollaborators(ctx, songID)
+	if err != nil {
+		return nil, api.CommitError(errors.Wrap(err, "Failed to get collaborators from DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to fetch the collaborators of this song")
+	}
+
+	return collaborators, nil
+}
+
+func (u Usecase) InviteCollaborator(ctx context.Context, authHeader string, songID string, invite songentity.CollaboratorInvite) (songentity.Collaborator, *api.Error) {
+	if !invite.Role.IsCollaboratorRole() {
+		return songentity.Collaborator{}, api.CommitError(
+			errors.Newf("Invalid collaborator role: %s", invite.Role),
+			songerrors.BadCollaboratorDataCode,
+			"Collaborators can only be invited as a viewer or an editor")
+	}
+
+	email := strings.TrimSpace(invite.Email)
+	if email == "" {
+		return songentity.Collaborator{}, api.CommitError(
+			errors.New("Invite email is empty"),
+			songerrors.BadCollaboratorDataCode,
+			"An email is needed to invite a collaborator")
+	}
+
+	song, apiErr := u.fetchSong(ctx, songID)
+	if apiErr != nil {
+		return songentity.Collaborator{}, api.WrapError(apiErr, "Failed to fetch song")
+	}
+
+	if apiErr := u.verifySongOwnerBySong(ctx, authHeader, FreshlyFetchedSong(song)); apiErr != nil {
+		return songentity.Collaborator{}, api.WrapError(apiErr, "Only the owner can invite collaborators")
+	}
+
+	invitee, apiErr := u.userUsecase.GetUserByEmail(ctx, email)
+	if apiErr != nil {
+		if apiErr.ErrorCode == auth.NoAccountCode {
+			return songentity.Collaborator{}, api.CommitError(
+				errors.Wrap(apiErr, "Failed to find the invitee"),
+				songerrors.InviteeNotFoundCode,
+				"There's no Chord Paper account with this email")
+		}
+
+		return songentity.Collaborator{}, api.WrapError(apiErr, "Failed to find the invitee")
+	}
+
+	if invitee.ID == song.Defined.Owner {
+		return songentity.Collaborator{}, api.CommitError(
+			errors.New("The owner can't be invited as a collaborator"),
+			songerrors.BadCollaboratorDataCode,
+			"You already own this song")
+	}
+
+	collaborator := songentity.Collaborator{
+		SongID: songID,
+		UserID: invitee.ID,
+		Email:  invitee.Email,
+		Name:   invitee.Name,
+		Role:   invite.Role,
+	}
+
+	// inviting an existing collaborator again just changes their role
+	if err := u.db.SetCollaborator(ctx, collaborator); err != nil {
+		return songentity.Collaborator{}, api.CommitError(errors.Wrap(err, "Failed to save collaborator to DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to invite the collaborator")
+	}
+
+	return collaborator, nil
+}
+
+func (u Usecase) RevokeCollaborator(ctx context.Context, authHeader string, songID string, userID string) *api.Error {
+	song, apiErr := u.fetchSong(ctx, songID)
+	if apiErr != nil {
+		return api.WrapError(apiErr, "Failed to fetch song")
+	}
+
+	user, apiErr := u.userUsecase.AuthenticateUser(ctx, authHeader)
+	if apiErr != nil {
+		return api.WrapError(apiErr, "Failed to authenticate user")
+	}
+
+	// collaborators are allowed to leave a song on their own
+	if user.ID != userID {
+		if apiErr := u.verifySongOwnerBySong(ctx, authHeader, FreshlyFetchedSong(song)); apiErr != nil {
+			return api.WrapError(apiErr, "Only the owner can revoke other collaborators")
+		}
+	}
+
+	err := u.db.DeleteCollaborator(ctx, songID, userID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to delete collaborator")
+		switch {
+		case markers.Is(err, songstorage.CollaboratorNotFoundMark):
+			return api.CommitError(err,
+				songerrors.CollaboratorNotFoundCode,
+				"This user isn't a collaborator of the song")
+
+		case markers.Is(err, songstorage.DefaultErrorMark):
+			fallthrough
+		default:
+			return api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to revoke the collaborator")
+		}
+	}
+
+	return nil
+}
+
+// AuthorizeSongAccess checks that the user from the auth header is the owner of the song,
+// or a collaborator with at least the required role
+func (u Usecase) AuthorizeSongAccess(ctx context.Context, authHeader string, songID string, requiredRole songentity.Role) *api.Error {
+	song, apiErr := u.fetchSong(ctx, songID)
+	if apiErr != nil {
+		return api.WrapError(apiErr, "Failed to fetch song")
+	}
+
+	_, apiErr = u.authorizeSong(ctx, authHeader, FreshlyFetchedSong(song), requiredRole)
+	return apiErr
+}
+
+func (u Usecase) authorizeSong(ctx context.Context, authHeader string, song FreshlyFetchedSong, requiredRole songentity.Role) (songentity.Role, *api.Error) {
+	if requiredRole == songentity.OwnerRole {
+		if apiErr := u.verifySongOwnerBySong(ctx, authHeader, song); apiErr != nil {
+			return "", apiErr
+		}
+
+		return songentity.OwnerRole, nil
+	}
+
+	user, apiErr := u.userUsecase.AuthenticateUser(ctx, authHeader)
+	if apiErr != nil {
+		return "", api.WrapError(apiErr, "Failed to authenticate user")
+	}
+
+	if user.ID == song.Defined.Owner {
+		return songentity.OwnerRole, nil
+	}
+
+	collaborator, err := u.db.GetCollaborator(ctx, song.Defined.ID, user.ID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to get collaborator")
+		switch {
+		case markers.Is(err, songstorage.CollaboratorNotFoundMark):
+			return "", api.CommitError(err,
+				auth.WrongOwnerCode,
+				"The user requesting access isn't the owner or a collaborator of this song")
+
+		case markers.Is(err, songstorage.DefaultErrorMark):
+			fallthrough
+		default:
+			return "", api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to check access to this song")
+		}
+	}
+
+	if !collaborator.Role.Permits(requiredRole) {
+		err := errors.Newf("Collaborator has role %s, but %s is required", collaborator.Role, requiredRole)
+		return "", api.CommitError(err,
+			auth.InsufficientRoleCode,
+			"You don't have permission to make changes to this song")
+	}
+
+	return collaborator.Role, nil
+}
@@ -0,0 +1,114 @@
+package songusecase
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/markers"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/storage"
+	"time"
+)
+
+func (u Usecase) GetSongRevisions(ctx context.Context, authHeader string, songID string) ([]songentity.SongSummary, *api.Error) {
+	if apiErr := u.AuthorizeSongRead(ctx, authHeader, songID); apiErr != nil {
+		return nil, api.WrapError(apiErr, "Failed to fetch song for its revisions")
+	}
+
+	revisions, err := u.db.GetSongRevisions(ctx, songID)
+	if err != nil {
+		return nil, api.CommitError(errors.Wrap(err, "Failed to get song revisions from DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to fetch the revisions of this song")
+	}
+
+	return revisions, nil
+}
+
+func (u Usecase) GetSongRevision(ctx context.Context, authHeader string, songID string, revision string) (songentity.Song, *api.Error) {
+	if apiErr := u.AuthorizeSongRead(ctx, authHeader, songID); apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch song for its revision")
+	}
+
+	return u.fetchSongRevision(ctx, songID, revision)
+}
+
+// fetchSongRevision takes either the revision key from the list of revisions,
+// or the time the revision was saved at
+func (u Usecase) fetchSongRevision(ctx context.Context, songID string, revision string) (songentity.Song, *api.Error) {
+	var song songentity.Song
+	var err error
+	if savedAt, parseErr := time.Parse(time.RFC3339, revision); parseErr == nil {
+		song, err = u.db.GetSongRevisionSavedAt(ctx, songID, savedAt)
+	} else {
+		song, err = u.db.GetSongRevision(ctx, songID, revision)
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "Failed to get the song revision from DB")
+
+		switch {
+		case markers.Is(err, songstorage.RevisionNotFoundMark):
+			return songentity.Song{}, api.CommitError(err,
+				songerrors.RevisionNotFoundCode,
+				"The song revision can't be found")
+
+		case markers.Is(err, songstorage.AmbiguousRevisionMark):
+			return songentity.Song{}, api.CommitError(err,
+				songerrors.RevisionNotFoundCode,
+				"More than one revision of the song was saved at this time, pick the revision from the list instead")
+
+		case markers.Is(err, songstorage.SongUnmarshalMark):
+			fallthrough
+		case markers.Is(err, songstorage.DefaultErrorMark):
+			fallthrough
+		default:
+			return songentity.Song{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to fetch the song revision")
+		}
+	}
+
+	return song, nil
+}
+
+func (u Usecase) RestoreSongRevision(ctx context.Context, authHeader string, songID string, revision string) (songentity.Song, *api.Error) {
+	revisionSong, apiErr := u.fetchSongRevision(ctx, songID, revision)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch the revision to restore")
+	}
+
+	dbSong, apiErr := u.fetchSong(ctx, songID)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch the song to restore onto")
+	}
+
+	// restoring is a regular save of the old content on top of the current copy,
+	// so it goes through the same ownership and overwrite checks as any other update
+	revisionSong.Defined.LastSavedAt = dbSong.Defined.LastSavedAt
+	revisionSong.Defined.Revision = dbSong.Defined.Revision
+	// visibility is a setting rather than content, don't let an old revision change it
+	revisionSong.Defined.Visibility = dbSong.Defined.Visibility
+
+	restoredSong, apiErr := u.UpdateSong(ctx, authHeader, songID, revisionSong)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to save the restored revision")
+	}
+
+	return restoredSong, nil
+}
+
+// pruneRevisions is best effort - the save has already succeeded at this point
+// so failing to prune shouldn't fail the request, it'll be retried on the next save
+func (u Usecase) pruneRevisions(ctx context.Context, songID string) {
+	keepAfter := time.Now().UTC().Add(-maxRevisionAge).Truncate(time.Second)
+
+	err := u.db.PruneSongRevisions(ctx, songID, maxRevisionCount, keepAfter)
+	if err != nil {
+		log.WithField("songID", songID).
+			WithError(err).
+			Error("Failed to prune song revisions")
+	}
+}
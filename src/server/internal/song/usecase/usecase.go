@@ -11,6 +11,13 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/storage"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/user/usecase"
 	"sync"
+	"time"
+)
+
+const (
+	// revisions are pruned after every save, whichever of these limits is hit first
+	maxRevisionCount = 50
+	maxRevisionAge   = 90 * 24 * time.Hour
 )
 
 type Usecase struct {
@@ -25,7 +32,44 @@ func NewUsecase(db songstorage.DB, userUsecase userusecase.Usecase) Usecase {
 	}
 }
 
-func (u Usecase) GetSong(ctx context.Context, songID string) (songentity.Song, *api.Error) {
+// GetSong returns the song if the user from the auth header can read it.
+// The auth header is only checked for private songs, and can be empty otherwise
+func (u Usecase) GetSong(ctx context.Context, authHeader string, songID string) (songentity.Song, *api.Error) {
+	song, apiErr := u.fetchSong(ctx, songID)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch song")
+	}
+
+	if apiErr := u.authorizeRead(ctx, authHeader, FreshlyFetchedSong(song)); apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Cannot verify that this user can read this song")
+	}
+
+	return song, nil
+}
+
+// AuthorizeSongRead checks that the user from the auth header can read the song,
+// for resources that share the visibility of their song
+func (u Usecase) AuthorizeSongRead(ctx context.Context, authHeader string, songID string) *api.Error {
+	_, apiErr := u.GetSong(ctx, authHeader, songID)
+	return apiErr
+}
+
+func (u Usecase) authorizeRead(ctx context.Context, authHeader string, song FreshlyFetchedSong) *api.Error {
+	if !song.Defined.Visibility.RequiresAuth() {
+		return nil
+	}
+
+	_, apiErr := u.authorizeSong(ctx, authHeader, song, songentity.ViewerRole)
+	return apiErr
+}
+
+// GetSongWithoutAuth returns the song regardless of its visibility,
+// for callers that have already authorized the request by other means
+func (u Usecase) GetSongWithoutAuth(ctx context.Context, songID string) (songentity.Song, *api.Error) {
+	return u.fetchSong(ctx, songID)
+}
+
+func (u Usecase) fetchSong(ctx context.Context, songID string) (songentity.Song, *api.Error) {
 	song, err := u.db.GetSong(ctx, songID)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to get the song from DB")
@@ -114,6 +158,10 @@ func (u Usecase) CreateSong(ctx context.Context, authHeader string, song songent
 		return songentity.Song{}, api.WrapError(apiErr, "Failed to verify song owner")
 	}
 
+	if apiErr := validateVisibility(song); apiErr != nil {
+		return songentity.Song{}, apiErr
+	}
+
 	song.CreateID()
 	song.SetSavedAtToNow()
 	err := u.db.CreateSong(ctx, song)
@@ -124,27 +172,57 @@ func (u Usecase) CreateSong(ctx context.Context, authHeader string, song songent
 			"Unknown error: Failed to create the song")
 	}
 
+	u.pruneRevisions(ctx, song.Defined.ID)
+
 	return song, nil
 }
 
 func (u Usecase) UpdateSong(ctx context.Context, authHeader string, songID string, song songentity.Song) (songentity.Song, *api.Error) {
-	dbSong, apiErr := u.GetSong(ctx, songID)
+	if apiErr := validateVisibility(song); apiErr != nil {
+		return songentity.Song{}, apiErr
+	}
+
+	dbSong, apiErr := u.fetchSong(ctx, songID)
 	if apiErr != nil {
 		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch song")
 	}
 
 	freshlyFetchedSong := FreshlyFetchedSong(dbSong)
 
-	apiErr = u.verifySongOwnerBySong(ctx, authHeader, freshlyFetchedSong)
+	role, apiErr := u.authorizeSong(ctx, authHeader, freshlyFetchedSong, songentity.EditorRole)
+	if apiErr != nil {
+		return songentity.Song{}, api.WrapError(apiErr, "Cannot verify that this user can edit this song")
+	}
+
+	return u.updateSong(ctx, song, freshlyFetchedSong, role)
+}
+
+// UpdateSongWithoutAuth saves the song with the access of an editor,
+// for callers that have already authorized the request by other means
+func (u Usecase) UpdateSongWithoutAuth(ctx context.Context, songID string, song songentity.Song) (songentity.Song, *api.Error) {
+	if apiErr := validateVisibility(song); apiErr != nil {
+		return songentity.Song{}, apiErr
+	}
+
+	dbSong, apiErr := u.fetchSong(ctx, songID)
 	if apiErr != nil {
-		return songentity.Song{}, api.WrapError(apiErr, "Cannot verify that this user owns this song")
+		return songentity.Song{}, api.WrapError(apiErr, "Failed to fetch song")
 	}
 
+	return u.updateSong(ctx, song, FreshlyFetchedSong(dbSong), songentity.EditorRole)
+}
+
+func (u Usecase) updateSong(ctx context.Context, song songentity.Song, freshlyFetchedSong FreshlyFetchedSong, role songentity.Role) (songentity.Song, *api.Error) {
 	// set these security fields in case someone wants to pull a fast one
 	song.Defined.ID = freshlyFetchedSong.Defined.ID
 	song.Defined.Owner = freshlyFetchedSong.Defined.Owner
 
-	apiErr = protectSongFromOverwriting(song, freshlyFetchedSong)
+	// only the owner decides who gets to see the song
+	if role != songentity.OwnerRole {
+		song.Defined.Visibility = freshlyFetchedSong.Defined.Visibility
+	}
+
+	song, apiErr := u.protectSongFromOverwriting(ctx, song, freshlyFetchedSong)
 	if apiErr != nil {
 		return songentity.Song{}, api.WrapError(apiErr, "Song protected from overwriting")
 	}
@@ -168,13 +246,15 @@ func (u Usecase) UpdateSong(ctx context.Context, authHeader string, songID strin
 		}
 	}
 
+	u.pruneRevisions(ctx, song.Defined.ID)
+
 	return song, nil
 }
 
-func protectSongFromOverwriting(songToUpdate songentity.Song, songFromDB FreshlyFetchedSong) *api.Error {
+func (u Usecase) protectSongFromOverwriting(ctx context.Context, songToUpdate songentity.Song, songFromDB FreshlyFetchedSong) (songentity.Song, *api.Error) {
 	if songToUpdate.Defined.LastSavedAt == nil {
 		err := errors.New("Song payload doesn't have a last saved at field set")
-		return api.CommitError(err,
+		return songentity.Song{}, api.CommitError(err,
 			songerrors.SongOverwriteCode,
 			"This song doesn't have a last saved at time, it may not have been created yet. Please upload the initial copy first")
 	}
@@ -182,7 +262,7 @@ func protectSongFromOverwriting(songToUpdate songentity.Song, songFromDB Freshly
 	if songFromDB.Defined.LastSavedAt == nil {
 		// unexpected, any song in the DB should have a last saved at
 		// but don't block on this
-		return nil
+		return songToUpdate, nil
 	}
 
 	// prevent overwriting a more recent save if the last saved at timestamp is greater than the current one
@@ -196,21 +276,70 @@ func protectSongFromOverwriting(songToUpdate songentity.Song, songFromDB Freshly
 	//
 	// Now I save another copy at time C, but the predecessor of my copy at time C was from A
 	// If I just go with last write wins, then all changes from the copy at time B would be overwritten
-	// So by comparing the timestamp at the save at C (A vs B), the save will fail to protect overwriting data.
+	// So by comparing the timestamp at the save at C (A vs B), the save is detected as stale.
 	//
-	// The user can then refetch the copy at time B and copy over their changes from time C (manual merge)
-	// and form a copy that will be accepted by the server:
+	// The revision at time A is still stored, so it's used as the common base to merge
+	// the changes from A to C on top of B, and form a copy that can be saved:
 	//
 	// A --> B----->B+C
 	//   \----->C---/
-	if songToUpdate.Defined.LastSavedAt.Before(*songFromDB.Defined.LastSavedAt) {
-		err := errors.New("New song has an earlier last saved at than the current last saved time")
-		return api.CommitError(err,
-			songerrors.SongOverwriteCode,
-			"Unable to save - there's been a more recent copy of this song saved and saving it will clobber it. Please try reloading this song in a new tab and copy your work over")
+	//
+	// If both B and C changed the same part of the song, the save fails with the conflicts
+	// so that the user can resolve them
+	//
+	// The revision key that the copy was loaded with is compared when there is one, since
+	// two saves can land within the same second and the save times can't tell those apart
+	if !isStaleSave(songToUpdate, songFromDB) {
+		return songToUpdate, nil
 	}
 
-	return nil
+	baseSong, err := u.fetchBaseRevision(ctx, songFromDB.Defined.ID, songToUpdate)
+	if err != nil {
+		err = errors.Wrap(err, "New song is based on an earlier copy than the current one, and its base revision can't be fetched")
+		switch {
+		case markers.Is(err, songstorage.RevisionNotFoundMark):
+			fallthrough
+		case markers.Is(err, songstorage.AmbiguousRevisionMark):
+			// without a base there's no telling what changed on either side
+			return songentity.Song{}, api.CommitError(err,
+				songerrors.SongOverwriteCode,
+				"Unable to save - there's been a more recent copy of this song saved and saving it will clobber it. Please try reloading this song in a new tab and copy your work over")
+
+		default:
+			return songentity.Song{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to compare your changes against the more recent copy of this song")
+		}
+	}
+
+	mergedSong, conflicts := songentity.MergeSongs(baseSong, songentity.Song(songFromDB), songToUpdate)
+	if conflicts != nil {
+		err := errors.Newf("Merging the stale song onto the current copy resulted in %d conflicts", len(conflicts.Conflicts))
+		return songentity.Song{}, api.CommitError(err,
+			songerrors.SongMergeConflictCode,
+			"Unable to save - there's been a more recent copy of this song saved, which has changes that conflict with yours").
+			WithDetails(conflicts)
+	}
+
+	return mergedSong, nil
+}
+
+func isStaleSave(songToUpdate songentity.Song, songFromDB FreshlyFetchedSong) bool {
+	if songToUpdate.Defined.Revision != "" && songFromDB.Defined.Revision != "" {
+		return songToUpdate.Defined.Revision != songFromDB.Defined.Revision
+	}
+
+	return songToUpdate.Defined.LastSavedAt.Before(*songFromDB.Defined.LastSavedAt)
+}
+
+// fetchBaseRevision gets the revision that the song to update was loaded from. Clients that loaded
+// the song before it carried its revision key only have its save time, which is looked up instead
+func (u Usecase) fetchBaseRevision(ctx context.Context, songID string, songToUpdate songentity.Song) (songentity.Song, error) {
+	if songToUpdate.Defined.Revision != "" {
+		return u.db.GetSongRevision(ctx, songID, songToUpdate.Defined.Revision)
+	}
+
+	return u.db.GetSongRevisionSavedAt(ctx, songID, *songToUpdate.Defined.LastSavedAt)
 }
 
 func (u Usecase) DeleteSong(ctx context.Context, authHeader string, songID string) *api.Error {
@@ -240,7 +369,7 @@ func (u Usecase) DeleteSong(ctx context.Context, authHeader string, songID strin
 }
 
 func (u Usecase) VerifySongOwnerBySongID(ctx context.Context, authHeader string, songID string) *api.Error {
-	song, apiErr := u.GetSong(ctx, songID)
+	song, apiErr := u.fetchSong(ctx, songID)
 	if apiErr != nil {
 		return api.WrapError(apiErr, "Failed to fetch song")
 	}
@@ -261,3 +390,14 @@ func (u Usecase) verifySongOwnerBySong(ctx context.Context, authHeader string, s
 
 	return nil
 }
+
+func validateVisibility(song songentity.Song) *api.Error {
+	if !song.Defined.Visibility.IsValid() {
+		err := errors.Newf("Unrecognized song visibility: %s", song.Defined.Visibility)
+		return api.CommitError(err,
+			songerrors.BadSongDataCode,
+			"The song visibility should be one of private, unlisted or public")
+	}
+
+	return nil
+}
@@ -5,6 +5,10 @@ import (
 )
 
 const (
-	TrackListSizeExceeded = api.ErrorCode("track_list_size_exceeded")
-	BadTracklistDataCode  = api.ErrorCode("bad_tracklist_data")
+	TrackListSizeExceeded   = api.ErrorCode("track_list_size_exceeded")
+	BadTracklistDataCode    = api.ErrorCode("bad_tracklist_data")
+	TrackNotFoundCode       = api.ErrorCode("track_not_found")
+	TrackNotCancellableCode = api.ErrorCode("track_not_cancellable")
+	TrackNotRetryableCode   = api.ErrorCode("track_not_retryable")
+	BadEventsTokenCode      = api.ErrorCode("bad_events_token")
 )
@@ -1,6 +1,8 @@
 package trackgateway
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/cockroachdb/errors"
 	"github.com/labstack/echo/v4"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
@@ -10,8 +12,12 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/server/internal/track/usecase"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"net/http"
+	"time"
 )
 
+// keepAliveInterval is less than the idle timeouts of the usual proxies
+const keepAliveInterval = 15 * time.Second
+
 type Gateway struct {
 	usecase trackusecase.Usecase
 }
@@ -25,7 +31,9 @@ func NewGateway(usecase trackusecase.Usecase) Gateway {
 func (g Gateway) GetTrackList(c echo.Context, songID string) error {
 	ctx := request.Context(c)
 
-	tracklist, apiErr := g.usecase.GetTrackList(ctx, songID)
+	authHeader := request.OptionalAuthHeader(c)
+
+	tracklist, apiErr := g.usecase.GetTrackList(ctx, authHeader, songID)
 	if apiErr != nil {
 		apiErr = api.WrapError(apiErr, "Failed to get track list")
 		return gateway.ErrorResponse(c, apiErr)
@@ -34,6 +42,74 @@ func (g Gateway) GetTrackList(c echo.Context, songID string) error {
 	return c.JSON(http.StatusOK, tracklist)
 }
 
+func (g Gateway) CreateEventsToken(c echo.Context, songID string) error {
+	ctx := request.Context(c)
+	authHeader := request.OptionalAuthHeader(c)
+
+	eventsToken, apiErr := g.usecase.CreateEventsToken(ctx, authHeader, songID)
+	if apiErr != nil {
+		apiErr = api.WrapError(apiErr, "Failed to create track list events token")
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, eventsToken)
+}
+
+func (g Gateway) StreamTrackListEvents(c echo.Context, songID string) error {
+	ctx := c.Request().Context()
+	authHeader := request.OptionalAuthHeader(c)
+	// EventSource can't set headers, so browsers pass a token from CreateEventsToken instead
+	eventsToken := c.QueryParam("token")
+
+	trackEvents, unsubscribe, apiErr := g.usecase.SubscribeToTrackList(ctx, authHeader, eventsToken, songID)
+	if apiErr != nil {
+		apiErr = api.WrapError(apiErr, "Failed to subscribe to track list events")
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	defer unsubscribe()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		// the request context is used even in development,
+		// otherwise the stream would outlive the browser closing it
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-trackEvents:
+			if !ok {
+				return nil
+			}
+
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				return errors.Wrap(err, "Failed to marshal track event")
+			}
+
+			if _, err := fmt.Fprintf(response, "event: track\ndata: %s\n\n", eventJSON); err != nil {
+				return nil
+			}
+			response.Flush()
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			response.Flush()
+		}
+	}
+}
+
 func (g Gateway) SetTrackList(c echo.Context, songID string) error {
 	ctx := request.Context(c)
 	authHeader, apiErr := request.AuthHeader(c)
@@ -58,3 +134,52 @@ func (g Gateway) SetTrackList(c echo.Context, songID string) error {
 
 	return c.JSON(http.StatusOK, newTracklist)
 }
+
+func (g Gateway) CancelTrack(c echo.Context, songID string, trackID string) error {
+	ctx := request.Context(c)
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	tracklist, apiErr := g.usecase.CancelTrack(ctx, authHeader, songID, trackID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, tracklist)
+}
+
+func (g Gateway) RetryTrack(c echo.Context, songID string, trackID string) error {
+	ctx := request.Context(c)
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	tracklist, apiErr := g.usecase.RetryTrack(ctx, authHeader, songID, trackID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, tracklist)
+}
+
+func (g Gateway) GetJobRuns(c echo.Context, songID string, trackID string) error {
+	ctx := request.Context(c)
+	authHeader, apiErr := request.AuthHeader(c)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	runs, apiErr := g.usecase.GetJobRuns(ctx, authHeader, songID, trackID)
+	if apiErr != nil {
+		return gateway.ErrorResponse(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+func (g Gateway) GetSplitEngines(c echo.Context) error {
+	return c.JSON(http.StatusOK, g.usecase.GetSplitEngines())
+}
@@ -1,11 +1,15 @@
 package track_test
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/auth"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/shared_tests/auth"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/errors"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/gateway"
@@ -16,19 +20,32 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/server/internal/track/usecase"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/user/storage"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/user/usecase"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/jsonlib"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	"github.com/veedubyou/chord-paper-be/src/shared/testing"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/storage"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	fakeStorageHost = "https://fake-storage"
+	fakeBucket      = "stems"
 )
 
 var _ = Describe("Track", func() {
 	var (
 		trackGateway trackgateway.Gateway
+		trackUsecase trackusecase.Usecase
 		songGateway  songgateway.Gateway
+		trackStorage trackstorage.DB
+		eventBus     *trackevents.Bus
 		publisher    *rabbitmq.QueuePublisher
 		validator    testing.Validator
 
@@ -47,8 +64,12 @@ var _ = Describe("Track", func() {
 		songUsecase := songusecase.NewUsecase(songStorage, userUsecase)
 		songGateway = songgateway.NewGateway(songUsecase)
 
-		trackStorage := trackstorage.NewDB(db)
-		trackUsecase := trackusecase.NewUsecase(trackStorage, songUsecase, publisher)
+		trackStorage = trackstorage.NewDB(db)
+		eventBus = trackevents.NewBus()
+		trackUsecase = trackusecase.NewUsecase(trackStorage, trackStorage, trackStorage, songUsecase, publisher, store.FakeURLSigner{
+			StorageHost: fakeStorageHost,
+			Bucket:      fakeBucket,
+		}, eventBus, testing.EventsTokenSigningKey, trackentity.NewInstalledSplitEngines())
 		trackGateway = trackgateway.NewGateway(trackUsecase)
 	})
 
@@ -126,6 +147,621 @@ var _ = Describe("Track", func() {
 		})
 	}
 
+	Describe("Get Tracklist", func() {
+		Describe("For a private song", func() {
+			var (
+				songID string
+			)
+
+			var getTracklistAs = func(user *testing.User) *httptest.ResponseRecorder {
+				requestFactory := testing.RequestFactory{
+					Method:  "GET",
+					Target:  "/songs/:id/tracklist",
+					JSONObj: nil,
+				}
+
+				if user != nil {
+					requestFactory.Mods.Add(testing.WithUserCred(*user))
+				}
+
+				response := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(requestFactory.MakeFake(), response)
+				Expect(trackGateway.GetTrackList(c, songID)).To(Succeed())
+				return response
+			}
+
+			BeforeEach(func() {
+				song := testing.LoadDemoSong()
+				song["visibility"] = "private"
+				songID, _ = createSong(song)
+			})
+
+			It("can be fetched by the owner", func() {
+				Expect(getTracklistAs(&testing.PrimaryUser).Code).To(Equal(http.StatusOK))
+			})
+
+			It("can't be fetched anonymously", func() {
+				response := getTracklistAs(nil)
+				Expect(response.Code).To(Equal(http.StatusBadRequest))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(auth.BadAuthorizationHeaderCode))
+			})
+
+			It("can't be fetched by other users", func() {
+				response := getTracklistAs(&testing.OtherUser)
+				Expect(response.Code).To(Equal(http.StatusForbidden))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(auth.WrongOwnerCode))
+			})
+		})
+	})
+
+	Describe("Stem URLs", func() {
+		var (
+			songID    string
+			stemTrack trackentity.StemTrack
+		)
+
+		var getStemURLs = func(tracklist map[string]any) map[string]any {
+			tracks := getTrackSliceFromResponse(tracklist)
+			Expect(tracks).To(HaveLen(1))
+			return testing.ExpectType[map[string]any](tracks[0]["stem_urls"])
+		}
+
+		var setTracklistAsOwner = func(tracklist map[string]any) *httptest.ResponseRecorder {
+			request := testing.RequestFactory{
+				Method:  "PUT",
+				Target:  "/songs/:id/tracklist",
+				JSONObj: tracklist,
+				Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(trackGateway.SetTrackList(c, songID)).To(Succeed())
+			return response
+		}
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+
+			By("Saving split stems the way the worker does")
+			stemTrack = trackentity.StemTrack{
+				TrackFields: trackentity.TrackFields{ID: uuid.New().String(), Label: "Split"},
+				TrackType:   trackentity.TwoStemsType,
+				StemURLs: map[string]string{
+					"vocals":        fmt.Sprintf("%s/split/2stems/vocals.mp3", songID),
+					"accompaniment": "https://legacy.storage/accompaniment.mp3",
+					"drums":         fmt.Sprintf("%s/%s/%s/split/2stems/drums.mp3", fakeStorageHost, fakeBucket, songID),
+				},
+			}
+
+			tracklist := trackentity.NewTrackList(songID)
+			tracklist.Defined.Tracks = trackentity.Tracks{&stemTrack}
+			Expect(trackStorage.SetTrackList(context.Background(), tracklist)).To(Succeed())
+		})
+
+		It("signs the stored object paths", func() {
+			stemURLs := getStemURLs(getTracklist(songID))
+			vocalsURL := testing.ExpectType[string](stemURLs["vocals"])
+			Expect(vocalsURL).To(HavePrefix(fmt.Sprintf("%s/%s/%s/split/2stems/vocals.mp3?expires=", fakeStorageHost, fakeBucket, songID)))
+		})
+
+		It("leaves full URLs alone", func() {
+			stemURLs := getStemURLs(getTracklist(songID))
+			Expect(stemURLs["accompaniment"]).To(Equal("https://legacy.storage/accompaniment.mp3"))
+		})
+
+		It("signs the public bucket URLs that stems were stored with before signing", func() {
+			stemURLs := getStemURLs(getTracklist(songID))
+			drumsURL := testing.ExpectType[string](stemURLs["drums"])
+			Expect(drumsURL).To(HavePrefix(fmt.Sprintf("%s/%s/%s/split/2stems/drums.mp3?expires=", fakeStorageHost, fakeBucket, songID)))
+		})
+
+		It("keeps the stored object paths when the signed URLs are sent back", func() {
+			response := setTracklistAsOwner(getTracklist(songID))
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			storedTracklist := testing.ExpectSuccess(trackStorage.GetTrackList(context.Background(), songID))
+			storedTrack := testing.ExpectType[*trackentity.StemTrack](storedTracklist.Defined.Tracks[0])
+			Expect(storedTrack.StemURLs).To(Equal(stemTrack.StemURLs))
+		})
+
+		It("rejects bucket URLs that weren't stored", func() {
+			tracklist := getTracklist(songID)
+			stemURLs := getStemURLs(tracklist)
+			stemURLs["bass"] = fmt.Sprintf("%s/%s/another-song/split/4stems/bass.mp3", fakeStorageHost, fakeBucket)
+
+			response := setTracklistAsOwner(tracklist)
+			Expect(response.Code).To(Equal(http.StatusBadRequest))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(trackerrors.BadTracklistDataCode))
+		})
+
+		It("rejects object paths that weren't stored", func() {
+			tracklist := getTracklist(songID)
+			stemURLs := getStemURLs(tracklist)
+			stemURLs["drums"] = "another-song/split/4stems/drums.mp3"
+
+			response := setTracklistAsOwner(tracklist)
+			Expect(response.Code).To(Equal(http.StatusBadRequest))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(trackerrors.BadTracklistDataCode))
+		})
+	})
+
+	Describe("Tracklist events", func() {
+		var (
+			songID string
+			server *httptest.Server
+		)
+
+		var subscriberCount = func() int {
+			return eventBus.SubscriberCount(songID)
+		}
+
+		var subscribeAs = func(user *testing.User) *http.Response {
+			request, err := http.NewRequest("GET", server.URL, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			if user != nil {
+				testing.WithUserCred(*user)(request)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).NotTo(HaveOccurred())
+			return response
+		}
+
+		BeforeEach(func() {
+			song := testing.LoadDemoSong()
+			song["visibility"] = "private"
+			songID, _ = createSong(song)
+
+			// a real server, so that the stream can be read while it's still open
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c := testing.PrepareEchoContext(r, w)
+				_ = trackGateway.StreamTrackListEvents(c, songID)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("streams the events of the tracklist", func() {
+			response := subscribeAs(&testing.PrimaryUser)
+			defer response.Body.Close()
+
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Header.Get(echo.HeaderContentType)).To(Equal("text/event-stream"))
+			Eventually(subscriberCount).Should(Equal(1))
+
+			otherEvent := trackentity.TrackEvent{TrackListID: "another-song", TrackID: "another-track"}
+			Expect(eventBus.PublishTrackEvent(context.Background(), otherEvent)).To(Succeed())
+
+			event := trackentity.TrackEvent{
+				TrackListID:   songID,
+				TrackID:       "track-id",
+				Type:          trackentity.ProgressEventType,
+				Status:        trackentity.ProcessingStatus,
+				StatusMessage: "Splitting the track into stems",
+				Progress:      30,
+			}
+			Expect(eventBus.PublishTrackEvent(context.Background(), event)).To(Succeed())
+
+			reader := bufio.NewReader(response.Body)
+			Expect(reader.ReadString('\n')).To(Equal("event: track\n"))
+
+			dataLine, err := reader.ReadString('\n')
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataLine).To(HavePrefix("data: "))
+
+			receivedEvent := trackentity.TrackEvent{}
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &receivedEvent)).To(Succeed())
+			Expect(receivedEvent).To(Equal(event))
+		})
+
+		It("stops listening when the client disconnects", func() {
+			response := subscribeAs(&testing.PrimaryUser)
+			Eventually(subscriberCount).Should(Equal(1))
+
+			Expect(response.Body.Close()).To(Succeed())
+			Eventually(subscriberCount).Should(BeZero())
+		})
+
+		It("can't be subscribed to by other users", func() {
+			response := subscribeAs(&testing.OtherUser)
+			defer response.Body.Close()
+
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(subscriberCount()).To(BeZero())
+		})
+
+		Describe("With an events token", func() {
+			var createEventsTokenAs = func(user testing.User) *httptest.ResponseRecorder {
+				request := testing.RequestFactory{
+					Method:  "POST",
+					Target:  fmt.Sprintf("/songs/%s/tracklist/events/token", songID),
+					JSONObj: nil,
+					Mods:    testing.RequestModifiers{testing.WithUserCred(user)},
+				}.MakeFake()
+
+				response := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, response)
+				Expect(trackGateway.CreateEventsToken(c, songID)).To(Succeed())
+				return response
+			}
+
+			var subscribeWithToken = func(token string) *http.Response {
+				response, err := http.Get(server.URL + "?token=" + url.QueryEscape(token))
+				Expect(err).NotTo(HaveOccurred())
+				return response
+			}
+
+			var token string
+
+			BeforeEach(func() {
+				response := createEventsTokenAs(testing.PrimaryUser)
+				Expect(response.Code).To(Equal(http.StatusOK))
+
+				eventsToken := testing.DecodeJSON[map[string]any](response.Body)
+				token = testing.ExpectType[string](eventsToken["token"])
+
+				expiresAt := testing.ExpectSuccess(time.Parse(time.RFC3339, testing.ExpectType[string](eventsToken["expiresAt"])))
+				Expect(expiresAt).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+			})
+
+			It("streams without the authorization header", func() {
+				response := subscribeWithToken(token)
+				defer response.Body.Close()
+
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				Eventually(subscriberCount).Should(Equal(1))
+			})
+
+			It("rejects a tampered token", func() {
+				response := subscribeWithToken(token + "x")
+				defer response.Body.Close()
+
+				Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(subscriberCount()).To(BeZero())
+			})
+
+			It("isn't handed out to other users", func() {
+				response := createEventsTokenAs(testing.OtherUser)
+				Expect(response.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("Cancel Track", func() {
+		var (
+			songID       string
+			splitTrackID string
+			stemTrackID  string
+		)
+
+		var cancelTrackAs = func(user testing.User, trackID string) *httptest.ResponseRecorder {
+			request := testing.RequestFactory{
+				Method:  "POST",
+				Target:  fmt.Sprintf("/songs/%s/tracklist/tracks/%s/cancel", songID, trackID),
+				JSONObj: nil,
+				Mods:    testing.RequestModifiers{testing.WithUserCred(user)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(trackGateway.CancelTrack(c, songID, trackID)).To(Succeed())
+			return response
+		}
+
+		var getSplitTrack = func() map[string]any {
+			tracks := getTrackSliceFromResponse(getTracklist(songID))
+			for _, track := range tracks {
+				if track["id"] == splitTrackID {
+					return track
+				}
+			}
+
+			Fail("Split track is missing from the tracklist")
+			return nil
+		}
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+
+			splitTrack := &trackentity.SplitRequestTrack{
+				TrackFields: trackentity.TrackFields{Label: "split me"},
+				EngineType:  trackentity.DemucsType,
+				TrackType:   trackentity.SplitFourStemsType,
+				OriginalURL: "https://www.youtube.com/watch?v=5FQpeqFmwVk",
+				Status:      trackentity.ProcessingStatus,
+				Progress:    30,
+			}
+			splitTrack.CreateID()
+			splitTrackID = splitTrack.ID
+
+			stemTrack := &trackentity.StemTrack{
+				TrackFields: trackentity.TrackFields{Label: "already split"},
+				TrackType:   trackentity.FourStemsType,
+				StemURLs:    map[string]string{"vocals": "vocals.mp3"},
+			}
+			stemTrack.CreateID()
+			stemTrackID = stemTrack.ID
+
+			tracklist := trackentity.NewTrackList(songID)
+			tracklist.Defined.Tracks = trackentity.Tracks{splitTrack, stemTrack}
+			Expect(trackStorage.SetTrackList(context.Background(), tracklist)).To(Succeed())
+		})
+
+		Describe("For an in progress split request", func() {
+			var response *httptest.ResponseRecorder
+
+			BeforeEach(func() {
+				response = cancelTrackAs(testing.PrimaryUser, splitTrackID)
+			})
+
+			It("returns success", func() {
+				Expect(response.Code).To(Equal(http.StatusOK))
+			})
+
+			It("persists the cancelled status", func() {
+				splitTrack := getSplitTrack()
+				Expect(splitTrack["job_status"]).To(BeEquivalentTo(trackentity.CancelledStatus))
+			})
+
+			It("can't be cancelled twice", func() {
+				response := cancelTrackAs(testing.PrimaryUser, splitTrackID)
+				Expect(response.Code).To(Equal(http.StatusConflict))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(trackerrors.TrackNotCancellableCode))
+			})
+		})
+
+		It("can't cancel a track that isn't a split request", func() {
+			response := cancelTrackAs(testing.PrimaryUser, stemTrackID)
+			Expect(response.Code).To(Equal(http.StatusConflict))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(trackerrors.TrackNotCancellableCode))
+		})
+
+		It("can't cancel a track that doesn't exist", func() {
+			response := cancelTrackAs(testing.PrimaryUser, uuid.New().String())
+			Expect(response.Code).To(Equal(http.StatusNotFound))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(trackerrors.TrackNotFoundCode))
+		})
+
+		It("can't be cancelled by other users", func() {
+			response := cancelTrackAs(testing.OtherUser, splitTrackID)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(auth.WrongOwnerCode))
+
+			splitTrack := getSplitTrack()
+			Expect(splitTrack["job_status"]).To(BeEquivalentTo(trackentity.ProcessingStatus))
+		})
+	})
+
+	Describe("Retry Track", func() {
+		var (
+			songID     string
+			splitTrack *trackentity.SplitRequestTrack
+			response   *httptest.ResponseRecorder
+		)
+
+		var retryTrackAs = func(user testing.User, trackID string) *httptest.ResponseRecorder {
+			request := testing.RequestFactory{
+				Method:  "POST",
+				Target:  fmt.Sprintf("/songs/%s/tracklist/tracks/%s/retry", songID, trackID),
+				JSONObj: nil,
+				Mods:    testing.RequestModifiers{testing.WithUserCred(user)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(trackGateway.RetryTrack(c, songID, trackID)).To(Succeed())
+			return response
+		}
+
+		var getStoredSplitTrack = func() *trackentity.SplitRequestTrack {
+			tracklist, err := trackStorage.GetTrackList(context.Background(), songID)
+			Expect(err).NotTo(HaveOccurred())
+
+			track, err := tracklist.GetTrack(splitTrack.ID)
+			Expect(err).NotTo(HaveOccurred())
+
+			return testing.ExpectType[*trackentity.SplitRequestTrack](track)
+		}
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+
+			splitTrack = &trackentity.SplitRequestTrack{
+				TrackFields:    trackentity.TrackFields{Label: "split me"},
+				EngineType:     trackentity.DemucsType,
+				TrackType:      trackentity.SplitFourStemsType,
+				OriginalURL:    "https://www.youtube.com/watch?v=5FQpeqFmwVk",
+				Status:         trackentity.ErrorStatus,
+				StatusMessage:  "Failed to split the source audio into stems",
+				StatusDebugLog: "demucs fell over",
+				Progress:       30,
+			}
+			splitTrack.CreateID()
+		})
+
+		JustBeforeEach(func() {
+			tracklist := trackentity.NewTrackList(songID)
+			tracklist.Defined.Tracks = trackentity.Tracks{splitTrack}
+			Expect(trackStorage.SetTrackList(context.Background(), tracklist)).To(Succeed())
+
+			response = retryTrackAs(testing.PrimaryUser, splitTrack.ID)
+		})
+
+		Describe("When nothing was completed", func() {
+			It("returns success", func() {
+				Expect(response.Code).To(Equal(http.StatusOK))
+			})
+
+			It("starts the job from the beginning", func() {
+				Eventually(consumer.Unload).Should(Equal([]testing.ReceivedMessage{
+					{
+						Type: "start_job",
+						Message: map[string]any{
+							"tracklist_id": songID,
+							"track_id":     splitTrack.ID,
+						},
+					},
+				}))
+			})
+
+			It("resets the track to requested", func() {
+				storedTrack := getStoredSplitTrack()
+				Expect(storedTrack.Status).To(Equal(trackentity.RequestedStatus))
+				Expect(storedTrack.StatusDebugLog).To(BeEmpty())
+				Expect(storedTrack.Progress).To(Equal(trackentity.InitialProgressPercentage))
+			})
+		})
+
+		Describe("When the original was already transferred", func() {
+			BeforeEach(func() {
+				splitTrack.CompletedStage = trackentity.TransferStage
+				splitTrack.SavedOriginalURL = fmt.Sprintf("%s/%s/original/original.mp3", songID, splitTrack.ID)
+			})
+
+			It("skips straight to splitting", func() {
+				Eventually(consumer.Unload).Should(Equal([]testing.ReceivedMessage{
+					{
+						Type: "split_track",
+						Message: map[string]any{
+							"tracklist_id":       songID,
+							"track_id":           splitTrack.ID,
+							"saved_original_url": splitTrack.SavedOriginalURL,
+						},
+					},
+				}))
+			})
+
+			It("keeps the progress of the completed stages", func() {
+				storedTrack := getStoredSplitTrack()
+				Expect(storedTrack.Status).To(Equal(trackentity.RequestedStatus))
+				Expect(storedTrack.Progress).To(Equal(30))
+				Expect(storedTrack.SavedOriginalURL).To(Equal(splitTrack.SavedOriginalURL))
+			})
+		})
+
+		Describe("When the stems were already split", func() {
+			BeforeEach(func() {
+				splitTrack.CompletedStage = trackentity.SplitStage
+				splitTrack.SavedOriginalURL = fmt.Sprintf("%s/%s/original/original.mp3", songID, splitTrack.ID)
+				splitTrack.SplitStemURLs = map[string]string{
+					"vocals": fmt.Sprintf("%s/%s/4stems/vocals.mp3", songID, splitTrack.ID),
+				}
+			})
+
+			It("skips straight to saving the stems", func() {
+				Eventually(consumer.Unload).Should(Equal([]testing.ReceivedMessage{
+					{
+						Type: "save_stems_to_db",
+						Message: map[string]any{
+							"tracklist_id": songID,
+							"track_id":     splitTrack.ID,
+							"stem_urls": map[string]any{
+								"vocals": splitTrack.SplitStemURLs["vocals"],
+							},
+						},
+					},
+				}))
+			})
+		})
+
+		Describe("When the job is still running", func() {
+			BeforeEach(func() {
+				splitTrack.Status = trackentity.ProcessingStatus
+			})
+
+			It("fails with the right error code", func() {
+				Expect(response.Code).To(Equal(http.StatusConflict))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(trackerrors.TrackNotRetryableCode))
+			})
+
+			ItDoesntQueueMessages()
+		})
+
+		Describe("When the cancelled split hasn't stopped yet", func() {
+			BeforeEach(func() {
+				splitTrack.Status = trackentity.CancelledStatus
+
+				Expect(trackStorage.SaveJobRun(context.Background(), trackentity.JobRun{
+					ID:          uuid.New().String(),
+					TrackListID: songID,
+					TrackID:     splitTrack.ID,
+					JobType:     string(trackentity.SplitStage),
+					StartedAt:   time.Now().Add(-time.Minute),
+				})).To(Succeed())
+			})
+
+			It("fails with the right error code", func() {
+				Expect(response.Code).To(Equal(http.StatusConflict))
+				resErr := testing.DecodeJSONError(response.Body)
+				Expect(resErr.Code).To(BeEquivalentTo(trackerrors.TrackNotRetryableCode))
+			})
+
+			It("leaves the track cancelled", func() {
+				Expect(getStoredSplitTrack().Status).To(Equal(trackentity.CancelledStatus))
+			})
+
+			ItDoesntQueueMessages()
+		})
+
+		Describe("When a worker went away in the middle of a job long ago", func() {
+			BeforeEach(func() {
+				Expect(trackStorage.SaveJobRun(context.Background(), trackentity.JobRun{
+					ID:          uuid.New().String(),
+					TrackListID: songID,
+					TrackID:     splitTrack.ID,
+					JobType:     string(trackentity.SplitStage),
+					StartedAt:   time.Now().Add(-2 * time.Hour),
+				})).To(Succeed())
+			})
+
+			It("retries the track", func() {
+				Expect(response.Code).To(Equal(http.StatusOK))
+				Expect(getStoredSplitTrack().Status).To(Equal(trackentity.RequestedStatus))
+			})
+		})
+
+		Describe("When the saved original is sent back changed", func() {
+			BeforeEach(func() {
+				splitTrack.CompletedStage = trackentity.TransferStage
+				splitTrack.SavedOriginalURL = fmt.Sprintf("%s/%s/original/original.mp3", songID, splitTrack.ID)
+			})
+
+			It("keeps the stored one", func() {
+				tracklist := getTracklist(songID)
+				tracks := getTrackSliceFromResponse(tracklist)
+				Expect(tracks).To(HaveLen(1))
+				tracks[0]["job_saved_original_url"] = "someone-elses-song/track/original/original.mp3"
+
+				request := testing.RequestFactory{
+					Method:  "PUT",
+					Target:  fmt.Sprintf("/songs/%s/tracklist", songID),
+					JSONObj: tracklist,
+					Mods:    testing.RequestModifiers{testing.WithUserCred(testing.PrimaryUser)},
+				}.MakeFake()
+
+				setResponse := httptest.NewRecorder()
+				c := testing.PrepareEchoContext(request, setResponse)
+				Expect(trackGateway.SetTrackList(c, songID)).To(Succeed())
+				Expect(setResponse.Code).To(Equal(http.StatusOK))
+
+				Expect(getStoredSplitTrack().SavedOriginalURL).To(Equal(splitTrack.SavedOriginalURL))
+			})
+		})
+	})
+
 	Describe("Set Tracklist", func() {
 		var (
 			tracklist trackentity.TrackList
@@ -346,6 +982,7 @@ var _ = Describe("Track", func() {
 
 							BeforeEach(func() {
 								splitRequestTrack = trackentity.SplitRequestTrack{}
+								splitRequestTrack.EngineType = trackentity.DemucsType
 								splitRequestTrack.TrackType = "split_4stems"
 								splitRequestTrack.OriginalURL = "thisplace.com/song.mp3"
 								splitRequestTrack.InitializeRequest()
@@ -376,6 +1013,14 @@ var _ = Describe("Track", func() {
 								Consistently(consumer.Unload).Should(BeEmpty())
 							})
 
+							It("leaves nothing pending in the outbox", func() {
+								Eventually(consumer.Unload).Should(HaveLen(1))
+
+								Eventually(func() []trackentity.OutboxJob {
+									return testing.ExpectSuccess(trackStorage.GetPendingJobs(context.Background(), time.Now().Add(time.Minute)))
+								}).Should(BeEmpty())
+							})
+
 							Describe("Updating a second time with an existing split request", func() {
 								BeforeEach(func() {
 									By("first setting the tracklist", func() {
@@ -400,6 +1045,80 @@ var _ = Describe("Track", func() {
 								ItDoesntQueueMessages()
 							})
 						})
+
+						Describe("With an invalid split request", func() {
+							var (
+								splitRequestTrack trackentity.SplitRequestTrack
+							)
+
+							BeforeEach(func() {
+								splitRequestTrack = trackentity.SplitRequestTrack{}
+								splitRequestTrack.EngineType = trackentity.DemucsType
+								splitRequestTrack.TrackType = trackentity.SplitSixStemsType
+								splitRequestTrack.OriginalURL = "thisplace.com/song.mp3"
+
+								tracklist.Defined.Tracks = append(tracklist.Defined.Tracks, &splitRequestTrack)
+							})
+
+							var ItRejectsTheTracklist = func() {
+								It("fails with the right error code", func() {
+									resErr := testing.DecodeJSONError(response.Body)
+									Expect(resErr.Code).To(BeEquivalentTo(trackerrors.BadTracklistDataCode))
+								})
+
+								It("fails with the right status code", func() {
+									Expect(response.Code).To(Equal(http.StatusBadRequest))
+								})
+
+								ItDoesntQueueMessages()
+
+								It("doesn't save the tracklist", func() {
+									tracks := getTrackSliceFromResponse(getTracklist(songID))
+									Expect(tracks).To(BeEmpty())
+								})
+							}
+
+							Describe("For an engine that doesn't exist", func() {
+								BeforeEach(func() {
+									splitRequestTrack.EngineType = "spleemucs"
+								})
+
+								ItRejectsTheTracklist()
+							})
+
+							Describe("For an optional engine that isn't installed", func() {
+								BeforeEach(func() {
+									splitRequestTrack.EngineType = trackentity.OpenUnmixType
+									splitRequestTrack.TrackType = trackentity.SplitFourStemsType
+								})
+
+								ItRejectsTheTracklist()
+							})
+
+							Describe("For a stem layout the engine can't do", func() {
+								BeforeEach(func() {
+									splitRequestTrack.EngineType = trackentity.SpleeterType
+								})
+
+								ItRejectsTheTracklist()
+							})
+
+							Describe("For 5 stems with demucs", func() {
+								BeforeEach(func() {
+									splitRequestTrack.TrackType = trackentity.SplitFiveStemsType
+								})
+
+								ItRejectsTheTracklist()
+							})
+
+							Describe("Without a link to the track", func() {
+								BeforeEach(func() {
+									splitRequestTrack.OriginalURL = ""
+								})
+
+								ItRejectsTheTracklist()
+							})
+						})
 					})
 				})
 			})
@@ -484,4 +1203,238 @@ var _ = Describe("Track", func() {
 			ItDoesntQueueMessages()
 		})
 	})
+
+	Describe("Outbox relay", func() {
+		var (
+			songID       string
+			splitRequest trackentity.SplitRequestTrack
+			outboxJob    trackentity.OutboxJob
+		)
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+
+			splitRequest = trackentity.SplitRequestTrack{}
+			splitRequest.CreateID()
+			splitRequest.TrackType = "split_4stems"
+			splitRequest.OriginalURL = "thisplace.com/song.mp3"
+			splitRequest.InitializeRequest()
+
+			tracklist := trackentity.TrackList{}
+			tracklist.Defined.SongID = songID
+			tracklist.Defined.Tracks = []trackentity.Track{&splitRequest}
+
+			outboxJob = trackentity.OutboxJob{
+				ID:          uuid.New().String(),
+				TrackListID: songID,
+				TrackID:     splitRequest.ID,
+				JobType:     "start_job",
+				Body: testing.ExpectSuccess(json.Marshal(map[string]any{
+					"tracklist_id": songID,
+					"track_id":     splitRequest.ID,
+				})),
+				CreatedAt: time.Now().Add(-time.Hour),
+			}
+
+			By("saving the job without publishing it, as if the server went down right after", func() {
+				jobs := []trackentity.OutboxJob{outboxJob}
+				Expect(trackStorage.SetTrackListWithJobs(context.Background(), tracklist, jobs)).To(Succeed())
+			})
+		})
+
+		var getPendingJobs = func() []trackentity.OutboxJob {
+			return testing.ExpectSuccess(trackStorage.GetPendingJobs(context.Background(), time.Now()))
+		}
+
+		It("has the job pending", func() {
+			pendingJobs := getPendingJobs()
+			Expect(pendingJobs).To(HaveLen(1))
+			Expect(pendingJobs[0].ID).To(Equal(outboxJob.ID))
+			Expect(pendingJobs[0].Status).To(Equal(trackentity.PendingOutboxJobStatus))
+		})
+
+		Describe("Relaying the pending jobs", func() {
+			BeforeEach(func() {
+				trackUsecase.RelayPendingJobs(context.Background(), time.Now())
+			})
+
+			It("queues the start job message", func() {
+				Eventually(consumer.Unload).Should(Equal([]testing.ReceivedMessage{
+					{
+						Type: "start_job",
+						Message: map[string]any{
+							"tracklist_id": songID,
+							"track_id":     splitRequest.ID,
+						},
+					},
+				}))
+			})
+
+			It("takes the job out of the outbox", func() {
+				Expect(getPendingJobs()).To(BeEmpty())
+			})
+
+			Describe("Relaying again", func() {
+				BeforeEach(func() {
+					Eventually(consumer.Unload).Should(HaveLen(1))
+					trackUsecase.RelayPendingJobs(context.Background(), time.Now())
+				})
+
+				ItDoesntQueueMessages()
+			})
+		})
+
+		Describe("Relaying with a cutoff before the job was made", func() {
+			BeforeEach(func() {
+				trackUsecase.RelayPendingJobs(context.Background(), time.Now().Add(-2*time.Hour))
+			})
+
+			ItDoesntQueueMessages()
+
+			It("leaves the job pending", func() {
+				Expect(getPendingJobs()).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("Job Runs", func() {
+		var (
+			songID  string
+			trackID string
+		)
+
+		var getJobRunsAs = func(user testing.User) *httptest.ResponseRecorder {
+			request := testing.RequestFactory{
+				Method:  "GET",
+				Target:  fmt.Sprintf("/songs/%s/tracklist/tracks/%s/jobs", songID, trackID),
+				JSONObj: nil,
+				Mods:    testing.RequestModifiers{testing.WithUserCred(user)},
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(trackGateway.GetJobRuns(c, songID, trackID)).To(Succeed())
+			return response
+		}
+
+		BeforeEach(func() {
+			songID, _ = createSong(testing.LoadDemoSong())
+			trackID = uuid.New().String()
+
+			startedAt := time.Now().Add(-20 * time.Minute).Truncate(time.Millisecond)
+			endedAt := startedAt.Add(20 * time.Minute)
+			runs := []trackentity.JobRun{
+				{
+					ID:          "split-run",
+					TrackListID: songID,
+					TrackID:     trackID,
+					JobID:       "split-job",
+					JobType:     "split_track",
+					Attempt:     1,
+					WorkerHost:  "worker-1",
+					Engine:      trackentity.DemucsType,
+					InputURL:    "original.mp3",
+					Error:       "demucs fell over",
+					StartedAt:   startedAt,
+					EndedAt:     &endedAt,
+					Duration:    endedAt.Sub(startedAt).Milliseconds(),
+				},
+				{
+					ID:          "transfer-run",
+					TrackListID: songID,
+					TrackID:     trackID,
+					JobID:       "transfer-job",
+					JobType:     "transfer_original",
+					Attempt:     1,
+					WorkerHost:  "worker-1",
+					StartedAt:   startedAt.Add(-time.Minute),
+				},
+				{
+					ID:          "other-tracklist-run",
+					TrackListID: "other-tracklist",
+					TrackID:     trackID,
+					JobType:     "start_job",
+					StartedAt:   startedAt,
+				},
+			}
+
+			for _, run := range runs {
+				Expect(trackStorage.SaveJobRun(context.Background(), run)).To(Succeed())
+			}
+		})
+
+		It("returns the track's runs to the owner, oldest first", func() {
+			response := getJobRunsAs(testing.PrimaryUser)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			runs := testing.DecodeJSON[[]map[string]any](response.Body)
+			Expect(runs).To(HaveLen(2))
+			Expect(runs[0]["id"]).To(Equal("transfer-run"))
+			Expect(runs[0]).NotTo(HaveKey("ended_at"))
+
+			Expect(runs[1]["id"]).To(Equal("split-run"))
+			Expect(runs[1]["engine"]).To(BeEquivalentTo(trackentity.DemucsType))
+			Expect(runs[1]["error"]).To(Equal("demucs fell over"))
+			Expect(runs[1]["duration_ms"]).To(BeEquivalentTo((20 * time.Minute).Milliseconds()))
+		})
+
+		It("can't be seen by other users", func() {
+			response := getJobRunsAs(testing.OtherUser)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+			resErr := testing.DecodeJSONError(response.Body)
+			Expect(resErr.Code).To(BeEquivalentTo(auth.WrongOwnerCode))
+		})
+	})
+
+	Describe("Split Engines", func() {
+		var getSplitEngines = func() []map[string]any {
+			request := testing.RequestFactory{
+				Method:  "GET",
+				Target:  "/split-engines",
+				JSONObj: nil,
+			}.MakeFake()
+
+			response := httptest.NewRecorder()
+			c := testing.PrepareEchoContext(request, response)
+			Expect(trackGateway.GetSplitEngines(c)).To(Succeed())
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			return testing.DecodeJSON[[]map[string]any](response.Body)
+		}
+
+		var findEngine = func(engines []map[string]any, engineType trackentity.SplitEngineType) map[string]any {
+			for _, engine := range engines {
+				if engine["engine_type"] == string(engineType) {
+					return engine
+				}
+			}
+
+			Fail("Engine is missing from the list")
+			return nil
+		}
+
+		It("lists every installed engine", func() {
+			engines := getSplitEngines()
+			Expect(engines).To(HaveLen(3))
+		})
+
+		It("leaves out the optional engines that aren't installed", func() {
+			for _, engine := range getSplitEngines() {
+				Expect(engine["engine_type"]).NotTo(BeEquivalentTo(trackentity.OpenUnmixType))
+			}
+		})
+
+		It("lists the stem layouts of an engine, with the stems they make", func() {
+			demucs := findEngine(getSplitEngines(), trackentity.DemucsType)
+			Expect(demucs["quality"]).To(BeEquivalentTo(trackentity.BestQuality))
+
+			layouts := testing.ExpectType[[]any](demucs["layouts"])
+			Expect(layouts).To(HaveLen(3))
+
+			sixStems := testing.ExpectType[map[string]any](layouts[2])
+			Expect(sixStems["track_type"]).To(BeEquivalentTo(trackentity.SplitSixStemsType))
+			Expect(sixStems["stems"]).To(ConsistOf("vocals", "drums", "bass", "guitar", "piano", "other"))
+			Expect(sixStems["runtime_seconds_per_minute"]).To(BeNumerically(">", 0))
+		})
+	})
 })
@@ -0,0 +1,82 @@
+package trackusecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/track/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventsTokenTTL only has to cover the browser opening the stream,
+// an open stream isn't cut off when its token expires
+const eventsTokenTTL = time.Minute
+
+const eventsTokenSeparator = "."
+
+type EventsToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CreateEventsToken authorizes the user once, so the event stream can be opened with the token
+// in the query string. EventSource in the browser has no way to set the authorization header
+func (u Usecase) CreateEventsToken(ctx context.Context, authHeader string, songID string) (EventsToken, *api.Error) {
+	if apiErr := u.songUsecase.AuthorizeSongRead(ctx, authHeader, songID); apiErr != nil {
+		return EventsToken{}, api.WrapError(apiErr, "Cannot verify that this user can read the tracklist")
+	}
+
+	expiresAt := time.Now().Add(eventsTokenTTL).UTC().Truncate(time.Second)
+	return EventsToken{
+		Token:     u.signEventsToken(songID, expiresAt),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// tokens are the expiry followed by the HMAC of the song ID and the expiry,
+// so a token only opens the stream of the song it was made for
+func (u Usecase) signEventsToken(songID string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + eventsTokenSeparator + u.eventsTokenSignature(songID, expiry)
+}
+
+func (u Usecase) verifyEventsToken(songID string, token string) *api.Error {
+	expiry, signature, found := strings.Cut(token, eventsTokenSeparator)
+	if !found {
+		err := errors.New("Events token is malformed")
+		return api.CommitError(err, trackerrors.BadEventsTokenCode,
+			"The link to the track updates is invalid. Please try reloading the page")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(u.eventsTokenSignature(songID, expiry))) {
+		err := errors.New("Events token has an invalid signature")
+		return api.CommitError(err, trackerrors.BadEventsTokenCode,
+			"The link to the track updates is invalid. Please try reloading the page")
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		err = errors.Wrap(err, "Events token has a malformed expiry")
+		return api.CommitError(err, trackerrors.BadEventsTokenCode,
+			"The link to the track updates is invalid. Please try reloading the page")
+	}
+
+	if time.Now().After(time.Unix(expiresAt, 0)) {
+		err := errors.New("Events token has expired")
+		return api.CommitError(err, trackerrors.BadEventsTokenCode,
+			"The link to the track updates has expired. Please try reloading the page")
+	}
+
+	return nil
+}
+
+func (u Usecase) eventsTokenSignature(songID string, expiry string) string {
+	mac := hmac.New(sha256.New, u.eventsTokenSigningKey)
+	mac.Write([]byte(songID + eventsTokenSeparator + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,26 @@
+package trackusecase
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+)
+
+// GetJobRuns returns every stage the workers have run for the track, oldest first.
+// They're only shown to the owner, as they have the worker's error logs in them
+func (u Usecase) GetJobRuns(ctx context.Context, authHeader string, songID string, trackID string) ([]trackentity.JobRun, *api.Error) {
+	if apiErr := u.songUsecase.AuthorizeSongAccess(ctx, authHeader, songID, songentity.OwnerRole); apiErr != nil {
+		return nil, api.WrapError(apiErr, "Cannot verify that this user owns the song")
+	}
+
+	runs, err := u.jobRuns.GetJobRuns(ctx, songID, trackID)
+	if err != nil {
+		return nil, api.CommitError(errors.Wrap(err, "Failed to get job runs from DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to fetch the job history of the track. Please contact the developer")
+	}
+
+	return runs, nil
+}
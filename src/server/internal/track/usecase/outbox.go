@@ -0,0 +1,125 @@
+package trackusecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"time"
+)
+
+const (
+	OutboxRelayInterval = 30 * time.Second
+
+	// a job younger than this is most likely still being
+	// published by the request that saved it
+	outboxGracePeriod = time.Minute
+
+	// after this many tries the track is marked as failed,
+	// rather than leaving it in requested for good
+	maxOutboxPublishAttempts = 10
+)
+
+func makeStartJobs(tracklistID string, splitRequests []*trackentity.SplitRequestTrack) ([]trackentity.OutboxJob, error) {
+	createdAt := time.Now()
+
+	jobs := []trackentity.OutboxJob{}
+	for _, splitRequest := range splitRequests {
+		jsonBytes, err := json.Marshal(TrackIdentifier{
+			TrackListID: tracklistID,
+			TrackID:     splitRequest.ID,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to marshal job params for outbox job")
+		}
+
+		jobs = append(jobs, trackentity.OutboxJob{
+			ID:          uuid.New().String(),
+			TrackListID: tracklistID,
+			TrackID:     splitRequest.ID,
+			JobType:     string(trackentity.StartStage),
+			Body:        jsonBytes,
+			Status:      trackentity.PendingOutboxJobStatus,
+			CreatedAt:   createdAt,
+		})
+	}
+
+	return jobs, nil
+}
+
+// RelayOutbox keeps publishing the jobs that are left over in the outbox until the context is done.
+// These are the ones that couldn't be sent when their tracklist was saved,
+// because RabbitMQ was unreachable or the server went down right after
+func (u Usecase) RelayOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		u.RelayPendingJobs(ctx, time.Now().Add(-outboxGracePeriod))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPendingJobs publishes the jobs in the outbox that were created before the cutoff and haven't been sent yet
+func (u Usecase) RelayPendingJobs(ctx context.Context, createdBefore time.Time) {
+	jobs, err := u.outbox.GetPendingJobs(ctx, createdBefore)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch pending jobs from the outbox")
+		return
+	}
+
+	if len(jobs) > 0 {
+		log.WithField("job_count", len(jobs)).Info("Relaying pending jobs from the outbox")
+	}
+
+	u.publishOutboxJobs(ctx, jobs)
+}
+
+func (u Usecase) publishOutboxJobs(ctx context.Context, jobs []trackentity.OutboxJob) {
+	for _, job := range jobs {
+		u.publishOutboxJob(ctx, job)
+	}
+}
+
+func (u Usecase) publishOutboxJob(ctx context.Context, job trackentity.OutboxJob) {
+	logger := log.WithFields(log.Fields{
+		"job_id":       job.ID,
+		"job_type":     job.JobType,
+		"tracklist_id": job.TrackListID,
+		"track_id":     job.TrackID,
+	})
+
+	// the outbox job's ID carries over, so that publishing it twice is still the one job
+	jobMsg := rabbitmq.NewJobPublishing(job.JobType, job.Body)
+	jobMsg.MessageId = job.ID
+
+	err := u.publisher.Publish(jobMsg)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to publish message to rabbitmq")
+		giveUp := job.Attempts+1 >= maxOutboxPublishAttempts
+		logger.WithError(err).WithField("give_up", giveUp).Error("Failed to publish job from the outbox")
+
+		if err := u.outbox.MarkJobPublishFailed(ctx, job.ID, err, giveUp); err != nil {
+			logger.WithError(err).Error("Failed to record the failed publish in the outbox")
+		}
+
+		if giveUp {
+			u.markSplitJobFailed(job.TrackListID, job.TrackID, err)
+		}
+
+		return
+	}
+
+	// if this fails the job goes out again on the next relay, which the worker has to put up with anyway
+	if err := u.outbox.DeleteSentJob(ctx, job.ID); err != nil {
+		logger.WithError(err).Error("Failed to delete the sent job from the outbox")
+	}
+}
@@ -0,0 +1,182 @@
+package trackusecase
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/markers"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/track/errors"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/storage"
+	"time"
+)
+
+var errTrackNotRetryable = errors.New("Track is not a split request that has stopped")
+
+// a job that's told to stop sees it within seconds, so one that's been running for
+// longer than this was left behind by a worker that went away, and won't ever end
+const orphanedJobRunAge = time.Hour
+
+type splitJobParams struct {
+	TrackIdentifier
+	SavedOriginalURL string `json:"saved_original_url"`
+}
+
+type saveStemsJobParams struct {
+	TrackIdentifier
+	StemURLs map[string]string `json:"stem_urls"`
+}
+
+// RetryTrack restarts a failed or cancelled split request from the
+// stage after the last one it completed, so finished work isn't redone
+func (u Usecase) RetryTrack(ctx context.Context, authHeader string, songID string, trackID string) (trackentity.TrackList, *api.Error) {
+	if apiErr := u.songUsecase.AuthorizeSongAccess(ctx, authHeader, songID, songentity.EditorRole); apiErr != nil {
+		return trackentity.TrackList{},
+			api.WrapError(apiErr, "Cannot verify that this user can edit the tracklist")
+	}
+
+	if apiErr := u.ensureJobsStopped(ctx, songID, trackID); apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Cannot retry a track that's still running")
+	}
+
+	var retriedTrack trackentity.SplitRequestTrack
+	var stage trackentity.SplitJobStage
+	retry := func(track trackentity.Track) (trackentity.Track, error) {
+		splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok || !splitRequest.IsRetryable() {
+			return nil, errTrackNotRetryable
+		}
+
+		stage, ok = splitRequest.ResumeStage()
+		if !ok {
+			return nil, errTrackNotRetryable
+		}
+
+		resumeSplitRequest(splitRequest, stage)
+		retriedTrack = *splitRequest
+		return splitRequest, nil
+	}
+
+	err := u.db.UpdateTrack(ctx, songID, trackID, retry)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to retry track")
+		switch {
+		case errors.Is(err, errTrackNotRetryable):
+			return trackentity.TrackList{}, api.CommitError(err,
+				trackerrors.TrackNotRetryableCode,
+				"Only a split that has failed or been cancelled can be retried")
+		case markers.Is(err, trackstorage.TrackListNotFound):
+			fallthrough
+		case markers.Is(err, trackstorage.TrackNotFound):
+			return trackentity.TrackList{}, api.CommitError(err,
+				trackerrors.TrackNotFoundCode,
+				"The track to retry couldn't be found")
+		default:
+			return trackentity.TrackList{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to retry the track. Please contact the developer")
+		}
+	}
+
+	if err := u.publishResumedJob(songID, retriedTrack, stage); err != nil {
+		u.markSplitJobFailed(songID, retriedTrack.ID, err)
+
+		return trackentity.TrackList{}, api.CommitError(err,
+			api.DefaultErrorCode,
+			"Unknown error: Failed to queue up the retry. Please contact the developer")
+	}
+
+	return u.GetTrackListWithoutAuth(ctx, songID)
+}
+
+// resumeSplitRequest resets the status to what the worker would have set it to
+// when it got to the resumed stage, stages whose results have been kept are skipped
+func resumeSplitRequest(splitRequest *trackentity.SplitRequestTrack, stage trackentity.SplitJobStage) {
+	definition, ok := stage.Definition()
+	if !ok || stage == trackentity.SplitJobStages[0].Stage {
+		splitRequest.InitializeRequest()
+		return
+	}
+
+	splitRequest.Status = trackentity.RequestedStatus
+	splitRequest.StatusMessage = definition.StatusMessage
+	splitRequest.StatusDebugLog = ""
+	splitRequest.Progress = stage.ProgressBefore()
+	splitRequest.StageStartedAt = nil
+	splitRequest.ReapCount = 0
+}
+
+func (u Usecase) publishResumedJob(tracklistID string, splitRequest trackentity.SplitRequestTrack, stage trackentity.SplitJobStage) error {
+	trackIdentifier := TrackIdentifier{
+		TrackListID: tracklistID,
+		TrackID:     splitRequest.ID,
+	}
+
+	switch stage {
+	case trackentity.SaveStemsStage:
+		return u.publishJob(stage, saveStemsJobParams{
+			TrackIdentifier: trackIdentifier,
+			StemURLs:        splitRequest.SplitStemURLs,
+		})
+	case trackentity.SplitStage:
+		return u.publishJob(stage, splitJobParams{
+			TrackIdentifier:  trackIdentifier,
+			SavedOriginalURL: splitRequest.SavedOriginalURL,
+		})
+	default:
+		return u.publishJob(stage, trackIdentifier)
+	}
+}
+
+// ensureJobsStopped refuses a retry while a job of the track is still running, which is
+// the case for a little while after a cancel. Otherwise the retry would run alongside it
+func (u Usecase) ensureJobsStopped(ctx context.Context, songID string, trackID string) *api.Error {
+	runs, err := u.jobRuns.GetJobRuns(ctx, songID, trackID)
+	if err != nil {
+		return api.CommitError(errors.Wrap(err, "Failed to get job runs from DB"),
+			api.DefaultErrorCode,
+			"Unknown error: Failed to check whether the track has stopped. Please contact the developer")
+	}
+
+	for _, run := range runs {
+		if run.EndedAt == nil && time.Since(run.StartedAt) < orphanedJobRunAge {
+			err := errors.Newf("Job run %s of the track hasn't ended", run.ID)
+			return api.CommitError(err,
+				trackerrors.TrackNotRetryableCode,
+				"The split is still stopping. Please try again in a moment")
+		}
+	}
+
+	return nil
+}
+
+// restoreSplitJobFields keeps what the worker recorded about its progress,
+// the client has no business changing which files the job resumes with
+func restoreSplitJobFields(storedTracklist trackentity.TrackList, tracklist trackentity.TrackList) {
+	storedSplitRequests := map[string]*trackentity.SplitRequestTrack{}
+	for _, track := range storedTracklist.Defined.Tracks {
+		if splitRequest, ok := track.(*trackentity.SplitRequestTrack); ok {
+			storedSplitRequests[splitRequest.ID] = splitRequest
+		}
+	}
+
+	for _, track := range tracklist.Defined.Tracks {
+		splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok {
+			continue
+		}
+
+		storedSplitRequest, ok := storedSplitRequests[splitRequest.ID]
+		if !ok {
+			storedSplitRequest = &trackentity.SplitRequestTrack{}
+		}
+
+		splitRequest.CompletedStage = storedSplitRequest.CompletedStage
+		splitRequest.SavedOriginalURL = storedSplitRequest.SavedOriginalURL
+		splitRequest.SplitStemURLs = storedSplitRequest.SplitStemURLs
+		splitRequest.LastUpdatedAt = storedSplitRequest.LastUpdatedAt
+		splitRequest.StageStartedAt = storedSplitRequest.StageStartedAt
+		splitRequest.ReapCount = storedSplitRequest.ReapCount
+	}
+}
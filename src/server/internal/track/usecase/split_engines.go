@@ -0,0 +1,11 @@
+package trackusecase
+
+import (
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+)
+
+// GetSplitEngines lists the installed engines that a split request can pick from,
+// along with the stem layouts that each of them can do
+func (u Usecase) GetSplitEngines() []trackentity.SplitEngine {
+	return u.splitEngines.List()
+}
@@ -0,0 +1,123 @@
+package trackusecase
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/track/errors"
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"strings"
+	"time"
+)
+
+// long enough to get through a practice session with the page left open
+const stemURLExpiry = 3 * time.Hour
+
+// stems split by the worker are stored as object paths in the bucket, while
+// stems added by the user, and the ones split before signing existed, are full URLs
+func isObjectPath(stemURL string) bool {
+	return !strings.HasPrefix(stemURL, "http://") && !strings.HasPrefix(stemURL, "https://")
+}
+
+// storedObjectPath is the object path to sign for a stored stem URL.
+// Stems split before signing existed are stored as public URLs to the bucket,
+// those are signed as well so that the bucket doesn't have to stay public for them
+func (u Usecase) storedObjectPath(stemURL string) (string, bool) {
+	if isObjectPath(stemURL) {
+		return stemURL, true
+	}
+
+	if resolver, ok := u.urlSigner.(cloudstorage.LegacyURLResolver); ok {
+		return resolver.ObjectPathFromURL(stemURL)
+	}
+
+	return "", false
+}
+
+func (u Usecase) signStemURLs(ctx context.Context, tracklist trackentity.TrackList) *api.Error {
+	for _, track := range tracklist.Defined.Tracks {
+		stemTrack, ok := track.(*trackentity.StemTrack)
+		if !ok || stemTrack.StemURLs == nil {
+			continue
+		}
+
+		signedURLs := map[string]string{}
+		for stemName, stemURL := range stemTrack.StemURLs {
+			objectPath, ok := u.storedObjectPath(stemURL)
+			if !ok {
+				signedURLs[stemName] = stemURL
+				continue
+			}
+
+			signedURL, err := u.urlSigner.SignURL(ctx, objectPath, stemURLExpiry)
+			if err != nil {
+				return api.CommitError(errors.Wrap(err, "Failed to sign stem URL"),
+					api.DefaultErrorCode,
+					"Unknown Error: Failed to prepare the links to the stem tracks")
+			}
+
+			signedURLs[stemName] = signedURL
+		}
+
+		stemTrack.StemURLs = signedURLs
+	}
+
+	return nil
+}
+
+// restoreStoredFields puts back the fields of the stored tracklist that only the server and worker can set
+func (u Usecase) restoreStoredFields(ctx context.Context, tracklist trackentity.TrackList) *api.Error {
+	storedTracklist, apiErr := u.fetchTrackList(ctx, tracklist.Defined.SongID)
+	if apiErr != nil {
+		return api.WrapError(apiErr, "Failed to fetch the stored tracklist")
+	}
+
+	if apiErr := u.restoreStemPaths(storedTracklist, tracklist); apiErr != nil {
+		return api.WrapError(apiErr, "Failed to restore stored stem paths")
+	}
+
+	restoreSplitJobFields(storedTracklist, tracklist)
+	return nil
+}
+
+// restoreStemPaths swaps the signed URLs that the client sends back for the stored object paths.
+// Object paths can't be set through the API, otherwise any path in the bucket could get signed
+func (u Usecase) restoreStemPaths(storedTracklist trackentity.TrackList, tracklist trackentity.TrackList) *api.Error {
+	storedStemURLs := map[string]map[string]string{}
+	for _, track := range storedTracklist.Defined.Tracks {
+		if stemTrack, ok := track.(*trackentity.StemTrack); ok {
+			storedStemURLs[stemTrack.ID] = stemTrack.StemURLs
+		}
+	}
+
+	for _, track := range tracklist.Defined.Tracks {
+		stemTrack, ok := track.(*trackentity.StemTrack)
+		if !ok || stemTrack.StemURLs == nil {
+			continue
+		}
+
+		restoredURLs := map[string]string{}
+		for stemName, stemURL := range stemTrack.StemURLs {
+			storedURL := storedStemURLs[stemTrack.ID][stemName]
+			_, storedURLIsSigned := u.storedObjectPath(storedURL)
+			_, stemURLIsSigned := u.storedObjectPath(stemURL)
+
+			switch {
+			case storedURL != "" && storedURLIsSigned:
+				restoredURLs[stemName] = storedURL
+			case stemURLIsSigned:
+				err := errors.Newf("Stem URL %s for stem %s is a path in the bucket", stemURL, stemName)
+				return api.CommitError(err,
+					trackerrors.BadTracklistDataCode,
+					"Stem tracks can only be added with links to the audio")
+			default:
+				restoredURLs[stemName] = stemURL
+			}
+		}
+
+		stemTrack.StemURLs = restoredURLs
+	}
+
+	return nil
+}
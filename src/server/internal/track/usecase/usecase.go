@@ -6,30 +6,85 @@ import (
 	"github.com/apex/log"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/markers"
-	"github.com/rabbitmq/amqp091-go"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/errors/api"
+	"github.com/veedubyou/chord-paper-be/src/server/internal/song/entity"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/song/usecase"
 	"github.com/veedubyou/chord-paper-be/src/server/internal/track/errors"
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/storage"
+	"time"
 )
 
 type Usecase struct {
-	db          trackentity.Store
-	songUsecase songusecase.Usecase
-	publisher   rabbitmq.Publisher
+	db              trackentity.Store
+	outbox          trackentity.JobOutbox
+	jobRuns         trackentity.JobRunStore
+	songUsecase     songusecase.Usecase
+	publisher       rabbitmq.Publisher
+	urlSigner       cloudstorage.URLSigner
+	eventSubscriber trackentity.EventSubscriber
+	// eventsTokenSigningKey signs the short lived tokens that open the event stream
+	eventsTokenSigningKey []byte
+	splitEngines          trackentity.InstalledSplitEngines
 }
 
-func NewUsecase(db trackentity.Store, songUsecase songusecase.Usecase, publisher rabbitmq.Publisher) Usecase {
+func NewUsecase(db trackentity.Store, outbox trackentity.JobOutbox, jobRuns trackentity.JobRunStore, songUsecase songusecase.Usecase, publisher rabbitmq.Publisher, urlSigner cloudstorage.URLSigner, eventSubscriber trackentity.EventSubscriber, eventsTokenSigningKey string, splitEngines trackentity.InstalledSplitEngines) Usecase {
 	return Usecase{
-		db:          db,
-		songUsecase: songUsecase,
-		publisher:   publisher,
+		db:                    db,
+		outbox:                outbox,
+		jobRuns:               jobRuns,
+		songUsecase:           songUsecase,
+		publisher:             publisher,
+		urlSigner:             urlSigner,
+		eventSubscriber:       eventSubscriber,
+		eventsTokenSigningKey: []byte(eventsTokenSigningKey),
+		splitEngines:          splitEngines,
 	}
 }
 
-func (u Usecase) GetTrackList(ctx context.Context, songID string) (trackentity.TrackList, *api.Error) {
+func (u Usecase) GetTrackList(ctx context.Context, authHeader string, songID string) (trackentity.TrackList, *api.Error) {
+	if apiErr := u.songUsecase.AuthorizeSongRead(ctx, authHeader, songID); apiErr != nil {
+		return trackentity.TrackList{},
+			api.WrapError(apiErr, "Cannot verify that this user can read the tracklist")
+	}
+
+	return u.GetTrackListWithoutAuth(ctx, songID)
+}
+
+// SubscribeToTrackList streams the status changes of the tracks being processed,
+// the caller has to unsubscribe once it's done listening.
+// The subscriber is authorized by either the events token or the auth header
+func (u Usecase) SubscribeToTrackList(ctx context.Context, authHeader string, eventsToken string, songID string) (<-chan trackentity.TrackEvent, func(), *api.Error) {
+	if eventsToken != "" {
+		if apiErr := u.verifyEventsToken(songID, eventsToken); apiErr != nil {
+			return nil, nil, api.WrapError(apiErr, "Cannot verify the events token")
+		}
+	} else if apiErr := u.songUsecase.AuthorizeSongRead(ctx, authHeader, songID); apiErr != nil {
+		return nil, nil, api.WrapError(apiErr, "Cannot verify that this user can read the tracklist")
+	}
+
+	events, unsubscribe := u.eventSubscriber.Subscribe(songID)
+	return events, unsubscribe, nil
+}
+
+// GetTrackListWithoutAuth returns the tracklist regardless of the song's visibility,
+// for callers that have already authorized the request by other means
+func (u Usecase) GetTrackListWithoutAuth(ctx context.Context, songID string) (trackentity.TrackList, *api.Error) {
+	tracklist, apiErr := u.fetchTrackList(ctx, songID)
+	if apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Failed to fetch tracklist")
+	}
+
+	if apiErr := u.signStemURLs(ctx, tracklist); apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Failed to sign stem URLs")
+	}
+
+	return tracklist, nil
+}
+
+func (u Usecase) fetchTrackList(ctx context.Context, songID string) (trackentity.TrackList, *api.Error) {
 	tracklist, err := u.db.GetTrackList(ctx, songID)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to get tracklist from DB")
@@ -54,19 +109,36 @@ func (u Usecase) GetTrackList(ctx context.Context, songID string) (trackentity.T
 }
 
 func (u Usecase) SetTrackList(ctx context.Context, authHeader string, songID string, tracklist trackentity.TrackList) (trackentity.TrackList, *api.Error) {
-	if apiErr := u.songUsecase.VerifySongOwnerBySongID(ctx, authHeader, songID); apiErr != nil {
+	if apiErr := u.songUsecase.AuthorizeSongAccess(ctx, authHeader, songID, songentity.EditorRole); apiErr != nil {
 		return trackentity.TrackList{},
-			api.WrapError(apiErr, "Cannot verify the song owner for the tracklist")
+			api.WrapError(apiErr, "Cannot verify that this user can edit the tracklist")
 	}
 
 	// just overwrite the song ID in case there's any discrepancies
 	tracklist.Defined.SongID = songID
 
+	if apiErr := u.restoreStoredFields(ctx, tracklist); apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Failed to restore stored track fields")
+	}
+
 	newSplitRequests := initializeNewSplitRequests(tracklist)
 
+	// turn away what the worker can't split, rather than queueing it up to fail there
+	if apiErr := u.validateSplitRequests(newSplitRequests); apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Invalid split request")
+	}
+
 	tracklist.EnsureTrackIDs()
 
-	err := u.db.SetTrackList(ctx, tracklist)
+	startJobs, err := makeStartJobs(songID, newSplitRequests)
+	if err != nil {
+		return trackentity.TrackList{}, api.CommitError(err,
+			api.DefaultErrorCode,
+			"Unknown error: Failed to prepare the split jobs. Please contact the developer")
+	}
+
+	// the jobs are saved along with the tracklist, so that they can't be lost in between
+	err = u.outbox.SetTrackListWithJobs(ctx, tracklist, startJobs)
 	if err != nil {
 		err = errors.Wrap(err, "Failed to set tracklist")
 		switch {
@@ -86,23 +158,72 @@ func (u Usecase) SetTrackList(ctx context.Context, authHeader string, songID str
 		}
 	}
 
-	// do this as non-blocking as it's a long term async work
-	go u.publishAllSplitRequests(tracklist.Defined.SongID, newSplitRequests)
+	// publish right away without holding up the response,
+	// whatever doesn't make it out is picked up by the outbox relay
+	go u.publishOutboxJobs(context.Background(), startJobs)
+
+	// the caller gets back what they would from a fetch
+	if apiErr := u.signStemURLs(ctx, tracklist); apiErr != nil {
+		return trackentity.TrackList{}, api.WrapError(apiErr, "Failed to sign stem URLs")
+	}
 
 	return tracklist, nil
 }
 
-type failedSplitJob struct {
-	track *trackentity.SplitRequestTrack
-	err   error
+var errTrackNotCancellable = errors.New("Track is not a split request that is in progress")
+
+// CancelTrack marks the split request as cancelled. The worker notices
+// between its stages, and stops the split if one is running
+func (u Usecase) CancelTrack(ctx context.Context, authHeader string, songID string, trackID string) (trackentity.TrackList, *api.Error) {
+	if apiErr := u.songUsecase.AuthorizeSongAccess(ctx, authHeader, songID, songentity.EditorRole); apiErr != nil {
+		return trackentity.TrackList{},
+			api.WrapError(apiErr, "Cannot verify that this user can edit the tracklist")
+	}
+
+	cancel := func(track trackentity.Track) (trackentity.Track, error) {
+		splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok || !splitRequest.IsInProgress() {
+			return nil, errTrackNotCancellable
+		}
+
+		splitRequest.Status = trackentity.CancelledStatus
+		splitRequest.StatusMessage = "The splitting job for the audio has been cancelled"
+		return splitRequest, nil
+	}
+
+	err := u.db.UpdateTrack(ctx, songID, trackID, cancel)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to cancel track")
+		switch {
+		case errors.Is(err, errTrackNotCancellable):
+			return trackentity.TrackList{}, api.CommitError(err,
+				trackerrors.TrackNotCancellableCode,
+				"This track isn't being split right now, so there's nothing to cancel")
+		case markers.Is(err, trackstorage.TrackListNotFound):
+			fallthrough
+		case markers.Is(err, trackstorage.TrackNotFound):
+			return trackentity.TrackList{}, api.CommitError(err,
+				trackerrors.TrackNotFoundCode,
+				"The track to cancel couldn't be found")
+		default:
+			return trackentity.TrackList{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"Unknown error: Failed to cancel the track. Please contact the developer")
+		}
+	}
+
+	return u.GetTrackListWithoutAuth(ctx, songID)
 }
 
 func initializeNewSplitRequests(tracklist trackentity.TrackList) []*trackentity.SplitRequestTrack {
 	newSplitRequests := []*trackentity.SplitRequestTrack{}
+	now := time.Now()
 	for _, track := range tracklist.Defined.Tracks {
 		if track.IsNew() {
 			if splitRequest, ok := track.(*trackentity.SplitRequestTrack); ok {
 				splitRequest.InitializeRequest()
+				// the reaper counts from here in case the start job is lost
+				splitRequest.Touch(now)
 				newSplitRequests = append(newSplitRequests, splitRequest)
 			}
 		}
@@ -111,22 +232,29 @@ func initializeNewSplitRequests(tracklist trackentity.TrackList) []*trackentity.
 	return newSplitRequests
 }
 
-func (u Usecase) publishAllSplitRequests(tracklistID string, newSplitRequests []*trackentity.SplitRequestTrack) {
-	failedSplitJobs := []failedSplitJob{}
-	for _, track := range newSplitRequests {
-		err := u.publishSplitJob(tracklistID, track.ID)
-		if err != nil {
-			err = errors.Wrap(err, "Failed to publish split job for track")
-			failedSplitJobs = append(failedSplitJobs, failedSplitJob{
-				track: track,
-				err:   err,
-			})
+// validateSplitRequests turns away the split requests that the worker would only fail on
+func (u Usecase) validateSplitRequests(splitRequests []*trackentity.SplitRequestTrack) *api.Error {
+	for _, splitRequest := range splitRequests {
+		switch {
+		case !u.splitEngines.Has(splitRequest.EngineType):
+			err := errors.Newf("Engine %s is not an installed split engine", splitRequest.EngineType)
+			return api.CommitError(err,
+				trackerrors.BadTracklistDataCode,
+				"The split request has to pick one of the available engines")
+		case !splitRequest.EngineType.Supports(splitRequest.TrackType):
+			err := errors.Newf("Engine %s does not support split type %s", splitRequest.EngineType, splitRequest.TrackType)
+			return api.CommitError(err,
+				trackerrors.BadTracklistDataCode,
+				"The chosen engine can't split the track into that many stems")
+		case splitRequest.OriginalURL == "":
+			err := errors.New("Split request has no original URL")
+			return api.CommitError(err,
+				trackerrors.BadTracklistDataCode,
+				"The split request needs a link to the track to split")
 		}
 	}
 
-	for _, failedJob := range failedSplitJobs {
-		u.markSplitJobFailed(tracklistID, failedJob)
-	}
+	return nil
 }
 
 type TrackIdentifier struct {
@@ -134,22 +262,13 @@ type TrackIdentifier struct {
 	TrackID     string `json:"track_id"`
 }
 
-func (u Usecase) publishSplitJob(tracklistID string, trackID string) error {
-	jsonBytes, err := json.Marshal(TrackIdentifier{
-		TrackListID: tracklistID,
-		TrackID:     trackID,
-	})
-
+func (u Usecase) publishJob(stage trackentity.SplitJobStage, jobParams any) error {
+	jsonBytes, err := json.Marshal(jobParams)
 	if err != nil {
-		return errors.Wrap(err, "Failed to marshal tracklist and track IDs for queue msg")
-	}
-
-	publishMsg := amqp091.Publishing{
-		Type: "start_job",
-		Body: jsonBytes,
+		return errors.Wrap(err, "Failed to marshal job params for queue msg")
 	}
 
-	err = u.publisher.Publish(publishMsg)
+	err = u.publisher.Publish(rabbitmq.NewJobPublishing(string(stage), jsonBytes))
 	if err != nil {
 		return errors.Wrap(err, "Failed to publish message to rabbitmq")
 	}
@@ -157,22 +276,26 @@ func (u Usecase) publishSplitJob(tracklistID string, trackID string) error {
 	return nil
 }
 
-func (u Usecase) markSplitJobFailed(tracklistID string, failedSplitJob failedSplitJob) {
+func (u Usecase) markSplitJobFailed(tracklistID string, trackID string, publishErr error) {
 	updater := func(track trackentity.Track) (trackentity.Track, error) {
-		failedTrack := failedSplitJob.track
-		failedTrack.Status = "error"
+		failedTrack, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok {
+			return nil, errors.New("Track is not a split request")
+		}
+
+		failedTrack.Status = trackentity.ErrorStatus
 		failedTrack.StatusMessage = ""
-		failedTrack.StatusDebugLog = failedSplitJob.err.Error()
+		failedTrack.StatusDebugLog = publishErr.Error()
 		failedTrack.Progress = 10
 		return failedTrack, nil
 	}
 
-	trackID := failedSplitJob.track.ID
-
 	err := u.db.UpdateTrack(context.Background(), tracklistID, trackID, updater)
 	if err != nil {
-		log.WithField("track", failedSplitJob.track).
-			Error("Failed to set track in DB")
+		log.WithFields(log.Fields{
+			"tracklist_id": tracklistID,
+			"track_id":     trackID,
+		}).WithError(err).Error("Failed to set track in DB")
 		return
 	}
 }
@@ -11,6 +11,7 @@ import (
 
 const (
 	UsersTable = "Users"
+	emailIndex = "email-index"
 )
 
 type DB struct {
@@ -47,6 +48,30 @@ func (d DB) GetUser(ctx context.Context, userID string) (userentity.User, error)
 	}, nil
 }
 
+func (d DB) GetUserByEmail(ctx context.Context, email string) (userentity.User, error) {
+	values := []dbUser{}
+	err := d.dynamoDB.Table(UsersTable).
+		Get(emailKey, email).
+		Index(emailIndex).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return userentity.User{}, mark.Wrap(err, DefaultErrorMark, "Failed to fetch user by email")
+	}
+
+	if len(values) == 0 {
+		return userentity.User{}, mark.Message(UserNotFoundMark, "No user is found with this email")
+	}
+
+	value := values[0]
+	return userentity.User{
+		ID:       value.ID,
+		Name:     value.Name,
+		Email:    value.Email,
+		Verified: value.Verified,
+	}, nil
+}
+
 func (d DB) SetUser(ctx context.Context, user userentity.User) error {
 	value := dbUser{
 		ID:       user.ID,
@@ -1,7 +1,8 @@
 package userstorage
 
 const (
-	idKey = "id"
+	idKey    = "id"
+	emailKey = "email"
 )
 
 type dbUser struct {
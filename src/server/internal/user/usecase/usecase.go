@@ -80,6 +80,42 @@ func (u Usecase) VerifyOwner(ctx context.Context, authHeader string, ownerID str
 	return nil
 }
 
+// AuthenticateUser returns the account of the user from the auth header
+func (u Usecase) AuthenticateUser(ctx context.Context, authHeader string) (userentity.User, *api.Error) {
+	userFromGoogle, apiErr := u.validateHeader(ctx, authHeader)
+	if apiErr != nil {
+		return userentity.User{}, api.WrapError(apiErr, "Failed to validate auth header")
+	}
+
+	user, apiErr := u.getUser(ctx, userFromGoogle.GoogleID)
+	if apiErr != nil {
+		return userentity.User{}, api.WrapError(apiErr, "Failed to find user account")
+	}
+
+	return user, nil
+}
+
+func (u Usecase) GetUserByEmail(ctx context.Context, email string) (userentity.User, *api.Error) {
+	user, err := u.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		switch {
+		case markers.Is(err, userstorage.UserNotFoundMark):
+			return userentity.User{}, api.CommitError(err,
+				auth.NoAccountCode,
+				"A Chord Paper account could not be found for this email")
+
+		case markers.Is(err, userstorage.DefaultErrorMark):
+			fallthrough
+		default:
+			return userentity.User{}, api.CommitError(err,
+				api.DefaultErrorCode,
+				"User information could not be retrieved")
+		}
+	}
+
+	return user, nil
+}
+
 func (u Usecase) Login(ctx context.Context, authHeader string) (userentity.User, *api.Error) {
 	userFromGoogle, apiErr := u.validateHeader(ctx, authHeader)
 	if apiErr != nil {
@@ -31,22 +31,31 @@ func main() {
 				SecretAccessKey: envvar.MustGet(envvar.AWS_SECRET_ACCESS_KEY),
 				Region:          prod.DynamoDBRegion,
 			},
-			RabbitMQURL:        envvar.MustGet(envvar.RABBITMQ_URL),
-			RabbitMQQueueName:  envvar.MustGet(envvar.RABBITMQ_QUEUE_NAME),
-			CORSAllowedOrigins: allowedOrigins,
-			UserValidator:      google_id.GoogleValidator{ClientID: googleClientID},
-			Port:               ":5000",
-			Log:                true,
+			CloudStorageConfig:    config.CloudStorageFromEnv(),
+			RabbitMQURL:           envvar.MustGet(envvar.RABBITMQ_URL),
+			RabbitMQQueueName:     envvar.MustGet(envvar.RABBITMQ_QUEUE_NAME),
+			CORSAllowedOrigins:    allowedOrigins,
+			UserValidator:         google_id.GoogleValidator{ClientID: googleClientID},
+			ShareLinkSigningKey:   envvar.MustGet(envvar.SHARE_LINK_SIGNING_KEY),
+			EventsTokenSigningKey: envvar.MustGet(envvar.EVENTS_TOKEN_SIGNING_KEY),
+			OpenUnmixEnabled:      envvar.GetBoolOrDefault(envvar.OPEN_UNMIX_ENABLED, false),
+			Port:                  ":5000",
+			Log:                   true,
 		}
 	case env.Development:
 		appConfig = application.Config{
-			DynamoConfig:       dev.DynamoConfig,
-			RabbitMQURL:        dev.RabbitMQHost,
-			RabbitMQQueueName:  dev.RabbitMQQueueName,
-			CORSAllowedOrigins: []string{"*"},
-			UserValidator:      google_id.GoogleValidator{ClientID: googleClientID},
-			Port:               ":5000",
-			Log:                true,
+			DynamoConfig: dev.DynamoConfig,
+			// same as the worker, the stems are in the prod bucket unless FILE_STORE_TYPE says otherwise
+			CloudStorageConfig:    config.CloudStorageFromEnv(),
+			RabbitMQURL:           dev.RabbitMQHost,
+			RabbitMQQueueName:     dev.RabbitMQQueueName,
+			CORSAllowedOrigins:    []string{"*"},
+			UserValidator:         google_id.GoogleValidator{ClientID: googleClientID},
+			ShareLinkSigningKey:   dev.ShareLinkSigningKey,
+			EventsTokenSigningKey: dev.EventsTokenSigningKey,
+			OpenUnmixEnabled:      envvar.GetBoolOrDefault(envvar.OPEN_UNMIX_ENABLED, false),
+			Port:                  ":5000",
+			Log:                   true,
 		}
 
 	default:
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// FileStore reads and writes objects by their path within the configured bucket.
+// Audio files can be large, so contents are streamed rather than held in memory
+type FileStore interface {
+	URLSigner
+	// OpenFile streams the object, the caller is responsible for closing the reader
+	OpenFile(ctx context.Context, objectPath string) (io.ReadCloser, error)
+	// UploadFile streams the content up in chunks until the reader is exhausted
+	UploadFile(ctx context.Context, objectPath string, content io.Reader) error
+}
+
+// LegacyURLResolver is for stores that used to hand out public URLs to their objects.
+// Stems split before stem URLs were signed are stored with those URLs,
+// which only work for as long as the bucket stays public unless they're signed too
+type LegacyURLResolver interface {
+	// ObjectPathFromURL is false when the URL isn't to an object in the bucket
+	ObjectPathFromURL(fileURL string) (string, bool)
+}
+
+// URLSigner turns the path of an object in the bucket into a URL
+// that can be fetched without credentials until it expires
+type URLSigner interface {
+	SignURL(ctx context.Context, objectPath string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/config"
+	"google.golang.org/api/option"
+	"time"
+)
+
+func NewFileStore(cloudStorageConfig config.CloudStorage) (entity.FileStore, error) {
+	switch t := cloudStorageConfig.(type) {
+	case config.ProdCloudStorage:
+		return NewGoogleFileStore(
+			t.StorageHost,
+			t.BucketName,
+			option.WithCredentialsJSON([]byte(t.SecretKey)),
+		)
+
+	case config.LocalCloudStorage:
+		googleFileStore, err := NewGoogleFileStore(
+			t.StorageHost,
+			t.BucketName,
+			option.WithEndpoint(t.HostEndpoint),
+			option.WithAPIKey("fake_api_key"),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create local Google file store")
+		}
+
+		// the local fake GCS has no service account to sign with
+		return localGoogleFileStore{
+			GoogleFileStore: googleFileStore,
+			signer: FakeURLSigner{
+				StorageHost: t.StorageHost,
+				Bucket:      t.BucketName,
+			},
+		}, nil
+
+	case config.LocalDiskStorage:
+		return NewLocalDiskFileStore(t.RootDir, t.PublicURL, t.SigningKey)
+
+	case config.S3Storage:
+		return NewS3FileStore(
+			t.Endpoint,
+			t.Region,
+			t.AccessKeyID,
+			t.SecretAccessKey,
+			t.BucketName,
+		)
+
+	default:
+		return nil, errors.New("Unrecognized cloud storage config")
+	}
+}
+
+type localGoogleFileStore struct {
+	GoogleFileStore
+	signer FakeURLSigner
+}
+
+func (l localGoogleFileStore) SignURL(ctx context.Context, objectPath string, expiry time.Duration) (string, error) {
+	return l.signer.SignURL(ctx, objectPath, expiry)
+}
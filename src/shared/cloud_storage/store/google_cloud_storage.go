@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+var _ entity.FileStore = GoogleFileStore{}
+var _ entity.LegacyURLResolver = GoogleFileStore{}
+
+type GoogleFileStore struct {
+	storageClient *storage.Client
+	storageHost   string
+	bucket        string
+}
+
+func NewGoogleFileStore(storageHost string, bucket string, options ...option.ClientOption) (GoogleFileStore, error) {
+	googleStorageClient, err := storage.NewClient(context.Background(), options...)
+
+	if err != nil {
+		return GoogleFileStore{}, errors.Wrap(err, "Failed to create Google Cloud Storage client")
+	}
+
+	return GoogleFileStore{
+		storageHost:   storageHost,
+		storageClient: googleStorageClient,
+		bucket:        bucket,
+	}, nil
+}
+
+func (g GoogleFileStore) OpenFile(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	reader, err := g.objectHandle(objectPath).NewReader(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create reader for Google object handle, object_path: %s", objectPath)
+	}
+
+	return reader, nil
+}
+
+// UploadFile uses a resumable upload, sending the content a chunk at a time
+func (g GoogleFileStore) UploadFile(ctx context.Context, objectPath string, content io.Reader) (err error) {
+	// closing the writer commits whatever it has been given,
+	// so a failed upload has to be cancelled before that
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := g.objectHandle(objectPath).NewWriter(ctx)
+	writer.ChunkSize = uploadChunkSize
+
+	if _, err = io.Copy(writer, content); err != nil {
+		cancel()
+		_ = writer.Close()
+		return errors.Wrapf(err, "Error occurred when uploading file, object_path: %s", objectPath)
+	}
+
+	if err = writer.Close(); err != nil {
+		return errors.Wrapf(err, "Error occurred when closing the upload stream, object_path: %s", objectPath)
+	}
+
+	return nil
+}
+
+// SignURL signs with the service account key from the client credentials
+func (g GoogleFileStore) SignURL(_ context.Context, objectPath string, expiry time.Duration) (string, error) {
+	signedURL, err := g.storageClient.Bucket(g.bucket).SignedURL(g.trimLegacyURL(objectPath), &storage.SignedURLOptions{
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expiry),
+		Scheme:  storage.SigningSchemeV4,
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to sign URL for object, object_path: %s", objectPath)
+	}
+
+	return signedURL, nil
+}
+
+func (g GoogleFileStore) ObjectPathFromURL(fileURL string) (string, bool) {
+	return objectPathFromBucketURL(g.storageHost, g.bucket, fileURL)
+}
+
+// trimLegacyURL accepts the full bucket URLs that jobs queued
+// before the switch to object paths may still be carrying
+func (g GoogleFileStore) trimLegacyURL(objectPath string) string {
+	if trimmedPath, ok := g.ObjectPathFromURL(objectPath); ok {
+		return trimmedPath
+	}
+
+	return objectPath
+}
+
+func objectPathFromBucketURL(storageHost string, bucket string, fileURL string) (string, bool) {
+	objectPath := strings.TrimPrefix(fileURL, fmt.Sprintf("%s/%s/", storageHost, bucket))
+	if objectPath == fileURL || objectPath == "" {
+		return "", false
+	}
+
+	return objectPath, true
+}
+
+func (g GoogleFileStore) objectHandle(objectPath string) *storage.ObjectHandle {
+	return g.storageClient.Bucket(g.bucket).Object(g.trimLegacyURL(objectPath))
+}
@@ -0,0 +1,106 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
+	"google.golang.org/api/option"
+)
+
+var errStreamBroken = errors.New("stream broken")
+
+// failingReader hands out its content, then fails instead of ending
+type failingReader struct {
+	content io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.content.Read(p)
+	if err == io.EOF {
+		return n, errStreamBroken
+	}
+
+	return n, err
+}
+
+var _ = Describe("GoogleFileStore", func() {
+	var (
+		fileStore store.GoogleFileStore
+		server    *httptest.Server
+
+		uploadsLock sync.Mutex
+		uploads     []string
+	)
+
+	BeforeEach(func() {
+		uploads = nil
+
+		// stands in for the upload endpoint, taking every upload as a finished object
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/upload/") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			_, _ = io.Copy(io.Discard, r.Body)
+
+			uploadsLock.Lock()
+			uploads = append(uploads, r.URL.Path)
+			uploadsLock.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"bucket": "bucket", "name": "tracklist/track/original/original.mp3"}`))
+		}))
+
+		var err error
+		fileStore, err = store.NewGoogleFileStore(server.URL, "bucket",
+			option.WithEndpoint(server.URL+"/storage/v1/"),
+			option.WithoutAuthentication(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	uploadCount := func() int {
+		uploadsLock.Lock()
+		defer uploadsLock.Unlock()
+		return len(uploads)
+	}
+
+	Describe("Uploading", func() {
+		It("saves the object", func() {
+			err := fileStore.UploadFile(context.Background(), "tracklist/track/original/original.mp3", bytes.NewReader([]byte("cool_jamz")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uploadCount()).To(Equal(1))
+		})
+
+		Describe("When the content fails partway through", func() {
+			var uploadErr error
+
+			BeforeEach(func() {
+				content := failingReader{content: bytes.NewReader([]byte("cool_ja"))}
+				uploadErr = fileStore.UploadFile(context.Background(), "tracklist/track/original/original.mp3", content)
+			})
+
+			It("returns the error", func() {
+				Expect(uploadErr).To(MatchError(ContainSubstring(errStreamBroken.Error())))
+			})
+
+			It("doesn't save the partial object", func() {
+				Consistently(uploadCount).Should(BeZero())
+			})
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ entity.FileStore = LocalDiskFileStore{}
+
+// LocalDiskFileStore keeps the objects as files under a root directory.
+// Signed URLs point at FileServer, which checks the signature before serving the file
+type LocalDiskFileStore struct {
+	rootDir    string
+	publicURL  string
+	signingKey []byte
+}
+
+func NewLocalDiskFileStore(rootDir string, publicURL string, signingKey string) (LocalDiskFileStore, error) {
+	if signingKey == "" {
+		return LocalDiskFileStore{}, errors.New("Signing key is required for the local disk file store")
+	}
+
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return LocalDiskFileStore{}, errors.Wrapf(err, "Failed to resolve the root dir, root_dir: %s", rootDir)
+	}
+
+	if err := os.MkdirAll(absRootDir, os.ModePerm); err != nil {
+		return LocalDiskFileStore{}, errors.Wrapf(err, "Failed to create the root dir, root_dir: %s", absRootDir)
+	}
+
+	return LocalDiskFileStore{
+		rootDir:    absRootDir,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+func (l LocalDiskFileStore) OpenFile(_ context.Context, objectPath string) (io.ReadCloser, error) {
+	file, err := os.Open(l.filePath(objectPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open file, object_path: %s", objectPath)
+	}
+
+	return file, nil
+}
+
+func (l LocalDiskFileStore) UploadFile(_ context.Context, objectPath string, content io.Reader) error {
+	filePath := l.filePath(objectPath)
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return errors.Wrapf(err, "Failed to create the directory for the file, object_path: %s", objectPath)
+	}
+
+	// write to the side and rename, so that a reader never sees half a file
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create temp file, object_path: %s", objectPath)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := io.Copy(tempFile, content); err != nil {
+		tempFile.Close()
+		return errors.Wrapf(err, "Failed to write file, object_path: %s", objectPath)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to close file, object_path: %s", objectPath)
+	}
+
+	if err := os.Rename(tempFile.Name(), filePath); err != nil {
+		return errors.Wrapf(err, "Failed to move file into place, object_path: %s", objectPath)
+	}
+
+	return nil
+}
+
+func (l LocalDiskFileStore) SignURL(_ context.Context, objectPath string, expiry time.Duration) (string, error) {
+	objectPath = cleanObjectPath(objectPath)
+	expiresAt := time.Now().Add(expiry).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", l.signature(objectPath, expiresAt))
+
+	escapedPath := (&url.URL{Path: objectPath}).EscapedPath()
+	return fmt.Sprintf("%s/%s?%s", l.publicURL, escapedPath, query.Encode()), nil
+}
+
+// FileServer serves the files behind signed URLs. It expects the
+// request path to be the object path, so mount it with http.StripPrefix
+func (l LocalDiskFileStore) FileServer() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		objectPath := cleanObjectPath(r.URL.Path)
+		expiresAt, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid expiry", http.StatusForbidden)
+			return
+		}
+
+		if time.Now().Unix() > expiresAt {
+			http.Error(w, "URL has expired", http.StatusForbidden)
+			return
+		}
+
+		expectedSignature := l.signature(objectPath, expiresAt)
+		if !hmac.Equal([]byte(expectedSignature), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "Invalid signature", http.StatusForbidden)
+			return
+		}
+
+		file, err := os.Open(l.filePath(objectPath))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		fileInfo, err := file.Stat()
+		if err != nil || fileInfo.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+	})
+}
+
+func (l LocalDiskFileStore) signature(objectPath string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(fmt.Sprintf("%s\n%d", objectPath, expiresAt)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// filePath never resolves outside of the root dir, since the
+// object path is cleaned as if it's rooted before joining
+func (l LocalDiskFileStore) filePath(objectPath string) string {
+	return filepath.Join(l.rootDir, filepath.FromSlash(cleanObjectPath(objectPath)))
+}
+
+func cleanObjectPath(objectPath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean("/"+objectPath)), "/")
+}
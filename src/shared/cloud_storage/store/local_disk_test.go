@@ -0,0 +1,117 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
+)
+
+var _ = Describe("LocalDiskFileStore", func() {
+	var (
+		rootDir   string
+		fileStore store.LocalDiskFileStore
+		server    *httptest.Server
+	)
+
+	BeforeEach(func() {
+		rootDir = GinkgoT().TempDir()
+
+		mux := http.NewServeMux()
+		server = httptest.NewServer(mux)
+
+		var err error
+		fileStore, err = store.NewLocalDiskFileStore(rootDir, server.URL+"/files", "test-signing-key")
+		Expect(err).NotTo(HaveOccurred())
+
+		mux.Handle("/files/", http.StripPrefix("/files", fileStore.FileServer()))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	upload := func(objectPath string, content []byte) error {
+		return fileStore.UploadFile(context.Background(), objectPath, bytes.NewReader(content))
+	}
+
+	Describe("Writing and reading", func() {
+		It("reads back what was written", func() {
+			err := upload("tracklist/track/original/original.mp3", []byte("cool_jamz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader, err := fileStore.OpenFile(context.Background(), "tracklist/track/original/original.mp3")
+			Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+
+			contents, err := io.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal([]byte("cool_jamz")))
+		})
+
+		It("errors on a file that doesn't exist", func() {
+			_, err := fileStore.OpenFile(context.Background(), "tracklist/track/nothing.mp3")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("keeps paths inside of the root dir", func() {
+			err := upload("../../escaped.mp3", []byte("cool_jamz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = os.Stat(filepath.Join(rootDir, "escaped.mp3"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Signed URLs", func() {
+		var signedURL string
+
+		BeforeEach(func() {
+			err := upload("tracklist/track/2stems/vocals.mp3", []byte("la la la"))
+			Expect(err).NotTo(HaveOccurred())
+
+			signedURL, err = fileStore.SignURL(context.Background(), "tracklist/track/2stems/vocals.mp3", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		get := func(fileURL string) *http.Response {
+			response, err := http.Get(fileURL)
+			Expect(err).NotTo(HaveOccurred())
+			return response
+		}
+
+		It("serves the file", func() {
+			response := get(signedURL)
+			defer response.Body.Close()
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects a URL for another file", func() {
+			tampered, err := url.Parse(signedURL)
+			Expect(err).NotTo(HaveOccurred())
+			tampered.Path = "/files/tracklist/track/2stems/accompaniment.mp3"
+
+			response := get(tampered.String())
+			defer response.Body.Close()
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("rejects an expired URL", func() {
+			expiredURL, err := fileStore.SignURL(context.Background(), "tracklist/track/2stems/vocals.mp3", -time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+
+			response := get(expiredURL)
+			defer response.Body.Close()
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+})
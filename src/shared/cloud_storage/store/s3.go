@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/cockroachdb/errors"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"io"
+	"time"
+)
+
+var _ entity.FileStore = S3FileStore{}
+
+type S3FileStore struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+// NewS3FileStore talks to AWS when endpoint is empty. Otherwise it
+// uses path style addressing, which is what MinIO and friends expect
+func NewS3FileStore(endpoint string, region string, accessKeyID string, secretAccessKey string, bucket string) (S3FileStore, error) {
+	s3Config := aws.NewConfig().
+		WithCredentials(credentials.NewStaticCredentials(
+			accessKeyID,
+			secretAccessKey,
+			"",
+		)).
+		WithRegion(region)
+
+	if endpoint != "" {
+		s3Config = s3Config.
+			WithEndpoint(endpoint).
+			WithS3ForcePathStyle(true)
+	}
+
+	s3Session, err := session.NewSession(s3Config)
+	if err != nil {
+		return S3FileStore{}, errors.Wrap(err, "Failed to create S3 session")
+	}
+
+	return S3FileStore{
+		client: s3.New(s3Session),
+		uploader: s3manager.NewUploader(s3Session, func(uploader *s3manager.Uploader) {
+			uploader.PartSize = uploadChunkSize
+		}),
+		bucket: bucket,
+	}, nil
+}
+
+func (s S3FileStore) OpenFile(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectPath),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get S3 object, object_path: %s", objectPath)
+	}
+
+	return output.Body, nil
+}
+
+// UploadFile switches to a multipart upload when the content is bigger than a chunk
+func (s S3FileStore) UploadFile(ctx context.Context, objectPath string, content io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectPath),
+		Body:   content,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error occurred when uploading file, object_path: %s", objectPath)
+	}
+
+	return nil
+}
+
+func (s S3FileStore) SignURL(_ context.Context, objectPath string, expiry time.Duration) (string, error) {
+	request, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectPath),
+	})
+
+	signedURL, err := request.Presign(expiry)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to presign S3 object, object_path: %s", objectPath)
+	}
+
+	return signedURL, nil
+}
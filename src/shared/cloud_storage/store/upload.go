@@ -0,0 +1,5 @@
+package store
+
+// uploadChunkSize is how much of a file is buffered at a time while uploading.
+// Each chunk is retried on its own, so a failure doesn't restart the whole file
+const uploadChunkSize = 8 * 1024 * 1024
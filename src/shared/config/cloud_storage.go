@@ -1,8 +1,13 @@
 package config
 
+import (
+	"fmt"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/envvar"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/prod"
+)
+
 type CloudStorage interface {
-	GetStorageHost() string
-	GetBucket() string
+	CloudStorageConfig()
 }
 
 var _ CloudStorage = ProdCloudStorage{}
@@ -13,13 +18,7 @@ type ProdCloudStorage struct {
 	BucketName  string
 }
 
-func (p ProdCloudStorage) GetStorageHost() string {
-	return p.StorageHost
-}
-
-func (p ProdCloudStorage) GetBucket() string {
-	return p.BucketName
-}
+func (p ProdCloudStorage) CloudStorageConfig() {}
 
 var _ CloudStorage = LocalCloudStorage{}
 
@@ -29,10 +28,69 @@ type LocalCloudStorage struct {
 	BucketName   string
 }
 
-func (l LocalCloudStorage) GetStorageHost() string {
-	return l.StorageHost
+func (l LocalCloudStorage) CloudStorageConfig() {}
+
+var _ CloudStorage = LocalDiskStorage{}
+
+// LocalDiskStorage keeps the files in a directory, and the server hands them out
+// under /files. PublicURL is where that route is reachable, e.g. https://example.com/files
+type LocalDiskStorage struct {
+	RootDir    string
+	PublicURL  string
+	SigningKey string
+}
+
+func (l LocalDiskStorage) CloudStorageConfig() {}
+
+var _ CloudStorage = S3Storage{}
+
+// S3Storage works with AWS S3, or any S3 compatible server like MinIO when Endpoint is set
+type S3Storage struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	BucketName      string
 }
 
-func (l LocalCloudStorage) GetBucket() string {
-	return l.BucketName
+func (s S3Storage) CloudStorageConfig() {}
+
+const (
+	GoogleFileStoreType    = "gcs"
+	LocalDiskFileStoreType = "local_disk"
+	S3FileStoreType        = "s3"
+)
+
+// CloudStorageFromEnv picks the file store from FILE_STORE_TYPE,
+// falling back to Google Cloud Storage when it isn't set
+func CloudStorageFromEnv() CloudStorage {
+	fileStoreType := envvar.GetOrDefault(envvar.FILE_STORE_TYPE, GoogleFileStoreType)
+
+	switch fileStoreType {
+	case GoogleFileStoreType:
+		return ProdCloudStorage{
+			StorageHost: prod.GOOGLE_STORAGE_HOST,
+			SecretKey:   envvar.MustGet(envvar.GOOGLE_CLOUD_KEY),
+			BucketName:  envvar.MustGet(envvar.GOOGLE_CLOUD_STORAGE_BUCKET_NAME),
+		}
+
+	case LocalDiskFileStoreType:
+		return LocalDiskStorage{
+			RootDir:    envvar.MustGet(envvar.LOCAL_FILE_STORE_DIR),
+			PublicURL:  envvar.MustGet(envvar.LOCAL_FILE_STORE_PUBLIC_URL),
+			SigningKey: envvar.MustGet(envvar.LOCAL_FILE_STORE_SIGNING_KEY),
+		}
+
+	case S3FileStoreType:
+		return S3Storage{
+			Endpoint:        envvar.GetOrDefault(envvar.S3_ENDPOINT, ""),
+			Region:          envvar.MustGet(envvar.S3_REGION),
+			AccessKeyID:     envvar.MustGet(envvar.S3_ACCESS_KEY_ID),
+			SecretAccessKey: envvar.MustGet(envvar.S3_SECRET_ACCESS_KEY),
+			BucketName:      envvar.MustGet(envvar.S3_BUCKET_NAME),
+		}
+
+	default:
+		panic(fmt.Sprintf("Unrecognized file store type %s", fileStoreType))
+	}
 }
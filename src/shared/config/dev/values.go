@@ -22,3 +22,18 @@ const (
 	RabbitMQHost      = "amqp://localhost:5672"
 	RabbitMQQueueName = "chord-paper-tracks-dev"
 )
+
+// Share links
+const (
+	ShareLinkSigningKey = "local-share-link-signing-key"
+)
+
+// Track events
+const (
+	EventsTokenSigningKey = "local-events-token-signing-key"
+)
+
+// Split service
+const (
+	SplitServiceSecret = "local-split-service-secret"
+)
@@ -3,6 +3,7 @@ package envvar
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 const (
@@ -15,6 +16,29 @@ const (
 	YOUTUBEDL_WORKING_DIR_PATH       = "YOUTUBEDL_WORKING_DIR_PATH"
 	SPLEETER_WORKING_DIR_PATH        = "SPLEETER_WORKING_DIR_PATH"
 	DEMUCS_WORKING_DIR_PATH          = "DEMUCS_WORKING_DIR_PATH"
+	OPEN_UNMIX_BIN_PATH              = "OPEN_UNMIX_BIN_PATH"
+	OPEN_UNMIX_WORKING_DIR_PATH      = "OPEN_UNMIX_WORKING_DIR_PATH"
+	OPEN_UNMIX_ENABLED               = "OPEN_UNMIX_ENABLED"
+	SPLIT_SERVICE_URL                = "SPLIT_SERVICE_URL"
+	SPLIT_SERVICE_WORKING_DIR_PATH   = "SPLIT_SERVICE_WORKING_DIR_PATH"
+	SPLIT_SERVICE_CONCURRENCY        = "SPLIT_SERVICE_CONCURRENCY"
+	SPLIT_SERVICE_SECRET             = "SPLIT_SERVICE_SECRET"
+	SHARE_LINK_SIGNING_KEY           = "SHARE_LINK_SIGNING_KEY"
+	EVENTS_TOKEN_SIGNING_KEY         = "EVENTS_TOKEN_SIGNING_KEY"
+	FILE_STORE_TYPE                  = "FILE_STORE_TYPE"
+	LOCAL_FILE_STORE_DIR             = "LOCAL_FILE_STORE_DIR"
+	LOCAL_FILE_STORE_PUBLIC_URL      = "LOCAL_FILE_STORE_PUBLIC_URL"
+	LOCAL_FILE_STORE_SIGNING_KEY     = "LOCAL_FILE_STORE_SIGNING_KEY"
+	S3_ENDPOINT                      = "S3_ENDPOINT"
+	S3_REGION                        = "S3_REGION"
+	S3_ACCESS_KEY_ID                 = "S3_ACCESS_KEY_ID"
+	S3_SECRET_ACCESS_KEY             = "S3_SECRET_ACCESS_KEY"
+	S3_BUCKET_NAME                   = "S3_BUCKET_NAME"
+	SPLIT_JOB_CONCURRENCY            = "SPLIT_JOB_CONCURRENCY"
+	LIGHT_JOB_CONCURRENCY            = "LIGHT_JOB_CONCURRENCY"
+	SHUTDOWN_TIMEOUT_SECONDS         = "SHUTDOWN_TIMEOUT_SECONDS"
+	STUCK_JOB_THRESHOLD_MINUTES      = "STUCK_JOB_THRESHOLD_MINUTES"
+	QUEUED_JOB_THRESHOLD_MINUTES     = "QUEUED_JOB_THRESHOLD_MINUTES"
 )
 
 func MustGet(key string) string {
@@ -29,3 +53,40 @@ func MustGet(key string) string {
 
 	return val
 }
+
+func GetOrDefault(key string, defaultVal string) string {
+	val, isSet := os.LookupEnv(key)
+	if !isSet || val == "" {
+		return defaultVal
+	}
+
+	return val
+}
+
+func GetIntOrDefault(key string, defaultVal int) int {
+	val, isSet := os.LookupEnv(key)
+	if !isSet || val == "" {
+		return defaultVal
+	}
+
+	intVal, err := strconv.Atoi(val)
+	if err != nil || intVal < 1 {
+		panic(fmt.Sprintf("Env variable for key %s should be a positive number, got %s", key, val))
+	}
+
+	return intVal
+}
+
+func GetBoolOrDefault(key string, defaultVal bool) bool {
+	val, isSet := os.LookupEnv(key)
+	if !isSet || val == "" {
+		return defaultVal
+	}
+
+	boolVal, err := strconv.ParseBool(val)
+	if err != nil {
+		panic(fmt.Sprintf("Env variable for key %s should be true or false, got %s", key, val))
+	}
+
+	return boolVal
+}
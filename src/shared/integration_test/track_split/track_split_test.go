@@ -46,6 +46,14 @@ var _ = Describe("TrackSplit", func() {
 		return fmt.Sprintf("%s/%s/%s", cloudStorage.PublicURL(), bucket, fileName)
 	}
 
+	cloudStorageConfig := func() config.LocalCloudStorage {
+		return config.LocalCloudStorage{
+			StorageHost:  cloudStorage.PublicURL(),
+			HostEndpoint: fmt.Sprintf("%s/storage/v1", cloudStorage.PublicURL()),
+			BucketName:   bucketName,
+		}
+	}
+
 	ExpectFileExists := func(fileURL string) {
 		response := ExpectSuccess(http.Get(fileURL))
 		Expect(response.StatusCode).To(Equal(http.StatusOK))
@@ -133,13 +141,7 @@ var _ = Describe("TrackSplit", func() {
 
 	BeforeEach(func() {
 		By("Initializing Worker", func() {
-			worker = worker_app.NewApp(
-				WorkerConfig(region, config.LocalCloudStorage{
-					StorageHost:  cloudStorage.PublicURL(),
-					HostEndpoint: fmt.Sprintf("%s/storage/v1", cloudStorage.PublicURL()),
-					BucketName:   bucketName,
-				}),
-			)
+			worker = worker_app.NewApp(WorkerConfig(region, cloudStorageConfig()))
 
 			go func() {
 				defer GinkgoRecover()
@@ -156,7 +158,7 @@ var _ = Describe("TrackSplit", func() {
 
 	BeforeEach(func() {
 		By("Initializing Server", func() {
-			server = server_app.NewApp(ServerConfig(region))
+			server = server_app.NewApp(ServerConfig(region, cloudStorageConfig()))
 
 			go func() {
 				defer GinkgoRecover()
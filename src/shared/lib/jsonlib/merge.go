@@ -0,0 +1,290 @@
+package jsonlib
+
+import (
+	"reflect"
+	"sort"
+)
+
+// MergeConflict describes a value that was changed in different ways by both sides
+// of a three way merge.
+//
+// Path is the list of object keys and array indices leading to the value.
+// When the conflict is over a range of an array, the last element of the path
+// is the index in the base array where the range starts, and the values are the
+// conflicting sub-arrays from each side. A value that was removed by one side is nil
+type MergeConflict struct {
+	Path   []any
+	Base   any
+	Ours   any
+	Theirs any
+}
+
+// ThreeWayMerge merges the changes made from base to ours, and from base to theirs.
+// The values are expected to be generic JSON values as produced by encoding/json,
+// i.e. maps, slices, strings, float64s, bools and nil.
+//
+// Objects are merged key by key. Arrays are aligned against the base by their
+// longest common subsequence, so that insertions, deletions and edits on different
+// elements can be combined. Changes that overlap are reported as conflicts, in which
+// case the merged result favours ours and shouldn't be used as is
+func ThreeWayMerge(base map[string]any, ours map[string]any, theirs map[string]any) (map[string]any, []MergeConflict) {
+	m := merger{}
+	merged := m.mergeMaps([]any{}, base, ours, theirs)
+	return merged, m.conflicts
+}
+
+type merger struct {
+	conflicts []MergeConflict
+}
+
+type optionalValue struct {
+	value   any
+	present bool
+}
+
+func (m *merger) addConflict(path []any, base any, ours any, theirs any) {
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   path,
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+	})
+}
+
+func (m *merger) mergeMaps(path []any, base map[string]any, ours map[string]any, theirs map[string]any) map[string]any {
+	lookup := func(obj map[string]any, key string) optionalValue {
+		value, ok := obj[key]
+		return optionalValue{value: value, present: ok}
+	}
+
+	merged := map[string]any{}
+	for _, key := range unionKeys(base, ours, theirs) {
+		result := m.mergeOptional(childPath(path, key), lookup(base, key), lookup(ours, key), lookup(theirs, key))
+		if result.present {
+			merged[key] = result.value
+		}
+	}
+
+	return merged
+}
+
+func (m *merger) mergeOptional(path []any, base optionalValue, ours optionalValue, theirs optionalValue) optionalValue {
+	switch {
+	case optionalEqual(ours, theirs):
+		return ours
+	case optionalEqual(base, ours):
+		return theirs
+	case optionalEqual(base, theirs):
+		return ours
+	}
+
+	// both sides changed the value differently, try to go deeper if they're still the same shape
+	if ours.present && theirs.present {
+		baseValue := base.value
+		if !base.present {
+			baseValue = emptyOfSameKind(ours.value)
+		}
+
+		return optionalValue{
+			value:   m.mergeValues(path, baseValue, ours.value, theirs.value),
+			present: true,
+		}
+	}
+
+	m.addConflict(path, base.value, ours.value, theirs.value)
+	return ours
+}
+
+func (m *merger) mergeValues(path []any, base any, ours any, theirs any) any {
+	switch baseValue := base.(type) {
+	case map[string]any:
+		oursValue, oursOK := ours.(map[string]any)
+		theirsValue, theirsOK := theirs.(map[string]any)
+		if oursOK && theirsOK {
+			return m.mergeMaps(path, baseValue, oursValue, theirsValue)
+		}
+
+	case []any:
+		oursValue, oursOK := ours.([]any)
+		theirsValue, theirsOK := theirs.([]any)
+		if oursOK && theirsOK {
+			return m.mergeArrays(path, baseValue, oursValue, theirsValue)
+		}
+	}
+
+	m.addConflict(path, base, ours, theirs)
+	return ours
+}
+
+// mergeArrays is a diff3 style merge - the array is split into stable runs where
+// all three sides agree, and unstable chunks in between which are resolved on their own
+func (m *merger) mergeArrays(path []any, base []any, ours []any, theirs []any) []any {
+	oursMatches := matchIndices(base, ours)
+	theirsMatches := matchIndices(base, theirs)
+
+	merged := []any{}
+	b, o, t := 0, 0, 0
+	for {
+		stable := 0
+		for b+stable < len(base) &&
+			oursMatches[b+stable] == o+stable &&
+			theirsMatches[b+stable] == t+stable {
+			stable++
+		}
+
+		if stable > 0 {
+			merged = append(merged, base[b:b+stable]...)
+			b, o, t = b+stable, o+stable, t+stable
+			continue
+		}
+
+		if b == len(base) && o == len(ours) && t == len(theirs) {
+			break
+		}
+
+		// the unstable chunk runs until the next base element that both sides kept
+		nextB := b
+		for nextB < len(base) && (oursMatches[nextB] == -1 || theirsMatches[nextB] == -1) {
+			nextB++
+		}
+
+		nextO, nextT := len(ours), len(theirs)
+		if nextB < len(base) {
+			nextO, nextT = oursMatches[nextB], theirsMatches[nextB]
+		}
+
+		chunk := m.mergeChunk(path, b, base[b:nextB], ours[o:nextO], theirs[t:nextT])
+		merged = append(merged, chunk...)
+		b, o, t = nextB, nextO, nextT
+	}
+
+	return merged
+}
+
+func (m *merger) mergeChunk(arrayPath []any, start int, base []any, ours []any, theirs []any) []any {
+	switch {
+	case sliceEqual(ours, theirs):
+		return ours
+	case sliceEqual(base, ours):
+		return theirs
+	case sliceEqual(base, theirs):
+		return ours
+	}
+
+	// both sides edited the same elements in place, e.g. different chord blocks on the same line
+	if len(base) == len(ours) && len(base) == len(theirs) {
+		merged := []any{}
+		for i := range base {
+			result := m.mergeOptional(childPath(arrayPath, start+i),
+				optionalValue{value: base[i], present: true},
+				optionalValue{value: ours[i], present: true},
+				optionalValue{value: theirs[i], present: true})
+			merged = append(merged, result.value)
+		}
+
+		return merged
+	}
+
+	m.addConflict(childPath(arrayPath, start), base, ours, theirs)
+	return ours
+}
+
+// matchIndices pairs up the elements of base and other along their longest common
+// subsequence. The result holds, for each index of base, the index of its match
+// in other, or -1 if it was removed in other
+func matchIndices(base []any, other []any) []int {
+	lengths := make([][]int, len(base)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(other)+1)
+	}
+
+	for i := len(base) - 1; i >= 0; i-- {
+		for j := len(other) - 1; j >= 0; j-- {
+			if reflect.DeepEqual(base[i], other[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	matches := make([]int, len(base))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	i, j := 0, 0
+	for i < len(base) && j < len(other) {
+		switch {
+		case reflect.DeepEqual(base[i], other[j]):
+			matches[i] = j
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return matches
+}
+
+func optionalEqual(a optionalValue, b optionalValue) bool {
+	if a.present != b.present {
+		return false
+	}
+
+	return reflect.DeepEqual(a.value, b.value)
+}
+
+func sliceEqual(a []any, b []any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func emptyOfSameKind(value any) any {
+	switch value.(type) {
+	case map[string]any:
+		return map[string]any{}
+	case []any:
+		return []any{}
+	default:
+		return nil
+	}
+}
+
+func childPath(path []any, element any) []any {
+	child := make([]any, len(path), len(path)+1)
+	copy(child, path)
+	return append(child, element)
+}
+
+func unionKeys(maps ...map[string]any) []string {
+	keySet := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			keySet[key] = true
+		}
+	}
+
+	keys := []string{}
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+
+	// keep the conflict order deterministic
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,204 @@
+package jsonlib_test
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/jsonlib"
+	. "github.com/veedubyou/chord-paper-be/src/shared/testing"
+)
+
+func parseJSONObject(s string) map[string]any {
+	obj := map[string]any{}
+	ExpectWithOffset(1, json.Unmarshal([]byte(s), &obj)).To(Succeed())
+	return obj
+}
+
+var _ = Describe("ThreeWayMerge", func() {
+	var (
+		base   map[string]any
+		ours   map[string]any
+		theirs map[string]any
+
+		merged    map[string]any
+		conflicts []jsonlib.MergeConflict
+	)
+
+	BeforeEach(func() {
+		base = parseJSONObject(`{
+			"metadata": {"title": "Song", "composedBy": "Someone"},
+			"elements": [
+				{"section": "verse", "elements": [{"chord": "C", "lyric": "one"}, {"chord": "G", "lyric": "two"}]},
+				{"elements": [{"chord": "Am", "lyric": "three"}]},
+				{"elements": [{"chord": "F", "lyric": "four"}]}
+			]
+		}`)
+		ours = ExpectSuccess(jsonlib.MapToStruct[map[string]any](base))
+		theirs = ExpectSuccess(jsonlib.MapToStruct[map[string]any](base))
+	})
+
+	JustBeforeEach(func() {
+		merged, conflicts = jsonlib.ThreeWayMerge(base, ours, theirs)
+	})
+
+	lines := func(obj map[string]any) []any {
+		return ExpectType[[]any](obj["elements"])
+	}
+
+	blocks := func(obj map[string]any, lineIndex int) []any {
+		line := ExpectType[map[string]any](lines(obj)[lineIndex])
+		return ExpectType[[]any](line["elements"])
+	}
+
+	block := func(obj map[string]any, lineIndex int, blockIndex int) map[string]any {
+		return ExpectType[map[string]any](blocks(obj, lineIndex)[blockIndex])
+	}
+
+	metadata := func(obj map[string]any) map[string]any {
+		return ExpectType[map[string]any](obj["metadata"])
+	}
+
+	Describe("With no changes", func() {
+		It("returns the base", func() {
+			Expect(conflicts).To(BeEmpty())
+			Expect(merged).To(Equal(base))
+		})
+	})
+
+	Describe("With changes on one side only", func() {
+		BeforeEach(func() {
+			metadata(theirs)["title"] = "New Song"
+			theirs["elements"] = append(lines(theirs), map[string]any{"elements": []any{}})
+		})
+
+		It("takes the changes", func() {
+			Expect(conflicts).To(BeEmpty())
+			Expect(merged).To(Equal(theirs))
+		})
+	})
+
+	Describe("With non overlapping changes", func() {
+		BeforeEach(func() {
+			metadata(ours)["title"] = "Our Song"
+			block(ours, 0, 0)["chord"] = "Cmaj7"
+
+			metadata(theirs)["composedBy"] = "Someone Else"
+			block(theirs, 0, 0)["lyric"] = "uno"
+		})
+
+		It("has no conflicts", func() {
+			Expect(conflicts).To(BeEmpty())
+		})
+
+		It("combines the metadata changes", func() {
+			Expect(metadata(merged)).To(Equal(map[string]any{
+				"title":      "Our Song",
+				"composedBy": "Someone Else",
+			}))
+		})
+
+		It("combines the changes to the same chord block", func() {
+			Expect(block(merged, 0, 0)).To(Equal(map[string]any{
+				"chord": "Cmaj7",
+				"lyric": "uno",
+			}))
+		})
+	})
+
+	Describe("With lines inserted and deleted in different places", func() {
+		var newLine map[string]any
+
+		BeforeEach(func() {
+			newLine = map[string]any{"elements": []any{}}
+			ours["elements"] = append([]any{newLine}, lines(ours)...)
+			theirs["elements"] = lines(theirs)[:2]
+		})
+
+		It("combines the insertions and deletions", func() {
+			Expect(conflicts).To(BeEmpty())
+			Expect(lines(merged)).To(Equal([]any{newLine, lines(base)[0], lines(base)[1]}))
+		})
+	})
+
+	Describe("With the same change on both sides", func() {
+		BeforeEach(func() {
+			block(ours, 1, 0)["chord"] = "Am7"
+			block(theirs, 1, 0)["chord"] = "Am7"
+		})
+
+		It("takes the change once", func() {
+			Expect(conflicts).To(BeEmpty())
+			Expect(block(merged, 1, 0)["chord"]).To(Equal("Am7"))
+		})
+	})
+
+	Describe("With overlapping changes", func() {
+		BeforeEach(func() {
+			metadata(ours)["title"] = "Our Song"
+			block(ours, 1, 0)["chord"] = "Am7"
+
+			metadata(theirs)["title"] = "Their Song"
+			block(theirs, 1, 0)["chord"] = "Am9"
+		})
+
+		It("reports each conflict with its path", func() {
+			Expect(conflicts).To(ConsistOf(
+				jsonlib.MergeConflict{
+					Path:   []any{"elements", 1, "elements", 0, "chord"},
+					Base:   "Am",
+					Ours:   "Am7",
+					Theirs: "Am9",
+				},
+				jsonlib.MergeConflict{
+					Path:   []any{"metadata", "title"},
+					Base:   "Song",
+					Ours:   "Our Song",
+					Theirs: "Their Song",
+				},
+			))
+		})
+	})
+
+	Describe("With a deletion on one side and an edit on the other", func() {
+		BeforeEach(func() {
+			delete(metadata(ours), "composedBy")
+			metadata(theirs)["composedBy"] = "Someone Else"
+		})
+
+		It("reports a conflict with the deleted side as nil", func() {
+			Expect(conflicts).To(ConsistOf(jsonlib.MergeConflict{
+				Path:   []any{"metadata", "composedBy"},
+				Base:   "Someone",
+				Ours:   nil,
+				Theirs: "Someone Else",
+			}))
+		})
+	})
+
+	Describe("With different lines inserted at the same place", func() {
+		var (
+			ourLine   map[string]any
+			theirLine map[string]any
+		)
+
+		BeforeEach(func() {
+			ourLine = map[string]any{"elements": []any{map[string]any{"chord": "D", "lyric": "ours"}}}
+			theirLine = map[string]any{"elements": []any{map[string]any{"chord": "E", "lyric": "theirs"}}}
+
+			ourLines := lines(ours)
+			ours["elements"] = []any{ourLines[0], ourLines[1], ourLine, ourLines[2]}
+
+			theirLines := lines(theirs)
+			theirs["elements"] = []any{theirLines[0], theirLines[1], theirLine, theirLines[2]}
+		})
+
+		It("reports the conflicting range", func() {
+			Expect(conflicts).To(ConsistOf(jsonlib.MergeConflict{
+				Path:   []any{"elements", 2},
+				Base:   []any{},
+				Ours:   []any{ourLine},
+				Theirs: []any{theirLine},
+			}))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package rabbitmq
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/cockroachdb/errors"
+	"github.com/rabbitmq/amqp091-go"
+	"sync"
+)
+
+var _ Publisher = &FanoutPublisher{}
+
+func NewFanoutPublisher(rabbitMQURL string, exchangeName string) *FanoutPublisher {
+	return &FanoutPublisher{
+		rabbitMQURL:  rabbitMQURL,
+		exchangeName: exchangeName,
+		channel:      nil,
+	}
+}
+
+// FanoutPublisher broadcasts to everyone bound to the exchange.
+// Unlike the queue, messages aren't persisted, so nothing is kept for subscribers that aren't listening
+type FanoutPublisher struct {
+	rabbitMQURL  string
+	exchangeName string
+	conn         *amqp091.Connection
+	channel      *amqp091.Channel
+	mutex        sync.Mutex
+}
+
+func (f *FanoutPublisher) Publish(msg amqp091.Publishing) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.channel == nil {
+		if err := f.resetChannel(); err != nil {
+			return errors.Wrap(err, "Failed to reset the channel")
+		}
+	}
+
+	err := f.publishWithoutRetry(msg)
+	if err != nil {
+		publishErr := errors.Wrap(err, "Failed to publish message to rabbitMQ exchange")
+		if !errors.Is(err, amqp091.ErrClosed) {
+			return publishErr
+		}
+
+		if err := f.resetChannel(); err != nil {
+			log.WithError(err).
+				Error("Unable to reconnect to rabbitMQ channel")
+			return publishErr
+		}
+
+		return f.publishWithoutRetry(msg)
+	}
+
+	return nil
+}
+
+func (f *FanoutPublisher) publishWithoutRetry(msg amqp091.Publishing) error {
+	msg.ContentType = "application/json"
+	msg.DeliveryMode = amqp091.Transient
+
+	return f.channel.PublishWithContext(
+		context.Background(),
+		f.exchangeName,
+		"",
+		false,
+		false,
+		msg,
+	)
+}
+
+func (f *FanoutPublisher) resetChannel() error {
+	f.channel = nil
+
+	// the old connection is dialed again below, so it has to be closed
+	// rather than left open for every reset
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn = nil
+	}
+
+	conn, err := amqp091.Dial(f.rabbitMQURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to dial rabbitMQURL")
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "Failed to create rabbit channel")
+	}
+
+	if err := declareFanoutExchange(channel, f.exchangeName); err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "Failed to declare the exchange")
+	}
+
+	f.conn = conn
+	f.channel = channel
+	return nil
+}
+
+// SubscribeFanout binds a temporary queue to the exchange. The queue is deleted
+// when the connection goes away, and the deliveries channel closes with it
+func SubscribeFanout(conn *amqp091.Connection, exchangeName string) (<-chan amqp091.Delivery, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create rabbit channel")
+	}
+
+	if err := declareFanoutExchange(channel, exchangeName); err != nil {
+		return nil, errors.Wrap(err, "Failed to declare the exchange")
+	}
+
+	queue, err := channel.QueueDeclare(
+		"",
+		false,
+		true,
+		true,
+		false,
+		nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to declare the subscriber queue")
+	}
+
+	if err := channel.QueueBind(queue.Name, "", exchangeName, false, nil); err != nil {
+		return nil, errors.Wrap(err, "Failed to bind the subscriber queue to the exchange")
+	}
+
+	deliveries, err := channel.Consume(
+		queue.Name,
+		"",
+		true,
+		true,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to consume from the subscriber queue")
+	}
+
+	return deliveries, nil
+}
+
+func declareFanoutExchange(channel *amqp091.Channel, exchangeName string) error {
+	return channel.ExchangeDeclare(
+		exchangeName,
+		amqp091.ExchangeFanout,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+}
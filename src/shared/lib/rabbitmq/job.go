@@ -0,0 +1,42 @@
+package rabbitmq
+
+import (
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// AttemptHeader counts the times a job has been handed to a worker,
+// starting from 1 when it's first published
+const AttemptHeader = "x-attempt"
+
+// NewJobPublishing makes a job message with an ID of its own. The ID stays the same
+// when the job is redelivered or retried, so that it can be told apart from a new job
+func NewJobPublishing(jobType string, body []byte) amqp091.Publishing {
+	return amqp091.Publishing{
+		MessageId: uuid.New().String(),
+		Headers: amqp091.Table{
+			AttemptHeader: int32(1),
+		},
+		Type: jobType,
+		Body: body,
+	}
+}
+
+// Attempt is the attempt that the job is on, jobs published without the header count as their first
+func Attempt(headers amqp091.Table) int {
+	switch attempt := headers[AttemptHeader].(type) {
+	case int:
+		return attempt
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	default:
+		return 1
+	}
+}
+
+// NextAttempt is the header value for handing the job to a worker again
+func NextAttempt(headers amqp091.Table) int32 {
+	return int32(Attempt(headers) + 1)
+}
@@ -9,15 +9,18 @@ import (
 	"path"
 )
 
-func ServerConfig(dbRegion string) server_app.Config {
+func ServerConfig(dbRegion string, cloudStorageConfig config.LocalCloudStorage) server_app.Config {
 	return server_app.Config{
-		DynamoConfig:       DynamoConfig(dbRegion),
-		RabbitMQURL:        RabbitMQHost,
-		RabbitMQQueueName:  RabbitMQQueueName,
-		CORSAllowedOrigins: []string{"*"},
-		UserValidator:      Validator{},
-		Port:               ServerPort,
-		Log:                false,
+		DynamoConfig:          DynamoConfig(dbRegion),
+		CloudStorageConfig:    cloudStorageConfig,
+		RabbitMQURL:           RabbitMQHost,
+		RabbitMQQueueName:     RabbitMQQueueName,
+		CORSAllowedOrigins:    []string{"*"},
+		UserValidator:         Validator{},
+		ShareLinkSigningKey:   ShareLinkSigningKey,
+		EventsTokenSigningKey: EventsTokenSigningKey,
+		Port:                  ServerPort,
+		Log:                   false,
 	}
 }
 
@@ -29,10 +32,12 @@ func WorkerConfig(dbRegion string, cloudStorageConfig config.LocalCloudStorage)
 		RabbitMQQueueName:       RabbitMQQueueName,
 		YoutubeDLBinPath:        "/not-a-real-path-until-we-need-one",
 		YoutubeDLWorkingDirPath: path.Join(local.ProjectRoot(), "/src/worker/wd/youtube-dl"),
-		SpleeterBinPath:         config.SpleeterPath(),
-		SpleeterWorkingDirPath:  path.Join(local.ProjectRoot(), "/src/worker/wd/spleeter"),
-		DemucsBinPath:           config.DemucsPath(),
-		DemucsWorkingDirPath:    path.Join(local.ProjectRoot(), "/src/worker/wd/demucs"),
+		SplitEngineConfig: worker_app.SplitEngineConfig{
+			SpleeterBinPath:        config.SpleeterPath(),
+			SpleeterWorkingDirPath: path.Join(local.ProjectRoot(), "/src/worker/wd/spleeter"),
+			DemucsBinPath:          config.DemucsPath(),
+			DemucsWorkingDirPath:   path.Join(local.ProjectRoot(), "/src/worker/wd/demucs"),
+		},
 	}
 }
 
@@ -60,5 +65,7 @@ const (
 
 // Server
 const (
-	ServerPort = ":5010"
+	ServerPort            = ":5010"
+	ShareLinkSigningKey   = "test-share-link-signing-key"
+	EventsTokenSigningKey = "test-events-token-signing-key"
 )
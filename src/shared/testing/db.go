@@ -10,9 +10,14 @@ import (
 )
 
 const (
-	SongsTable      = "Songs"
-	UsersTable      = "Users"
-	TrackListsTable = "TrackLists"
+	SongsTable         = "Songs"
+	SongRevisionsTable = "SongRevisions"
+	CollaboratorsTable = "SongCollaborators"
+	UsersTable         = "Users"
+	TrackListsTable    = "TrackLists"
+	ShareLinksTable    = "ShareLinks"
+	JobOutboxTable     = "JobOutbox"
+	JobRunsTable       = "JobRuns"
 )
 
 type song struct {
@@ -20,14 +25,41 @@ type song struct {
 	Owner string `dynamo:"owner" index:"owner-index,hash"`
 }
 
-type tracklist struct {
+type songRevision struct {
+	ID       string `dynamo:"id,hash"`
+	Revision string `dynamo:"revision,range"`
+}
+
+type songCollaborator struct {
 	SongID string `dynamo:"song_id,hash"`
+	UserID string `dynamo:"user_id,range"`
+}
+
+type shareLink struct {
+	ID     string `dynamo:"id,hash"`
+	SongID string `dynamo:"song_id" index:"song_id-index,hash"`
+}
+
+type tracklist struct {
+	SongID       string `dynamo:"song_id,hash"`
+	ActiveSplits int    `dynamo:"active_splits" index:"active_splits-index,hash"`
+}
+
+type outboxJob struct {
+	ID        string `dynamo:"id,hash"`
+	Pending   int    `dynamo:"pending" index:"pending-index,hash"`
+	CreatedAt int64  `dynamo:"created_at" index:"pending-index,range"`
+}
+
+type jobRun struct {
+	TrackID string `dynamo:"track_id,hash"`
+	ID      string `dynamo:"id,range"`
 }
 
 type User struct {
 	ID       string `dynamo:"id,hash"`
 	Name     string `dynamo:"username"`
-	Email    string `dynamo:"email"`
+	Email    string `dynamo:"email" index:"email-index,hash"`
 	Verified bool   `dynamo:"verified"`
 }
 
@@ -63,11 +95,26 @@ func CreateAllTables(db dynamolib.DynamoDBWrapper) {
 	err := db.CreateTable(SongsTable, song{}).Run()
 	ExpectWithOffset(1, err).NotTo(HaveOccurred())
 
+	err = db.CreateTable(SongRevisionsTable, songRevision{}).Run()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	err = db.CreateTable(CollaboratorsTable, songCollaborator{}).Run()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
 	err = db.CreateTable(UsersTable, User{}).Run()
 	ExpectWithOffset(1, err).NotTo(HaveOccurred())
 
 	err = db.CreateTable(TrackListsTable, tracklist{}).Run()
 	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	err = db.CreateTable(ShareLinksTable, shareLink{}).Run()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	err = db.CreateTable(JobOutboxTable, outboxJob{}).Run()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	err = db.CreateTable(JobRunsTable, jobRun{}).Run()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
 }
 
 func DeleteAllTables(db dynamolib.DynamoDBWrapper) {
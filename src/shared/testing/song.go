@@ -8,8 +8,11 @@ import (
 
 // compare most of the fields, except last saved at
 func ExpectJSONEqualExceptLastSavedAt(a map[string]any, b map[string]any) {
+	// every save gets a new revision along with its save time
 	a["lastSavedAt"] = nil
 	b["lastSavedAt"] = nil
+	delete(a, "revision")
+	delete(b, "revision")
 	ExpectWithOffset(1, a).To(Equal(b))
 }
 
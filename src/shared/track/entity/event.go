@@ -0,0 +1,32 @@
+package trackentity
+
+import "context"
+
+type TrackEventType string
+
+const (
+	ProgressEventType  TrackEventType = "progress"
+	ErrorEventType     TrackEventType = "error"
+	CompletedEventType TrackEventType = "completed"
+	CancelledEventType TrackEventType = "cancelled"
+)
+
+// TrackEvent is a status change of a track that is being processed.
+// The tracklist in the DB is still the source of truth, events are only a nudge
+type TrackEvent struct {
+	TrackListID   string             `json:"tracklist_id"`
+	TrackID       string             `json:"track_id"`
+	Type          TrackEventType     `json:"type"`
+	Status        SplitRequestStatus `json:"job_status,omitempty"`
+	StatusMessage string             `json:"job_status_message,omitempty"`
+	Progress      int                `json:"job_progress"`
+}
+
+type EventPublisher interface {
+	PublishTrackEvent(ctx context.Context, event TrackEvent) error
+}
+
+type EventSubscriber interface {
+	// Subscribe receives the events for one tracklist until unsubscribe is called
+	Subscribe(tracklistID string) (events <-chan TrackEvent, unsubscribe func())
+}
@@ -0,0 +1,44 @@
+package trackentity
+
+import (
+	"context"
+	"time"
+)
+
+// JobRun is the record of one stage of a split request being run by a worker.
+// Unlike the status on the track, these are kept after the track is done,
+// so that a slow or failed split can be looked into afterwards
+type JobRun struct {
+	ID          string            `json:"id"`
+	TrackListID string            `json:"tracklist_id"`
+	TrackID     string            `json:"track_id"`
+	JobID       string            `json:"job_id"`
+	JobType     string            `json:"job_type"`
+	Attempt     int               `json:"attempt"`
+	WorkerHost  string            `json:"worker_host"`
+	Engine      SplitEngineType   `json:"engine,omitempty"`
+	InputURL    string            `json:"input_url,omitempty"`
+	OutputURLs  map[string]string `json:"output_urls,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	// EndedAt is nil while the run is still going
+	EndedAt  *time.Time `json:"ended_at,omitempty"`
+	Duration int64      `json:"duration_ms,omitempty"`
+}
+
+func (j *JobRun) End(endedAt time.Time, outputURLs map[string]string, runErr error) {
+	j.EndedAt = &endedAt
+	j.Duration = endedAt.Sub(j.StartedAt).Milliseconds()
+	j.OutputURLs = outputURLs
+
+	if runErr != nil {
+		j.Error = runErr.Error()
+	}
+}
+
+type JobRunStore interface {
+	// SaveJobRun creates the run, or replaces it when it's saved again with the same ID
+	SaveJobRun(ctx context.Context, run JobRun) error
+	// GetJobRuns returns all the runs for the track, oldest first
+	GetJobRuns(ctx context.Context, tracklistID string, trackID string) ([]JobRun, error)
+}
@@ -0,0 +1,38 @@
+package trackentity
+
+import (
+	"context"
+	"time"
+)
+
+type OutboxJobStatus string
+
+const (
+	PendingOutboxJobStatus OutboxJobStatus = "pending"
+	FailedOutboxJobStatus  OutboxJobStatus = "failed"
+)
+
+// OutboxJob is a job message that is saved together with the tracklist that asked for it,
+// so that it still gets published if the server goes down before it could be sent
+type OutboxJob struct {
+	ID          string
+	TrackListID string
+	TrackID     string
+	JobType     string
+	Body        []byte
+	Status      OutboxJobStatus
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+}
+
+type JobOutbox interface {
+	// SetTrackListWithJobs saves the tracklist and its pending jobs all at once, or none of them
+	SetTrackListWithJobs(ctx context.Context, tracklist TrackList, jobs []OutboxJob) error
+	// GetPendingJobs returns the jobs that are still waiting to be published and were created before the cutoff
+	GetPendingJobs(ctx context.Context, createdBefore time.Time) ([]OutboxJob, error)
+	// DeleteSentJob removes the job once it's been published, there's nothing left to keep it around for
+	DeleteSentJob(ctx context.Context, jobID string) error
+	// MarkJobPublishFailed counts up a failed attempt, leaving the job pending unless it's given up on
+	MarkJobPublishFailed(ctx context.Context, jobID string, publishErr error, giveUp bool) error
+}
@@ -0,0 +1,177 @@
+package trackentity
+
+type SplitQuality string
+
+const (
+	BasicQuality SplitQuality = "basic"
+	GoodQuality  SplitQuality = "good"
+	BestQuality  SplitQuality = "best"
+)
+
+// SplitEngine is what an engine can split a track into, for the client to offer only the choices that work
+type SplitEngine struct {
+	EngineType SplitEngineType `json:"engine_type"`
+	Quality    SplitQuality    `json:"quality"`
+	Layouts    []StemLayout    `json:"layouts"`
+	// optional engines are only installed where the deployment turns them on
+	optional bool
+}
+
+// StemLayout is one way an engine can split a track
+type StemLayout struct {
+	TrackType SplitRequestType `json:"track_type"`
+	Stems     []string         `json:"stems"`
+	// a rough estimate of how long the split takes on the worker's CPU, per minute of audio
+	RuntimeSecondsPerMinute int `json:"runtime_seconds_per_minute"`
+}
+
+// splitEngines is the capability matrix of engine and stem layout, which the server offers
+// and the worker splits with. Spleeter has no 6 stem model, and Demucs has no 5 stem model
+var splitEngines = []SplitEngine{
+	{
+		EngineType: SpleeterType,
+		Quality:    BasicQuality,
+		Layouts: []StemLayout{
+			{
+				TrackType:               SplitTwoStemsType,
+				Stems:                   []string{"vocals", "accompaniment"},
+				RuntimeSecondsPerMinute: 5,
+			},
+			{
+				TrackType:               SplitFourStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "other"},
+				RuntimeSecondsPerMinute: 8,
+			},
+			{
+				TrackType:               SplitFiveStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "piano", "other"},
+				RuntimeSecondsPerMinute: 10,
+			},
+		},
+	},
+	{
+		EngineType: DemucsType,
+		Quality:    BestQuality,
+		Layouts: []StemLayout{
+			{
+				TrackType:               SplitTwoStemsType,
+				Stems:                   []string{"vocals", "no_vocals"},
+				RuntimeSecondsPerMinute: 30,
+			},
+			{
+				TrackType:               SplitFourStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "other"},
+				RuntimeSecondsPerMinute: 30,
+			},
+			{
+				TrackType:               SplitSixStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "guitar", "piano", "other"},
+				RuntimeSecondsPerMinute: 60,
+			},
+		},
+	},
+	{
+		EngineType: DemucsV3Type,
+		Quality:    GoodQuality,
+		Layouts: []StemLayout{
+			{
+				TrackType:               SplitTwoStemsType,
+				Stems:                   []string{"vocals", "no_vocals"},
+				RuntimeSecondsPerMinute: 40,
+			},
+			{
+				TrackType:               SplitFourStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "other"},
+				RuntimeSecondsPerMinute: 40,
+			},
+		},
+	},
+	{
+		EngineType: OpenUnmixType,
+		Quality:    GoodQuality,
+		optional:   true,
+		Layouts: []StemLayout{
+			{
+				TrackType:               SplitTwoStemsType,
+				Stems:                   []string{"vocals", "accompaniment"},
+				RuntimeSecondsPerMinute: 20,
+			},
+			{
+				TrackType:               SplitFourStemsType,
+				Stems:                   []string{"vocals", "drums", "bass", "other"},
+				RuntimeSecondsPerMinute: 20,
+			},
+		},
+	},
+}
+
+// InstalledSplitEngines is the part of the capability matrix that a deployment can split with,
+// every engine that isn't optional along with the optional ones that it has turned on
+type InstalledSplitEngines struct {
+	optionalEngines map[SplitEngineType]bool
+}
+
+func NewInstalledSplitEngines(optionalEngines ...SplitEngineType) InstalledSplitEngines {
+	installed := InstalledSplitEngines{
+		optionalEngines: map[SplitEngineType]bool{},
+	}
+
+	for _, engineType := range optionalEngines {
+		installed.optionalEngines[engineType] = true
+	}
+
+	return installed
+}
+
+// List is every installed engine along with what it can do.
+// It's a copy, so callers are free to change it
+func (i InstalledSplitEngines) List() []SplitEngine {
+	engines := []SplitEngine{}
+	for _, engine := range splitEngines {
+		if !i.isInstalled(engine) {
+			continue
+		}
+
+		layouts := []StemLayout{}
+		for _, layout := range engine.Layouts {
+			layout.Stems = append([]string{}, layout.Stems...)
+			layouts = append(layouts, layout)
+		}
+
+		engine.Layouts = layouts
+		engines = append(engines, engine)
+	}
+
+	return engines
+}
+
+func (i InstalledSplitEngines) Has(engineType SplitEngineType) bool {
+	for _, engine := range splitEngines {
+		if engine.EngineType == engineType {
+			return i.isInstalled(engine)
+		}
+	}
+
+	return false
+}
+
+func (i InstalledSplitEngines) isInstalled(engine SplitEngine) bool {
+	return !engine.optional || i.optionalEngines[engine.EngineType]
+}
+
+// Supports is whether the engine can split a track into the stems the request type asks for
+func (e SplitEngineType) Supports(trackType SplitRequestType) bool {
+	for _, engine := range splitEngines {
+		if engine.EngineType != e {
+			continue
+		}
+
+		for _, layout := range engine.Layouts {
+			if layout.TrackType == trackType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package trackentity
+
+// SplitJobStage is a step of the split job, named after the job message that runs it
+type SplitJobStage string
+
+const (
+	StartStage     SplitJobStage = "start_job"
+	TransferStage  SplitJobStage = "transfer_original"
+	SplitStage     SplitJobStage = "split_track"
+	SaveStemsStage SplitJobStage = "save_stems_to_db"
+)
+
+// SplitJobStageDefinition is how a stage fits into the split job as a whole.
+// The worker runs its pipeline from these, and the server resumes retried splits with them
+type SplitJobStageDefinition struct {
+	Stage SplitJobStage
+	// ProgressWeight is how much of the track's progress the stage makes up, relative to the other stages
+	ProgressWeight int
+	// StatusMessage is shown on the track while the stage is running
+	StatusMessage string
+	// Heavy stages are CPU and memory hungry, so they run in a lane of their own
+	// with a lower concurrency, and the light stages don't wait behind them
+	Heavy bool
+}
+
+// SplitJobStages are the stages of the split job, in the order they run
+var SplitJobStages = []SplitJobStageDefinition{
+	{
+		Stage:          StartStage,
+		ProgressWeight: 10,
+		StatusMessage:  "The splitting job for the audio has been requested",
+	},
+	{
+		Stage:          TransferStage,
+		ProgressWeight: 20,
+		StatusMessage:  "Retrieving the original track from provided URL",
+	},
+	{
+		Stage:          SplitStage,
+		ProgressWeight: 60,
+		StatusMessage:  "Splitting the track into stems",
+		Heavy:          true,
+	},
+	{
+		Stage:          SaveStemsStage,
+		ProgressWeight: 10,
+		StatusMessage:  "Saving processed stems into database",
+	},
+}
+
+func splitJobStageIndex(stage SplitJobStage) (int, bool) {
+	for i, definition := range SplitJobStages {
+		if definition.Stage == stage {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s SplitJobStage) Definition() (SplitJobStageDefinition, bool) {
+	i, ok := splitJobStageIndex(s)
+	if !ok {
+		return SplitJobStageDefinition{}, false
+	}
+
+	return SplitJobStages[i], true
+}
+
+// IsHeavy is whether the stage runs in the lane for heavy stages
+func (s SplitJobStage) IsHeavy() bool {
+	definition, ok := s.Definition()
+	return ok && definition.Heavy
+}
+
+// Next is the stage that runs after this one, if there is one
+func (s SplitJobStage) Next() (SplitJobStage, bool) {
+	i, ok := splitJobStageIndex(s)
+	if !ok || i+1 >= len(SplitJobStages) {
+		return "", false
+	}
+
+	return SplitJobStages[i+1].Stage, true
+}
+
+// ProgressBefore is the track's progress as the stage starts, out of 100
+func (s SplitJobStage) ProgressBefore() int {
+	i, _ := splitJobStageIndex(s)
+	return progressThrough(i)
+}
+
+// ProgressAfter is the track's progress once the stage is done, out of 100
+func (s SplitJobStage) ProgressAfter() int {
+	i, ok := splitJobStageIndex(s)
+	if !ok {
+		return 0
+	}
+
+	return progressThrough(i + 1)
+}
+
+// progressThrough is the progress once the first n stages are done
+func progressThrough(n int) int {
+	totalWeight := 0
+	doneWeight := 0
+	for i, definition := range SplitJobStages {
+		totalWeight += definition.ProgressWeight
+		if i < n {
+			doneWeight += definition.ProgressWeight
+		}
+	}
+
+	if totalWeight == 0 {
+		return 0
+	}
+
+	return 100 * doneWeight / totalWeight
+}
+
+// HasCompleted is whether the job has already gotten through the stage,
+// so that a redelivered job for it has nothing left to do
+func (s SplitRequestTrack) HasCompleted(stage SplitJobStage) bool {
+	completed, ok := splitJobStageIndex(s.CompletedStage)
+	if !ok {
+		return false
+	}
+
+	i, ok := splitJobStageIndex(stage)
+	return ok && completed >= i
+}
+
+// ResumeStage is the stage after the last one the job completed, which is
+// where it picks up from when retried. There's none once every stage is done
+func (s SplitRequestTrack) ResumeStage() (SplitJobStage, bool) {
+	if _, ok := splitJobStageIndex(s.CompletedStage); !ok {
+		return SplitJobStages[0].Stage, true
+	}
+
+	return s.CompletedStage.Next()
+}
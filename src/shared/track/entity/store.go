@@ -2,8 +2,12 @@ package trackentity
 
 import (
 	"context"
+	"errors"
 )
 
+// ErrTrackChanged is what a conditional update fails with when someone else updated the track in the meantime
+var ErrTrackChanged = errors.New("The track has been changed since it was read")
+
 type TrackUpdater func(track Track) (Track, error)
 
 type Store interface {
@@ -11,3 +15,17 @@ type Store interface {
 	SetTrackList(ctx context.Context, tracklist TrackList) error
 	UpdateTrack(ctx context.Context, tracklistID string, trackID string, updater TrackUpdater) error
 }
+
+// ActiveSplitFinder looks up the split requests that are in progress, without going through every tracklist
+type ActiveSplitFinder interface {
+	GetTrackListsWithActiveSplits(ctx context.Context) ([]TrackList, error)
+	// ClearActiveSplits takes the tracklist off the lookup, once none of its split requests are in progress anymore
+	ClearActiveSplits(ctx context.Context, tracklistID string) error
+}
+
+// ConditionalTrackUpdater updates a split request only if nobody else has updated it in the meantime
+type ConditionalTrackUpdater interface {
+	// UpdateTrackIfUnchanged works like UpdateTrack, except that the write is dropped with ErrTrackChanged
+	// if the split request's LastUpdatedAt has moved on since the updater was given the track
+	UpdateTrackIfUnchanged(ctx context.Context, tracklistID string, trackID string, updater TrackUpdater) error
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/google/uuid"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/jsonlib"
+	"time"
 )
 
 const (
@@ -17,12 +18,14 @@ const (
 	TwoStemsType  StemTrackType = "2stems"
 	FourStemsType StemTrackType = "4stems"
 	FiveStemsType StemTrackType = "5stems"
+	SixStemsType  StemTrackType = "6stems"
 )
 
 var stemTrackTypes = map[string]bool{
 	string(TwoStemsType):  true,
 	string(FourStemsType): true,
 	string(FiveStemsType): true,
+	string(SixStemsType):  true,
 }
 
 type SplitRequestType string
@@ -31,28 +34,25 @@ const (
 	SplitTwoStemsType  SplitRequestType = "split_2stems"
 	SplitFourStemsType SplitRequestType = "split_4stems"
 	SplitFiveStemsType SplitRequestType = "split_5stems"
+	SplitSixStemsType  SplitRequestType = "split_6stems"
 )
 
 var splitTrackTypes = map[string]bool{
 	string(SplitTwoStemsType):  true,
 	string(SplitFourStemsType): true,
 	string(SplitFiveStemsType): true,
+	string(SplitSixStemsType):  true,
 }
 
 type SplitEngineType string
 
 const (
-	SpleeterType SplitEngineType = "spleeter"
-	DemucsType   SplitEngineType = "demucs"
-	DemucsV3Type SplitEngineType = "demucs-v3"
+	SpleeterType  SplitEngineType = "spleeter"
+	DemucsType    SplitEngineType = "demucs"
+	DemucsV3Type  SplitEngineType = "demucs-v3"
+	OpenUnmixType SplitEngineType = "open-unmix"
 )
 
-var splitEngineTypes = map[string]bool{
-	string(SpleeterType): true,
-	string(DemucsType):   true,
-	string(DemucsV3Type): true,
-}
-
 type Tracks []Track
 
 func (t *Tracks) UnmarshalJSON(b []byte) error {
@@ -102,6 +102,7 @@ const (
 	RequestedStatus  SplitRequestStatus = "requested"
 	ProcessingStatus SplitRequestStatus = "processing"
 	ErrorStatus      SplitRequestStatus = "error"
+	CancelledStatus  SplitRequestStatus = "cancelled"
 )
 
 type SplitRequestTrack struct {
@@ -113,6 +114,19 @@ type SplitRequestTrack struct {
 	StatusMessage  string             `json:"job_status_message"`
 	StatusDebugLog string             `json:"job_status_debug_log"`
 	Progress       int                `json:"job_progress"`
+
+	// what the job has gotten done so far, so that a retry can pick up from there
+	CompletedStage   SplitJobStage     `json:"job_completed_stage,omitempty"`
+	SavedOriginalURL string            `json:"job_saved_original_url,omitempty"`
+	SplitStemURLs    map[string]string `json:"job_split_stem_urls,omitempty"`
+
+	// when the job last made any progress, when a worker picked up the stage that's
+	// running now, and how many times it's been picked up again after it stopped
+	// making progress, for finding stuck jobs. StageStartedAt is nil while the
+	// next stage is still waiting in the queue
+	LastUpdatedAt  *time.Time `json:"job_last_updated_at,omitempty"`
+	StageStartedAt *time.Time `json:"job_stage_started_at,omitempty"`
+	ReapCount      int        `json:"job_reap_count,omitempty"`
 }
 
 func (g GenericTrack) GetID() string {
@@ -175,11 +189,31 @@ func (s SplitRequestTrack) ToMap() (map[string]any, error) {
 	return jsonlib.StructToMap(s)
 }
 
+// IsInProgress is whether the split job for this track can still be running
+func (s SplitRequestTrack) IsInProgress() bool {
+	return s.Status == RequestedStatus || s.Status == ProcessingStatus
+}
+
 func (s *SplitRequestTrack) InitializeRequest() {
 	s.Status = "requested"
 	s.StatusMessage = "The splitting job for the audio has been requested"
 	s.StatusDebugLog = ""
 	s.Progress = InitialProgressPercentage
+	s.CompletedStage = ""
+	s.SavedOriginalURL = ""
+	s.SplitStemURLs = nil
+	s.StageStartedAt = nil
+	s.ReapCount = 0
+}
+
+// Touch records that the job has just made progress
+func (s *SplitRequestTrack) Touch(now time.Time) {
+	s.LastUpdatedAt = &now
+}
+
+// IsRetryable is whether the split job has stopped without finishing
+func (s SplitRequestTrack) IsRetryable() bool {
+	return s.Status == ErrorStatus || s.Status == CancelledStatus
 }
 
 type Track interface {
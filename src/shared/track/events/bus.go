@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"github.com/apex/log"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"sync"
+)
+
+// subscriberBufferSize is how far a slow subscriber can fall behind before its events are dropped
+const subscriberBufferSize = 32
+
+var _ trackentity.EventPublisher = &Bus{}
+var _ trackentity.EventSubscriber = &Bus{}
+
+// Bus hands out track events to the subscribers of each tracklist within this process
+type Bus struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[chan trackentity.TrackEvent]bool
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[string]map[chan trackentity.TrackEvent]bool{},
+	}
+}
+
+func (b *Bus) PublishTrackEvent(_ context.Context, event trackentity.TrackEvent) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for subscriber := range b.subscribers[event.TrackListID] {
+		select {
+		case subscriber <- event:
+		default:
+			log.WithField("tracklist_id", event.TrackListID).
+				Warn("Subscriber is not keeping up, dropping track event")
+		}
+	}
+
+	return nil
+}
+
+func (b *Bus) Subscribe(tracklistID string) (<-chan trackentity.TrackEvent, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber := make(chan trackentity.TrackEvent, subscriberBufferSize)
+	if b.subscribers[tracklistID] == nil {
+		b.subscribers[tracklistID] = map[chan trackentity.TrackEvent]bool{}
+	}
+	b.subscribers[tracklistID][subscriber] = true
+
+	unsubscribeOnce := sync.Once{}
+	unsubscribe := func() {
+		unsubscribeOnce.Do(func() {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+
+			delete(b.subscribers[tracklistID], subscriber)
+			if len(b.subscribers[tracklistID]) == 0 {
+				delete(b.subscribers, tracklistID)
+			}
+
+			close(subscriber)
+		})
+	}
+
+	return subscriber, unsubscribe
+}
+
+func (b *Bus) SubscriberCount(tracklistID string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.subscribers[tracklistID])
+}
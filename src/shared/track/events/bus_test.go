@@ -0,0 +1,68 @@
+package events_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/events"
+)
+
+var _ = Describe("Bus", func() {
+	var (
+		bus   *events.Bus
+		event trackentity.TrackEvent
+	)
+
+	BeforeEach(func() {
+		bus = events.NewBus()
+		event = trackentity.TrackEvent{
+			TrackListID: "tracklist-id",
+			TrackID:     "track-id",
+			Type:        trackentity.ProgressEventType,
+			Progress:    30,
+		}
+	})
+
+	It("delivers events to every subscriber of the tracklist", func() {
+		first, unsubscribeFirst := bus.Subscribe("tracklist-id")
+		defer unsubscribeFirst()
+		second, unsubscribeSecond := bus.Subscribe("tracklist-id")
+		defer unsubscribeSecond()
+
+		Expect(bus.PublishTrackEvent(context.Background(), event)).To(Succeed())
+
+		Expect(first).To(Receive(Equal(event)))
+		Expect(second).To(Receive(Equal(event)))
+	})
+
+	It("doesn't deliver events of other tracklists", func() {
+		subscriber, unsubscribe := bus.Subscribe("another-tracklist-id")
+		defer unsubscribe()
+
+		Expect(bus.PublishTrackEvent(context.Background(), event)).To(Succeed())
+		Expect(subscriber).NotTo(Receive())
+	})
+
+	It("closes the channel on unsubscribe", func() {
+		subscriber, unsubscribe := bus.Subscribe("tracklist-id")
+		unsubscribe()
+		unsubscribe()
+
+		Expect(subscriber).To(BeClosed())
+		Expect(bus.SubscriberCount("tracklist-id")).To(BeZero())
+		Expect(bus.PublishTrackEvent(context.Background(), event)).To(Succeed())
+	})
+
+	It("drops events for a subscriber that isn't keeping up instead of blocking", func() {
+		subscriber, unsubscribe := bus.Subscribe("tracklist-id")
+		defer unsubscribe()
+
+		for i := 0; i < 100; i++ {
+			Expect(bus.PublishTrackEvent(context.Background(), event)).To(Succeed())
+		}
+
+		Expect(len(subscriber)).To(BeNumerically("<", 100))
+	})
+})
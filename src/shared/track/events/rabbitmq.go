@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/cockroachdb/errors"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"time"
+)
+
+const (
+	trackEventType = "track_event"
+	reconnectDelay = 5 * time.Second
+)
+
+// ExchangeName keeps the events of each environment apart, in the same way the job queues are
+func ExchangeName(queueName string) string {
+	return queueName + ".track-events"
+}
+
+var _ trackentity.EventPublisher = RabbitMQPublisher{}
+
+type RabbitMQPublisher struct {
+	publisher rabbitmq.Publisher
+}
+
+func NewRabbitMQPublisher(publisher rabbitmq.Publisher) RabbitMQPublisher {
+	return RabbitMQPublisher{
+		publisher: publisher,
+	}
+}
+
+func (r RabbitMQPublisher) PublishTrackEvent(_ context.Context, event trackentity.TrackEvent) error {
+	jsonBytes, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal track event")
+	}
+
+	err = r.publisher.Publish(amqp091.Publishing{
+		Type: trackEventType,
+		Body: jsonBytes,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to publish track event")
+	}
+
+	return nil
+}
+
+// Relay forwards the events from the exchange to the bus until the context is done,
+// reconnecting whenever the connection to RabbitMQ drops
+func Relay(ctx context.Context, rabbitMQURL string, exchangeName string, bus *Bus) {
+	for {
+		err := relayUntilDisconnected(ctx, rabbitMQURL, exchangeName, bus)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.WithError(err).Error("Track event relay disconnected, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func relayUntilDisconnected(ctx context.Context, rabbitMQURL string, exchangeName string, bus *Bus) error {
+	conn, err := amqp091.Dial(rabbitMQURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to dial rabbitMQURL")
+	}
+
+	defer conn.Close()
+
+	deliveries, err := rabbitmq.SubscribeFanout(conn, exchangeName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to subscribe to the track events exchange")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errors.New("Track events subscription was closed")
+			}
+
+			event := trackentity.TrackEvent{}
+			if err := json.Unmarshal(delivery.Body, &event); err != nil {
+				log.WithError(err).Error("Failed to unmarshal track event, skipping")
+				continue
+			}
+
+			_ = bus.PublishTrackEvent(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package trackstorage
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+)
+
+const (
+	// activeSplitsKey counts the split requests in progress on the tracklist.
+	// It's only there while the count is above zero, so the index on it
+	// holds just the tracklists that have something going on
+	activeSplitsKey   = "active_splits"
+	activeSplitsIndex = "active_splits-index"
+)
+
+var _ trackentity.ActiveSplitFinder = DB{}
+
+func isActiveSplit(track trackentity.Track) bool {
+	splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+	return ok && splitRequest.IsInProgress()
+}
+
+func countActiveSplits(tracklist trackentity.TrackList) int {
+	count := 0
+	for _, track := range tracklist.Defined.Tracks {
+		if isActiveSplit(track) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (d DB) GetTrackListsWithActiveSplits(ctx context.Context) ([]trackentity.TrackList, error) {
+	values := []dbTrackList{}
+	err := d.dynamoDB.Table(TracklistsTable).
+		Scan().
+		Index(activeSplitsIndex).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch tracklists with active splits")
+	}
+
+	tracklists := []trackentity.TrackList{}
+	for _, value := range values {
+		tracklist, err := value.toEntity()
+		if err != nil {
+			return nil, err
+		}
+
+		tracklists = append(tracklists, tracklist)
+	}
+
+	return tracklists, nil
+}
+
+func (d DB) ClearActiveSplits(ctx context.Context, tracklistID string) error {
+	// the count has the final say, a split that became active in the meantime keeps it above zero
+	err := d.dynamoDB.Table(TracklistsTable).Table.
+		Update(idKey, tracklistID).
+		Remove(activeSplitsKey).
+		If("$ <= ?", activeSplitsKey, 0).
+		RunWithContext(ctx)
+
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil
+		}
+
+		return mark.Wrap(err, DefaultErrorMark, "Failed to clear the active splits of the tracklist")
+	}
+
+	return nil
+}
@@ -3,20 +3,25 @@ package trackstorage
 import (
 	"context"
 	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/markers"
 	"github.com/guregu/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
 	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"time"
 )
 
 const (
 	TracklistsTable = "TrackLists"
 	maxTrackSize    = 10
+
+	lastUpdatedAtKey = "job_last_updated_at"
 )
 
 var _ trackentity.Store = DB{}
+var _ trackentity.ConditionalTrackUpdater = DB{}
 
 type DB struct {
 	dynamoDB dynamolib.DynamoDBWrapper
@@ -45,49 +50,63 @@ func (d DB) GetTrackList(ctx context.Context, songID string) (trackentity.TrackL
 		}
 	}
 
-	tracklist := trackentity.TrackList{}
-	err = tracklist.FromMap(value)
+	return value.toEntity()
+}
+
+func (d DB) SetTrackList(ctx context.Context, tracklist trackentity.TrackList) error {
+	putExpr, err := d.putTrackList(tracklist)
 	if err != nil {
-		return trackentity.TrackList{},
-			mark.Wrap(err, UnmarshalMark, "Failed to transform DB map back to entity tracklist")
+		return err
 	}
 
-	return tracklist, nil
+	err = putExpr.RunWithContext(ctx)
+	if err != nil {
+		return mark.Wrap(err,
+			DefaultErrorMark,
+			"Failed to put the tracklist in the DB")
+	}
+
+	return nil
 }
 
-func (d DB) SetTrackList(ctx context.Context, tracklist trackentity.TrackList) error {
+func (d DB) putTrackList(tracklist trackentity.TrackList) (*dynamo.Put, error) {
 	if tracklist.Defined.SongID == "" {
-		return mark.Message(IDEmptyMark, "Song ID is not defined on tracklist")
+		return nil, mark.Message(IDEmptyMark, "Song ID is not defined on tracklist")
 	}
 
 	if len(tracklist.Defined.Tracks) > maxTrackSize {
-		return mark.Message(TrackSizeExceeded, "The tracklist has more tracks than allowed")
+		return nil, mark.Message(TrackSizeExceeded, "The tracklist has more tracks than allowed")
 	}
 
 	for _, track := range tracklist.Defined.Tracks {
 		if track.GetID() == "" {
-			return mark.Message(IDEmptyMark, "A track in the tracklist has an empty ID")
+			return nil, mark.Message(IDEmptyMark, "A track in the tracklist has an empty ID")
 		}
 	}
 
 	dbObject, err := tracklist.ToMap()
 	if err != nil {
-		return mark.Wrap(err,
+		return nil, mark.Wrap(err,
 			MarshalMark,
 			"Failed to transform entity tracklist to a generic map object")
 	}
 
-	err = d.dynamoDB.Table(TracklistsTable).Put(dbObject).RunWithContext(ctx)
-	if err != nil {
-		return mark.Wrap(err,
-			DefaultErrorMark,
-			"Failed to put the tracklist in the DB")
+	if activeSplits := countActiveSplits(tracklist); activeSplits > 0 {
+		dbObject[activeSplitsKey] = activeSplits
 	}
 
-	return nil
+	return d.dynamoDB.Table(TracklistsTable).Put(dbObject), nil
 }
 
 func (d DB) UpdateTrack(ctx context.Context, tracklistID string, trackID string, updater trackentity.TrackUpdater) error {
+	return d.updateTrack(ctx, tracklistID, trackID, updater, false)
+}
+
+func (d DB) UpdateTrackIfUnchanged(ctx context.Context, tracklistID string, trackID string, updater trackentity.TrackUpdater) error {
+	return d.updateTrack(ctx, tracklistID, trackID, updater, true)
+}
+
+func (d DB) updateTrack(ctx context.Context, tracklistID string, trackID string, updater trackentity.TrackUpdater, ifUnchanged bool) error {
 	if trackID == "" {
 		return mark.Message(TrackNotFound, "No track ID was provided")
 	}
@@ -102,18 +121,55 @@ func (d DB) UpdateTrack(ctx context.Context, tracklistID string, trackID string,
 		return mark.Wrap(err, TrackNotFound, "Can't find the track in this tracklist")
 	}
 
+	var unchanged *lastUpdatedCheck
+	if ifUnchanged {
+		unchanged, err = checkLastUpdated(tracklist, track)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the updater is free to change the track in place
+	wasActive := isActiveSplit(track)
+
 	updatedTrack, err := updater(track)
 	if err != nil {
 		return mark.Wrap(err, DefaultErrorMark, "The updater failed to make changes to the track")
 	}
 
+	if splitRequest, ok := updatedTrack.(*trackentity.SplitRequestTrack); ok {
+		splitRequest.Touch(time.Now())
+	}
+
+	activeSplitsChange := 0
+	switch isActive := isActiveSplit(updatedTrack); {
+	case isActive && !wasActive:
+		activeSplitsChange = 1
+	case !isActive && wasActive:
+		activeSplitsChange = -1
+	}
+
 	trackAsMap, err := updatedTrack.ToMap()
 	if err != nil {
 		return mark.Wrap(err, MarshalMark, "Failed to marshal track entity to map")
 	}
 
+	// the track has to still be where it was read from, otherwise it's been changed too
+	if unchanged != nil {
+		err = d.setTrackDBAtIndex(ctx, tracklistID, track.GetID(), trackAsMap, activeSplitsChange, unchanged.trackIndex, unchanged)
+		if _, ok := errors.Cause(err).(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(trackentity.ErrTrackChanged, "Unable to set the track")
+		}
+
+		if err != nil {
+			return mark.Wrap(err, DefaultErrorMark, "Unable to set the track")
+		}
+
+		return nil
+	}
+
 	for i := 0; i < maxTrackSize; i++ {
-		err = d.setTrackDBAtIndex(ctx, tracklistID, track.GetID(), trackAsMap, i)
+		err = d.setTrackDBAtIndex(ctx, tracklistID, track.GetID(), trackAsMap, activeSplitsChange, i, nil)
 		if err == nil {
 			break
 		}
@@ -126,12 +182,53 @@ func (d DB) UpdateTrack(ctx context.Context, tracklistID string, trackID string,
 	return nil
 }
 
-func (d DB) setTrackDBAtIndex(ctx context.Context, tracklistID string, trackID string, trackAsMap map[string]any, trackIndex int) error {
-	err := d.dynamoDB.Table(TracklistsTable).
+// lastUpdatedCheck is where the split request was read from, and what its
+// last update was then. A nil lastUpdatedAt is a split request that has never been updated
+type lastUpdatedCheck struct {
+	trackIndex    int
+	lastUpdatedAt any
+}
+
+func checkLastUpdated(tracklist trackentity.TrackList, track trackentity.Track) (*lastUpdatedCheck, error) {
+	trackAsMap, err := track.ToMap()
+	if err != nil {
+		return nil, mark.Wrap(err, MarshalMark, "Failed to marshal track entity to map")
+	}
+
+	for i, t := range tracklist.Defined.Tracks {
+		if t.GetID() == track.GetID() {
+			return &lastUpdatedCheck{
+				trackIndex:    i,
+				lastUpdatedAt: trackAsMap[lastUpdatedAtKey],
+			}, nil
+		}
+	}
+
+	return nil, mark.Message(TrackNotFound, "Can't find the track in this tracklist")
+}
+
+func (d DB) setTrackDBAtIndex(ctx context.Context, tracklistID string, trackID string, trackAsMap map[string]any, activeSplitsChange int, trackIndex int, unchanged *lastUpdatedCheck) error {
+	updateExpr := d.dynamoDB.Table(TracklistsTable).
 		Update(idKey, tracklistID).
 		Set(fmt.Sprintf("tracks[%d]", trackIndex), trackAsMap).
-		If(fmt.Sprintf("tracks[%d].id = ?", trackIndex), trackID).
-		RunWithContext(ctx)
+		If(fmt.Sprintf("tracks[%d].id = ?", trackIndex), trackID)
+
+	if unchanged != nil {
+		lastUpdatedAtPath := fmt.Sprintf("tracks[%d].%s", trackIndex, lastUpdatedAtKey)
+		if unchanged.lastUpdatedAt == nil {
+			updateExpr = updateExpr.If(fmt.Sprintf("attribute_not_exists(%s)", lastUpdatedAtPath))
+		} else {
+			updateExpr = updateExpr.If(lastUpdatedAtPath+" = ?", unchanged.lastUpdatedAt)
+		}
+	}
+
+	// counted up and down in the same update as the track,
+	// so that concurrent updates to other tracks don't throw it off
+	if activeSplitsChange != 0 {
+		updateExpr = updateExpr.Add(activeSplitsKey, activeSplitsChange)
+	}
+
+	err := updateExpr.RunWithContext(ctx)
 
 	if err != nil {
 		return errors.Wrap(err, "Failed to update track at index")
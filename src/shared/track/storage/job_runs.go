@@ -0,0 +1,117 @@
+package trackstorage
+
+import (
+	"context"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"sort"
+	"time"
+)
+
+const (
+	JobRunsTable = "JobRuns"
+
+	jobRunTrackIDKey       = "track_id"
+	jobRunTrackListIDField = "tracklist_id"
+
+	// jobRunRetention is how long a run is kept around to look into.
+	// The table's TTL is set on expires_at, so DynamoDB deletes the runs after that
+	jobRunRetention = 90 * 24 * time.Hour
+)
+
+var _ trackentity.JobRunStore = DB{}
+
+type dbJobRun struct {
+	TrackID     string            `dynamo:"track_id"`
+	ID          string            `dynamo:"id"`
+	TrackListID string            `dynamo:"tracklist_id"`
+	JobID       string            `dynamo:"job_id"`
+	JobType     string            `dynamo:"job_type"`
+	Attempt     int               `dynamo:"attempt"`
+	WorkerHost  string            `dynamo:"worker_host"`
+	Engine      string            `dynamo:"engine,omitempty"`
+	InputURL    string            `dynamo:"input_url,omitempty"`
+	OutputURLs  map[string]string `dynamo:"output_urls,omitempty"`
+	Error       string            `dynamo:"error,omitempty"`
+	StartedAt   time.Time         `dynamo:"started_at"`
+	EndedAt     *time.Time        `dynamo:"ended_at,omitempty"`
+	Duration    int64             `dynamo:"duration_ms,omitempty"`
+	ExpiresAt   time.Time         `dynamo:"expires_at,unixtime"`
+}
+
+func (d dbJobRun) toEntity() trackentity.JobRun {
+	return trackentity.JobRun{
+		ID:          d.ID,
+		TrackListID: d.TrackListID,
+		TrackID:     d.TrackID,
+		JobID:       d.JobID,
+		JobType:     d.JobType,
+		Attempt:     d.Attempt,
+		WorkerHost:  d.WorkerHost,
+		Engine:      trackentity.SplitEngineType(d.Engine),
+		InputURL:    d.InputURL,
+		OutputURLs:  d.OutputURLs,
+		Error:       d.Error,
+		StartedAt:   d.StartedAt,
+		EndedAt:     d.EndedAt,
+		Duration:    d.Duration,
+	}
+}
+
+func (d DB) SaveJobRun(ctx context.Context, run trackentity.JobRun) error {
+	if run.ID == "" {
+		return mark.Message(IDEmptyMark, "Job run ID is empty")
+	}
+
+	value := dbJobRun{
+		TrackID:     run.TrackID,
+		ID:          run.ID,
+		TrackListID: run.TrackListID,
+		JobID:       run.JobID,
+		JobType:     run.JobType,
+		Attempt:     run.Attempt,
+		WorkerHost:  run.WorkerHost,
+		Engine:      string(run.Engine),
+		InputURL:    run.InputURL,
+		OutputURLs:  run.OutputURLs,
+		Error:       run.Error,
+		StartedAt:   run.StartedAt,
+		EndedAt:     run.EndedAt,
+		Duration:    run.Duration,
+		ExpiresAt:   run.StartedAt.Add(jobRunRetention),
+	}
+
+	err := d.dynamoDB.Table(JobRunsTable).Table.
+		Put(value).
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to put the job run in the DB")
+	}
+
+	return nil
+}
+
+func (d DB) GetJobRuns(ctx context.Context, tracklistID string, trackID string) ([]trackentity.JobRun, error) {
+	values := []dbJobRun{}
+	err := d.dynamoDB.Table(JobRunsTable).
+		Get(jobRunTrackIDKey, trackID).
+		Filter("$ = ?", jobRunTrackListIDField, tracklistID).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch the job runs for the track")
+	}
+
+	runs := []trackentity.JobRun{}
+	for _, value := range values {
+		runs = append(runs, value.toEntity())
+	}
+
+	// the run IDs are random, so the runs come back in no particular order
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+
+	return runs, nil
+}
@@ -0,0 +1,153 @@
+package trackstorage
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/guregu/dynamo"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"time"
+)
+
+const (
+	OutboxTable = "JobOutbox"
+
+	outboxIDKey          = "id"
+	outboxStatusField    = "status"
+	outboxCreatedAtField = "created_at"
+	outboxAttemptsField  = "attempts"
+	outboxLastErrorField = "last_error"
+
+	// outboxPendingKey is only there while the job is waiting to be published,
+	// so the index on it holds just the pending jobs, oldest first
+	outboxPendingKey   = "pending"
+	outboxPendingIndex = "pending-index"
+	outboxPendingValue = 1
+
+	newOutboxJobCondition = "attribute_not_exists(" + outboxIDKey + ")"
+)
+
+var _ trackentity.JobOutbox = DB{}
+
+type dbOutboxJob struct {
+	ID          string    `dynamo:"id"`
+	TrackListID string    `dynamo:"tracklist_id"`
+	TrackID     string    `dynamo:"track_id"`
+	JobType     string    `dynamo:"job_type"`
+	Body        string    `dynamo:"body"`
+	Status      string    `dynamo:"status"`
+	Pending     int       `dynamo:"pending,omitempty"`
+	Attempts    int       `dynamo:"attempts"`
+	LastError   string    `dynamo:"last_error"`
+	CreatedAt   time.Time `dynamo:"created_at,unixtime"`
+}
+
+func (d dbOutboxJob) toEntity() trackentity.OutboxJob {
+	return trackentity.OutboxJob{
+		ID:          d.ID,
+		TrackListID: d.TrackListID,
+		TrackID:     d.TrackID,
+		JobType:     d.JobType,
+		Body:        []byte(d.Body),
+		Status:      trackentity.OutboxJobStatus(d.Status),
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+	}
+}
+
+func (d DB) SetTrackListWithJobs(ctx context.Context, tracklist trackentity.TrackList, jobs []trackentity.OutboxJob) error {
+	putExpr, err := d.putTrackList(tracklist)
+	if err != nil {
+		return err
+	}
+
+	tx := d.dynamoDB.WriteTx().Put(putExpr)
+	for _, job := range jobs {
+		if job.ID == "" {
+			return mark.Message(IDEmptyMark, "A job in the outbox has an empty ID")
+		}
+
+		value := dbOutboxJob{
+			ID:          job.ID,
+			TrackListID: job.TrackListID,
+			TrackID:     job.TrackID,
+			JobType:     job.JobType,
+			Body:        string(job.Body),
+			Status:      string(trackentity.PendingOutboxJobStatus),
+			Pending:     outboxPendingValue,
+			CreatedAt:   job.CreatedAt,
+		}
+
+		jobPutExpr := d.dynamoDB.Table(OutboxTable).Table.
+			Put(value).
+			If(newOutboxJobCondition)
+
+		tx = tx.Put(jobPutExpr)
+	}
+
+	err = tx.RunWithContext(ctx)
+	if err != nil {
+		return mark.Wrap(err,
+			DefaultErrorMark,
+			"Failed to put the tracklist and its jobs in the DB")
+	}
+
+	return nil
+}
+
+func (d DB) GetPendingJobs(ctx context.Context, createdBefore time.Time) ([]trackentity.OutboxJob, error) {
+	values := []dbOutboxJob{}
+	err := d.dynamoDB.Table(OutboxTable).
+		Get(outboxPendingKey, outboxPendingValue).
+		Index(outboxPendingIndex).
+		Range(outboxCreatedAtField, dynamo.Less, createdBefore.Unix()).
+		AllWithContext(ctx, &values)
+
+	if err != nil {
+		return nil, mark.Wrap(err, DefaultErrorMark, "Failed to fetch pending jobs from the outbox")
+	}
+
+	jobs := []trackentity.OutboxJob{}
+	for _, value := range values {
+		jobs = append(jobs, value.toEntity())
+	}
+
+	return jobs, nil
+}
+
+func (d DB) DeleteSentJob(ctx context.Context, jobID string) error {
+	err := d.dynamoDB.Table(OutboxTable).Table.
+		Delete(outboxIDKey, jobID).
+		RunWithContext(ctx)
+
+	if err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to delete the sent job from the outbox")
+	}
+
+	return nil
+}
+
+func (d DB) MarkJobPublishFailed(ctx context.Context, jobID string, publishErr error, giveUp bool) error {
+	if publishErr == nil {
+		publishErr = errors.New("Unknown publish error")
+	}
+
+	updateExpr := d.dynamoDB.Table(OutboxTable).Table.
+		Update(outboxIDKey, jobID).
+		Add(outboxAttemptsField, 1).
+		Set(outboxLastErrorField, publishErr.Error()).
+		If("attribute_exists(" + outboxIDKey + ")")
+
+	if giveUp {
+		updateExpr = updateExpr.
+			Set(outboxStatusField, trackentity.FailedOutboxJobStatus).
+			Remove(outboxPendingKey)
+	}
+
+	if err := updateExpr.RunWithContext(ctx); err != nil {
+		return mark.Wrap(err, DefaultErrorMark, "Failed to record the failed publish on the outbox job")
+	}
+
+	return nil
+}
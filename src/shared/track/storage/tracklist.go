@@ -5,6 +5,7 @@ import (
 	"github.com/guregu/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/errors/mark"
+	"github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 )
 
 const (
@@ -30,3 +31,17 @@ func (d *dbTrackList) UnmarshalDynamoItem(dynamoItem map[string]*dynamodb.Attrib
 
 	return nil
 }
+
+func (d dbTrackList) toEntity() (trackentity.TrackList, error) {
+	// only kept for the DB's lookups, it's not part of the tracklist
+	delete(d, activeSplitsKey)
+
+	tracklist := trackentity.TrackList{}
+	err := tracklist.FromMap(d)
+	if err != nil {
+		return trackentity.TrackList{},
+			mark.Wrap(err, UnmarshalMark, "Failed to transform DB map back to entity tracklist")
+	}
+
+	return tracklist, nil
+}
@@ -1,19 +1,23 @@
 package application
 
 import (
+	"context"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/guregu/dynamo"
 	"github.com/rabbitmq/amqp091-go"
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	filestore "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
 	"github.com/veedubyou/chord-paper-be/src/shared/config"
 	dynamolib "github.com/veedubyou/chord-paper-be/src/shared/lib/dynamo"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
 	trackstorage "github.com/veedubyou/chord-paper-be/src/shared/track/storage"
-	filestore "github.com/veedubyou/chord-paper-be/src/worker/internal/application/cloud_storage/store"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_router"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
@@ -21,11 +25,14 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer/download"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/reaper"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/split_service"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/storagepath"
-	"google.golang.org/api/option"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
 	"os"
+	"time"
 )
 
 func must[T any](t T, err error) T {
@@ -37,7 +44,10 @@ func must[T any](t T, err error) T {
 }
 
 type App struct {
-	worker worker.QueueWorker
+	worker      worker.QueueWorker
+	startReaper func()
+	stopReaper  context.CancelFunc
+	config      Config
 }
 
 type Config struct {
@@ -48,21 +58,64 @@ type Config struct {
 
 	YoutubeDLBinPath        string
 	YoutubeDLWorkingDirPath string
-	SpleeterBinPath         string
-	SpleeterWorkingDirPath  string
-	DemucsBinPath           string
-	DemucsWorkingDirPath    string
+	SplitEngineConfig
+
+	// when set, the splits are sent off to the split service rather than run on the worker,
+	// so only the working dirs of the split engines need to be set up
+	SplitServiceURL string
+	// SplitServiceSecret is shared with the split service, which turns away requests without it
+	SplitServiceSecret string
+
+	SplitJobConcurrency int
+	LightJobConcurrency int
+
+	// how long a shutdown waits on running jobs before cancelling them
+	ShutdownTimeout time.Duration
+	// how long a split request can go without progress before the reaper steps in
+	StuckJobThreshold time.Duration
+	// how long a split request's job can wait in the queue before the reaper steps in
+	QueuedJobThreshold time.Duration
 }
 
+// how often the reaper looks for stuck split requests
+const reapInterval = 5 * time.Minute
+
+// how often a split sent to the split service is checked on
+const splitServicePollInterval = 2 * time.Second
+
+// how long a split waits on the split service to come back, e.g. from a restart,
+// before the job gives up on it and retries
+const splitServiceUnavailableTimeout = 10 * time.Minute
+
 func NewApp(config Config) App {
 	consumerConn := must(amqp091.Dial(config.RabbitMQURL))
 
+	// left behind by a worker that didn't get to shut down properly
+	clearTempDirs(config)
+
+	publisher := newPublisher(config)
+	eventPublisher := newEventPublisher(config)
+	trackStore := trackstorage.NewDB(newDynamoDB(config.DynamoConfig))
+
+	jobPipeline := newPipeline(config, trackStore, eventPublisher)
+
+	stuckJobReaper := newReaper(config, trackStore, publisher, eventPublisher, jobPipeline)
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	startReaper := func() {
+		stuckJobReaper.Run(reaperCtx, reapInterval)
+	}
+
 	return App{
-		worker: newWorker(config, consumerConn),
+		worker:      newWorker(config, consumerConn, trackStore, publisher, eventPublisher, jobPipeline),
+		startReaper: startReaper,
+		stopReaper:  stopReaper,
+		config:      config,
 	}
 }
 
 func (a *App) Start() error {
+	go a.startReaper()
+
 	err := a.worker.Start()
 	if err != nil {
 		return cerr.Wrap(err).Error("Failed to start worker")
@@ -71,24 +124,63 @@ func (a *App) Start() error {
 	return nil
 }
 
+// Stop lets the running jobs finish, up to the shutdown timeout,
+// and makes Start return once the worker has stopped
 func (a *App) Stop() {
-	a.worker.Stop()
+	a.stopReaper()
+	a.worker.Shutdown(a.config.ShutdownTimeout)
+	clearTempDirs(a.config)
 }
 
-func newWorker(config Config, consumerConn *amqp091.Connection) worker.QueueWorker {
-	publisher := newPublisher(config)
+func clearTempDirs(config Config) {
+	workingDirPaths := append([]string{config.YoutubeDLWorkingDirPath}, config.SplitEngineConfig.workingDirPaths()...)
 
-	trackStore := trackstorage.NewDB(newDynamoDB(config.DynamoConfig))
-	queueWorker := must(worker.NewQueueWorkerFromConnection(
+	for _, workingDirPath := range workingDirPaths {
+		workingDir, err := working_dir.NewWorkingDir(workingDirPath)
+		if err != nil {
+			cerr.Log(err)
+			continue
+		}
+
+		if err := workingDir.ClearTempDir(); err != nil {
+			cerr.Log(err)
+		}
+	}
+}
+
+func newWorker(config Config, consumerConn *amqp091.Connection, trackStore trackstorage.DB, publisher rabbitmq.Publisher, eventPublisher trackentity.EventPublisher, jobPipeline pipeline.Pipeline) worker.QueueWorker {
+	return must(worker.NewQueueWorkerFromConnection(
 		consumerConn,
 		config.RabbitMQQueueName,
-		newJobRouter(config, trackStore, publisher)))
+		job_router.NewJobRouter(trackStore, trackStore, publisher, eventPublisher, jobPipeline),
+		worker.DefaultRetryPolicy(),
+		worker.ConcurrencyLimits{
+			SplitJobs: config.SplitJobConcurrency,
+			LightJobs: config.LightJobConcurrency,
+		}))
+}
 
-	return queueWorker
+func newReaper(config Config, trackStore trackstorage.DB, publisher rabbitmq.Publisher, eventPublisher trackentity.EventPublisher, jobPipeline pipeline.Pipeline) reaper.Reaper {
+	// the thresholds that aren't set keep their defaults, a zero threshold would reap every split
+	policy := reaper.DefaultPolicy()
+	if config.StuckJobThreshold > 0 {
+		policy.StuckAfter = config.StuckJobThreshold
+	}
+
+	if config.QueuedJobThreshold > 0 {
+		policy.QueuedStuckAfter = config.QueuedJobThreshold
+	}
+
+	return reaper.NewReaper(trackStore, trackStore, publisher, eventPublisher, jobPipeline, policy)
+}
+
+func newPublisher(config Config) worker.JobPublisher {
+	return worker.NewJobPublisherFromURL(config.RabbitMQURL, config.RabbitMQQueueName)
 }
 
-func newPublisher(config Config) *rabbitmq.QueuePublisher {
-	return rabbitmq.NewQueuePublisher(config.RabbitMQURL, config.RabbitMQQueueName)
+func newEventPublisher(config Config) trackevents.RabbitMQPublisher {
+	exchangeName := trackevents.ExchangeName(config.RabbitMQQueueName)
+	return trackevents.NewRabbitMQPublisher(rabbitmq.NewFanoutPublisher(config.RabbitMQURL, exchangeName))
 }
 
 func newDynamoDB(dynamoConfig config.Dynamo) dynamolib.DynamoDBWrapper {
@@ -125,39 +217,19 @@ func newDynamoDB(dynamoConfig config.Dynamo) dynamolib.DynamoDBWrapper {
 	}
 }
 
-func newGoogleFileStore(cloudStorageConfig config.CloudStorage) filestore.GoogleFileStore {
-	switch t := cloudStorageConfig.(type) {
-	case config.ProdCloudStorage:
-		return must(filestore.NewGoogleFileStore(
-			t.StorageHost,
-			option.WithCredentialsJSON([]byte(t.SecretKey)),
-		))
-
-	case config.LocalCloudStorage:
-		return must(filestore.NewGoogleFileStore(
-			t.StorageHost,
-			option.WithEndpoint(t.HostEndpoint),
-			option.WithAPIKey("fake_api_key"),
-		))
-
-	default:
-		panic("Unrecognized cloud storage config")
-	}
+func newFileStore(cloudStorageConfig config.CloudStorage) cloudstorage.FileStore {
+	return must(filestore.NewFileStore(cloudStorageConfig))
 }
 
-func newJobRouter(config Config, trackStore trackentity.Store, publisher rabbitmq.Publisher) job_router.JobRouter {
-	pathGenerator := storagepath.Generator{
-		Host:   config.CloudStorageConfig.GetStorageHost(),
-		Bucket: config.CloudStorageConfig.GetBucket(),
-	}
+func newPipeline(config Config, trackStore trackentity.Store, eventPublisher trackentity.EventPublisher) pipeline.Pipeline {
+	pathGenerator := storagepath.Generator{}
 
-	return job_router.NewJobRouter(
-		trackStore,
-		publisher,
-		newStartJobHandler(trackStore),
-		newDownloadJobHandler(config, pathGenerator),
-		newSplitJobHandler(config, pathGenerator),
-		newSaveToDBJobHandler(trackStore))
+	return pipeline.NewSplitPipeline(pipeline.SplitHandlers{
+		Start:     newStartJobHandler(trackStore),
+		Transfer:  newDownloadJobHandler(config, pathGenerator),
+		Split:     newSplitJobHandler(config, eventPublisher, pathGenerator),
+		SaveStems: newSaveToDBJobHandler(trackStore),
+	})
 }
 
 func newStartJobHandler(trackStore trackentity.Store) start.JobHandler {
@@ -178,36 +250,27 @@ func newDownloadJobHandler(config Config, pathGenerator storagepath.Generator) t
 	trackDownloader := must(transfer.NewTrackTransferrer(
 		selectdler,
 		trackStore,
-		newGoogleFileStore(config.CloudStorageConfig),
+		newFileStore(config.CloudStorageConfig),
 		pathGenerator,
 		config.YoutubeDLWorkingDirPath,
 	))
 
-	return transfer.NewJobHandler(trackDownloader)
+	return transfer.NewJobHandler(trackDownloader, trackStore)
 }
 
-func newSplitJobHandler(config Config, pathGenerator storagepath.Generator) split.JobHandler {
-	if err := os.MkdirAll(config.SpleeterWorkingDirPath, os.ModePerm); err != nil {
-		panic(err)
+func newSplitJobHandler(config Config, eventPublisher trackentity.EventPublisher, pathGenerator storagepath.Generator) split.JobHandler {
+	var fileSplitter splitter.FileSplitter
+	if config.SplitServiceURL != "" {
+		fileSplitter = split_service.NewClient(config.SplitServiceURL, config.SplitServiceSecret, splitServicePollInterval, splitServiceUnavailableTimeout)
+	} else {
+		fileSplitter = file_splitter.NewLocalFileSplitter(newEngineRegistry(config.SplitEngineConfig), executor.BinaryFileExecutor{})
 	}
 
-	if err := os.MkdirAll(config.DemucsWorkingDirPath, os.ModePerm); err != nil {
-		panic(err)
-	}
-
-	localUsecase := must(file_splitter.NewLocalFileSplitter(
-		config.SpleeterWorkingDirPath,
-		config.SpleeterBinPath,
-		config.DemucsWorkingDirPath,
-		config.DemucsBinPath,
-		executor.BinaryFileExecutor{},
-	))
-
-	googleFileStore := newGoogleFileStore(config.CloudStorageConfig)
+	fileStore := newFileStore(config.CloudStorageConfig)
 	remoteUsecase := must(file_splitter.NewRemoteFileSplitter(
 		config.SpleeterWorkingDirPath,
-		googleFileStore,
-		localUsecase,
+		fileStore,
+		fileSplitter,
 	))
 
 	trackStore := trackstorage.NewDB(newDynamoDB(config.DynamoConfig))
@@ -215,10 +278,11 @@ func newSplitJobHandler(config Config, pathGenerator storagepath.Generator) spli
 	songSplitUsecase := splitter.NewTrackSplitter(
 		remoteUsecase,
 		trackStore,
+		eventPublisher,
 		pathGenerator,
 	)
 
-	return split.NewJobHandler(songSplitUsecase)
+	return split.NewJobHandler(songSplitUsecase, trackStore)
 }
 
 func newSaveToDBJobHandler(trackStore trackentity.Store) save_stems_to_db.JobHandler {
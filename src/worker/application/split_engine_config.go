@@ -0,0 +1,43 @@
+package application
+
+import (
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter/file_splitter"
+)
+
+// SplitEngineConfig is where the split engines are installed, and where they can do their work
+type SplitEngineConfig struct {
+	SpleeterBinPath        string
+	SpleeterWorkingDirPath string
+	DemucsBinPath          string
+	DemucsWorkingDirPath   string
+	// Open-Unmix is optional, the engine is only registered when its bin path is set
+	OpenUnmixBinPath        string
+	OpenUnmixWorkingDirPath string
+}
+
+func (s SplitEngineConfig) workingDirPaths() []string {
+	workingDirPaths := []string{
+		s.SpleeterWorkingDirPath,
+		s.DemucsWorkingDirPath,
+	}
+
+	if s.OpenUnmixBinPath != "" {
+		workingDirPaths = append(workingDirPaths, s.OpenUnmixWorkingDirPath)
+	}
+
+	return workingDirPaths
+}
+
+func newEngineRegistry(config SplitEngineConfig) file_splitter.EngineRegistry {
+	engines := []file_splitter.SplitEngine{
+		must(file_splitter.NewSpleeterEngine(config.SpleeterWorkingDirPath, config.SpleeterBinPath)),
+		must(file_splitter.NewDemucsEngine(config.DemucsWorkingDirPath, config.DemucsBinPath)),
+		must(file_splitter.NewDemucsV3Engine(config.DemucsWorkingDirPath, config.DemucsBinPath)),
+	}
+
+	if config.OpenUnmixBinPath != "" {
+		engines = append(engines, must(file_splitter.NewOpenUnmixEngine(config.OpenUnmixWorkingDirPath, config.OpenUnmixBinPath)))
+	}
+
+	return must(file_splitter.NewEngineRegistry(engines...))
+}
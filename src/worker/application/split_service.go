@@ -0,0 +1,91 @@
+package application
+
+import (
+	"context"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter/file_splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/split_service"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+	"net/http"
+	"time"
+)
+
+// SplitServiceApp runs splits for the workers, so that splitting can be
+// scaled separately from them, on machines that have the hardware for it
+type SplitServiceApp struct {
+	server *http.Server
+	config SplitServiceConfig
+}
+
+type SplitServiceConfig struct {
+	SplitEngineConfig
+
+	Port string
+	// where the uploaded originals and the split stems are kept until the worker has them
+	WorkingDirPath string
+	// how many splits run at once, the workers are turned away when they're all taken
+	Concurrency int
+	// Secret is shared with the workers, requests without it are turned away
+	Secret string
+
+	// how long a shutdown waits on the requests being served
+	ShutdownTimeout time.Duration
+}
+
+func NewSplitServiceApp(config SplitServiceConfig) SplitServiceApp {
+	clearSplitServiceTempDirs(config)
+
+	server := must(split_service.NewServer(
+		file_splitter.NewLocalFileSplitter(newEngineRegistry(config.SplitEngineConfig), executor.BinaryFileExecutor{}),
+		config.WorkingDirPath,
+		config.Concurrency,
+		config.Secret,
+	))
+
+	return SplitServiceApp{
+		server: &http.Server{
+			Addr:    config.Port,
+			Handler: server,
+		},
+		config: config,
+	}
+}
+
+func (a *SplitServiceApp) Start() error {
+	err := a.server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		return cerr.Wrap(err).Error("Failed to start split service")
+	}
+
+	return nil
+}
+
+// Stop doesn't wait on the splits themselves, whatever the workers
+// haven't fetched is given up on and they split the track again
+func (a *SplitServiceApp) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := a.server.Shutdown(ctx); err != nil {
+		cerr.Log(cerr.Wrap(err).Error("Failed to shut down split service"))
+	}
+
+	clearSplitServiceTempDirs(a.config)
+}
+
+func clearSplitServiceTempDirs(config SplitServiceConfig) {
+	workingDirPaths := append([]string{config.WorkingDirPath}, config.SplitEngineConfig.workingDirPaths()...)
+
+	for _, workingDirPath := range workingDirPaths {
+		workingDir, err := working_dir.NewWorkingDir(workingDirPath)
+		if err != nil {
+			cerr.Log(err)
+			continue
+		}
+
+		if err := workingDir.ClearTempDir(); err != nil {
+			cerr.Log(err)
+		}
+	}
+}
@@ -1,16 +1,25 @@
 package executor
 
-import "os/exec"
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
 
 var _ Executor = BinaryFileExecutor{}
 
 type Executor interface {
 	Command(name string, arg ...string) Command
+	// CommandContext kills the process if the context is done before it exits
+	CommandContext(ctx context.Context, name string, arg ...string) Command
 }
 
 type Command interface {
 	SetDir(dir string)
 	CombinedOutput() ([]byte, error)
+	// StreamOutput runs the command the same way as CombinedOutput,
+	// but also hands over each line of stdout and stderr as soon as it's written
+	StreamOutput(onLine func(line string)) ([]byte, error)
 }
 
 // the only reason this is here is to create an interface for testing
@@ -23,6 +32,12 @@ func (b BinaryFileExecutor) Command(name string, arg ...string) Command {
 
 }
 
+func (b BinaryFileExecutor) CommandContext(ctx context.Context, name string, arg ...string) Command {
+	return &BinaryFileCommand{
+		cmd: exec.CommandContext(ctx, name, arg...),
+	}
+}
+
 type BinaryFileCommand struct {
 	cmd *exec.Cmd
 }
@@ -34,3 +49,47 @@ func (b *BinaryFileCommand) SetDir(dir string) {
 func (b *BinaryFileCommand) CombinedOutput() ([]byte, error) {
 	return b.cmd.CombinedOutput()
 }
+
+func (b *BinaryFileCommand) StreamOutput(onLine func(line string)) ([]byte, error) {
+	writer := &lineWriter{onLine: onLine}
+	// with the same writer for both, exec makes sure only one goroutine writes at a time
+	b.cmd.Stdout = writer
+	b.cmd.Stderr = writer
+
+	err := b.cmd.Run()
+	writer.flush()
+
+	return writer.output.Bytes(), err
+}
+
+// lineWriter splits output into lines as it's written. Carriage returns
+// count as line endings, since that's how progress bars redraw themselves
+type lineWriter struct {
+	onLine      func(line string)
+	output      bytes.Buffer
+	currentLine []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.output.Write(p)
+
+	for _, char := range p {
+		if char == '\n' || char == '\r' {
+			l.flush()
+			continue
+		}
+
+		l.currentLine = append(l.currentLine, char)
+	}
+
+	return len(p), nil
+}
+
+func (l *lineWriter) flush() {
+	if len(l.currentLine) == 0 {
+		return
+	}
+
+	l.onLine(string(l.currentLine))
+	l.currentLine = l.currentLine[:0]
+}
@@ -0,0 +1,69 @@
+package dummy
+
+import (
+	"context"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
+	"path/filepath"
+)
+
+var _ executor.Executor = DemucsExecutor{}
+
+func NewDummyDemucsExecutor() *DemucsExecutor {
+	return &DemucsExecutor{}
+}
+
+type DemucsExecutor struct {
+	FakeSplit
+}
+
+func (d DemucsExecutor) Command(name string, arg ...string) executor.Command {
+	return d.CommandContext(context.Background(), name, arg...)
+}
+
+func (d DemucsExecutor) CommandContext(ctx context.Context, _ string, arg ...string) executor.Command {
+	return fakeSplitCommand{
+		FakeSplit: d.FakeSplit,
+		ctx:       ctx,
+		split: func() ([]byte, error) {
+			return d.split(arg)
+		},
+	}
+}
+
+func (d DemucsExecutor) split(args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, UnexpectedInput
+	}
+
+	sourcePath := args[len(args)-1]
+
+	destinationDir, err := getOptionValue(args, "-o")
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := getOptionValue(args, "--name")
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Unavailable {
+		return nil, NetworkFailure
+	}
+
+	stems := []string{"vocals", "other", "bass", "drums"}
+	if model == "htdemucs_6s" {
+		stems = append(stems, "guitar", "piano")
+	}
+
+	if hasOption(args, "--two-stems") {
+		stems = []string{"vocals", "no_vocals"}
+	}
+
+	// demucs puts the stems in a folder named after the model
+	if err := writeFakeStems(sourcePath, filepath.Join(destinationDir, model), stems); err != nil {
+		return nil, err
+	}
+
+	return []byte("Success"), nil
+}
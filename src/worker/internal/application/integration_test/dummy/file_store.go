@@ -1,9 +1,13 @@
 package dummy
 
 import (
+	"bytes"
 	"context"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/cloud_storage/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
+	"github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/store"
+	"io"
 	"sync"
+	"time"
 )
 
 var _ entity.FileStore = &FileStore{}
@@ -21,7 +25,7 @@ type FileStore struct {
 	mutex       sync.RWMutex
 }
 
-func (t *FileStore) GetFile(_ context.Context, url string) ([]byte, error) {
+func (t *FileStore) GetFile(_ context.Context, objectPath string) ([]byte, error) {
 	if t.Unavailable {
 		return nil, NetworkFailure
 	}
@@ -29,7 +33,7 @@ func (t *FileStore) GetFile(_ context.Context, url string) ([]byte, error) {
 	t.mutex.RLock()
 	defer t.mutex.RUnlock()
 
-	content, ok := t.State[url]
+	content, ok := t.State[objectPath]
 	if !ok {
 		return nil, NotFound
 	}
@@ -37,14 +41,44 @@ func (t *FileStore) GetFile(_ context.Context, url string) ([]byte, error) {
 	return content, nil
 }
 
-func (t *FileStore) WriteFile(_ context.Context, url string, fileContent []byte) error {
+func (t *FileStore) WriteFile(_ context.Context, objectPath string, fileContent []byte) error {
 	if t.Unavailable {
 		return NetworkFailure
 	}
 
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
-	t.State[url] = append([]byte{}, fileContent...)
+	t.State[objectPath] = append([]byte{}, fileContent...)
 
 	return nil
 }
+
+func (t *FileStore) OpenFile(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	content, err := t.GetFile(ctx, objectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (t *FileStore) UploadFile(ctx context.Context, objectPath string, content io.Reader) error {
+	if t.Unavailable {
+		return NetworkFailure
+	}
+
+	fileContent, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	return t.WriteFile(ctx, objectPath, fileContent)
+}
+
+func (t *FileStore) SignURL(ctx context.Context, objectPath string, expiry time.Duration) (string, error) {
+	if t.Unavailable {
+		return "", NetworkFailure
+	}
+
+	return store.FakeURLSigner{StorageHost: "https://dummy-storage", Bucket: "dummy-bucket"}.SignURL(ctx, objectPath, expiry)
+}
@@ -0,0 +1,57 @@
+package dummy
+
+import (
+	"context"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"sort"
+	"sync"
+)
+
+var _ trackentity.JobRunStore = &JobRunStore{}
+
+func NewDummyJobRunStore() *JobRunStore {
+	return &JobRunStore{
+		Unavailable: false,
+		State:       make(map[string]trackentity.JobRun),
+	}
+}
+
+type JobRunStore struct {
+	Unavailable bool
+	State       map[string]trackentity.JobRun
+	mutex       sync.RWMutex
+}
+
+func (j *JobRunStore) SaveJobRun(ctx context.Context, run trackentity.JobRun) error {
+	if j.Unavailable {
+		return NetworkFailure
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.State[run.ID] = run
+	return nil
+}
+
+func (j *JobRunStore) GetJobRuns(ctx context.Context, tracklistID string, trackID string) ([]trackentity.JobRun, error) {
+	if j.Unavailable {
+		return nil, NetworkFailure
+	}
+
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	runs := []trackentity.JobRun{}
+	for _, run := range j.State {
+		if run.TrackListID == tracklistID && run.TrackID == trackID {
+			runs = append(runs, run)
+		}
+	}
+
+	sort.SliceStable(runs, func(i, k int) bool {
+		return runs[i].StartedAt.Before(runs[k].StartedAt)
+	})
+
+	return runs, nil
+}
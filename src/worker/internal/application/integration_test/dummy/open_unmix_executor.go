@@ -0,0 +1,107 @@
+package dummy
+
+import (
+	"context"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
+	"path/filepath"
+	"strings"
+)
+
+var _ executor.Executor = OpenUnmixExecutor{}
+
+func NewDummyOpenUnmixExecutor() *OpenUnmixExecutor {
+	return &OpenUnmixExecutor{}
+}
+
+type OpenUnmixExecutor struct {
+	FakeSplit
+}
+
+func (o OpenUnmixExecutor) Command(name string, arg ...string) executor.Command {
+	return o.CommandContext(context.Background(), name, arg...)
+}
+
+func (o OpenUnmixExecutor) CommandContext(ctx context.Context, _ string, arg ...string) executor.Command {
+	return fakeSplitCommand{
+		FakeSplit: o.FakeSplit,
+		ctx:       ctx,
+		split: func() ([]byte, error) {
+			return o.split(arg)
+		},
+	}
+}
+
+// openUnmixOptionArgs is how many arguments each of umx's options takes,
+// -1 being an option like --targets that takes every argument up to the next option
+var openUnmixOptionArgs = map[string]int{
+	"--outdir":   1,
+	"--model":    1,
+	"--ext":      1,
+	"--residual": 1,
+	"--no-cuda":  0,
+	"--targets":  -1,
+}
+
+// parseOpenUnmixArgs reads the arguments the way umx's argparse does,
+// where whatever no option takes is the input
+func parseOpenUnmixArgs(args []string) ([]string, map[string][]string, error) {
+	inputs := []string{}
+	options := map[string][]string{}
+
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			inputs = append(inputs, args[i])
+			continue
+		}
+
+		option := args[i]
+		argCount, ok := openUnmixOptionArgs[option]
+		if !ok {
+			return nil, nil, UnexpectedInput
+		}
+
+		values := []string{}
+		for argCount != 0 && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			i++
+			values = append(values, args[i])
+			argCount--
+		}
+
+		if argCount > 0 || (argCount < 0 && len(values) == 0) {
+			return nil, nil, UnexpectedInput
+		}
+
+		options[option] = values
+	}
+
+	return inputs, options, nil
+}
+
+func (o OpenUnmixExecutor) split(args []string) ([]byte, error) {
+	inputs, options, err := parseOpenUnmixArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// umx can split several inputs at once, but the engine only ever gives it the one
+	if len(inputs) != 1 || len(options["--outdir"]) != 1 || len(options["--targets"]) == 0 {
+		return nil, UnexpectedInput
+	}
+
+	if o.Unavailable {
+		return nil, NetworkFailure
+	}
+
+	sourcePath := inputs[0]
+	destinationDir := options["--outdir"][0]
+	stems := append([]string{}, options["--targets"]...)
+	stems = append(stems, options["--residual"]...)
+
+	// open-unmix puts the stems in a folder named after the source file
+	sourceName := strings.TrimSuffix(filepath.Base(sourcePath), filepath.Ext(sourcePath))
+	if err := writeFakeStems(sourcePath, filepath.Join(destinationDir, sourceName), stems); err != nil {
+		return nil, err
+	}
+
+	return []byte("Success"), nil
+}
@@ -1,9 +1,12 @@
 package dummy
 
 import (
+	"context"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"strings"
+	"sync"
 )
 
 var _ rabbitmq.Publisher = &RabbitMQ{}
@@ -11,39 +14,75 @@ var _ worker.MessageChannel = &RabbitMQ{}
 var _ amqp091.Acknowledger = RabbitMQAcknowledger{}
 
 type RabbitMQ struct {
-	AckCounter     int
-	NackCounter    int
+	AckCounter  int
+	NackCounter int
+	// RequeueCounter counts the nacks that put the message back on its queue
+	RequeueCounter int
 	Unavailable    bool
-	MessageChannel chan amqp091.Delivery
+	// DeadLetterUnavailable fails publishes to the dead letter queue only
+	DeadLetterUnavailable bool
+	MessageChannel        chan amqp091.Delivery
+	// SplitChannel stands in for the split lane's queue
+	SplitChannel chan amqp091.Delivery
+	DeadLetters  []amqp091.Publishing
+	// the worker handles messages from several goroutines
+	mutex     sync.Mutex
+	consumers map[string]chan struct{}
 }
 
 type RabbitMQAcknowledger struct {
 	ack  func()
-	nack func()
+	nack func(requeue bool)
 }
 
 func NewRabbitMQ() *RabbitMQ {
 	return &RabbitMQ{
 		Unavailable:    false,
 		MessageChannel: make(chan amqp091.Delivery, 100),
+		SplitChannel:   make(chan amqp091.Delivery, 100),
+		consumers:      map[string]chan struct{}{},
 	}
 }
 
 func (r *RabbitMQ) Publish(msg amqp091.Publishing) error {
+	return r.deliver(r.MessageChannel, msg)
+}
+
+// SplitQueue publishes straight to the split lane
+func (r *RabbitMQ) SplitQueue() rabbitmq.Publisher {
+	return splitQueuePublisher{rabbitMQ: r}
+}
+
+type splitQueuePublisher struct {
+	rabbitMQ *RabbitMQ
+}
+
+func (s splitQueuePublisher) Publish(msg amqp091.Publishing) error {
+	return s.rabbitMQ.deliver(s.rabbitMQ.SplitChannel, msg)
+}
+
+func (r *RabbitMQ) deliver(channel chan amqp091.Delivery, msg amqp091.Publishing) error {
 	if r.Unavailable {
 		return NetworkFailure
 	}
 
 	acknowledger := RabbitMQAcknowledger{
 		ack: func() {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
 			r.AckCounter++
 		},
-		nack: func() {
+		nack: func(requeue bool) {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
 			r.NackCounter++
+			if requeue {
+				r.RequeueCounter++
+			}
 		},
 	}
 
-	r.MessageChannel <- amqp091.Delivery{
+	channel <- amqp091.Delivery{
 		Acknowledger:    acknowledger,
 		ContentType:     msg.ContentType,
 		ContentEncoding: msg.ContentEncoding,
@@ -51,16 +90,90 @@ func (r *RabbitMQ) Publish(msg amqp091.Publishing) error {
 		Timestamp:       msg.Timestamp,
 		Type:            msg.Type,
 		Body:            msg.Body,
+		Headers:         msg.Headers,
+	}
+	return nil
+}
+
+// PublishConfirmed delivers messages sent to a retry queue straight
+// back to their lane, as if the delay has already passed
+func (r *RabbitMQ) PublishConfirmed(_ context.Context, key string, msg amqp091.Publishing) error {
+	if r.Unavailable {
+		return NetworkFailure
+	}
+
+	if strings.HasSuffix(key, worker.DeadLetterQueueSuffix) {
+		if r.DeadLetterUnavailable {
+			return NetworkFailure
+		}
+
+		r.mutex.Lock()
+		r.DeadLetters = append(r.DeadLetters, msg)
+		r.mutex.Unlock()
+		return nil
+	}
+
+	// covers the split queue, and the retry queues that feed back into it
+	if strings.Contains(key, worker.SplitQueueSuffix) {
+		return r.deliver(r.SplitChannel, msg)
 	}
+
+	return r.Publish(msg)
+}
+
+func (r *RabbitMQ) Qos(_ int, _ int, _ bool) error {
 	return nil
 }
 
-func (r *RabbitMQ) Consume(_ string, _ string, _ bool, _ bool, _ bool, _ bool, _ amqp091.Table) (<-chan amqp091.Delivery, error) {
+// Consume passes messages along from the queue's channel until the consumer is
+// cancelled, then closes the deliveries like the real client does
+func (r *RabbitMQ) Consume(queue string, consumer string, _ bool, _ bool, _ bool, _ bool, _ amqp091.Table) (<-chan amqp091.Delivery, error) {
 	if r.Unavailable {
 		return nil, NetworkFailure
 	}
 
-	return r.MessageChannel, nil
+	source := r.MessageChannel
+	if strings.HasSuffix(queue, worker.SplitQueueSuffix) {
+		source = r.SplitChannel
+	}
+
+	cancelled := make(chan struct{})
+	r.mutex.Lock()
+	r.consumers[consumer] = cancelled
+	r.mutex.Unlock()
+
+	deliveries := make(chan amqp091.Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			select {
+			case <-cancelled:
+				return
+			case message := <-source:
+				select {
+				case deliveries <- message:
+				case <-cancelled:
+					// nobody took it, so it stays on the queue
+					source <- message
+					return
+				}
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (r *RabbitMQ) Cancel(consumer string, _ bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if cancelled, ok := r.consumers[consumer]; ok {
+		close(cancelled)
+		delete(r.consumers, consumer)
+	}
+
+	return nil
 }
 
 func (r *RabbitMQ) Close() error {
@@ -72,10 +185,10 @@ func (r RabbitMQAcknowledger) Ack(tag uint64, multiple bool) error {
 	return nil
 }
 func (r RabbitMQAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
-	r.nack()
+	r.nack(requeue)
 	return nil
 }
 func (r RabbitMQAcknowledger) Reject(tag uint64, requeue bool) error {
-	r.nack()
+	r.nack(requeue)
 	return nil
 }
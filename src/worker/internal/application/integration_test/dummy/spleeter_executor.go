@@ -1,62 +1,47 @@
 package dummy
 
 import (
+	"context"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
-	"os"
-	"path/filepath"
 )
 
 var _ executor.Executor = SpleeterExecutor{}
 
 func NewDummySpleeterExecutor() *SpleeterExecutor {
-	return &SpleeterExecutor{
-		Unavailable: false,
-	}
+	return &SpleeterExecutor{}
 }
 
 type SpleeterExecutor struct {
-	Unavailable bool
+	FakeSplit
 }
 
-type SpleeterCommand struct {
-	Unavailable bool
-	Args        []string
+func (s SpleeterExecutor) Command(name string, arg ...string) executor.Command {
+	return s.CommandContext(context.Background(), name, arg...)
 }
 
-func (y SpleeterExecutor) Command(_ string, arg ...string) executor.Command {
-	return SpleeterCommand{
-		Unavailable: y.Unavailable,
-		Args:        arg,
+func (s SpleeterExecutor) CommandContext(ctx context.Context, _ string, arg ...string) executor.Command {
+	return fakeSplitCommand{
+		FakeSplit: s.FakeSplit,
+		ctx:       ctx,
+		split: func() ([]byte, error) {
+			return s.split(arg)
+		},
 	}
 }
 
-func getOptionValue(args []string, key string) (string, error) {
-	for i, arg := range args {
-		if arg == key {
-			return args[i+1], nil
-		}
-	}
-
-	return "", UnexpectedInput
-}
-
-func (s SpleeterCommand) SetDir(_ string) {}
-
-func (s SpleeterCommand) CombinedOutput() ([]byte, error) {
-	if s.Args[0] != "separate" {
+func (s SpleeterExecutor) split(args []string) ([]byte, error) {
+	if len(args) == 0 || args[0] != "separate" {
 		return nil, UnexpectedInput
 	}
 
-	lastIndex := len(s.Args) - 1
-
-	sourcePath := s.Args[lastIndex]
+	sourcePath := args[len(args)-1]
 
-	splitParam, err := getOptionValue(s.Args, "-p")
+	splitParam, err := getOptionValue(args, "-p")
 	if err != nil {
 		return nil, err
 	}
 
-	destinationDir, err := getOptionValue(s.Args, "-o")
+	destinationDir, err := getOptionValue(args, "-o")
 	if err != nil {
 		return nil, err
 	}
@@ -65,39 +50,20 @@ func (s SpleeterCommand) CombinedOutput() ([]byte, error) {
 		return nil, NetworkFailure
 	}
 
-	contents, err := os.ReadFile(sourcePath)
-	if err != nil {
-		return nil, err
-	}
-
-	stems := []string{}
-
+	var stems []string
 	switch splitParam {
 	case "spleeter:2stems-16kHz":
-		{
-			stems = append(stems, "vocals", "accompaniment")
-		}
+		stems = []string{"vocals", "accompaniment"}
 	case "spleeter:4stems-16kHz":
-		{
-			stems = append(stems, "vocals", "other", "bass", "drums")
-		}
+		stems = []string{"vocals", "other", "bass", "drums"}
 	case "spleeter:5stems-16kHz":
-		{
-			stems = append(stems, "vocals", "other", "piano", "bass", "drums")
-		}
+		stems = []string{"vocals", "other", "piano", "bass", "drums"}
 	default:
-		{
-			return nil, UnexpectedInput
-		}
+		return nil, UnexpectedInput
 	}
 
-	for _, stem := range stems {
-		stemPath := filepath.Join(destinationDir, stem+".mp3")
-		stemContents := []byte(string(contents) + "-" + stem)
-		err := os.WriteFile(stemPath, stemContents, os.ModePerm)
-		if err != nil {
-			return nil, err
-		}
+	if err := writeFakeStems(sourcePath, destinationDir, stems); err != nil {
+		return nil, err
 	}
 
 	return []byte("Success"), nil
@@ -0,0 +1,129 @@
+package dummy
+
+import (
+	"context"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
+	"os"
+	"path/filepath"
+)
+
+var _ executor.Executor = SplitExecutor{}
+
+// NewDummySplitExecutor runs each command with the fake of the engine that the binary belongs to,
+// the same way the real executor runs whichever binary it's given
+func NewDummySplitExecutor(engineExecutors map[string]executor.Executor) SplitExecutor {
+	return SplitExecutor{
+		engineExecutors: engineExecutors,
+	}
+}
+
+type SplitExecutor struct {
+	engineExecutors map[string]executor.Executor
+}
+
+func (s SplitExecutor) Command(name string, arg ...string) executor.Command {
+	return s.CommandContext(context.Background(), name, arg...)
+}
+
+func (s SplitExecutor) CommandContext(ctx context.Context, name string, arg ...string) executor.Command {
+	engineExecutor, ok := s.engineExecutors[name]
+	if !ok {
+		return failedCommand{err: UnexpectedInput}
+	}
+
+	return engineExecutor.CommandContext(ctx, name, arg...)
+}
+
+// FakeSplit is what all the split engine fakes can be told to do.
+// OutputLines are streamed out before the split runs, like the real binaries' progress output.
+// Hang keeps the split running until it's cancelled
+type FakeSplit struct {
+	Unavailable bool
+	OutputLines []string
+	Hang        bool
+}
+
+type fakeSplitCommand struct {
+	FakeSplit
+	ctx   context.Context
+	split func() ([]byte, error)
+}
+
+func (f fakeSplitCommand) SetDir(_ string) {}
+
+func (f fakeSplitCommand) StreamOutput(onLine func(line string)) ([]byte, error) {
+	for _, line := range f.OutputLines {
+		onLine(line)
+	}
+
+	return f.CombinedOutput()
+}
+
+func (f fakeSplitCommand) CombinedOutput() ([]byte, error) {
+	if f.Hang {
+		<-f.ctx.Done()
+	}
+
+	// the real process would have been killed by now
+	if err := f.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return f.split()
+}
+
+type failedCommand struct {
+	err error
+}
+
+func (f failedCommand) SetDir(_ string) {}
+
+func (f failedCommand) StreamOutput(_ func(line string)) ([]byte, error) {
+	return f.CombinedOutput()
+}
+
+func (f failedCommand) CombinedOutput() ([]byte, error) {
+	return nil, f.err
+}
+
+func getOptionValue(args []string, key string) (string, error) {
+	for i, arg := range args {
+		if arg == key && i+1 < len(args) {
+			return args[i+1], nil
+		}
+	}
+
+	return "", UnexpectedInput
+}
+
+func hasOption(args []string, key string) bool {
+	for _, arg := range args {
+		if arg == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeFakeStems writes each stem as the source contents with the stem name at the end
+func writeFakeStems(sourcePath string, stemsDir string, stems []string) error {
+	contents, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(stemsDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, stem := range stems {
+		stemPath := filepath.Join(stemsDir, stem+".mp3")
+		stemContents := []byte(string(contents) + "-" + stem)
+		if err := os.WriteFile(stemPath, stemContents, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 )
 
 var _ trackentity.Store = &TrackStore{}
+var _ trackentity.ActiveSplitFinder = &TrackStore{}
+var _ trackentity.ConditionalTrackUpdater = &TrackStore{}
 
 func NewDummyTrackStore() *TrackStore {
 	return &TrackStore{
@@ -43,8 +45,8 @@ func (t *TrackStore) SetTrackList(ctx context.Context, tracklist trackentity.Tra
 		return NetworkFailure
 	}
 
-	t.mutex.RLock()
-	defer t.mutex.RUnlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
 	t.State[tracklist.Defined.SongID] = tracklist
 	return nil
@@ -74,3 +76,63 @@ func (t *TrackStore) UpdateTrack(ctx context.Context, trackListID string, trackI
 
 	return cerr.Error("Track ID not found in tracklist")
 }
+
+// UpdateTrackIfUnchanged holds the lock through the whole update, so the track can't change in the meantime
+func (t *TrackStore) UpdateTrackIfUnchanged(ctx context.Context, trackListID string, trackID string, updater trackentity.TrackUpdater) error {
+	if t.Unavailable {
+		return NetworkFailure
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tracklist, ok := t.State[trackListID]
+	if !ok {
+		return cerr.Wrap(NotFound).Error("Failed to get tracklist from DB")
+	}
+
+	for i, track := range tracklist.Defined.Tracks {
+		if track.GetID() == trackID {
+			updatedTrack, err := updater(track)
+			if err != nil {
+				return cerr.Wrap(err).Error("Track update function failed")
+			}
+
+			tracklist.Defined.Tracks[i] = updatedTrack
+			t.State[trackListID] = tracklist
+			return nil
+		}
+	}
+
+	return cerr.Error("Track ID not found in tracklist")
+}
+
+func (t *TrackStore) GetTrackListsWithActiveSplits(ctx context.Context) ([]trackentity.TrackList, error) {
+	if t.Unavailable {
+		return nil, NetworkFailure
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tracklists := []trackentity.TrackList{}
+	for _, tracklist := range t.State {
+		for _, track := range tracklist.Defined.Tracks {
+			if splitRequest, ok := track.(*trackentity.SplitRequestTrack); ok && splitRequest.IsInProgress() {
+				tracklists = append(tracklists, tracklist)
+				break
+			}
+		}
+	}
+
+	return tracklists, nil
+}
+
+// ClearActiveSplits has nothing to do, the active splits are worked out from the state every time
+func (t *TrackStore) ClearActiveSplits(ctx context.Context, tracklistID string) error {
+	if t.Unavailable {
+		return NetworkFailure
+	}
+
+	return nil
+}
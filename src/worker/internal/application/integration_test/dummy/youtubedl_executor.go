@@ -1,6 +1,7 @@
 package dummy
 
 import (
+	"context"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
 	"os"
 )
@@ -39,8 +40,16 @@ func (y YoutubeDLExecutor) Command(_ string, arg ...string) executor.Command {
 	}
 }
 
+func (y YoutubeDLExecutor) CommandContext(_ context.Context, name string, arg ...string) executor.Command {
+	return y.Command(name, arg...)
+}
+
 func (y YoutubeDLCommand) SetDir(_ string) {}
 
+func (y YoutubeDLCommand) StreamOutput(_ func(line string)) ([]byte, error) {
+	return y.CombinedOutput()
+}
+
 func (y YoutubeDLCommand) CombinedOutput() ([]byte, error) {
 	if y.Args[0] != "-o" {
 		return nil, UnexpectedInput
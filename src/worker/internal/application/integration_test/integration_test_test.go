@@ -7,11 +7,12 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/rabbitmq/amqp091-go"
-	"github.com/veedubyou/chord-paper-be/src/shared/config/prod"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_router"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
@@ -21,6 +22,9 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer/download"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/storagepath"
+	"os"
+	"path/filepath"
+	"time"
 )
 
 var _ = Describe("IntegrationTest", func() {
@@ -29,7 +33,6 @@ var _ = Describe("IntegrationTest", func() {
 		trackID           string
 		originalURL       string
 		originalTrackData []byte
-		bucketName        string
 
 		rabbitMQ          *dummy.RabbitMQ
 		fileStore         *dummy.FileStore
@@ -47,7 +50,6 @@ var _ = Describe("IntegrationTest", func() {
 			trackID = "track-ID"
 			originalURL = "https://www.youtube.com/jams.mp3"
 			originalTrackData = []byte("cool-jamz")
-			bucketName = "bucket-head"
 		})
 
 		By("Instantiating all dummies", func() {
@@ -84,10 +86,7 @@ var _ = Describe("IntegrationTest", func() {
 			startHandler = start.NewJobHandler(trackStore)
 		})
 
-		pathGenerator := storagepath.Generator{
-			Host:   prod.GOOGLE_STORAGE_HOST,
-			Bucket: bucketName,
-		}
+		pathGenerator := storagepath.Generator{}
 
 		var transferHandler transfer.JobHandler
 		By("Creating the download job handler", func() {
@@ -98,17 +97,21 @@ var _ = Describe("IntegrationTest", func() {
 			trackDownloader, err := transfer.NewTrackTransferrer(selectdler, trackStore, fileStore, pathGenerator, workingDir)
 			Expect(err).NotTo(HaveOccurred())
 
-			transferHandler = transfer.NewJobHandler(trackDownloader)
+			transferHandler = transfer.NewJobHandler(trackDownloader, trackStore)
 		})
 
 		var splitHandler split.JobHandler
 		By("Creating the split job handler", func() {
-			localFileSplitter, err := file_splitter.NewLocalFileSplitter(workingDir, "/whatever/spleeter", workingDir, "/whatever/demucs", spleeterExecutor)
+			spleeter, err := file_splitter.NewSpleeterEngine(workingDir, "/whatever/spleeter")
 			Expect(err).NotTo(HaveOccurred())
+			engines, err := file_splitter.NewEngineRegistry(spleeter)
+			Expect(err).NotTo(HaveOccurred())
+
+			localFileSplitter := file_splitter.NewLocalFileSplitter(engines, spleeterExecutor)
 			remoteFileSplitter, err := file_splitter.NewRemoteFileSplitter(workingDir, fileStore, localFileSplitter)
 			Expect(err).NotTo(HaveOccurred())
-			trackSplitter := splitter.NewTrackSplitter(remoteFileSplitter, trackStore, pathGenerator)
-			splitHandler = split.NewJobHandler(trackSplitter)
+			trackSplitter := splitter.NewTrackSplitter(remoteFileSplitter, trackStore, trackevents.NewBus(), pathGenerator)
+			splitHandler = split.NewJobHandler(trackSplitter, trackStore)
 		})
 
 		var saveHandler save_stems_to_db.JobHandler
@@ -119,13 +122,24 @@ var _ = Describe("IntegrationTest", func() {
 		By("Instantiating the worker", func() {
 			router := job_router.NewJobRouter(
 				trackStore,
-				rabbitMQ,
-				startHandler,
-				transferHandler,
-				splitHandler,
-				saveHandler,
+				dummy.NewDummyJobRunStore(),
+				worker.NewJobPublisher(rabbitMQ, rabbitMQ.SplitQueue()),
+				trackevents.NewBus(),
+				pipeline.NewSplitPipeline(pipeline.SplitHandlers{
+					Start:     startHandler,
+					Transfer:  transferHandler,
+					Split:     splitHandler,
+					SaveStems: saveHandler,
+				}),
 			)
-			queueWorker = worker.NewQueueWorker(rabbitMQ, "test-queue", router)
+			// the dummy redelivers retries straight away, so the delays don't matter
+			var err error
+			queueWorker, err = worker.NewQueueWorker(rabbitMQ, "test-queue", router, worker.RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Second,
+				MaxDelay:    time.Second,
+			}, worker.DefaultConcurrencyLimits())
+			Expect(err).NotTo(HaveOccurred())
 		})
 
 		By("Setting up the run routine", func() {
@@ -216,20 +230,198 @@ var _ = Describe("IntegrationTest", func() {
 		})
 	})
 
+	Describe("Split job is published to the job queue, like a resumed split", func() {
+		var savedOriginalURL string
+
+		BeforeEach(func() {
+			savedOriginalURL = "original/track-ID.mp3"
+
+			err := fileStore.WriteFile(context.Background(), savedOriginalURL, originalTrackData)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = trackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+				splitRequest := track.(*trackentity.SplitRequestTrack)
+				splitRequest.Status = trackentity.ProcessingStatus
+				splitRequest.SavedOriginalURL = savedOriginalURL
+				return splitRequest, nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("forwards the job to the split lane and finishes the track", func() {
+			go func() {
+				err := queueWorker.Start()
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			jsonBytes, err := json.Marshal(split.JobParams{
+				TrackIdentifier: job_message.TrackIdentifier{
+					TrackListID: tracklistID,
+					TrackID:     trackID,
+				},
+				SavedOriginalURL: savedOriginalURL,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = rabbitMQ.Publish(amqp091.Publishing{
+				Type: split.JobType,
+				Body: jsonBytes,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// one for forwarding, one for the split, one for saving the stems
+			Eventually(func() int {
+				return rabbitMQ.AckCounter
+			}).Should(Equal(3))
+
+			Eventually(func() bool {
+				tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
+				if err != nil {
+					return false
+				}
+
+				track, err := tracklist.GetTrack(trackID)
+				if err != nil {
+					return false
+				}
+
+				_, ok := track.(*trackentity.StemTrack)
+				return ok
+			}).Should(BeTrue())
+		})
+	})
+
+	Describe("Worker is set up without any concurrency", func() {
+		It("refuses to build the worker", func() {
+			_, err := worker.NewQueueWorker(rabbitMQ, "test-queue", job_router.JobRouter{}, worker.RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Second,
+				MaxDelay:    time.Second,
+			}, worker.ConcurrencyLimits{SplitJobs: 0, LightJobs: 2})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Worker shuts down", func() {
+		var (
+			stopped     chan struct{}
+			startWorker func()
+		)
+
+		BeforeEach(func() {
+			stopped = make(chan struct{})
+			startWorker = func() {
+				go func() {
+					defer close(stopped)
+					err := queueWorker.Start()
+					Expect(err).NotTo(HaveOccurred())
+				}()
+
+				jsonBytes, err := json.Marshal(start.JobParams{
+					TrackIdentifier: job_message.TrackIdentifier{
+						TrackListID: tracklistID,
+						TrackID:     trackID,
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = rabbitMQ.Publish(amqp091.Publishing{
+					Type: start.JobType,
+					Body: jsonBytes,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		Describe("With no jobs running", func() {
+			It("stops right away", func() {
+				startWorker()
+
+				Eventually(func() int {
+					return rabbitMQ.AckCounter
+				}).Should(Equal(4))
+
+				queueWorker.Shutdown(time.Minute)
+				Expect(stopped).To(BeClosed())
+			})
+		})
+
+		Describe("With a split running past the timeout", func() {
+			BeforeEach(func() {
+				spleeterExecutor.Hang = true
+			})
+
+			It("cancels the split and requeues it", func() {
+				startWorker()
+
+				// start and transfer are done, the split is running
+				Eventually(func() int {
+					return rabbitMQ.AckCounter
+				}).Should(Equal(2))
+
+				queueWorker.Shutdown(100 * time.Millisecond)
+				Expect(stopped).To(BeClosed())
+
+				Expect(rabbitMQ.SplitChannel).To(HaveLen(1))
+				requeued := <-rabbitMQ.SplitChannel
+				Expect(requeued.Type).To(Equal(split.JobType))
+				Expect(worker.InterruptedCount(requeued.Headers)).To(Equal(1))
+				Expect(worker.RetryCount(requeued.Headers)).To(Equal(0))
+
+				Expect(rabbitMQ.AckCounter).To(Equal(3))
+				Expect(rabbitMQ.DeadLetters).To(BeEmpty())
+			})
+
+			It("doesn't mark the track as errored", func() {
+				startWorker()
+
+				Eventually(func() int {
+					return rabbitMQ.AckCounter
+				}).Should(Equal(2))
+
+				queueWorker.Shutdown(100 * time.Millisecond)
+
+				tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
+				Expect(err).NotTo(HaveOccurred())
+
+				track, err := tracklist.GetTrack(trackID)
+				Expect(err).NotTo(HaveOccurred())
+
+				splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+				Expect(ok).To(BeTrue())
+				Expect(splitRequest.Status).To(Equal(trackentity.ProcessingStatus))
+			})
+
+			It("cleans up the split's temp dirs", func() {
+				startWorker()
+
+				Eventually(func() int {
+					return rabbitMQ.AckCounter
+				}).Should(Equal(2))
+
+				queueWorker.Shutdown(100 * time.Millisecond)
+
+				tempDirEntries, err := os.ReadDir(filepath.Join(workingDir, "tmp"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tempDirEntries).To(BeEmpty())
+			})
+		})
+	})
+
 	Describe("File storage is down", func() {
 		BeforeEach(func() {
 			fileStore.Unavailable = true
 		})
 
-		It("gets 1 ack for the start job", func() {
+		It("gets 1 ack for the start job and 1 for each retry of the transfer job", func() {
 			run()
 
 			Eventually(func() int {
 				return rabbitMQ.AckCounter
-			}).Should(Equal(1))
+			}).Should(Equal(3))
 		})
 
-		It("gets 1 nack for the transfer/download job failing", func() {
+		It("gets 1 nack for the transfer/download job running out of attempts", func() {
 			run()
 
 			Eventually(func() int {
@@ -237,6 +429,20 @@ var _ = Describe("IntegrationTest", func() {
 			}).Should(Equal(1))
 		})
 
+		It("moves the transfer job to the dead letter queue", func() {
+			run()
+
+			Eventually(func() int {
+				return len(rabbitMQ.DeadLetters)
+			}).Should(Equal(1))
+
+			deadLetter := rabbitMQ.DeadLetters[0]
+			Expect(deadLetter.Type).To(Equal(transfer.JobType))
+			Expect(deadLetter.Headers["x-retry-count"]).To(BeEquivalentTo(2))
+			Expect(deadLetter.Headers["x-last-error"]).NotTo(BeEmpty())
+			Expect(worker.ErrorHistory(deadLetter.Headers)).To(HaveLen(3))
+		})
+
 		It("reports the error status", func() {
 			run()
 
@@ -268,4 +474,51 @@ var _ = Describe("IntegrationTest", func() {
 			}).Should(BeTrue())
 		})
 	})
+
+	Describe("Track is not a split request", func() {
+		BeforeEach(func() {
+			tracklist := trackentity.TrackList{}
+			tracklist.Defined.SongID = tracklistID
+			tracklist.Defined.Tracks = trackentity.Tracks{
+				&trackentity.StemTrack{
+					TrackFields: trackentity.TrackFields{ID: trackID},
+					TrackType:   trackentity.FourStemsType,
+				},
+			}
+
+			err := trackStore.SetTrackList(context.Background(), tracklist)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("gives up on the start job without retrying", func() {
+			run()
+
+			Eventually(func() int {
+				return rabbitMQ.NackCounter
+			}).Should(Equal(1))
+
+			Consistently(func() int {
+				return rabbitMQ.AckCounter
+			}).Should(Equal(0))
+
+			Expect(rabbitMQ.DeadLetters).To(HaveLen(1))
+			Expect(rabbitMQ.DeadLetters[0].Headers).NotTo(HaveKey("x-retry-count"))
+		})
+
+		Describe("When the dead letter queue is down", func() {
+			BeforeEach(func() {
+				rabbitMQ.DeadLetterUnavailable = true
+			})
+
+			It("puts the start job back on the queue instead of dropping it", func() {
+				run()
+
+				Eventually(func() int {
+					return rabbitMQ.RequeueCounter
+				}).Should(Equal(1))
+
+				Expect(rabbitMQ.DeadLetters).To(BeEmpty())
+			})
+		})
+	})
 })
@@ -0,0 +1,30 @@
+package job_message
+
+import (
+	"context"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+// GetSplitRequest looks up the split request that the job is for, as it is in the DB right now
+func GetSplitRequest(ctx context.Context, trackStore trackentity.Store, trackParams TrackIdentifier) (*trackentity.SplitRequestTrack, error) {
+	errctx := cerr.Field("tracklist_id", trackParams.TrackListID).
+		Field("track_id", trackParams.TrackID)
+
+	tracklist, err := trackStore.GetTrackList(ctx, trackParams.TrackListID)
+	if err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to get tracklist")
+	}
+
+	track, err := tracklist.GetTrack(trackParams.TrackID)
+	if err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to find track in tracklist")
+	}
+
+	splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+	if !ok {
+		return nil, errctx.Error("Track from DB is not a split stem track")
+	}
+
+	return splitRequest, nil
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_router"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db/save_stems_to_dbfakes"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
@@ -21,6 +24,7 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer/transferfakes"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"time"
 )
 
 var _ = Describe("JobRouter", func() {
@@ -33,8 +37,11 @@ var _ = Describe("JobRouter", func() {
 		splitHandler     *splitfakes.FakeSplitJobHandler
 		saveStemsHandler *save_stems_to_dbfakes.FakeSaveStemsJobHandler
 
-		trackStore *dummy.TrackStore
-		rabbitMQ   *dummy.RabbitMQ
+		trackStore  *dummy.TrackStore
+		jobRuns     *dummy.JobRunStore
+		rabbitMQ    *dummy.RabbitMQ
+		trackEvents <-chan trackentity.TrackEvent
+		unsubscribe func()
 
 		jobRouter job_router.JobRouter
 
@@ -46,10 +53,30 @@ var _ = Describe("JobRouter", func() {
 			Describe("When job fails", func() {
 				BeforeEach(failureSetup)
 
-				It("updates the track to error status", func() {
+				It("leaves the track status for the worker to decide on", func() {
 					Expect(message).NotTo(BeZero())
 
-					_ = jobRouter.HandleMessage(message)
+					_ = jobRouter.HandleMessage(context.Background(), message)
+
+					tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
+					Expect(err).NotTo(HaveOccurred())
+
+					track, err := tracklist.GetTrack(trackID)
+					Expect(err).NotTo(HaveOccurred())
+
+					stemTrack, ok := track.(*trackentity.SplitRequestTrack)
+					Expect(ok).To(BeTrue())
+
+					Expect(stemTrack.Status).NotTo(Equal(trackentity.ErrorStatus))
+					Expect(trackEvents).To(BeEmpty())
+				})
+
+				It("updates the track to error status once the job has failed for good", func() {
+					Expect(message).NotTo(BeZero())
+
+					jobErr := jobRouter.HandleMessage(context.Background(), message)
+					err := jobRouter.HandleFailedJob(message, jobErr)
+					Expect(err).NotTo(HaveOccurred())
 
 					tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
 					Expect(err).NotTo(HaveOccurred())
@@ -64,19 +91,42 @@ var _ = Describe("JobRouter", func() {
 				})
 
 				It("returns an error", func() {
-					err := jobRouter.HandleMessage(message)
+					err := jobRouter.HandleMessage(context.Background(), message)
 					Expect(err).To(HaveOccurred())
 				})
 
 				It("doesn't publish any new jobs", func() {
 					Expect(rabbitMQ.MessageChannel).To(BeEmpty())
 				})
+
+				It("publishes an error event once the job has failed for good", func() {
+					jobErr := jobRouter.HandleMessage(context.Background(), message)
+					_ = jobRouter.HandleFailedJob(message, jobErr)
+					Expect(trackEvents).To(HaveLen(1))
+
+					event := <-trackEvents
+					Expect(event.Type).To(Equal(trackentity.ErrorEventType))
+					Expect(event.TrackID).To(Equal(trackID))
+					Expect(event.Status).To(Equal(trackentity.ErrorStatus))
+				})
 			})
 		}
 
+		getSplitRequestTrack = func() *trackentity.SplitRequestTrack {
+			tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
+			Expect(err).NotTo(HaveOccurred())
+
+			track, err := tracklist.GetTrack(trackID)
+			Expect(err).NotTo(HaveOccurred())
+
+			splitRequestTrack, ok := track.(*trackentity.SplitRequestTrack)
+			Expect(ok).To(BeTrue())
+			return splitRequestTrack
+		}
+
 		ItUpdatesProgress = func() {
 			It("updates the progress", func() {
-				_ = jobRouter.HandleMessage(message)
+				_ = jobRouter.HandleMessage(context.Background(), message)
 
 				tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
 				Expect(err).NotTo(HaveOccurred())
@@ -89,6 +139,17 @@ var _ = Describe("JobRouter", func() {
 
 				Expect(stemTrack.Progress).To(BeNumerically(">", 0))
 			})
+
+			It("publishes a progress event", func() {
+				_ = jobRouter.HandleMessage(context.Background(), message)
+				Expect(trackEvents).To(HaveLen(1))
+
+				event := <-trackEvents
+				Expect(event.Type).To(Equal(trackentity.ProgressEventType))
+				Expect(event.TrackID).To(Equal(trackID))
+				Expect(event.Progress).To(BeNumerically(">", 0))
+				Expect(event.StatusMessage).NotTo(BeEmpty())
+			})
 		}
 	)
 
@@ -111,9 +172,17 @@ var _ = Describe("JobRouter", func() {
 			saveStemsHandler = &save_stems_to_dbfakes.FakeSaveStemsJobHandler{}
 
 			trackStore = dummy.NewDummyTrackStore()
+			jobRuns = dummy.NewDummyJobRunStore()
 			rabbitMQ = dummy.NewRabbitMQ()
-
-			jobRouter = job_router.NewJobRouter(trackStore, rabbitMQ, startHandler, transferHandler, splitHandler, saveStemsHandler)
+			eventBus := trackevents.NewBus()
+			trackEvents, unsubscribe = eventBus.Subscribe(tracklistID)
+
+			jobRouter = job_router.NewJobRouter(trackStore, jobRuns, rabbitMQ, eventBus, pipeline.NewSplitPipeline(pipeline.SplitHandlers{
+				Start:     startHandler,
+				Transfer:  transferHandler,
+				Split:     splitHandler,
+				SaveStems: saveStemsHandler,
+			}))
 		})
 
 		By("Setting up the track store", func() {
@@ -136,6 +205,10 @@ var _ = Describe("JobRouter", func() {
 		})
 	})
 
+	AfterEach(func() {
+		unsubscribe()
+	})
+
 	Describe("Start job", func() {
 		BeforeEach(func() {
 			message = amqp091.Delivery{
@@ -155,12 +228,12 @@ var _ = Describe("JobRouter", func() {
 			})
 
 			It("doesn't return an error", func() {
-				err := jobRouter.HandleMessage(message)
+				err := jobRouter.HandleMessage(context.Background(), message)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
 			It("publishes the next job", func() {
-				_ = jobRouter.HandleMessage(message)
+				_ = jobRouter.HandleMessage(context.Background(), message)
 				Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
 
 				nextJob := <-rabbitMQ.MessageChannel
@@ -203,12 +276,12 @@ var _ = Describe("JobRouter", func() {
 			})
 
 			It("doesn't return an error", func() {
-				err := jobRouter.HandleMessage(message)
+				err := jobRouter.HandleMessage(context.Background(), message)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
 			It("publishes the next job", func() {
-				_ = jobRouter.HandleMessage(message)
+				_ = jobRouter.HandleMessage(context.Background(), message)
 				Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
 
 				nextJob := <-rabbitMQ.MessageChannel
@@ -222,6 +295,14 @@ var _ = Describe("JobRouter", func() {
 				Expect(splitJob.SavedOriginalURL).To(Equal(savedOriginalURL))
 			})
 
+			It("records the saved original on the track", func() {
+				_ = jobRouter.HandleMessage(context.Background(), message)
+
+				splitRequestTrack := getSplitRequestTrack()
+				Expect(splitRequestTrack.CompletedStage).To(Equal(trackentity.TransferStage))
+				Expect(splitRequestTrack.SavedOriginalURL).To(Equal(savedOriginalURL))
+			})
+
 			ItUpdatesProgress()
 		})
 
@@ -259,12 +340,12 @@ var _ = Describe("JobRouter", func() {
 			})
 
 			It("doesn't return an error", func() {
-				err := jobRouter.HandleMessage(message)
+				err := jobRouter.HandleMessage(context.Background(), message)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
 			It("publishes the next job", func() {
-				_ = jobRouter.HandleMessage(message)
+				_ = jobRouter.HandleMessage(context.Background(), message)
 				Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
 
 				nextJob := <-rabbitMQ.MessageChannel
@@ -278,6 +359,15 @@ var _ = Describe("JobRouter", func() {
 				Expect(saveStemsJob.StemURLS).To(Equal(stemURLs))
 			})
 
+			It("records the split stems on the track", func() {
+				_ = jobRouter.HandleMessage(context.Background(), message)
+
+				splitRequestTrack := getSplitRequestTrack()
+				Expect(splitRequestTrack.CompletedStage).To(Equal(trackentity.SplitStage))
+				Expect(splitRequestTrack.SplitStemURLs).To(Equal(map[string]string(stemURLs)))
+				Expect(splitRequestTrack.Status).To(Equal(trackentity.ProcessingStatus))
+			})
+
 			ItUpdatesProgress()
 		})
 
@@ -300,15 +390,24 @@ var _ = Describe("JobRouter", func() {
 			})
 
 			It("doesn't return an error", func() {
-				err := jobRouter.HandleMessage(message)
+				err := jobRouter.HandleMessage(context.Background(), message)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
 			It("doesn't publishes the next job", func() {
-				_ = jobRouter.HandleMessage(message)
+				_ = jobRouter.HandleMessage(context.Background(), message)
 				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
 			})
 
+			It("publishes a completed event", func() {
+				_ = jobRouter.HandleMessage(context.Background(), message)
+				Expect(trackEvents).To(HaveLen(1))
+
+				event := <-trackEvents
+				Expect(event.Type).To(Equal(trackentity.CompletedEventType))
+				Expect(event.TrackID).To(Equal(trackID))
+			})
+
 			It("doesn't update progress", func() {
 				tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
 				Expect(err).NotTo(HaveOccurred())
@@ -330,4 +429,457 @@ var _ = Describe("JobRouter", func() {
 			saveStemsHandler.HandleSaveStemsToDBJobReturns(cerr.Error("i failed"))
 		})
 	})
+
+	Describe("Pipeline with a stage of its own", func() {
+		var (
+			normalizeCalls int
+			err            error
+		)
+
+		BeforeEach(func() {
+			normalizeCalls = 0
+
+			normalizeStage := pipeline.Stage{
+				JobType:        "normalize",
+				Next:           "waveform",
+				ProgressWeight: 1,
+				ErrorMessage:   "Failed to normalize the track",
+				JobParams: func(trackParams job_message.TrackIdentifier, _ trackentity.SplitRequestTrack) any {
+					return trackParams
+				},
+				Handler: pipeline.HandlerFunc(func(_ context.Context, _ []byte) (pipeline.Result, error) {
+					normalizeCalls++
+					return pipeline.Result{
+						Record: func(track *trackentity.SplitRequestTrack) {
+							track.SavedOriginalURL = "normalized.mp3"
+						},
+					}, nil
+				}),
+			}
+
+			waveformStage := pipeline.Stage{
+				JobType:        "waveform",
+				ProgressWeight: 1,
+				StatusMessage:  "Drawing the waveform",
+				JobParams: func(trackParams job_message.TrackIdentifier, track trackentity.SplitRequestTrack) any {
+					return split.JobParams{
+						TrackIdentifier:  trackParams,
+						SavedOriginalURL: track.SavedOriginalURL,
+					}
+				},
+			}
+
+			customPipeline, pipelineErr := pipeline.New(normalizeStage, waveformStage)
+			Expect(pipelineErr).NotTo(HaveOccurred())
+
+			jobRouter = job_router.NewJobRouter(trackStore, jobRuns, rabbitMQ, trackevents.NewBus(), customPipeline)
+
+			message = amqp091.Delivery{
+				Type: "normalize",
+				Body: messageJson,
+			}
+
+			err = jobRouter.HandleMessage(context.Background(), message)
+		})
+
+		It("runs the stage's handler", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(normalizeCalls).To(Equal(1))
+		})
+
+		It("moves the track on to the next stage", func() {
+			splitRequestTrack := getSplitRequestTrack()
+			Expect(splitRequestTrack.CompletedStage).To(BeEquivalentTo("normalize"))
+			Expect(splitRequestTrack.StatusMessage).To(Equal("Drawing the waveform"))
+			Expect(splitRequestTrack.Progress).To(Equal(50))
+		})
+
+		It("queues up the next stage with what the stage recorded", func() {
+			Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
+
+			nextJob := <-rabbitMQ.MessageChannel
+			Expect(nextJob.Type).To(Equal("waveform"))
+
+			var jobParams split.JobParams
+			Expect(json.Unmarshal(nextJob.Body, &jobParams)).To(Succeed())
+			Expect(jobParams.TrackID).To(Equal(trackID))
+			Expect(jobParams.SavedOriginalURL).To(Equal("normalized.mp3"))
+		})
+
+		It("uses the stage's error message once the job has failed for good", func() {
+			Expect(jobRouter.HandleFailedJob(message, cerr.Error("i failed"))).To(Succeed())
+			Expect(getSplitRequestTrack().StatusMessage).To(Equal("Failed to normalize the track"))
+		})
+	})
+
+	Describe("Job run history", func() {
+		var (
+			stemURLs splitter.StemFilePaths
+
+			getJobRuns = func() []trackentity.JobRun {
+				runs, err := jobRuns.GetJobRuns(context.Background(), tracklistID, trackID)
+				Expect(err).NotTo(HaveOccurred())
+				return runs
+			}
+		)
+
+		BeforeEach(func() {
+			stemURLs = map[string]string{
+				"vocals":        "vocals.mp3",
+				"accompaniment": "accompaniment.mp3",
+			}
+
+			message = amqp091.Delivery{
+				MessageId: "job-id",
+				Headers:   amqp091.Table{rabbitmq.AttemptHeader: int32(2)},
+				Type:      split.JobType,
+				Body:      messageJson,
+			}
+		})
+
+		Describe("When job succeeds", func() {
+			BeforeEach(func() {
+				splitHandler.HandleSplitJobReturns(split.JobParams{
+					TrackIdentifier: job_message.TrackIdentifier{
+						TrackListID: tracklistID,
+						TrackID:     trackID,
+					},
+				}, stemURLs, nil)
+			})
+
+			It("records the run with what it produced", func() {
+				Expect(jobRouter.HandleMessage(context.Background(), message)).To(Succeed())
+
+				runs := getJobRuns()
+				Expect(runs).To(HaveLen(1))
+
+				run := runs[0]
+				Expect(run.JobID).To(Equal("job-id"))
+				Expect(run.JobType).To(Equal(split.JobType))
+				Expect(run.Attempt).To(Equal(2))
+				Expect(run.WorkerHost).NotTo(BeEmpty())
+				Expect(run.OutputURLs).To(Equal(stemURLs))
+				Expect(run.Error).To(BeEmpty())
+				Expect(run.EndedAt).NotTo(BeNil())
+				Expect(*run.EndedAt).NotTo(BeTemporally("<", run.StartedAt))
+			})
+
+			Describe("When the run history can't be saved", func() {
+				BeforeEach(func() {
+					jobRuns.Unavailable = true
+				})
+
+				It("still runs the job", func() {
+					Expect(jobRouter.HandleMessage(context.Background(), message)).To(Succeed())
+					Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
+				})
+			})
+		})
+
+		Describe("When job fails", func() {
+			BeforeEach(func() {
+				splitHandler.HandleSplitJobReturns(split.JobParams{}, nil, cerr.Error("i failed"))
+			})
+
+			It("records the run with the error", func() {
+				Expect(jobRouter.HandleMessage(context.Background(), message)).NotTo(Succeed())
+
+				runs := getJobRuns()
+				Expect(runs).To(HaveLen(1))
+				Expect(runs[0].Error).To(ContainSubstring("i failed"))
+				Expect(runs[0].EndedAt).NotTo(BeNil())
+			})
+		})
+
+		It("adds a run every time the job is run", func() {
+			splitHandler.HandleSplitJobReturns(split.JobParams{}, nil, cerr.Error("i failed"))
+			_ = jobRouter.HandleMessage(context.Background(), message)
+			_ = jobRouter.HandleMessage(context.Background(), message)
+
+			Expect(getJobRuns()).To(HaveLen(2))
+		})
+	})
+
+	Describe("Cancelled track", func() {
+		var (
+			cancelTrack = func() {
+				err := trackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitStemTrack := track.(*trackentity.SplitRequestTrack)
+					splitStemTrack.Status = trackentity.CancelledStatus
+					return splitStemTrack, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			expectTrackCancelled = func() {
+				tracklist, err := trackStore.GetTrackList(context.Background(), tracklistID)
+				Expect(err).NotTo(HaveOccurred())
+
+				track, err := tracklist.GetTrack(trackID)
+				Expect(err).NotTo(HaveOccurred())
+
+				stemTrack, ok := track.(*trackentity.SplitRequestTrack)
+				Expect(ok).To(BeTrue())
+				Expect(stemTrack.Status).To(Equal(trackentity.CancelledStatus))
+			}
+
+			expectCancelledEvent = func() {
+				Expect(trackEvents).To(HaveLen(1))
+
+				event := <-trackEvents
+				Expect(event.Type).To(Equal(trackentity.CancelledEventType))
+				Expect(event.TrackID).To(Equal(trackID))
+				Expect(event.Status).To(Equal(trackentity.CancelledStatus))
+			}
+		)
+
+		BeforeEach(func() {
+			message = amqp091.Delivery{
+				Type: split.JobType,
+				Body: messageJson,
+			}
+		})
+
+		Describe("When the track was cancelled before the job", func() {
+			var err error
+
+			BeforeEach(func() {
+				cancelTrack()
+				err = jobRouter.HandleMessage(context.Background(), message)
+			})
+
+			It("doesn't return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("doesn't run the job", func() {
+				Expect(splitHandler.HandleSplitJobCallCount()).To(BeZero())
+			})
+
+			It("doesn't publish any new jobs", func() {
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+			})
+
+			It("leaves the track cancelled", expectTrackCancelled)
+
+			It("publishes a cancelled event", expectCancelledEvent)
+		})
+
+		Describe("When the track is cancelled while the job is running", func() {
+			var err error
+
+			BeforeEach(func() {
+				splitHandler.HandleSplitJobCalls(func(ctx context.Context, _ []byte) (split.JobParams, splitter.StemFilePaths, error) {
+					cancelTrack()
+
+					select {
+					case <-ctx.Done():
+						return split.JobParams{}, nil, cerr.Wrap(ctx.Err()).Error("Split was stopped")
+					case <-time.After(3 * time.Second):
+						return split.JobParams{}, nil, cerr.Error("Split was never stopped")
+					}
+				})
+
+				err = jobRouter.HandleMessage(context.Background(), message)
+			})
+
+			It("stops the job without returning an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("doesn't publish any new jobs", func() {
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+			})
+
+			It("doesn't overwrite the cancelled status", expectTrackCancelled)
+
+			It("publishes a cancelled event", expectCancelledEvent)
+		})
+	})
+
+	Describe("Job redelivered after the stems were saved", func() {
+		var err error
+
+		BeforeEach(func() {
+			tracklist := trackentity.TrackList{}
+			tracklist.Defined.SongID = tracklistID
+			tracklist.Defined.Tracks = trackentity.Tracks{
+				&trackentity.StemTrack{
+					TrackFields: trackentity.TrackFields{ID: trackID},
+					TrackType:   trackentity.FourStemsType,
+				},
+			}
+			Expect(trackStore.SetTrackList(context.Background(), tracklist)).To(Succeed())
+
+			message = amqp091.Delivery{
+				Type: split.JobType,
+				Body: messageJson,
+			}
+
+			err = jobRouter.HandleMessage(context.Background(), message)
+		})
+
+		It("doesn't return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("doesn't run the job", func() {
+			Expect(splitHandler.HandleSplitJobCallCount()).To(BeZero())
+		})
+
+		It("doesn't publish any new jobs", func() {
+			Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+		})
+	})
+
+	Describe("Start job redelivered after the split has finished", func() {
+		var err error
+
+		BeforeEach(func() {
+			err = trackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+				splitRequestTrack := track.(*trackentity.SplitRequestTrack)
+				splitRequestTrack.Status = trackentity.ProcessingStatus
+				splitRequestTrack.Progress = trackentity.SplitStage.ProgressAfter()
+				splitRequestTrack.CompletedStage = trackentity.SplitStage
+				splitRequestTrack.SavedOriginalURL = "original.mp3"
+				splitRequestTrack.SplitStemURLs = map[string]string{"vocals": "vocals.mp3"}
+				return splitRequestTrack, nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			message = amqp091.Delivery{
+				Type: start.JobType,
+				Body: messageJson,
+			}
+
+			err = jobRouter.HandleMessage(context.Background(), message)
+		})
+
+		It("doesn't return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("doesn't run the job", func() {
+			Expect(startHandler.HandleStartJobCallCount()).To(BeZero())
+		})
+
+		It("doesn't publish any new jobs", func() {
+			Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+		})
+
+		It("leaves the track where it got to", func() {
+			splitRequestTrack := getSplitRequestTrack()
+			Expect(splitRequestTrack.CompletedStage).To(Equal(trackentity.SplitStage))
+			Expect(splitRequestTrack.Progress).To(Equal(trackentity.SplitStage.ProgressAfter()))
+		})
+	})
+
+	Describe("Job whose copy finishes the stage first", func() {
+		var err error
+
+		BeforeEach(func() {
+			transferHandler.HandleTransferJobCalls(func(_ []byte) (transfer.JobParams, string, error) {
+				err := trackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitRequestTrack := track.(*trackentity.SplitRequestTrack)
+					splitRequestTrack.CompletedStage = trackentity.SplitStage
+					return splitRequestTrack, nil
+				})
+				return transfer.JobParams{}, "original.mp3", err
+			})
+
+			message = amqp091.Delivery{
+				Type: transfer.JobType,
+				Body: messageJson,
+			}
+
+			err = jobRouter.HandleMessage(context.Background(), message)
+		})
+
+		It("doesn't return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("doesn't queue up the next stage again", func() {
+			Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+		})
+
+		It("doesn't send the track back a stage", func() {
+			Expect(getSplitRequestTrack().CompletedStage).To(Equal(trackentity.SplitStage))
+		})
+	})
+
+	Describe("Job redelivered after its worker went away before sending the next job", func() {
+		var err error
+
+		BeforeEach(func() {
+			transferHandler.HandleTransferJobReturns(transfer.JobParams{}, "original.mp3", nil)
+
+			message = amqp091.Delivery{
+				Type: transfer.JobType,
+				Body: messageJson,
+			}
+
+			By("Going away between recording the progress and sending the next job", func() {
+				crashingRouter := job_router.NewJobRouter(trackStore, jobRuns, failingPublisher{}, trackevents.NewBus(), pipeline.NewSplitPipeline(pipeline.SplitHandlers{
+					Start:     startHandler,
+					Transfer:  transferHandler,
+					Split:     splitHandler,
+					SaveStems: saveStemsHandler,
+				}))
+
+				Expect(crashingRouter.HandleMessage(context.Background(), message)).NotTo(Succeed())
+				Expect(getSplitRequestTrack().CompletedStage).To(Equal(trackentity.TransferStage))
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+			})
+
+			err = jobRouter.HandleMessage(context.Background(), message)
+		})
+
+		It("doesn't return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("doesn't run the job again", func() {
+			Expect(transferHandler.HandleTransferJobCallCount()).To(Equal(1))
+		})
+
+		It("sends the next job", func() {
+			Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
+
+			nextJob := <-rabbitMQ.MessageChannel
+			Expect(nextJob.Type).To(Equal(split.JobType))
+
+			var splitJob split.JobParams
+			Expect(json.Unmarshal(nextJob.Body, &splitJob)).To(Succeed())
+			Expect(splitJob.TrackID).To(Equal(trackID))
+			Expect(splitJob.SavedOriginalURL).To(Equal("original.mp3"))
+		})
+
+		Describe("Delivered again once the next stage has been picked up", func() {
+			BeforeEach(func() {
+				<-rabbitMQ.MessageChannel
+				err = trackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitRequestTrack := track.(*trackentity.SplitRequestTrack)
+					now := time.Now()
+					splitRequestTrack.StageStartedAt = &now
+					return splitRequestTrack, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = jobRouter.HandleMessage(context.Background(), message)
+			})
+
+			It("drops the job", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+				Expect(transferHandler.HandleTransferJobCallCount()).To(Equal(1))
+			})
+		})
+	})
 })
+
+type failingPublisher struct{}
+
+func (f failingPublisher) Publish(_ amqp091.Publishing) error {
+	return cerr.Error("The worker went away")
+}
@@ -0,0 +1,62 @@
+package job_router
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"os"
+	"time"
+)
+
+func workerHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return host
+}
+
+// startJobRun adds the job to the track's run history as it starts. The history is
+// only there to look into afterwards, so failing to write it doesn't stop the job.
+// The split request is nil when it couldn't be found, which the job will report
+func (j JobRouter) startJobRun(message amqp091.Delivery, trackParams job_message.TrackIdentifier, splitRequest *trackentity.SplitRequestTrack) *trackentity.JobRun {
+	run := trackentity.JobRun{
+		ID:          uuid.New().String(),
+		TrackListID: trackParams.TrackListID,
+		TrackID:     trackParams.TrackID,
+		JobID:       message.MessageId,
+		JobType:     message.Type,
+		Attempt:     rabbitmq.Attempt(message.Headers),
+		WorkerHost:  j.workerHost,
+		StartedAt:   time.Now(),
+	}
+
+	if splitRequest != nil {
+		run.Engine = splitRequest.EngineType
+		if stage, ok := j.pipeline.Stage(message.Type); ok && stage.InputURL != nil {
+			run.InputURL = stage.InputURL(*splitRequest)
+		}
+	}
+
+	j.saveJobRun(run)
+	return &run
+}
+
+func (j JobRouter) endJobRun(run trackentity.JobRun, outputURLs map[string]string, runErr error) {
+	run.End(time.Now(), outputURLs, runErr)
+	j.saveJobRun(run)
+}
+
+func (j JobRouter) saveJobRun(run trackentity.JobRun) {
+	if err := j.jobRuns.SaveJobRun(context.Background(), run); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"job_type": run.JobType,
+			"track_id": run.TrackID,
+		}).Error("Failed to save the job run")
+	}
+}
@@ -3,252 +3,429 @@ package job_router
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"github.com/apex/log"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"time"
 )
 
+// how often a running job looks at its track to see if it's been cancelled. Each look reads the
+// whole track list, for every running job, so it's kept slow. Cancelling doesn't wait on it,
+// the track shows as cancelled right away and the job's results are thrown out when it finishes
+const cancellationPollInterval = 30 * time.Second
+
+// shown on the track when a job fails that isn't part of the pipeline
+const defaultErrorMessage = "Failed to process the track"
+
+var errTrackCancelled = errors.New("The track has been cancelled")
+var errStageAlreadyCompleted = errors.New("The stage has already been completed")
+var errNextStageNotStarted = errors.New("The stage has been completed, but the next one hasn't been picked up")
+
 func NewJobRouter(
 	trackStore trackentity.Store,
+	jobRuns trackentity.JobRunStore,
 	publisher rabbitmq.Publisher,
-	startHandler start.StartJobHandler,
-	transferHandler transfer.TransferJobHandler,
-	splitHandler split.SplitJobHandler,
-	saveStemsHandler save_stems_to_db.SaveStemsJobHandler,
+	eventPublisher trackentity.EventPublisher,
+	jobPipeline pipeline.Pipeline,
 ) JobRouter {
 	return JobRouter{
-		trackStore:       trackStore,
-		publisher:        publisher,
-		startHandler:     startHandler,
-		transferHandler:  transferHandler,
-		splitHandler:     splitHandler,
-		saveStemsHandler: saveStemsHandler,
+		trackStore:     trackStore,
+		jobRuns:        jobRuns,
+		workerHost:     workerHost(),
+		publisher:      publisher,
+		eventPublisher: eventPublisher,
+		pipeline:       jobPipeline,
 	}
 }
 
 type JobRouter struct {
-	publisher  rabbitmq.Publisher
-	trackStore trackentity.Store
+	publisher      rabbitmq.Publisher
+	eventPublisher trackentity.EventPublisher
+	trackStore     trackentity.Store
+	jobRuns        trackentity.JobRunStore
+	workerHost     string
 
-	startHandler     start.StartJobHandler
-	transferHandler  transfer.TransferJobHandler
-	splitHandler     split.SplitJobHandler
-	saveStemsHandler save_stems_to_db.SaveStemsJobHandler
+	pipeline pipeline.Pipeline
 }
 
-func (j JobRouter) HandleMessage(message amqp091.Delivery) error {
-	err := j.handleMessageWithoutErrorHandling(message)
-	if err != nil {
-		j.handleError(message, err)
-		return err
+// HandleMessage runs the job until it's done, or until ctx is cancelled
+func (j JobRouter) HandleMessage(ctx context.Context, message amqp091.Delivery) error {
+	var trackParams job_message.TrackIdentifier
+	// a malformed message is left for the job handlers to report
+	hasTrackParams := json.Unmarshal(message.Body, &trackParams) == nil
+
+	var splitRequest *trackentity.SplitRequestTrack
+	if hasTrackParams {
+		var err error
+		splitRequest, err = j.startStage(ctx, message.Type, trackParams)
+
+		switch {
+		case errors.Is(err, errTrackCancelled):
+			j.dropCancelledJob(message, trackParams)
+			return nil
+
+		// a job delivered again for a stage the split has already gotten through has nothing left to do
+		case errors.Is(err, errStageAlreadyCompleted):
+			log.WithFields(log.Fields{
+				"job_type": message.Type,
+				"job_id":   message.MessageId,
+				"track_id": trackParams.TrackID,
+			}).Info("Dropping job for a stage that has already been completed")
+			return nil
+
+		case errors.Is(err, errNextStageNotStarted):
+			return j.requeueNextStage(message, trackParams, *splitRequest)
+
+		// the job will run into the same problem and report it
+		case err != nil:
+			log.WithError(err).WithField("track_id", trackParams.TrackID).Warn("Failed to mark the stage as started")
+		}
 	}
 
-	return nil
-}
+	ctx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
 
-func (j JobRouter) handleMessageWithoutErrorHandling(message amqp091.Delivery) error {
-	var nextJobMsg amqp091.Publishing
-	var nextJobMessage string
-	var nextJobProgress int
-	wasLastJob := false
-
-	switch message.Type {
-	case start.JobType:
-		startJobParams, err := j.startHandler.HandleStartJob(message.Body)
-		if err != nil {
-			return cerr.Field("message_body", string(message.Body)).Wrap(err).Error("Failed to handle start job")
-		}
+	var run *trackentity.JobRun
+	if hasTrackParams {
+		go j.watchForCancellation(ctx, stopWatching, trackParams)
+		run = j.startJobRun(message, trackParams, splitRequest)
+	}
 
-		nextJobMessage = "Retrieving the original track from provided URL"
-		nextJobProgress = 10
-		nextJobMsg, err = createTransferJobMessage(startJobParams.TrackListID, startJobParams.TrackID)
-		if err != nil {
-			return cerr.Field("tracklist_id", startJobParams.TrackListID).
-				Field("track_id", startJobParams.TrackID).
-				Wrap(err).
-				Error("Failed to create transfer job message")
-		}
+	outputURLs, err := j.handleMessageWithoutErrorHandling(ctx, message)
+	if run != nil {
+		j.endJobRun(*run, outputURLs, err)
+	}
 
-	case transfer.JobType:
-		transferJobParams, savedOriginalURL, err := j.transferHandler.HandleTransferJob(message.Body)
-		if err != nil {
-			return cerr.Field("message_body", string(message.Body)).Wrap(err).Error("Failed to handle transfer job")
+	if err != nil {
+		// the job failing could be a result of the cancellation, e.g. a killed split
+		if hasTrackParams && j.isCancelled(trackParams) {
+			j.dropCancelledJob(message, trackParams)
+			return nil
 		}
 
-		nextJobMessage = "Splitting the track into stems"
-		nextJobProgress = 30
-		nextJobMsg, err = createSplitJobMessage(transferJobParams.TrackListID, transferJobParams.TrackID, savedOriginalURL)
-		if err != nil {
-			return cerr.Field("tracklist_id", transferJobParams.TrackListID).
-				Field("track_id", transferJobParams.TrackID).
-				Field("saved_original_url", savedOriginalURL).
-				Wrap(err).
-				Error("Failed to create split job message")
-		}
+		return cerr.Field("job_id", message.MessageId).
+			Field("attempt", rabbitmq.Attempt(message.Headers)).
+			Wrap(err).Error("Failed to handle job")
+	}
 
-	case split.JobType:
-		splitJobParams, stemURLs, err := j.splitHandler.HandleSplitJob(message.Body)
-		if err != nil {
-			return cerr.Field("message_body", string(message.Body)).Wrap(err).Error("Failed to handle split job")
-		}
+	return nil
+}
 
-		nextJobMessage = "Saving processed stems into database"
-		nextJobProgress = 90
-		nextJobMsg, err = createSaveStemsToDBJobMessage(splitJobParams.TrackListID, splitJobParams.TrackID, stemURLs)
-		if err != nil {
-			return cerr.Field("tracklist_id", splitJobParams.TrackListID).
-				Field("track_id", splitJobParams.TrackID).
-				Field("stem_urls", stemURLs).
-				Wrap(err).
-				Error("Failed to create save stems to DB job message")
-		}
+// handleMessageWithoutErrorHandling runs the job's stage, returning the files it produced
+func (j JobRouter) handleMessageWithoutErrorHandling(ctx context.Context, message amqp091.Delivery) (map[string]string, error) {
+	stage, ok := j.pipeline.Stage(message.Type)
+	if !ok {
+		return nil, cerr.Permanent(cerr.Field("job_type", message.Type).Error("Unrecognized amqp job type"))
+	}
 
-	case save_stems_to_db.JobType:
-		err := j.saveStemsHandler.HandleSaveStemsToDBJob(message.Body)
-		if err != nil {
-			return cerr.Field("message_body", string(message.Body)).Wrap(err).Error("Failed to handle save stems to DB job")
-		}
+	errctx := cerr.Field("job_type", stage.JobType)
 
-		wasLastJob = true
+	result, err := stage.Handler.Handle(ctx, message.Body)
+	if err != nil {
+		return nil, errctx.Field("message_body", string(message.Body)).Wrap(err).Error("Failed to handle job stage")
+	}
 
-	default:
-		return cerr.Field("job_type", message.Type).Error("Unrecognized amqp job type")
+	nextStage, hasNext := j.pipeline.Next(stage)
+	if !hasNext {
+		j.publishCompletedEvent(message)
+		return result.OutputURLs, nil
 	}
 
-	if !wasLastJob {
-		if err := j.updateProgress(message, nextJobMessage, nextJobProgress); err != nil {
-			return cerr.Wrap(err).Error("Failed to publish next job message")
-		}
+	trackParams, updatedTrack, err := j.updateProgress(message, stage, nextStage, result)
+	// a copy of the job got there first, and has queued up the next stage already
+	if errors.Is(err, errStageAlreadyCompleted) {
+		log.WithFields(log.Fields{
+			"job_type": stage.JobType,
+			"track_id": trackParams.TrackID,
+		}).Info("Stage has already been completed, not queueing up the next one again")
+		return result.OutputURLs, nil
+	}
 
-		if err := j.publisher.Publish(nextJobMsg); err != nil {
-			return cerr.Field("next_job", nextJobMsg).
-				Wrap(err).Error("Failed to publish next job message")
-		}
+	if err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to publish next job message")
 	}
 
-	return nil
+	nextJobMsg, err := nextStage.JobMessage(trackParams, updatedTrack)
+	if err != nil {
+		return nil, errctx.Field("next_job_type", nextStage.JobType).
+			Wrap(err).Error("Failed to create next job message")
+	}
+
+	// the stage is recorded as completed by now, the retried job sends the next one without running again
+	if err := j.publisher.Publish(nextJobMsg); err != nil {
+		return nil, errctx.Field("next_job", nextJobMsg).
+			Wrap(err).Error("Failed to publish next job message")
+	}
+
+	return result.OutputURLs, nil
 }
 
-func (j JobRouter) updateProgress(message amqp091.Delivery, statusMessage string, progress int) error {
+// updateProgress records the stage as done on the track, and moves it on to the next stage
+func (j JobRouter) updateProgress(message amqp091.Delivery, stage pipeline.Stage, nextStage pipeline.Stage, result pipeline.Result) (job_message.TrackIdentifier, trackentity.SplitRequestTrack, error) {
 	var trackParams job_message.TrackIdentifier
 	err := json.Unmarshal(message.Body, &trackParams)
 	if err != nil {
-		return cerr.Wrap(err).Error("Failed to unmarshal job message")
+		return job_message.TrackIdentifier{}, trackentity.SplitRequestTrack{}, cerr.Wrap(err).Error("Failed to unmarshal job message")
 	}
 
+	progress := j.pipeline.ProgressAfter(stage)
+
+	var updatedTrack trackentity.SplitRequestTrack
 	updater := func(track trackentity.Track) (trackentity.Track, error) {
 		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
 		if !ok {
 			return nil, cerr.Error("Track from DB is not a split stem track")
 		}
 
-		splitStemTrack.StatusMessage = statusMessage
+		// stops the next job from being queued up
+		if splitStemTrack.Status == trackentity.CancelledStatus {
+			return nil, errTrackCancelled
+		}
+
+		// the track only ever moves forward, a copy of the job that ran
+		// alongside this one must not send it back a stage
+		if j.pipeline.HasCompleted(*splitStemTrack, stage) {
+			return nil, errStageAlreadyCompleted
+		}
+
+		// a retried job can start partway through, so the status
+		// isn't left to the start job to set
+		splitStemTrack.Status = trackentity.ProcessingStatus
+		splitStemTrack.StatusMessage = nextStage.StatusMessage
 		splitStemTrack.Progress = progress
+		splitStemTrack.CompletedStage = trackentity.SplitJobStage(stage.JobType)
+		// the next stage waits in the queue until a worker picks it up
+		splitStemTrack.StageStartedAt = nil
+		if result.Record != nil {
+			result.Record(splitStemTrack)
+		}
 
+		updatedTrack = *splitStemTrack
 		return splitStemTrack, nil
 	}
 
 	err = j.trackStore.UpdateTrack(context.Background(), trackParams.TrackListID, trackParams.TrackID, updater)
 	if err != nil {
-		return cerr.Wrap(err).Error("Failed to update track")
+		return trackParams, trackentity.SplitRequestTrack{}, cerr.Wrap(err).Error("Failed to update track")
 	}
 
-	return nil
+	j.publishEvent(trackentity.TrackEvent{
+		TrackListID:   trackParams.TrackListID,
+		TrackID:       trackParams.TrackID,
+		Type:          trackentity.ProgressEventType,
+		Status:        trackentity.ProcessingStatus,
+		StatusMessage: nextStage.StatusMessage,
+		Progress:      progress,
+	})
+
+	return trackParams, updatedTrack, nil
 }
 
-func (j JobRouter) getErrorMessage(jobType string) string {
-	switch jobType {
-	case start.JobType:
-		return start.ErrorMessage
-	case transfer.JobType:
-		return transfer.ErrorMessage
-	case split.JobType:
-		return split.ErrorMessage
-	case save_stems_to_db.JobType:
-		return save_stems_to_db.ErrorMessage
-	default:
-		panic(fmt.Sprintf("Unhandled message type in error handling, type: %s", jobType))
+// startStage stamps the split request with when a worker picked up its stage, which is where the
+// reaper starts counting from, and returns it as it is now. The job isn't run at all if the track
+// has been cancelled, or has already gotten through the stage
+func (j JobRouter) startStage(ctx context.Context, jobType string, trackParams job_message.TrackIdentifier) (*trackentity.SplitRequestTrack, error) {
+	stage, isPipelineStage := j.pipeline.Stage(jobType)
+
+	var startedTrack trackentity.SplitRequestTrack
+	updater := func(track trackentity.Track) (trackentity.Track, error) {
+		switch track := track.(type) {
+		case *trackentity.StemTrack:
+			// every stage is done once the stems are saved. The first job
+			// is never sent for a finished track though, so that one is left to fail
+			if isPipelineStage && stage.JobType != j.pipeline.First().JobType {
+				return nil, errStageAlreadyCompleted
+			}
+
+		case *trackentity.SplitRequestTrack:
+			if track.Status == trackentity.CancelledStatus {
+				return nil, errTrackCancelled
+			}
+
+			// the worker that completed the stage could have gone away before sending
+			// the next job, in which case the job is delivered again to send it
+			if isPipelineStage && !stage.IsLast() && track.CompletedStage == trackentity.SplitJobStage(stage.JobType) && track.StageStartedAt == nil {
+				startedTrack = *track
+				return nil, errNextStageNotStarted
+			}
+
+			if isPipelineStage && j.pipeline.HasCompleted(*track, stage) {
+				return nil, errStageAlreadyCompleted
+			}
+
+			now := time.Now()
+			track.StageStartedAt = &now
+			startedTrack = *track
+			return track, nil
+		}
+
+		return nil, cerr.Error("Track from DB is not a split stem track")
 	}
+
+	if err := j.trackStore.UpdateTrack(ctx, trackParams.TrackListID, trackParams.TrackID, updater); err != nil {
+		if errors.Is(err, errNextStageNotStarted) {
+			return &startedTrack, err
+		}
+
+		return nil, err
+	}
+
+	return &startedTrack, nil
 }
 
-func (j JobRouter) handleError(message amqp091.Delivery, jobError error) error {
-	var trackParams job_message.TrackIdentifier
-	err := json.Unmarshal(message.Body, &trackParams)
+// requeueNextStage sends the job of the stage after the completed one again, for a job that was
+// delivered again before the next stage was picked up. Should the next job have made it into the
+// queue after all, its copy is dropped once either of them completes the stage
+func (j JobRouter) requeueNextStage(message amqp091.Delivery, trackParams job_message.TrackIdentifier, splitRequest trackentity.SplitRequestTrack) error {
+	errctx := cerr.Field("job_id", message.MessageId).Field("job_type", message.Type)
+
+	nextJobMsg, err := j.pipeline.ResumeJobMessage(trackParams.TrackListID, splitRequest)
 	if err != nil {
-		return cerr.Wrap(err).Error("Failed to report error to track DB")
+		return errctx.Wrap(err).Error("Failed to create next job message")
 	}
 
-	setToErrorStatus := func(track trackentity.Track) (trackentity.Track, error) {
-		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
-		if !ok {
-			return nil, cerr.Error("Track from DB is not a split stem track")
-		}
+	if err := j.publisher.Publish(nextJobMsg); err != nil {
+		return errctx.Field("next_job", nextJobMsg).Wrap(err).Error("Failed to publish next job message")
+	}
 
-		splitStemTrack.Status = trackentity.ErrorStatus
-		splitStemTrack.StatusMessage = j.getErrorMessage(message.Type)
-		splitStemTrack.StatusDebugLog = jobError.Error()
+	log.WithFields(log.Fields{
+		"job_type":      message.Type,
+		"next_job_type": nextJobMsg.Type,
+		"track_id":      trackParams.TrackID,
+	}).Info("Queueing up the next stage again for a job delivered after its stage was completed")
 
-		return splitStemTrack, nil
+	return nil
+}
+
+func (j JobRouter) isCancelled(trackParams job_message.TrackIdentifier) bool {
+	tracklist, err := j.trackStore.GetTrackList(context.Background(), trackParams.TrackListID)
+	if err != nil {
+		// the job will run into the same problem and report it
+		return false
 	}
 
-	err = j.trackStore.UpdateTrack(context.Background(), trackParams.TrackListID, trackParams.TrackID, setToErrorStatus)
+	track, err := tracklist.GetTrack(trackParams.TrackID)
 	if err != nil {
-		return cerr.Wrap(err).Error("Failed to update track as error")
+		return false
 	}
 
-	return nil
+	splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
+	return ok && splitStemTrack.Status == trackentity.CancelledStatus
 }
 
-func createTransferJobMessage(tracklistID string, trackID string) (amqp091.Publishing, error) {
-	job := transfer.JobParams{
-		job_message.TrackIdentifier{
-			TrackListID: tracklistID,
-			TrackID:     trackID,
-		},
+// watchForCancellation cancels the job's context once the track is cancelled,
+// which is what stops a split that's in the middle of running
+func (j JobRouter) watchForCancellation(ctx context.Context, cancel context.CancelFunc, trackParams job_message.TrackIdentifier) {
+	ticker := time.NewTicker(cancellationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if j.isCancelled(trackParams) {
+				log.WithField("track_id", trackParams.TrackID).Info("Track has been cancelled, stopping the job")
+				cancel()
+				return
+			}
+		}
 	}
+}
 
-	return createJobMessage(transfer.JobType, job)
+// dropCancelledJob lets the job go without queueing the next one,
+// the track already has the cancelled status so there's nothing to update
+func (j JobRouter) dropCancelledJob(message amqp091.Delivery, trackParams job_message.TrackIdentifier) {
+	log.WithFields(log.Fields{
+		"job_type":     message.Type,
+		"tracklist_id": trackParams.TrackListID,
+		"track_id":     trackParams.TrackID,
+	}).Info("Dropping job for a cancelled track")
+
+	j.publishEvent(trackentity.TrackEvent{
+		TrackListID: trackParams.TrackListID,
+		TrackID:     trackParams.TrackID,
+		Type:        trackentity.CancelledEventType,
+		Status:      trackentity.CancelledStatus,
+	})
 }
 
-func createSplitJobMessage(tracklistID string, trackID string, savedOriginalURL string) (amqp091.Publishing, error) {
-	job := split.JobParams{
-		TrackIdentifier: job_message.TrackIdentifier{
-			TrackListID: tracklistID,
-			TrackID:     trackID,
-		},
-		SavedOriginalURL: savedOriginalURL,
+func (j JobRouter) publishCompletedEvent(message amqp091.Delivery) {
+	var trackParams job_message.TrackIdentifier
+	if err := json.Unmarshal(message.Body, &trackParams); err != nil {
+		log.WithError(err).Error("Failed to unmarshal job message for the completed event")
+		return
 	}
 
-	return createJobMessage(split.JobType, job)
+	j.publishEvent(trackentity.TrackEvent{
+		TrackListID: trackParams.TrackListID,
+		TrackID:     trackParams.TrackID,
+		Type:        trackentity.CompletedEventType,
+		Progress:    100,
+	})
+}
+
+// events are only a nudge for whoever is watching, the track in the DB
+// is already up to date, so failing to publish doesn't fail the job
+func (j JobRouter) publishEvent(event trackentity.TrackEvent) {
+	if err := j.eventPublisher.PublishTrackEvent(context.Background(), event); err != nil {
+		log.WithError(err).WithField("event", event).Error("Failed to publish track event")
+	}
 }
 
-func createSaveStemsToDBJobMessage(tracklistID string, trackID string, stemURLs map[string]string) (amqp091.Publishing, error) {
-	job := save_stems_to_db.JobParams{
-		TrackIdentifier: job_message.TrackIdentifier{
-			TrackListID: tracklistID,
-			TrackID:     trackID,
-		},
-		StemURLS: stemURLs,
+func (j JobRouter) getErrorMessage(jobType string) string {
+	stage, ok := j.pipeline.Stage(jobType)
+	if !ok || stage.ErrorMessage == "" {
+		return defaultErrorMessage
 	}
 
-	return createJobMessage(save_stems_to_db.JobType, job)
+	return stage.ErrorMessage
 }
 
-func createJobMessage(jobType string, message any) (amqp091.Publishing, error) {
-	jsonBytes, err := json.Marshal(message)
+// HandleFailedJob reports the error on the track, once the job
+// has failed for good and won't be retried anymore
+func (j JobRouter) HandleFailedJob(message amqp091.Delivery, jobError error) error {
+	var trackParams job_message.TrackIdentifier
+	err := json.Unmarshal(message.Body, &trackParams)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to report error to track DB")
+	}
+
+	setToErrorStatus := func(track trackentity.Track) (trackentity.Track, error) {
+		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok {
+			return nil, cerr.Error("Track from DB is not a split stem track")
+		}
+
+		if splitStemTrack.Status == trackentity.CancelledStatus {
+			return splitStemTrack, nil
+		}
+
+		splitStemTrack.Status = trackentity.ErrorStatus
+		splitStemTrack.StatusMessage = j.getErrorMessage(message.Type)
+		splitStemTrack.StatusDebugLog = jobError.Error()
+
+		return splitStemTrack, nil
+	}
+
+	err = j.trackStore.UpdateTrack(context.Background(), trackParams.TrackListID, trackParams.TrackID, setToErrorStatus)
 	if err != nil {
-		return amqp091.Publishing{}, cerr.Wrap(err).Error("Failed to marshal job params")
+		return cerr.Wrap(err).Error("Failed to update track as error")
 	}
 
-	return amqp091.Publishing{
-		Type: jobType,
-		Body: jsonBytes,
-	}, nil
+	j.publishEvent(trackentity.TrackEvent{
+		TrackListID:   trackParams.TrackListID,
+		TrackID:       trackParams.TrackID,
+		Type:          trackentity.ErrorEventType,
+		Status:        trackentity.ErrorStatus,
+		StatusMessage: j.getErrorMessage(message.Type),
+	})
+
+	return nil
 }
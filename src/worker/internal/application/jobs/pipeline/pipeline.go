@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+// Result is what a stage got done, for the router to pass on to the stages after it
+type Result struct {
+	// Record saves the stage's results on the track, which is
+	// where the stages after it and any retries pick them up from
+	Record func(track *trackentity.SplitRequestTrack)
+	// OutputURLs are the files the stage made, kept in the job run history
+	OutputURLs map[string]string
+}
+
+// Handler runs a stage's job from its message
+type Handler interface {
+	Handle(ctx context.Context, message []byte) (Result, error)
+}
+
+type HandlerFunc func(ctx context.Context, message []byte) (Result, error)
+
+func (h HandlerFunc) Handle(ctx context.Context, message []byte) (Result, error) {
+	return h(ctx, message)
+}
+
+// Stage is one step of the job graph, run by a job message of its own
+type Stage struct {
+	JobType string
+	// Next is the job type of the stage that runs after this one, empty for the last stage
+	Next string
+	// ProgressWeight is how much of the track's progress the stage makes up, relative to the other stages
+	ProgressWeight int
+	// StatusMessage is shown on the track while the stage is running
+	StatusMessage string
+	// ErrorMessage is shown on the track when the stage has failed for good
+	ErrorMessage string
+	// JobParams makes the body of the stage's job message, out of what
+	// the stages before it have recorded on the track
+	JobParams func(trackParams job_message.TrackIdentifier, track trackentity.SplitRequestTrack) any
+	// InputURL is the file the stage works on, for the job run history. Optional
+	InputURL func(track trackentity.SplitRequestTrack) string
+	Handler  Handler
+}
+
+func (s Stage) IsLast() bool {
+	return s.Next == ""
+}
+
+// JobMessage makes the message that runs the stage for the track
+func (s Stage) JobMessage(trackParams job_message.TrackIdentifier, track trackentity.SplitRequestTrack) (amqp091.Publishing, error) {
+	jsonBytes, err := json.Marshal(s.JobParams(trackParams, track))
+	if err != nil {
+		return amqp091.Publishing{}, cerr.Field("job_type", s.JobType).Wrap(err).Error("Failed to marshal job params")
+	}
+
+	return rabbitmq.NewJobPublishing(s.JobType, jsonBytes), nil
+}
+
+// Pipeline is the graph of stages that a split request goes through, from the first stage to the last
+type Pipeline struct {
+	first  string
+	stages map[string]Stage
+	// the track's progress once each stage is done
+	progress map[string]int
+	// where each stage comes in the order they run
+	position map[string]int
+}
+
+// New puts the stages together into a pipeline, starting at the first stage given.
+// Every stage has to be reachable from the first one, and none of them can lead back to an earlier one
+func New(stages ...Stage) (Pipeline, error) {
+	if len(stages) == 0 {
+		return Pipeline{}, cerr.Error("A pipeline needs at least one stage")
+	}
+
+	p := Pipeline{
+		first:    stages[0].JobType,
+		stages:   map[string]Stage{},
+		progress: map[string]int{},
+		position: map[string]int{},
+	}
+
+	for _, stage := range stages {
+		errctx := cerr.Field("job_type", stage.JobType)
+
+		if stage.JobType == "" {
+			return Pipeline{}, cerr.Error("A stage is missing its job type")
+		}
+
+		if _, ok := p.stages[stage.JobType]; ok {
+			return Pipeline{}, errctx.Error("The stage is defined more than once")
+		}
+
+		if stage.JobParams == nil {
+			return Pipeline{}, errctx.Error("The stage has no way to make its job params")
+		}
+
+		p.stages[stage.JobType] = stage
+	}
+
+	order, err := p.walk()
+	if err != nil {
+		return Pipeline{}, err
+	}
+
+	if len(order) != len(stages) {
+		return Pipeline{}, cerr.Error("Some stages can't be reached from the first stage")
+	}
+
+	totalWeight := 0
+	for _, stage := range order {
+		totalWeight += stage.ProgressWeight
+	}
+
+	doneWeight := 0
+	for i, stage := range order {
+		p.position[stage.JobType] = i
+		doneWeight += stage.ProgressWeight
+		if totalWeight > 0 {
+			p.progress[stage.JobType] = 100 * doneWeight / totalWeight
+		}
+	}
+
+	return p, nil
+}
+
+// walk follows the stages from the first one to the last, in the order they run
+func (p Pipeline) walk() ([]Stage, error) {
+	order := []Stage{}
+	visited := map[string]bool{}
+
+	for jobType := p.first; jobType != ""; {
+		errctx := cerr.Field("job_type", jobType)
+
+		if visited[jobType] {
+			return nil, errctx.Error("The stages loop back on themselves")
+		}
+		visited[jobType] = true
+
+		stage, ok := p.stages[jobType]
+		if !ok {
+			return nil, errctx.Error("A stage leads on to a stage that isn't defined")
+		}
+
+		order = append(order, stage)
+		jobType = stage.Next
+	}
+
+	return order, nil
+}
+
+func (p Pipeline) First() Stage {
+	return p.stages[p.first]
+}
+
+func (p Pipeline) Stage(jobType string) (Stage, bool) {
+	stage, ok := p.stages[jobType]
+	return stage, ok
+}
+
+// Next is the stage that runs after the given one, if there is one
+func (p Pipeline) Next(stage Stage) (Stage, bool) {
+	if stage.IsLast() {
+		return Stage{}, false
+	}
+
+	return p.Stage(stage.Next)
+}
+
+// ProgressAfter is the track's progress once the stage is done, out of 100
+func (p Pipeline) ProgressAfter(stage Stage) int {
+	return p.progress[stage.JobType]
+}
+
+// HasCompleted is whether the split request has already gotten through the stage,
+// going by the order the stages run in
+func (p Pipeline) HasCompleted(splitRequest trackentity.SplitRequestTrack, stage Stage) bool {
+	completed, ok := p.position[string(splitRequest.CompletedStage)]
+	if !ok {
+		return false
+	}
+
+	position, ok := p.position[stage.JobType]
+	return ok && completed >= position
+}
+
+// ResumeJobMessage makes the message for the stage after
+// the last one that the split request has completed
+func (p Pipeline) ResumeJobMessage(tracklistID string, splitRequest trackentity.SplitRequestTrack) (amqp091.Publishing, error) {
+	resumeStage := p.First()
+	if completed, ok := p.Stage(string(splitRequest.CompletedStage)); ok {
+		next, hasNext := p.Next(completed)
+		if !hasNext {
+			return amqp091.Publishing{}, cerr.Field("completed_stage", splitRequest.CompletedStage).
+				Error("The split request has already gotten through every stage")
+		}
+
+		resumeStage = next
+	}
+
+	trackParams := job_message.TrackIdentifier{
+		TrackListID: tracklistID,
+		TrackID:     splitRequest.ID,
+	}
+
+	return resumeStage.JobMessage(trackParams, splitRequest)
+}
@@ -0,0 +1,153 @@
+package pipeline_test
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
+)
+
+var _ = Describe("Pipeline", func() {
+	var stage = func(jobType string, next string, weight int) pipeline.Stage {
+		return pipeline.Stage{
+			JobType:        jobType,
+			Next:           next,
+			ProgressWeight: weight,
+			JobParams: func(trackParams job_message.TrackIdentifier, _ trackentity.SplitRequestTrack) any {
+				return trackParams
+			},
+		}
+	}
+
+	Describe("Putting the stages together", func() {
+		It("works out the progress from the weights of the stages run so far", func() {
+			p, err := pipeline.New(
+				stage("first", "second", 1),
+				stage("second", "third", 2),
+				stage("third", "", 1),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(p.First().JobType).To(Equal("first"))
+
+			first, _ := p.Stage("first")
+			second, _ := p.Stage("second")
+			third, _ := p.Stage("third")
+			Expect(p.ProgressAfter(first)).To(Equal(25))
+			Expect(p.ProgressAfter(second)).To(Equal(75))
+			Expect(p.ProgressAfter(third)).To(Equal(100))
+
+			next, ok := p.Next(first)
+			Expect(ok).To(BeTrue())
+			Expect(next.JobType).To(Equal("second"))
+
+			_, ok = p.Next(third)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("fails when a stage leads on to one that isn't defined", func() {
+			_, err := pipeline.New(stage("first", "missing", 1))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when a stage can't be reached from the first one", func() {
+			_, err := pipeline.New(
+				stage("first", "", 1),
+				stage("orphan", "", 1),
+			)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when the stages loop back on themselves", func() {
+			_, err := pipeline.New(
+				stage("first", "second", 1),
+				stage("second", "first", 1),
+			)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when a stage is defined twice", func() {
+			_, err := pipeline.New(
+				stage("first", "first", 1),
+				stage("first", "", 1),
+			)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Split pipeline", func() {
+		var splitPipeline pipeline.Pipeline
+
+		BeforeEach(func() {
+			splitPipeline = pipeline.NewSplitPipeline(pipeline.SplitHandlers{})
+		})
+
+		It("keeps the progress that the stages have always reported", func() {
+			progresses := []int{}
+			for stage, ok := splitPipeline.First(), true; ok; stage, ok = splitPipeline.Next(stage) {
+				progresses = append(progresses, splitPipeline.ProgressAfter(stage))
+			}
+
+			Expect(progresses).To(Equal([]int{10, 30, 90, 100}))
+		})
+
+		Describe("Resuming a split request", func() {
+			var splitRequest trackentity.SplitRequestTrack
+
+			BeforeEach(func() {
+				splitRequest = trackentity.SplitRequestTrack{
+					TrackFields: trackentity.TrackFields{ID: "track-id"},
+				}
+			})
+
+			It("starts from the beginning when nothing was completed", func() {
+				message, err := splitPipeline.ResumeJobMessage("tracklist-id", splitRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(message.Type).To(Equal(start.JobType))
+			})
+
+			It("picks up after the original was transferred", func() {
+				splitRequest.CompletedStage = trackentity.TransferStage
+				splitRequest.SavedOriginalURL = "original.mp3"
+
+				message, err := splitPipeline.ResumeJobMessage("tracklist-id", splitRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(message.Type).To(Equal(split.JobType))
+
+				var jobParams split.JobParams
+				Expect(json.Unmarshal(message.Body, &jobParams)).To(Succeed())
+				Expect(jobParams.TrackListID).To(Equal("tracklist-id"))
+				Expect(jobParams.TrackID).To(Equal("track-id"))
+				Expect(jobParams.SavedOriginalURL).To(Equal("original.mp3"))
+			})
+
+			It("picks up after the track was split", func() {
+				splitRequest.CompletedStage = trackentity.SplitStage
+				splitRequest.SplitStemURLs = map[string]string{"vocals": "vocals.mp3"}
+
+				message, err := splitPipeline.ResumeJobMessage("tracklist-id", splitRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(message.Type).To(Equal(save_stems_to_db.JobType))
+
+				var jobParams save_stems_to_db.JobParams
+				Expect(json.Unmarshal(message.Body, &jobParams)).To(Succeed())
+				Expect(jobParams.StemURLS).To(Equal(splitRequest.SplitStemURLs))
+			})
+
+			It("gives every resumed job an ID", func() {
+				splitRequest.CompletedStage = trackentity.StartStage
+
+				message, err := splitPipeline.ResumeJobMessage("tracklist-id", splitRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(message.Type).To(Equal(transfer.JobType))
+				Expect(message.MessageId).NotTo(BeEmpty())
+			})
+		})
+	})
+})
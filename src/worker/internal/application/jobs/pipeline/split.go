@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"context"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
+)
+
+type SplitHandlers struct {
+	Start     start.StartJobHandler
+	Transfer  transfer.TransferJobHandler
+	Split     split.SplitJobHandler
+	SaveStems save_stems_to_db.SaveStemsJobHandler
+}
+
+// NewSplitPipeline is the pipeline that turns a split request into stems:
+// start -> transfer the original -> split it -> save the stems on the track.
+// The order of the stages and how they're shown on the track come from
+// trackentity.SplitJobStages, which the server resumes retried splits from too
+func NewSplitPipeline(handlers SplitHandlers) Pipeline {
+	jobs := map[trackentity.SplitJobStage]Stage{
+		trackentity.StartStage: {
+			ErrorMessage: start.ErrorMessage,
+			JobParams: func(trackParams job_message.TrackIdentifier, _ trackentity.SplitRequestTrack) any {
+				return start.JobParams{TrackIdentifier: trackParams}
+			},
+			Handler: startHandler(handlers.Start),
+		},
+		trackentity.TransferStage: {
+			ErrorMessage: transfer.ErrorMessage,
+			JobParams: func(trackParams job_message.TrackIdentifier, _ trackentity.SplitRequestTrack) any {
+				return transfer.JobParams{TrackIdentifier: trackParams}
+			},
+			InputURL: func(track trackentity.SplitRequestTrack) string {
+				return track.OriginalURL
+			},
+			Handler: transferHandler(handlers.Transfer),
+		},
+		trackentity.SplitStage: {
+			ErrorMessage: split.ErrorMessage,
+			JobParams: func(trackParams job_message.TrackIdentifier, track trackentity.SplitRequestTrack) any {
+				return split.JobParams{
+					TrackIdentifier:  trackParams,
+					SavedOriginalURL: track.SavedOriginalURL,
+				}
+			},
+			InputURL: func(track trackentity.SplitRequestTrack) string {
+				return track.SavedOriginalURL
+			},
+			Handler: splitHandler(handlers.Split),
+		},
+		trackentity.SaveStemsStage: {
+			ErrorMessage: save_stems_to_db.ErrorMessage,
+			JobParams: func(trackParams job_message.TrackIdentifier, track trackentity.SplitRequestTrack) any {
+				return save_stems_to_db.JobParams{
+					TrackIdentifier: trackParams,
+					StemURLS:        track.SplitStemURLs,
+				}
+			},
+			Handler: saveStemsHandler(handlers.SaveStems),
+		},
+	}
+
+	stages := []Stage{}
+	for _, definition := range trackentity.SplitJobStages {
+		stage := jobs[definition.Stage]
+		stage.JobType = string(definition.Stage)
+		stage.ProgressWeight = definition.ProgressWeight
+		stage.StatusMessage = definition.StatusMessage
+		if next, ok := definition.Stage.Next(); ok {
+			stage.Next = string(next)
+		}
+
+		stages = append(stages, stage)
+	}
+
+	p, err := New(stages...)
+
+	// the stages are all defined right above, so this is a bug rather than something to handle
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+func startHandler(handler start.StartJobHandler) Handler {
+	return HandlerFunc(func(_ context.Context, message []byte) (Result, error) {
+		_, err := handler.HandleStartJob(message)
+		return Result{}, err
+	})
+}
+
+func transferHandler(handler transfer.TransferJobHandler) Handler {
+	return HandlerFunc(func(_ context.Context, message []byte) (Result, error) {
+		_, savedOriginalURL, err := handler.HandleTransferJob(message)
+		if err != nil {
+			return Result{}, err
+		}
+
+		return Result{
+			Record: func(track *trackentity.SplitRequestTrack) {
+				track.SavedOriginalURL = savedOriginalURL
+			},
+			OutputURLs: map[string]string{"original": savedOriginalURL},
+		}, nil
+	})
+}
+
+func splitHandler(handler split.SplitJobHandler) Handler {
+	return HandlerFunc(func(ctx context.Context, message []byte) (Result, error) {
+		_, stemURLs, err := handler.HandleSplitJob(ctx, message)
+		if err != nil {
+			return Result{}, err
+		}
+
+		return Result{
+			Record: func(track *trackentity.SplitRequestTrack) {
+				track.SplitStemURLs = stemURLs
+			},
+			OutputURLs: stemURLs,
+		}, nil
+	})
+}
+
+func saveStemsHandler(handler save_stems_to_db.SaveStemsJobHandler) Handler {
+	return HandlerFunc(func(_ context.Context, message []byte) (Result, error) {
+		return Result{}, handler.HandleSaveStemsToDBJob(message)
+	})
+}
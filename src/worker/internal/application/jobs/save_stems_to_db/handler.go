@@ -14,9 +14,10 @@ var postSplitTrackType = map[trackentity.SplitRequestType]trackentity.StemTrackT
 	trackentity.SplitTwoStemsType:  trackentity.TwoStemsType,
 	trackentity.SplitFourStemsType: trackentity.FourStemsType,
 	trackentity.SplitFiveStemsType: trackentity.FiveStemsType,
+	trackentity.SplitSixStemsType:  trackentity.SixStemsType,
 }
 
-const JobType string = "save_stems_to_db"
+const JobType = string(trackentity.SaveStemsStage)
 const ErrorMessage string = "Failed to save stem URLs to database"
 
 type JobParams struct {
@@ -42,21 +43,26 @@ type JobHandler struct {
 func (s JobHandler) HandleSaveStemsToDBJob(message []byte) error {
 	params, err := unmarshalMessage(message)
 	if err != nil {
-		return cerr.Wrap(err).Error("Failed to unmarshal message JSON")
+		return cerr.Permanent(cerr.Wrap(err).Error("Failed to unmarshal message JSON"))
 	}
 
 	errctx := cerr.Field("job_params", params)
 
 	updater := func(track trackentity.Track) (trackentity.Track, error) {
+		// a redelivered job finds the stems already saved
+		if stemTrack, ok := track.(*trackentity.StemTrack); ok {
+			return stemTrack, nil
+		}
+
 		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
 		if !ok {
-			return nil, errctx.Error("Unexpected - track is not a split request")
+			return nil, cerr.Permanent(errctx.Error("Unexpected - track is not a split request"))
 		}
 
 		newTrackType, ok := postSplitTrackType[trackentity.SplitRequestType(splitStemTrack.TrackType)]
 		if !ok {
-			return nil, errctx.Field("track", splitStemTrack).
-				Error("No matching entry for setting the new track type")
+			return nil, cerr.Permanent(errctx.Field("track", splitStemTrack).
+				Error("No matching entry for setting the new track type"))
 		}
 
 		newTrack := &trackentity.StemTrack{
@@ -65,7 +71,8 @@ func (s JobHandler) HandleSaveStemsToDBJob(message []byte) error {
 				Label: splitStemTrack.Label,
 			},
 			TrackType: newTrackType,
-			StemURLs:  params.StemURLS,
+			// these are object paths in the bucket, the server signs them when they're fetched
+			StemURLs: params.StemURLS,
 		}
 
 		return newTrack, nil
@@ -107,6 +107,30 @@ var _ = Describe("JobHandler", func() {
 					})
 				})
 
+				Describe("Job redelivered after the stems were saved", func() {
+					It("does not error", func() {
+						Expect(handler.HandleSaveStemsToDBJob(messageBytes)).To(Succeed())
+
+						err := handler.HandleSaveStemsToDBJob(messageBytes)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("leaves the saved stems as they were", func() {
+						Expect(handler.HandleSaveStemsToDBJob(messageBytes)).To(Succeed())
+						_ = handler.HandleSaveStemsToDBJob(messageBytes)
+
+						tracklist, err := dummyTrackStore.GetTrackList(context.Background(), tracklistID)
+						Expect(err).NotTo(HaveOccurred())
+
+						track, err := tracklist.GetTrack(trackID)
+						Expect(err).NotTo(HaveOccurred())
+
+						stemTrack, ok := track.(*trackentity.StemTrack)
+						Expect(ok).To(BeTrue())
+						Expect(stemTrack.StemURLs).To(Equal(stemURLs))
+					})
+				})
+
 				Describe("Store is unavailable", func() {
 					BeforeEach(func() {
 						dummyTrackStore.Unavailable = true
@@ -230,6 +254,63 @@ var _ = Describe("JobHandler", func() {
 				})
 			})
 
+			Describe("6stems", func() {
+				BeforeEach(func() {
+					stemURLs = map[string]string{
+						"vocals": "vocals.mp3",
+						"other":  "other.mp3",
+						"bass":   "bass.mp3",
+						"drums":  "drums.mp3",
+						"piano":  "piano.mp3",
+						"guitar": "guitar.mp3",
+					}
+					jobParams = save_stems_to_db.JobParams{
+						TrackIdentifier: job_message.TrackIdentifier{
+							TrackListID: tracklistID,
+							TrackID:     trackID,
+						},
+						StemURLS: stemURLs,
+					}
+					trackType = trackentity.SplitSixStemsType
+
+					var err error
+					messageBytes, err = json.Marshal(jobParams)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Describe("Store saves successfully", func() {
+					It("does not error", func() {
+						err := handler.HandleSaveStemsToDBJob(messageBytes)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("updates the track store", func() {
+						_ = handler.HandleSaveStemsToDBJob(messageBytes)
+						tracklist, err := dummyTrackStore.GetTrackList(context.Background(), tracklistID)
+						Expect(err).NotTo(HaveOccurred())
+
+						track, err := tracklist.GetTrack(trackID)
+						Expect(err).NotTo(HaveOccurred())
+
+						stemTrack, ok := track.(*trackentity.StemTrack)
+						Expect(ok).To(BeTrue())
+						Expect(stemTrack.TrackType).To(Equal(trackentity.SixStemsType))
+						Expect(stemTrack.StemURLs).To(Equal(stemURLs))
+					})
+				})
+
+				Describe("Store is unavailable", func() {
+					BeforeEach(func() {
+						dummyTrackStore.Unavailable = true
+					})
+
+					It("also returns a failure", func() {
+						err := handler.HandleSaveStemsToDBJob(messageBytes)
+						Expect(err).To(HaveOccurred())
+					})
+				})
+			})
+
 		})
 
 		Describe("Malformed job message", func() {
@@ -3,12 +3,14 @@ package split
 import (
 	"context"
 	"encoding/json"
+	"github.com/apex/log"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 )
 
-const JobType string = "split_track"
+const JobType = string(trackentity.SplitStage)
 const ErrorMessage string = "Failed to split the source audio into stems"
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -20,29 +22,42 @@ type JobParams struct {
 
 //counterfeiter:generate . SplitJobHandler
 type SplitJobHandler interface {
-	HandleSplitJob(message []byte) (JobParams, splitter.StemFilePaths, error)
+	HandleSplitJob(ctx context.Context, message []byte) (JobParams, splitter.StemFilePaths, error)
 }
 
-func NewJobHandler(splitter splitter.TrackSplitter) JobHandler {
+func NewJobHandler(splitter splitter.TrackSplitter, trackStore trackentity.Store) JobHandler {
 	return JobHandler{
-		splitter: splitter,
+		splitter:   splitter,
+		trackStore: trackStore,
 	}
 }
 
 type JobHandler struct {
-	splitter splitter.TrackSplitter
+	splitter   splitter.TrackSplitter
+	trackStore trackentity.Store
 }
 
-func (s JobHandler) HandleSplitJob(message []byte) (JobParams, splitter.StemFilePaths, error) {
+func (s JobHandler) HandleSplitJob(ctx context.Context, message []byte) (JobParams, splitter.StemFilePaths, error) {
 	params := JobParams{}
 	err := json.Unmarshal(message, &params)
 	if err != nil {
-		return JobParams{}, nil, cerr.Wrap(err).Error("Failed to unmarshal message JSON")
+		return JobParams{}, nil, cerr.Permanent(cerr.Wrap(err).Error("Failed to unmarshal message JSON"))
 	}
 
 	errctx := cerr.Field("job_params", params)
 
-	stemURLs, err := s.splitter.SplitTrack(context.Background(), params.TrackListID, params.TrackID, params.SavedOriginalURL)
+	splitRequest, err := job_message.GetSplitRequest(ctx, s.trackStore, params.TrackIdentifier)
+	if err != nil {
+		return JobParams{}, nil, errctx.Wrap(err).Error("Failed to look up the split request")
+	}
+
+	// a redelivered job gets the stems that have already been uploaded, rather than splitting all over again
+	if splitRequest.HasCompleted(trackentity.SplitStage) && len(splitRequest.SplitStemURLs) > 0 {
+		log.WithField("track_id", params.TrackID).Info("Track has already been split, skipping")
+		return params, splitRequest.SplitStemURLs, nil
+	}
+
+	stemURLs, err := s.splitter.SplitTrack(ctx, params.TrackListID, params.TrackID, params.SavedOriginalURL)
 	if err != nil {
 		return JobParams{}, nil, errctx.Wrap(err).Error("Failed to split the track")
 	}
@@ -6,23 +6,25 @@ import (
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/veedubyou/chord-paper-be/src/shared/config/prod"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter/file_splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/storagepath"
 )
 
 var _ = Describe("Split handler", func() {
 	var (
-		bucketName string
-
 		dummyTrackStore *dummy.TrackStore
 		dummyFileStore  *dummy.FileStore
-		dummyExecutor   *dummy.SpleeterExecutor
+		demucsExecutor  *dummy.DemucsExecutor
+		splitExecutor   dummy.SplitExecutor
+		eventBus        *trackevents.Bus
 
 		handler split.JobHandler
 
@@ -34,6 +36,7 @@ var _ = Describe("Split handler", func() {
 		tracklistID string
 		trackID     string
 		trackType   trackentity.SplitRequestType
+		engineType  trackentity.SplitEngineType
 	)
 
 	BeforeEach(func() {
@@ -41,9 +44,9 @@ var _ = Describe("Split handler", func() {
 			tracklistID = "tracklist-ID"
 			trackID = "track-ID"
 			trackType = ""
-			bucketName = "bucket-head"
+			engineType = trackentity.SpleeterType
 
-			remoteURLBase = fmt.Sprintf("%s/%s/%s/%s", prod.GOOGLE_STORAGE_HOST, bucketName, tracklistID, trackID)
+			remoteURLBase = fmt.Sprintf("%s/%s", tracklistID, trackID)
 			savedOriginalURL = fmt.Sprintf("%s/original/original.mp3", remoteURLBase)
 			originalTrackData = []byte("cool_jamz")
 		})
@@ -51,7 +54,13 @@ var _ = Describe("Split handler", func() {
 		By("Instantiating all mocks", func() {
 			dummyTrackStore = dummy.NewDummyTrackStore()
 			dummyFileStore = dummy.NewDummyFileStore()
-			dummyExecutor = dummy.NewDummySpleeterExecutor()
+			demucsExecutor = dummy.NewDummyDemucsExecutor()
+			splitExecutor = dummy.NewDummySplitExecutor(map[string]executor.Executor{
+				"/somewhere/spleeter": dummy.NewDummySpleeterExecutor(),
+				"/somewhere/demucs":   demucsExecutor,
+				"/somewhere/umx":      dummy.NewDummyOpenUnmixExecutor(),
+			})
+			eventBus = trackevents.NewBus()
 		})
 
 		By("Setting up file on the file store", func() {
@@ -60,18 +69,24 @@ var _ = Describe("Split handler", func() {
 		})
 
 		By("Instantiating the handler", func() {
-			localSplitter, err := file_splitter.NewLocalFileSplitter(workingDir, "/somewhere/spleeter", workingDir, "/somewhere/demucs", dummyExecutor)
+			spleeter, err := file_splitter.NewSpleeterEngine(workingDir, "/somewhere/spleeter")
+			Expect(err).NotTo(HaveOccurred())
+			demucs, err := file_splitter.NewDemucsEngine(workingDir, "/somewhere/demucs")
+			Expect(err).NotTo(HaveOccurred())
+			openUnmix, err := file_splitter.NewOpenUnmixEngine(workingDir, "/somewhere/umx")
+			Expect(err).NotTo(HaveOccurred())
+
+			engines, err := file_splitter.NewEngineRegistry(spleeter, demucs, openUnmix)
 			Expect(err).NotTo(HaveOccurred())
 
+			localSplitter := file_splitter.NewLocalFileSplitter(engines, splitExecutor)
+
 			remoteSplitter, err := file_splitter.NewRemoteFileSplitter(workingDir, dummyFileStore, localSplitter)
 			Expect(err).NotTo(HaveOccurred())
 
-			pathGenerator := storagepath.Generator{
-				Host:   prod.GOOGLE_STORAGE_HOST,
-				Bucket: bucketName,
-			}
-			trackSplitter := splitter.NewTrackSplitter(remoteSplitter, dummyTrackStore, pathGenerator)
-			handler = split.NewJobHandler(trackSplitter)
+			pathGenerator := storagepath.Generator{}
+			trackSplitter := splitter.NewTrackSplitter(remoteSplitter, dummyTrackStore, eventBus, pathGenerator)
+			handler = split.NewJobHandler(trackSplitter, dummyTrackStore)
 		})
 	})
 
@@ -87,8 +102,9 @@ var _ = Describe("Split handler", func() {
 			&trackentity.SplitRequestTrack{
 				TrackFields: trackentity.TrackFields{ID: trackID},
 				TrackType:   trackType,
-				EngineType:  trackentity.SpleeterType,
+				EngineType:  engineType,
 				OriginalURL: "https://whocares",
+				Progress:    30,
 			},
 		}
 
@@ -149,7 +165,7 @@ var _ = Describe("Split handler", func() {
 			})
 
 			JustBeforeEach(func() {
-				returnedJobParams, returnedStemUrls, err = handler.HandleSplitJob(message)
+				returnedJobParams, returnedStemUrls, err = handler.HandleSplitJob(context.Background(), message)
 			})
 
 			Describe("2stems", func() {
@@ -247,6 +263,272 @@ var _ = Describe("Split handler", func() {
 
 				It("returns the right values", expectReturnValues)
 			})
+
+			Describe("demucs", func() {
+				var (
+					events      <-chan trackentity.TrackEvent
+					unsubscribe func()
+				)
+
+				BeforeEach(func() {
+					trackType = trackentity.SplitFourStemsType
+					engineType = trackentity.DemucsType
+
+					demucsExecutor.OutputLines = []string{
+						"Selected model is a bag of 1 models. You will see that many progress bars per track.",
+						"Separating track /somewhere/original.mp3",
+						"  0%|          | 0.0/58.5 [00:00<?, ?seconds/s]",
+						" 10%|█         | 5.85/58.5 [00:02<00:21,  2.45seconds/s]",
+						" 12%|█▏        | 7.02/58.5 [00:03<00:21,  2.45seconds/s]",
+						" 50%|█████     | 29.25/58.5 [00:12<00:12,  2.41seconds/s]",
+						"100%|██████████| 58.5/58.5 [00:24<00:00,  2.40seconds/s]",
+					}
+
+					events, unsubscribe = eventBus.Subscribe(tracklistID)
+
+					vocalsURL := remoteURLBase + "/4stems/vocals.mp3"
+					otherURL := remoteURLBase + "/4stems/other.mp3"
+					bassURL := remoteURLBase + "/4stems/bass.mp3"
+					drumsURL := remoteURLBase + "/4stems/drums.mp3"
+
+					expectedReturnedStemUrls = map[string]string{
+						"vocals": vocalsURL,
+						"other":  otherURL,
+						"bass":   bassURL,
+						"drums":  drumsURL,
+					}
+
+					expectedStemFileContent = map[string][]byte{
+						vocalsURL: []byte(string(originalTrackData) + "-vocals"),
+						otherURL:  []byte(string(originalTrackData) + "-other"),
+						bassURL:   []byte(string(originalTrackData) + "-bass"),
+						drumsURL:  []byte(string(originalTrackData) + "-drums"),
+					}
+				})
+
+				AfterEach(func() {
+					unsubscribe()
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("uploaded the stem files", expectUploadedStemFiles)
+
+				It("returns the right values", expectReturnValues)
+
+				It("saves the split progress to the track", func() {
+					tracklist, err := dummyTrackStore.GetTrackList(context.Background(), tracklistID)
+					Expect(err).NotTo(HaveOccurred())
+
+					splitTrack, ok := tracklist.Defined.Tracks[0].(*trackentity.SplitRequestTrack)
+					Expect(ok).To(BeTrue())
+					Expect(splitTrack.Progress).To(Equal(90))
+				})
+
+				It("only publishes progress when it moves far enough", func() {
+					progresses := []int{}
+					for len(events) > 0 {
+						event := <-events
+						Expect(event.Type).To(Equal(trackentity.ProgressEventType))
+						progresses = append(progresses, event.Progress)
+					}
+
+					Expect(progresses).To(Equal([]int{36, 60, 90}))
+				})
+
+				Describe("With a bag of models", func() {
+					BeforeEach(func() {
+						demucsExecutor.OutputLines = []string{
+							"Selected model is a bag of 2 models. You will see that many progress bars per track.",
+							"Separating track /somewhere/original.mp3",
+							"  0%|          | 0.0/58.5 [00:00<?, ?seconds/s]",
+							" 50%|█████     | 29.25/58.5 [00:12<00:12,  2.41seconds/s]",
+							"100%|██████████| 58.5/58.5 [00:24<00:00,  2.40seconds/s]",
+							"  0%|          | 0.0/58.5 [00:00<?, ?seconds/s]",
+							" 50%|█████     | 29.25/58.5 [00:12<00:12,  2.41seconds/s]",
+							"100%|██████████| 58.5/58.5 [00:24<00:00,  2.40seconds/s]",
+						}
+					})
+
+					It("counts each bar as a part of the split", func() {
+						progresses := []int{}
+						for len(events) > 0 {
+							event := <-events
+							progresses = append(progresses, event.Progress)
+						}
+
+						Expect(progresses).To(Equal([]int{45, 60, 75, 90}))
+					})
+				})
+			})
+
+			Describe("demucs 6stems", func() {
+				BeforeEach(func() {
+					trackType = trackentity.SplitSixStemsType
+					engineType = trackentity.DemucsType
+
+					vocalsURL := remoteURLBase + "/6stems/vocals.mp3"
+					otherURL := remoteURLBase + "/6stems/other.mp3"
+					bassURL := remoteURLBase + "/6stems/bass.mp3"
+					drumsURL := remoteURLBase + "/6stems/drums.mp3"
+					guitarURL := remoteURLBase + "/6stems/guitar.mp3"
+					pianoURL := remoteURLBase + "/6stems/piano.mp3"
+
+					expectedReturnedStemUrls = map[string]string{
+						"vocals": vocalsURL,
+						"other":  otherURL,
+						"bass":   bassURL,
+						"drums":  drumsURL,
+						"guitar": guitarURL,
+						"piano":  pianoURL,
+					}
+
+					expectedStemFileContent = map[string][]byte{
+						vocalsURL: []byte(string(originalTrackData) + "-vocals"),
+						otherURL:  []byte(string(originalTrackData) + "-other"),
+						bassURL:   []byte(string(originalTrackData) + "-bass"),
+						drumsURL:  []byte(string(originalTrackData) + "-drums"),
+						guitarURL: []byte(string(originalTrackData) + "-guitar"),
+						pianoURL:  []byte(string(originalTrackData) + "-piano"),
+					}
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("uploaded the stem files", expectUploadedStemFiles)
+
+				It("returns the right values", expectReturnValues)
+			})
+
+			Describe("demucs 5stems", func() {
+				BeforeEach(func() {
+					trackType = trackentity.SplitFiveStemsType
+					engineType = trackentity.DemucsType
+				})
+
+				It("fails without retrying, since demucs has no 5 stem model", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(cerr.IsPermanent(err)).To(BeTrue())
+				})
+			})
+
+			Describe("open-unmix 4stems", func() {
+				BeforeEach(func() {
+					trackType = trackentity.SplitFourStemsType
+					engineType = trackentity.OpenUnmixType
+
+					vocalsURL := remoteURLBase + "/4stems/vocals.mp3"
+					drumsURL := remoteURLBase + "/4stems/drums.mp3"
+					bassURL := remoteURLBase + "/4stems/bass.mp3"
+					otherURL := remoteURLBase + "/4stems/other.mp3"
+
+					expectedReturnedStemUrls = map[string]string{
+						"vocals": vocalsURL,
+						"drums":  drumsURL,
+						"bass":   bassURL,
+						"other":  otherURL,
+					}
+
+					expectedStemFileContent = map[string][]byte{
+						vocalsURL: []byte(string(originalTrackData) + "-vocals"),
+						drumsURL:  []byte(string(originalTrackData) + "-drums"),
+						bassURL:   []byte(string(originalTrackData) + "-bass"),
+						otherURL:  []byte(string(originalTrackData) + "-other"),
+					}
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("uploaded the stem files", expectUploadedStemFiles)
+
+				It("returns the right values", expectReturnValues)
+			})
+
+			Describe("open-unmix 2stems", func() {
+				BeforeEach(func() {
+					trackType = trackentity.SplitTwoStemsType
+					engineType = trackentity.OpenUnmixType
+
+					vocalsURL := remoteURLBase + "/2stems/vocals.mp3"
+					accompanimentURL := remoteURLBase + "/2stems/accompaniment.mp3"
+
+					expectedReturnedStemUrls = map[string]string{
+						"vocals":        vocalsURL,
+						"accompaniment": accompanimentURL,
+					}
+
+					expectedStemFileContent = map[string][]byte{
+						vocalsURL:        []byte(string(originalTrackData) + "-vocals"),
+						accompanimentURL: []byte(string(originalTrackData) + "-accompaniment"),
+					}
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("uploaded the stem files", expectUploadedStemFiles)
+
+				It("returns the right values", expectReturnValues)
+			})
+
+			Describe("An engine this worker doesn't have", func() {
+				BeforeEach(func() {
+					trackType = trackentity.SplitFourStemsType
+					engineType = trackentity.DemucsV3Type
+				})
+
+				It("fails without retrying", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(cerr.IsPermanent(err)).To(BeTrue())
+				})
+			})
+		})
+
+		Describe("Job redelivered after the track was split", func() {
+			var (
+				err              error
+				returnedStemUrls splitter.StemFilePaths
+				alreadySplitUrls map[string]string
+			)
+
+			BeforeEach(func() {
+				alreadySplitUrls = map[string]string{
+					"vocals":        remoteURLBase + "/2stems/vocals.mp3",
+					"accompaniment": remoteURLBase + "/2stems/accompaniment.mp3",
+				}
+			})
+
+			JustBeforeEach(func() {
+				err = dummyTrackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitRequest := track.(*trackentity.SplitRequestTrack)
+					splitRequest.CompletedStage = trackentity.SplitStage
+					splitRequest.SplitStemURLs = alreadySplitUrls
+					return splitRequest, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, returnedStemUrls, err = handler.HandleSplitJob(context.Background(), message)
+			})
+
+			It("succeeds", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the stems that were already uploaded", func() {
+				Expect(returnedStemUrls).To(Equal(alreadySplitUrls))
+			})
+
+			It("doesn't split the track again", func() {
+				_, err := dummyFileStore.GetFile(context.Background(), alreadySplitUrls["vocals"])
+				Expect(err).To(HaveOccurred())
+			})
 		})
 
 		Describe("When the file store is down", func() {
@@ -255,7 +537,7 @@ var _ = Describe("Split handler", func() {
 			})
 
 			It("returns an error", func() {
-				_, _, err := handler.HandleSplitJob(message)
+				_, _, err := handler.HandleSplitJob(context.Background(), message)
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -266,7 +548,7 @@ var _ = Describe("Split handler", func() {
 			})
 
 			It("returns an error", func() {
-				_, _, err := handler.HandleSplitJob(message)
+				_, _, err := handler.HandleSplitJob(context.Background(), message)
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -289,7 +571,7 @@ var _ = Describe("Split handler", func() {
 		})
 
 		It("failaroo", func() {
-			_, _, err := handler.HandleSplitJob(message)
+			_, _, err := handler.HandleSplitJob(context.Background(), message)
 			Expect(err).To(HaveOccurred())
 		})
 	})
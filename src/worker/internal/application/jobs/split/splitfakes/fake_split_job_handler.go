@@ -2,15 +2,17 @@
 package splitfakes
 
 import (
+	"context"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
 	"sync"
 )
 
 type FakeSplitJobHandler struct {
-	HandleSplitJobStub        func([]byte) (split.JobParams, map[string]string, error)
+	HandleSplitJobStub        func(context.Context, []byte) (split.JobParams, map[string]string, error)
 	handleSplitJobMutex       sync.RWMutex
 	handleSplitJobArgsForCall []struct {
-		arg1 []byte
+		arg1 context.Context
+		arg2 []byte
 	}
 	handleSplitJobReturns struct {
 		result1 split.JobParams
@@ -26,23 +28,24 @@ type FakeSplitJobHandler struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeSplitJobHandler) HandleSplitJob(arg1 []byte) (split.JobParams, map[string]string, error) {
-	var arg1Copy []byte
-	if arg1 != nil {
-		arg1Copy = make([]byte, len(arg1))
-		copy(arg1Copy, arg1)
+func (fake *FakeSplitJobHandler) HandleSplitJob(arg1 context.Context, arg2 []byte) (split.JobParams, map[string]string, error) {
+	var arg2Copy []byte
+	if arg2 != nil {
+		arg2Copy = make([]byte, len(arg2))
+		copy(arg2Copy, arg2)
 	}
 	fake.handleSplitJobMutex.Lock()
 	ret, specificReturn := fake.handleSplitJobReturnsOnCall[len(fake.handleSplitJobArgsForCall)]
 	fake.handleSplitJobArgsForCall = append(fake.handleSplitJobArgsForCall, struct {
-		arg1 []byte
-	}{arg1Copy})
+		arg1 context.Context
+		arg2 []byte
+	}{arg1, arg2Copy})
 	stub := fake.HandleSplitJobStub
 	fakeReturns := fake.handleSplitJobReturns
-	fake.recordInvocation("HandleSplitJob", []any{arg1Copy})
+	fake.recordInvocation("HandleSplitJob", []any{arg1, arg2Copy})
 	fake.handleSplitJobMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -56,17 +59,17 @@ func (fake *FakeSplitJobHandler) HandleSplitJobCallCount() int {
 	return len(fake.handleSplitJobArgsForCall)
 }
 
-func (fake *FakeSplitJobHandler) HandleSplitJobCalls(stub func([]byte) (split.JobParams, map[string]string, error)) {
+func (fake *FakeSplitJobHandler) HandleSplitJobCalls(stub func(context.Context, []byte) (split.JobParams, map[string]string, error)) {
 	fake.handleSplitJobMutex.Lock()
 	defer fake.handleSplitJobMutex.Unlock()
 	fake.HandleSplitJobStub = stub
 }
 
-func (fake *FakeSplitJobHandler) HandleSplitJobArgsForCall(i int) []byte {
+func (fake *FakeSplitJobHandler) HandleSplitJobArgsForCall(i int) (context.Context, []byte) {
 	fake.handleSplitJobMutex.RLock()
 	defer fake.handleSplitJobMutex.RUnlock()
 	argsForCall := fake.handleSplitJobArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeSplitJobHandler) HandleSplitJobReturns(result1 split.JobParams, result2 map[string]string, result3 error) {
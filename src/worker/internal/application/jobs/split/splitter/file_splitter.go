@@ -8,5 +8,5 @@ import (
 type StemFilePaths = map[string]string
 
 type FileSplitter interface {
-	SplitFile(ctx context.Context, originalFilePath string, stemOutputDir string, splitType SplitType, engineType trackentity.SplitEngineType) (StemFilePaths, error)
+	SplitFile(ctx context.Context, originalFilePath string, stemOutputDir string, splitType SplitType, engineType trackentity.SplitEngineType, reportProgress ProgressReporter) (StemFilePaths, error)
 }
@@ -0,0 +1,148 @@
+package file_splitter
+
+import (
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+var _ SplitEngine = DemucsEngine{}
+
+// NewDemucsEngine is the newest demucs, which also has a model that separates guitar and piano
+func NewDemucsEngine(workingDirStr string, binPath string) (DemucsEngine, error) {
+	return newDemucsEngine(workingDirStr, binPath, trackentity.DemucsType, map[splitter.SplitType]string{
+		splitter.SplitTwoStemsType:  "htdemucs",
+		splitter.SplitFourStemsType: "htdemucs",
+		splitter.SplitSixStemsType:  "htdemucs_6s",
+	})
+}
+
+// NewDemucsV3Engine runs the models from the previous version of demucs
+func NewDemucsV3Engine(workingDirStr string, binPath string) (DemucsEngine, error) {
+	return newDemucsEngine(workingDirStr, binPath, trackentity.DemucsV3Type, map[splitter.SplitType]string{
+		splitter.SplitTwoStemsType:  "hdemucs_mmi",
+		splitter.SplitFourStemsType: "hdemucs_mmi",
+	})
+}
+
+func newDemucsEngine(workingDirStr string, binPath string, engineType trackentity.SplitEngineType, models map[splitter.SplitType]string) (DemucsEngine, error) {
+	workingDir, err := working_dir.NewWorkingDir(workingDirStr)
+	if err != nil {
+		return DemucsEngine{}, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
+	}
+
+	return DemucsEngine{
+		engineType: engineType,
+		models:     models,
+		workingDir: workingDir,
+		binPath:    binPath,
+	}, nil
+}
+
+type DemucsEngine struct {
+	engineType trackentity.SplitEngineType
+	// the model that does each split type
+	models     map[splitter.SplitType]string
+	workingDir working_dir.WorkingDir
+	binPath    string
+}
+
+func (d DemucsEngine) Name() trackentity.SplitEngineType {
+	return d.engineType
+}
+
+func (d DemucsEngine) Command(sourcePath string, destPath string, splitType splitter.SplitType) (EngineCommand, error) {
+	model, ok := d.models[splitType]
+	if !ok {
+		return EngineCommand{}, cerr.Permanent(cerr.Field("split_type", splitType).Error("Unsupported split type"))
+	}
+
+	args := []string{"-o", destPath, "--name", model, "--device", "cpu", "--filename", "{stem}.{ext}", "--mp3"}
+
+	// every other split type is whatever the model separates out
+	if splitType == splitter.SplitTwoStemsType {
+		args = append(args, "--two-stems", "vocals")
+	}
+
+	args = append(args, sourcePath)
+
+	return EngineCommand{
+		BinPath:       d.binPath,
+		Args:          args,
+		WorkingDir:    d.workingDir.Root(),
+		ParseProgress: newDemucsProgressParser(),
+	}, nil
+}
+
+func (d DemucsEngine) CollectOutput(_ string, destPath string, splitType splitter.SplitType) (splitter.StemFilePaths, error) {
+	// demucs puts the stems in a folder named after the model
+	return collectStemFilePaths(path.Join(destPath, d.models[splitType]))
+}
+
+// tqdm redraws its bar with lines like " 45%|████▌     | 26.0/58.5 [00:12<00:15,  2.10seconds/s]"
+var tqdmPercentRegex = regexp.MustCompile(`(\d{1,3})%\|`)
+
+func parseTqdmPercent(line string) (int, bool) {
+	matches := tqdmPercentRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, false
+	}
+
+	percent, err := strconv.Atoi(matches[1])
+	if err != nil || percent > 100 {
+		return 0, false
+	}
+
+	return percent, true
+}
+
+// demucs says up front how many bars it's going to draw:
+// "Selected model is a bag of 4 models. You will see that many progress bars per track."
+var demucsBagRegex = regexp.MustCompile(`bag of (\d+) models`)
+
+// demucsProgress follows demucs through its progress bars, one for each model in the bag,
+// which each go from 0 to 100 again
+type demucsProgress struct {
+	bars        int
+	bar         int
+	lastPercent int
+	reported    int
+}
+
+func newDemucsProgressParser() func(line string) (int, bool) {
+	progress := &demucsProgress{bars: 1}
+	return progress.parse
+}
+
+func (d *demucsProgress) parse(line string) (int, bool) {
+	if matches := demucsBagRegex.FindStringSubmatch(line); matches != nil {
+		if bars, err := strconv.Atoi(matches[1]); err == nil && bars > 0 {
+			d.bars = bars
+		}
+
+		return 0, false
+	}
+
+	percent, ok := parseTqdmPercent(line)
+	if !ok {
+		return 0, false
+	}
+
+	// a bar starting over means the next model is running
+	if percent < d.lastPercent && d.bar < d.bars-1 {
+		d.bar++
+	}
+	d.lastPercent = percent
+
+	// the progress of the whole split never goes back, even if there are more bars than announced
+	overall := (d.bar*100 + percent) / d.bars
+	if overall > d.reported {
+		d.reported = overall
+	}
+
+	return d.reported, true
+}
@@ -0,0 +1,61 @@
+package file_splitter
+
+import (
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+// SplitEngine is a command line tool that splits a track into stems.
+// Adding an engine is a matter of implementing this, registering it
+// and adding what it can split into to the capability matrix in trackentity
+type SplitEngine interface {
+	Name() trackentity.SplitEngineType
+	// Command is what to run to split the source file into the destination dir
+	Command(sourcePath string, destPath string, splitType splitter.SplitType) (EngineCommand, error)
+	// CollectOutput finds the stem files that the command wrote, once it has finished
+	CollectOutput(sourcePath string, destPath string, splitType splitter.SplitType) (splitter.StemFilePaths, error)
+}
+
+type EngineCommand struct {
+	BinPath    string
+	Args       []string
+	WorkingDir string
+	// ParseProgress picks out how far along the split is from a line of the output,
+	// nil for the tools that don't report it. It's made for each command, so it can
+	// keep track of the lines that came before
+	ParseProgress func(line string) (int, bool)
+}
+
+// supportsSplitType goes by the capability matrix that the server offers the engines from,
+// so the worker never turns down a split that the server took
+func supportsSplitType(engine SplitEngine, splitType splitter.SplitType) bool {
+	trackType, ok := splitType.TrackType()
+	return ok && engine.Name().Supports(trackType)
+}
+
+// EngineRegistry is the engines that this worker can split with, by name
+type EngineRegistry struct {
+	engines map[trackentity.SplitEngineType]SplitEngine
+}
+
+func NewEngineRegistry(engines ...SplitEngine) (EngineRegistry, error) {
+	registry := EngineRegistry{
+		engines: map[trackentity.SplitEngineType]SplitEngine{},
+	}
+
+	for _, engine := range engines {
+		if _, ok := registry.engines[engine.Name()]; ok {
+			return EngineRegistry{}, cerr.Field("engine_type", engine.Name()).Error("The engine is registered more than once")
+		}
+
+		registry.engines[engine.Name()] = engine
+	}
+
+	return registry, nil
+}
+
+func (r EngineRegistry) Get(engineType trackentity.SplitEngineType) (SplitEngine, bool) {
+	engine, ok := r.engines[engineType]
+	return engine, ok
+}
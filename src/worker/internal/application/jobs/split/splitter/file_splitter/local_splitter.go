@@ -7,9 +7,7 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
@@ -18,41 +16,19 @@ import (
 
 var _ splitter.FileSplitter = LocalFileSplitter{}
 
-var spleeterParamMap = map[splitter.SplitType]string{
-	splitter.SplitTwoStemsType:  "spleeter:2stems-16kHz",
-	splitter.SplitFourStemsType: "spleeter:4stems-16kHz",
-	splitter.SplitFiveStemsType: "spleeter:5stems-16kHz",
-}
-
-func NewLocalFileSplitter(spleeterWorkingDirStr string, spleeterBinPath string, demucsWorkingDirStr string, demucsBinPath string, executor executor.Executor) (LocalFileSplitter, error) {
-	spleeterWorkingDir, err := working_dir.NewWorkingDir(spleeterWorkingDirStr)
-	if err != nil {
-		return LocalFileSplitter{}, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
-	}
-
-	demucsWorkingDir, err := working_dir.NewWorkingDir(demucsWorkingDirStr)
-	if err != nil {
-		return LocalFileSplitter{}, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
-	}
-
+func NewLocalFileSplitter(engines EngineRegistry, executor executor.Executor) LocalFileSplitter {
 	return LocalFileSplitter{
-		spleeterWorkingDir: spleeterWorkingDir,
-		spleeterBinPath:    spleeterBinPath,
-		demucsWorkingDir:   demucsWorkingDir,
-		demucsBinPath:      demucsBinPath,
-		executor:           executor,
-	}, nil
+		engines:  engines,
+		executor: executor,
+	}
 }
 
 type LocalFileSplitter struct {
-	spleeterWorkingDir working_dir.WorkingDir
-	spleeterBinPath    string
-	demucsWorkingDir   working_dir.WorkingDir
-	demucsBinPath      string
-	executor           executor.Executor
+	engines  EngineRegistry
+	executor executor.Executor
 }
 
-func (l LocalFileSplitter) SplitFile(ctx context.Context, originalTrackFilePath string, stemsOutputDir string, splitType splitter.SplitType, engineType trackentity.SplitEngineType) (splitter.StemFilePaths, error) {
+func (l LocalFileSplitter) SplitFile(ctx context.Context, originalTrackFilePath string, stemsOutputDir string, splitType splitter.SplitType, engineType trackentity.SplitEngineType, reportProgress splitter.ProgressReporter) (splitter.StemFilePaths, error) {
 	absOriginalTrackFilePath, err := filepath.Abs(originalTrackFilePath)
 	if err != nil {
 		return nil, cerr.Wrap(err).Error("Cannot convert source path to absolute format")
@@ -65,131 +41,75 @@ func (l LocalFileSplitter) SplitFile(ctx context.Context, originalTrackFilePath
 		return nil, errctx.Wrap(err).Error("Cannot convert destination path to absolute format")
 	}
 
-	// splitting is a lengthy process, if we want to halt now is the time
-	if ctx.Err() != nil {
-		return nil, cerr.Wrap(ctx.Err()).Error("Context cancelled before splitting could happen")
-	}
-
-	switch engineType {
-	case trackentity.SpleeterType:
-		filePaths, err := l.runSpleeter(absOriginalTrackFilePath, absStemsOutputDir, splitType)
-		if err != nil {
-			return nil, cerr.Field("output_dir", absStemsOutputDir).
-				Wrap(err).Error("Failed to execute spleeter")
-		}
-
-		return filePaths, nil
-
-	case trackentity.DemucsType:
-		fallthrough
-	case trackentity.DemucsV3Type:
-		filePaths, err := l.runDemucs(absOriginalTrackFilePath, absStemsOutputDir, splitType, engineType)
-		if err != nil {
-			return nil, cerr.Field("output_dir", absStemsOutputDir).
-				Wrap(err).Error("Failed to execute demucs")
-		}
-
-		return filePaths, nil
-
-	default:
-		return nil, cerr.Field("engine_type", engineType).Error("Unexpected engine type")
-	}
-}
-
-func (l LocalFileSplitter) runDemucs(sourcePath string, destPath string, splitType splitter.SplitType, engineType trackentity.SplitEngineType) (splitter.StemFilePaths, error) {
-	logger := log.WithFields(log.Fields{
-		"sourcePath":       sourcePath,
-		"destPath":         destPath,
-		"splitType":        splitType,
-		"demucsWorkingDir": l.demucsWorkingDir,
-	})
-
-	logger.Info("Running demucs command")
-
-	var model string
-
-	switch engineType {
-	case trackentity.DemucsType:
-		model = "htdemucs"
-	case trackentity.DemucsV3Type:
-		model = "hdemucs_mmi"
-	default:
-		return nil, cerr.Field("engine_type", engineType).Error("Unsupported engine type")
+	engine, ok := l.engines.Get(engineType)
+	if !ok {
+		return nil, cerr.Permanent(cerr.Field("engine_type", engineType).Error("Unexpected engine type"))
 	}
 
-	args := []string{"-o", destPath, "--name", model, "--device", "cpu", "--filename", "{stem}.{ext}", "--mp3"}
-
-	switch splitType {
-	case splitter.SplitTwoStemsType:
-		args = append(args, "--two-stems", "vocals")
-	case splitter.SplitFourStemsType:
-		// do nothing, all good
-	default:
-		return nil, cerr.Field("split_type", splitType).Error("Unsupported split type")
+	if !supportsSplitType(engine, splitType) {
+		return nil, cerr.Permanent(cerr.Field("engine_type", engineType).Field("split_type", splitType).
+			Error("Unsupported split type for the engine"))
 	}
 
-	args = append(args, sourcePath)
-
-	errctx := cerr.Field("demucs_bin_path", l.demucsBinPath).Field("demucs_args", args)
-
-	cmd := l.executor.Command(l.demucsBinPath, args...)
-	cmd.SetDir(l.demucsWorkingDir.Root())
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, errctx.Field("demucs_output", string(output)).
-			Wrap(err).
-			Error(fmt.Sprintf("Error occurred while running demucs: %s", string(output)))
+	// splitting is a lengthy process, if we want to halt now is the time
+	if ctx.Err() != nil {
+		return nil, cerr.Wrap(ctx.Err()).Error("Context cancelled before splitting could happen")
 	}
 
-	logger.Debug(string(output))
-	logger.Info("Finished demucs command")
-
-	outputDir := path.Join(destPath, model)
-
-	filePaths, err := collectStemFilePaths(outputDir)
+	filePaths, err := l.runEngine(ctx, engine, absOriginalTrackFilePath, absStemsOutputDir, splitType, reportProgress)
 	if err != nil {
-		return nil, errctx.Field("output_dir", outputDir).Wrap(err).Error("Failed to collect stem file paths")
+		return nil, cerr.Field("output_dir", absStemsOutputDir).
+			Wrap(err).Error(fmt.Sprintf("Failed to execute %s", engineType))
 	}
 
 	return filePaths, nil
 }
 
-func (l LocalFileSplitter) runSpleeter(sourcePath string, destPath string, splitType splitter.SplitType) (splitter.StemFilePaths, error) {
+func (l LocalFileSplitter) runEngine(ctx context.Context, engine SplitEngine, sourcePath string, destPath string, splitType splitter.SplitType, reportProgress splitter.ProgressReporter) (splitter.StemFilePaths, error) {
 	logger := log.WithFields(log.Fields{
-		"sourcePath":         sourcePath,
-		"destPath":           destPath,
-		"splitType":          splitType,
-		"spleeterWorkingDir": l.spleeterWorkingDir,
+		"engine":     engine.Name(),
+		"sourcePath": sourcePath,
+		"destPath":   destPath,
+		"splitType":  splitType,
 	})
 
-	splitParam, ok := spleeterParamMap[splitType]
-	if !ok {
-		return nil, cerr.Error("Invalid split type passed in!")
+	engineCmd, err := engine.Command(sourcePath, destPath, splitType)
+	if err != nil {
+		return nil, cerr.Wrap(err).Error("Failed to make the split command")
 	}
 
-	logger.Info("Running spleeter command")
+	errctx := cerr.Field("bin_path", engineCmd.BinPath).Field("args", engineCmd.Args)
 
-	args := []string{"separate", "-p", splitParam, "-o", destPath, "-c", "mp3", "-b", "320k", "-f", "{instrument}.mp3", sourcePath}
+	logger.Info("Running split command")
 
-	errctx := cerr.Field("spleeter_bin_path", l.spleeterBinPath).Field("spleeter_args", args)
+	cmd := l.executor.CommandContext(ctx, engineCmd.BinPath, engineCmd.Args...)
+	cmd.SetDir(engineCmd.WorkingDir)
 
-	cmd := l.executor.Command(l.spleeterBinPath, args...)
-	cmd.SetDir(l.spleeterWorkingDir.Root())
+	output, err := cmd.StreamOutput(func(line string) {
+		logger.Debug(line)
+		if engineCmd.ParseProgress == nil {
+			return
+		}
+
+		if percent, ok := engineCmd.ParseProgress(line); ok {
+			reportProgress(percent)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil, errctx.Wrap(ctx.Err()).Error("The split was stopped before it could finish")
+	}
 
-	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, errctx.Field("spleeter_output", string(output)).
+		return nil, errctx.Field("output", string(output)).
 			Wrap(err).
-			Error(fmt.Sprintf("Error occurred while running spleeter: %s", string(output)))
+			Error(fmt.Sprintf("Error occurred while running %s: %s", engine.Name(), string(output)))
 	}
 
-	logger.Debug(string(output))
-	logger.Info("Finished spleeter command")
+	logger.Info("Finished split command")
 
-	filePaths, err := collectStemFilePaths(destPath)
+	filePaths, err := engine.CollectOutput(sourcePath, destPath, splitType)
 	if err != nil {
-		return nil, errctx.Field("output_dir", destPath).Wrap(err).Error("Failed to collect stem file paths")
+		return nil, errctx.Wrap(err).Error("Failed to collect stem file paths")
 	}
 
 	return filePaths, nil
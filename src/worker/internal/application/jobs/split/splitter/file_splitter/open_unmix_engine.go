@@ -0,0 +1,74 @@
+package file_splitter
+
+import (
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var _ SplitEngine = OpenUnmixEngine{}
+
+const openUnmixModel = "umxl"
+
+var openUnmixTargets = map[splitter.SplitType][]string{
+	splitter.SplitTwoStemsType:  {"vocals"},
+	splitter.SplitFourStemsType: {"vocals", "drums", "bass", "other"},
+}
+
+func NewOpenUnmixEngine(workingDirStr string, binPath string) (OpenUnmixEngine, error) {
+	workingDir, err := working_dir.NewWorkingDir(workingDirStr)
+	if err != nil {
+		return OpenUnmixEngine{}, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
+	}
+
+	return OpenUnmixEngine{
+		workingDir: workingDir,
+		binPath:    binPath,
+	}, nil
+}
+
+// OpenUnmixEngine runs the umx command from Open-Unmix
+type OpenUnmixEngine struct {
+	workingDir working_dir.WorkingDir
+	binPath    string
+}
+
+func (o OpenUnmixEngine) Name() trackentity.SplitEngineType {
+	return trackentity.OpenUnmixType
+}
+
+func (o OpenUnmixEngine) Command(sourcePath string, destPath string, splitType splitter.SplitType) (EngineCommand, error) {
+	targets, ok := openUnmixTargets[splitType]
+	if !ok {
+		return EngineCommand{}, cerr.Permanent(cerr.Field("split_type", splitType).Error("Unsupported split type"))
+	}
+
+	// --targets takes every argument after it that isn't an option,
+	// so the source has to come before it rather than at the end
+	args := []string{sourcePath, "--outdir", destPath, "--model", openUnmixModel, "--no-cuda", "--ext", ".mp3"}
+
+	// what's left after taking out the vocals is the accompaniment
+	if splitType == splitter.SplitTwoStemsType {
+		args = append(args, "--residual", "accompaniment")
+	}
+
+	args = append(args, "--targets")
+	args = append(args, targets...)
+
+	// umx doesn't report how far along it is, the output is only for the logs
+	return EngineCommand{
+		BinPath:    o.binPath,
+		Args:       args,
+		WorkingDir: o.workingDir.Root(),
+	}, nil
+}
+
+func (o OpenUnmixEngine) CollectOutput(sourcePath string, destPath string, _ splitter.SplitType) (splitter.StemFilePaths, error) {
+	// umx puts the stems in a folder named after the source file
+	sourceName := strings.TrimSuffix(filepath.Base(sourcePath), filepath.Ext(sourcePath))
+	return collectStemFilePaths(path.Join(destPath, sourceName))
+}
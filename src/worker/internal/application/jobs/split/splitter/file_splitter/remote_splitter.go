@@ -3,11 +3,12 @@ package file_splitter
 import (
 	"context"
 	"fmt"
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
-	cloudstorage "github.com/veedubyou/chord-paper-be/src/worker/internal/application/cloud_storage/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -17,7 +18,7 @@ import (
 
 var _ splitter.FileSplitter = RemoteFileSplitter{}
 
-func NewRemoteFileSplitter(workingDirStr string, remoteFileStore cloudstorage.FileStore, localSplitter LocalFileSplitter) (RemoteFileSplitter, error) {
+func NewRemoteFileSplitter(workingDirStr string, remoteFileStore cloudstorage.FileStore, fileSplitter splitter.FileSplitter) (RemoteFileSplitter, error) {
 	workingDir, err := working_dir.NewWorkingDir(workingDirStr)
 	if err != nil {
 		return RemoteFileSplitter{}, cerr.Wrap(err).Error("Failed to create working directory object")
@@ -26,29 +27,23 @@ func NewRemoteFileSplitter(workingDirStr string, remoteFileStore cloudstorage.Fi
 	return RemoteFileSplitter{
 		workingDir:      workingDir,
 		remoteFileStore: remoteFileStore,
-		localSplitter:   localSplitter,
+		fileSplitter:    fileSplitter,
 	}, nil
 }
 
 type RemoteFileSplitter struct {
 	workingDir      working_dir.WorkingDir
 	remoteFileStore cloudstorage.FileStore
-	localSplitter   LocalFileSplitter
+	fileSplitter    splitter.FileSplitter
 }
 
-func (r RemoteFileSplitter) SplitFile(ctx context.Context, remoteSourcePath string, remoteDestPath string, splitType splitter.SplitType, engineType trackentity.SplitEngineType) (splitter.StemFilePaths, error) {
+func (r RemoteFileSplitter) SplitFile(ctx context.Context, remoteSourcePath string, remoteDestPath string, splitType splitter.SplitType, engineType trackentity.SplitEngineType, reportProgress splitter.ProgressReporter) (splitter.StemFilePaths, error) {
 	logger := log.WithFields(log.Fields{
 		"remoteSourcePath": remoteSourcePath,
 		"remoteDestPath":   remoteDestPath,
 		"splitType":        splitType,
 	})
 
-	logger.Info("Fetching file from remote file store")
-	fileContents, err := r.remoteFileStore.GetFile(ctx, remoteSourcePath)
-	if err != nil {
-		return nil, cerr.Wrap(err).Error("Failed to get remote file")
-	}
-
 	logger.Info("Creating temp directory to store the original track")
 	originalTrackDir, removeOriginalTrackDir, err := r.createTempDir("original")
 	if err != nil {
@@ -57,10 +52,10 @@ func (r RemoteFileSplitter) SplitFile(ctx context.Context, remoteSourcePath stri
 
 	defer removeOriginalTrackDir()
 
-	logger.Info("Writing original track into temp directory")
+	logger.Info("Downloading original track into temp directory")
 	originalTrackFilePath := filepath.Join(originalTrackDir, "original.mp3")
-	if err := os.WriteFile(originalTrackFilePath, fileContents, os.ModePerm); err != nil {
-		return nil, cerr.Wrap(err).Error("Failed to write file temporarily to disk")
+	if err := r.downloadFile(ctx, remoteSourcePath, originalTrackFilePath); err != nil {
+		return nil, cerr.Wrap(err).Error("Failed to download remote file to disk")
 	}
 
 	logger.Info("Creating temp directory to store the split stem track")
@@ -72,9 +67,9 @@ func (r RemoteFileSplitter) SplitFile(ctx context.Context, remoteSourcePath stri
 	defer removeStemTrackDir()
 
 	logger.Info("Starting to run the split operation")
-	localFilePaths, err := r.localSplitter.SplitFile(ctx, originalTrackFilePath, stemTrackDir, splitType, engineType)
+	localFilePaths, err := r.fileSplitter.SplitFile(ctx, originalTrackFilePath, stemTrackDir, splitType, engineType, reportProgress)
 	if err != nil {
-		return nil, cerr.Wrap(err).Error("Failed to run local stem splitter")
+		return nil, cerr.Wrap(err).Error("Failed to run stem splitter")
 	}
 
 	logger.Info("Uploading stem files")
@@ -86,6 +81,34 @@ func (r RemoteFileSplitter) SplitFile(ctx context.Context, remoteSourcePath stri
 	return remoteFilePaths, nil
 }
 
+func (r RemoteFileSplitter) downloadFile(ctx context.Context, remoteSourcePath string, localFilePath string) (err error) {
+	reader, err := r.remoteFileStore.OpenFile(ctx, remoteSourcePath)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to open remote file")
+	}
+
+	defer reader.Close()
+
+	file, err := os.Create(localFilePath)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to create local file")
+	}
+
+	// a failed close can mean the file wasn't fully written
+	defer func() {
+		closeErr := file.Close()
+		if err == nil && closeErr != nil {
+			err = cerr.Wrap(closeErr).Error("Failed to finish writing remote file to disk")
+		}
+	}()
+
+	if _, err := io.Copy(file, reader); err != nil {
+		return cerr.Wrap(err).Error("Failed to copy remote file to disk")
+	}
+
+	return nil
+}
+
 func (r RemoteFileSplitter) createTempDir(prefix string) (string, func(), error) {
 	tempDir, err := ioutil.TempDir(r.workingDir.TempDir(), fmt.Sprintf("%s-*", prefix))
 	if err != nil {
@@ -110,14 +133,16 @@ func (r RemoteFileSplitter) uploadStem(ctx context.Context, done chan error, sou
 
 	logger.Info("Uploading stem track")
 
-	fileContents, err := os.ReadFile(sourceStemFilePath)
+	file, err := os.Open(sourceStemFilePath)
 	if err != nil {
-		logger.Error("Failed to read local file")
-		done <- cerr.Wrap(err).Error("Failed to read local file")
+		logger.Error("Failed to open local file")
+		done <- cerr.Wrap(err).Error("Failed to open local file")
 		return
 	}
 
-	err = r.remoteFileStore.WriteFile(ctx, destStemFilePath, fileContents)
+	defer file.Close()
+
+	err = r.remoteFileStore.UploadFile(ctx, destStemFilePath, file)
 	if err != nil {
 		logger.Error("Failed to upload stem file")
 		done <- cerr.Wrap(err).Error("Failed to upload stem file")
@@ -0,0 +1,55 @@
+package file_splitter
+
+import (
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+)
+
+var _ SplitEngine = SpleeterEngine{}
+
+var spleeterParamMap = map[splitter.SplitType]string{
+	splitter.SplitTwoStemsType:  "spleeter:2stems-16kHz",
+	splitter.SplitFourStemsType: "spleeter:4stems-16kHz",
+	splitter.SplitFiveStemsType: "spleeter:5stems-16kHz",
+}
+
+func NewSpleeterEngine(workingDirStr string, binPath string) (SpleeterEngine, error) {
+	workingDir, err := working_dir.NewWorkingDir(workingDirStr)
+	if err != nil {
+		return SpleeterEngine{}, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
+	}
+
+	return SpleeterEngine{
+		workingDir: workingDir,
+		binPath:    binPath,
+	}, nil
+}
+
+type SpleeterEngine struct {
+	workingDir working_dir.WorkingDir
+	binPath    string
+}
+
+func (s SpleeterEngine) Name() trackentity.SplitEngineType {
+	return trackentity.SpleeterType
+}
+
+func (s SpleeterEngine) Command(sourcePath string, destPath string, splitType splitter.SplitType) (EngineCommand, error) {
+	splitParam, ok := spleeterParamMap[splitType]
+	if !ok {
+		return EngineCommand{}, cerr.Permanent(cerr.Field("split_type", splitType).Error("Invalid split type passed in!"))
+	}
+
+	// spleeter doesn't report how far along it is, the output is only for the logs
+	return EngineCommand{
+		BinPath:    s.binPath,
+		Args:       []string{"separate", "-p", splitParam, "-o", destPath, "-c", "mp3", "-b", "320k", "-f", "{instrument}.mp3", sourcePath},
+		WorkingDir: s.workingDir.Root(),
+	}, nil
+}
+
+func (s SpleeterEngine) CollectOutput(_ string, destPath string, _ splitter.SplitType) (splitter.StemFilePaths, error) {
+	return collectStemFilePaths(destPath)
+}
@@ -0,0 +1,87 @@
+package splitter
+
+import (
+	"context"
+	"github.com/apex/log"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+// ProgressReporter is told how far along a split is, from 0 to 100
+type ProgressReporter func(percent int)
+
+// the split is the part of the job between these two points of the track's progress
+var (
+	splitStartProgress = trackentity.SplitStage.ProgressBefore()
+	splitEndProgress   = trackentity.SplitStage.ProgressAfter()
+)
+
+const (
+	// progress is only saved when it moves by at least this much,
+	// which keeps a split down to a handful of writes to the track store
+	progressSaveStep = 5
+)
+
+type progressUpdater struct {
+	ctx            context.Context
+	trackStore     trackentity.Store
+	eventPublisher trackentity.EventPublisher
+	tracklistID    string
+	trackID        string
+	savedProgress  int
+}
+
+func (p *progressUpdater) report(percent int) {
+	progress := splitStartProgress + (splitEndProgress-splitStartProgress)*percent/100
+	if progress > splitEndProgress {
+		progress = splitEndProgress
+	}
+
+	if progress-p.savedProgress < progressSaveStep {
+		return
+	}
+
+	// failing to save progress shouldn't stop the split, the next step will try again
+	if err := p.save(progress); err != nil {
+		log.WithError(err).Error("Failed to save split progress")
+		return
+	}
+
+	p.savedProgress = progress
+}
+
+func (p *progressUpdater) save(progress int) error {
+	var statusMessage string
+	updater := func(track trackentity.Track) (trackentity.Track, error) {
+		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok {
+			return nil, cerr.Error("Track from DB is not a split stem track")
+		}
+
+		if splitStemTrack.Status == trackentity.CancelledStatus {
+			return nil, cerr.Error("Track has been cancelled")
+		}
+
+		statusMessage = splitStemTrack.StatusMessage
+		splitStemTrack.Progress = progress
+		return splitStemTrack, nil
+	}
+
+	if err := p.trackStore.UpdateTrack(p.ctx, p.tracklistID, p.trackID, updater); err != nil {
+		return cerr.Wrap(err).Error("Failed to update track progress")
+	}
+
+	err := p.eventPublisher.PublishTrackEvent(p.ctx, trackentity.TrackEvent{
+		TrackListID:   p.tracklistID,
+		TrackID:       p.trackID,
+		Type:          trackentity.ProgressEventType,
+		Status:        trackentity.ProcessingStatus,
+		StatusMessage: statusMessage,
+		Progress:      progress,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to publish split progress event")
+	}
+
+	return nil
+}
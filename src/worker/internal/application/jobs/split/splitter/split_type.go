@@ -12,8 +12,25 @@ const (
 	SplitTwoStemsType  SplitType = "2stems"
 	SplitFourStemsType SplitType = "4stems"
 	SplitFiveStemsType SplitType = "5stems"
+	SplitSixStemsType  SplitType = "6stems"
 )
 
+// TrackType is the split request type that asks for the split type
+func (s SplitType) TrackType() (trackentity.SplitRequestType, bool) {
+	switch s {
+	case SplitTwoStemsType:
+		return trackentity.SplitTwoStemsType, true
+	case SplitFourStemsType:
+		return trackentity.SplitFourStemsType, true
+	case SplitFiveStemsType:
+		return trackentity.SplitFiveStemsType, true
+	case SplitSixStemsType:
+		return trackentity.SplitSixStemsType, true
+	default:
+		return "", false
+	}
+}
+
 func ConvertToSplitType(trackType trackentity.SplitRequestType) (SplitType, error) {
 	switch trackType {
 	case trackentity.SplitTwoStemsType:
@@ -22,6 +39,8 @@ func ConvertToSplitType(trackType trackentity.SplitRequestType) (SplitType, erro
 		return SplitFourStemsType, nil
 	case trackentity.SplitFiveStemsType:
 		return SplitFiveStemsType, nil
+	case trackentity.SplitSixStemsType:
+		return SplitSixStemsType, nil
 	default:
 		return InvalidSplitType,
 			cerr.Field("track_type", trackType).Error("Value does not match any split type")
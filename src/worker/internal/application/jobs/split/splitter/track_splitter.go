@@ -11,19 +11,22 @@ var splitDirNames = map[SplitType]string{
 	SplitTwoStemsType:  "2stems",
 	SplitFourStemsType: "4stems",
 	SplitFiveStemsType: "5stems",
+	SplitSixStemsType:  "6stems",
 }
 
 type TrackSplitter struct {
-	trackStore    trackentity.Store
-	splitter      FileSplitter
-	pathGenerator storagepath.Generator
+	trackStore     trackentity.Store
+	eventPublisher trackentity.EventPublisher
+	splitter       FileSplitter
+	pathGenerator  storagepath.Generator
 }
 
-func NewTrackSplitter(splitter FileSplitter, trackStore trackentity.Store, pathGenerator storagepath.Generator) TrackSplitter {
+func NewTrackSplitter(splitter FileSplitter, trackStore trackentity.Store, eventPublisher trackentity.EventPublisher, pathGenerator storagepath.Generator) TrackSplitter {
 	return TrackSplitter{
-		trackStore:    trackStore,
-		splitter:      splitter,
-		pathGenerator: pathGenerator,
+		trackStore:     trackStore,
+		eventPublisher: eventPublisher,
+		splitter:       splitter,
+		pathGenerator:  pathGenerator,
 	}
 }
 
@@ -41,12 +44,13 @@ func (t TrackSplitter) SplitTrack(ctx context.Context, tracklistID string, track
 
 	track, err := tracklist.GetTrack(trackID)
 	if err != nil {
-		return nil, errctx.Wrap(err).Error("Failed to get track from tracklist")
+		// the track has been removed from the tracklist, it's not coming back
+		return nil, cerr.Permanent(errctx.Wrap(err).Error("Failed to get track from tracklist"))
 	}
 
 	splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
 	if !ok {
-		return nil, errctx.Error("Unexpected: track is not a split request")
+		return nil, cerr.Permanent(errctx.Error("Unexpected: track is not a split request"))
 	}
 
 	errctx = errctx.Field("track", splitStemTrack)
@@ -62,13 +66,22 @@ func (t TrackSplitter) SplitTrack(ctx context.Context, tracklistID string, track
 			Wrap(err).Error("Failed to generate a destination path for stem tracks")
 	}
 
-	return t.splitter.SplitFile(ctx, savedOriginalURL, destPath, splitType, splitStemTrack.EngineType)
+	progress := progressUpdater{
+		ctx:            ctx,
+		trackStore:     t.trackStore,
+		eventPublisher: t.eventPublisher,
+		tracklistID:    tracklistID,
+		trackID:        trackID,
+		savedProgress:  splitStemTrack.Progress,
+	}
+
+	return t.splitter.SplitFile(ctx, savedOriginalURL, destPath, splitType, splitStemTrack.EngineType, progress.report)
 }
 
 func (t TrackSplitter) generatePath(tracklistID string, trackID string, splitType SplitType) (string, error) {
 	splitDir, ok := splitDirNames[splitType]
 	if !ok {
-		return "", cerr.Error("Invalid split type provided")
+		return "", cerr.Permanent(cerr.Error("Invalid split type provided"))
 	}
 
 	return t.pathGenerator.GeneratePath(tracklistID, trackID, splitDir), nil
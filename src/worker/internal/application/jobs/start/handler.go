@@ -10,7 +10,7 @@ import (
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
-const JobType string = "start_job"
+const JobType = string(trackentity.StartStage)
 const ErrorMessage string = "Failed to start processing audio splitting"
 
 //counterfeiter:generate . StartJobHandler
@@ -35,7 +35,7 @@ type JobHandler struct {
 func (d JobHandler) HandleStartJob(message []byte) (JobParams, error) {
 	params, err := unmarshalMessage(message)
 	if err != nil {
-		return JobParams{}, cerr.Wrap(err).Error("Failed to unmarshal message JSON")
+		return JobParams{}, cerr.Permanent(cerr.Wrap(err).Error("Failed to unmarshal message JSON"))
 	}
 
 	errCtx := cerr.Field("tracklist_id", params.TrackListID).
@@ -44,11 +44,16 @@ func (d JobHandler) HandleStartJob(message []byte) (JobParams, error) {
 	updater := func(track trackentity.Track) (trackentity.Track, error) {
 		splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
 		if !ok {
-			return nil, errCtx.Error("Track from DB is not a split stem track")
+			return nil, cerr.Permanent(errCtx.Error("Track from DB is not a split stem track"))
+		}
+
+		// a redelivered start job finds the track already started, which is all it would have done
+		if splitStemTrack.Status == trackentity.ProcessingStatus {
+			return splitStemTrack, nil
 		}
 
 		if splitStemTrack.Status != trackentity.RequestedStatus {
-			return nil, errCtx.Error("Track is not in requested status, abort processing to be safe")
+			return nil, cerr.Permanent(errCtx.Error("Track is not in requested status, abort processing to be safe"))
 		}
 
 		splitStemTrack.Status = trackentity.ProcessingStatus
@@ -101,6 +101,22 @@ var _ = Describe("Start", func() {
 			})
 		})
 
+		Describe("Job redelivered after the track was started", func() {
+			BeforeEach(func() {
+				err := dummyTrackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitRequest := track.(*trackentity.SplitRequestTrack)
+					splitRequest.Status = trackentity.ProcessingStatus
+					return splitRequest, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("doesn't return an error", func() {
+				_, err := handler.HandleStartJob(message)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		Describe("Can't reach track store", func() {
 			BeforeEach(func() {
 				dummyTrackStore.Unavailable = true
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/veedubyou/chord-paper-be/src/shared/config/prod"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
@@ -18,7 +17,6 @@ import (
 var _ = Describe("Download Job Handler", func() {
 	var (
 		youtubeDLBinPath string
-		bucketName       string
 
 		dummyTrackStore *dummy.TrackStore
 		dummyFileStore  *dummy.FileStore
@@ -38,7 +36,6 @@ var _ = Describe("Download Job Handler", func() {
 		By("Initializing all variables", func() {
 			message = nil
 			youtubeDLBinPath = "/bin/youtube-dl"
-			bucketName = "bucket-head"
 
 			tracklistID = "tracklist-id"
 			trackID = "track-id"
@@ -74,14 +71,11 @@ var _ = Describe("Download Job Handler", func() {
 			genericDownloader := download.NewGenericDLer()
 			selectDownloader := download.NewSelectDLer(youtubeDownloader, genericDownloader)
 
-			pathGenerator := storagepath.Generator{
-				Host:   prod.GOOGLE_STORAGE_HOST,
-				Bucket: bucketName,
-			}
+			pathGenerator := storagepath.Generator{}
 			trackDownloader, err := transfer.NewTrackTransferrer(selectDownloader, dummyTrackStore, dummyFileStore, pathGenerator, workingDir)
 			Expect(err).NotTo(HaveOccurred())
 
-			handler = transfer.NewJobHandler(trackDownloader)
+			handler = transfer.NewJobHandler(trackDownloader, dummyTrackStore)
 		})
 	})
 
@@ -108,7 +102,7 @@ var _ = Describe("Download Job Handler", func() {
 
 			BeforeEach(func() {
 				jobParams, savedOriginalURL, err = handler.HandleTransferJob(message)
-				expectedSavedURL = fmt.Sprintf("%s/%s/%s/%s/original/original.mp3", prod.GOOGLE_STORAGE_HOST, bucketName, tracklistID, trackID)
+				expectedSavedURL = fmt.Sprintf("%s/%s/original/original.mp3", tracklistID, trackID)
 			})
 
 			It("doesn't return an error", func() {
@@ -128,6 +122,38 @@ var _ = Describe("Download Job Handler", func() {
 			})
 		})
 
+		Describe("Job redelivered after the original was transferred", func() {
+			var err error
+			var savedOriginalURL string
+			alreadySavedURL := "tracklist-id/track-id/original/already-saved.mp3"
+
+			BeforeEach(func() {
+				err = dummyTrackStore.UpdateTrack(context.Background(), tracklistID, trackID, func(track trackentity.Track) (trackentity.Track, error) {
+					splitRequest := track.(*trackentity.SplitRequestTrack)
+					splitRequest.CompletedStage = trackentity.TransferStage
+					splitRequest.SavedOriginalURL = alreadySavedURL
+					return splitRequest, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, savedOriginalURL, err = handler.HandleTransferJob(message)
+			})
+
+			It("doesn't return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the original that was already saved", func() {
+				Expect(savedOriginalURL).To(Equal(alreadySavedURL))
+			})
+
+			It("doesn't download the track again", func() {
+				expectedSavedURL := fmt.Sprintf("%s/%s/original/original.mp3", tracklistID, trackID)
+				_, err := dummyFileStore.GetFile(context.Background(), expectedSavedURL)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
 		Describe("Can't reach track store", func() {
 			BeforeEach(func() {
 				dummyTrackStore.Unavailable = true
@@ -1,14 +1,17 @@
 package transfer
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/apex/log"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
-const JobType string = "transfer_original"
+const JobType = string(trackentity.TransferStage)
 const ErrorMessage string = "Failed to download source audio for processing"
 
 type JobParams struct {
@@ -20,24 +23,37 @@ type TransferJobHandler interface {
 	HandleTransferJob(message []byte) (JobParams, string, error)
 }
 
-func NewJobHandler(downloader TrackTransferrer) JobHandler {
+func NewJobHandler(downloader TrackTransferrer, trackStore trackentity.Store) JobHandler {
 	return JobHandler{
 		trackDownloader: downloader,
+		trackStore:      trackStore,
 	}
 }
 
 type JobHandler struct {
 	trackDownloader TrackTransferrer
+	trackStore      trackentity.Store
 }
 
 func (d JobHandler) HandleTransferJob(message []byte) (JobParams, string, error) {
 	params, err := unmarshalMessage(message)
 	if err != nil {
-		return JobParams{}, "", cerr.Wrap(err).Error("Failed to unmarshal message JSON")
+		return JobParams{}, "", cerr.Permanent(cerr.Wrap(err).Error("Failed to unmarshal message JSON"))
 	}
 
 	errctx := cerr.Field("params", params)
 
+	splitRequest, err := job_message.GetSplitRequest(context.Background(), d.trackStore, params.TrackIdentifier)
+	if err != nil {
+		return JobParams{}, "", errctx.Wrap(err).Error("Failed to look up the split request")
+	}
+
+	// a redelivered job gets the original that's already been saved, rather than downloading it again
+	if splitRequest.HasCompleted(trackentity.TransferStage) && splitRequest.SavedOriginalURL != "" {
+		log.WithField("track_id", params.TrackID).Info("Original has already been transferred, skipping")
+		return params, splitRequest.SavedOriginalURL, nil
+	}
+
 	savedOriginalURL, err := d.trackDownloader.Download(params.TrackListID, params.TrackID)
 	if err != nil {
 		return JobParams{}, "", errctx.Wrap(err).Error("Failed to download track")
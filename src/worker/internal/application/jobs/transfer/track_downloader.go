@@ -1,8 +1,8 @@
 package transfer
 
 import (
+	cloudstorage "github.com/veedubyou/chord-paper-be/src/shared/cloud_storage/entity"
 	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
-	cloudstorage "github.com/veedubyou/chord-paper-be/src/worker/internal/application/cloud_storage/entity"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer/download"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/storagepath"
@@ -49,12 +49,13 @@ func (t TrackTransferrer) Download(tracklistID string, trackID string) (string,
 
 	track, err := tracklist.GetTrack(trackID)
 	if err != nil {
-		return "", errctx.Wrap(err).Error("Failed to GetTrack")
+		// the track has been removed from the tracklist, it's not coming back
+		return "", cerr.Permanent(errctx.Wrap(err).Error("Failed to GetTrack"))
 	}
 
 	splitStemTrack, ok := track.(*trackentity.SplitRequestTrack)
 	if !ok {
-		return "", errctx.Wrap(err).Error("Unexpected - track is not a split request")
+		return "", cerr.Permanent(errctx.Wrap(err).Error("Unexpected - track is not a split request"))
 	}
 
 	tempFilePath, cleanUpTempDir, err := t.makeTempOutFilePath()
@@ -70,16 +71,18 @@ func (t TrackTransferrer) Download(tracklistID string, trackID string) (string,
 			Wrap(err).Error("Failed to download track to cloud")
 	}
 
-	log.Info("Reading output file to memory")
-	fileContent, err := os.ReadFile(tempFilePath)
+	log.Info("Opening output file")
+	file, err := os.Open(tempFilePath)
 	if err != nil {
-		return "", errctx.Wrap(err).Error("Failed to read outputed youtubedl mp3")
+		return "", errctx.Wrap(err).Error("Failed to open outputed youtubedl mp3")
 	}
 
+	defer file.Close()
+
 	destinationURL := t.pathGenerator.GeneratePath(tracklistID, trackID, "original/original.mp3")
 
-	log.Info("Writing file to remote file store")
-	err = t.fileStore.WriteFile(context.Background(), destinationURL, fileContent)
+	log.Info("Uploading file to remote file store")
+	err = t.fileStore.UploadFile(context.Background(), destinationURL, file)
 	if err != nil {
 		return "", errctx.Wrap(err).Error("Failed to write file to the cloud")
 	}
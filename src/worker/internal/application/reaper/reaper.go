@@ -0,0 +1,235 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"time"
+)
+
+const StuckStatusMessage = "The splitting job stopped making progress. Please try again"
+
+// Policy decides when a split request counts as stuck, and what's done about it
+type Policy struct {
+	// how long a split request can go without any progress, once a worker has picked up its stage,
+	// before it's stuck. Time spent waiting in the queue for a worker doesn't count
+	StuckAfter time.Duration
+	// how long a split request's job can wait in the queue without a worker picking it up before
+	// it's stuck, which is what happens when the job message is lost. It's longer than StuckAfter,
+	// as a split can be queued up behind many others
+	QueuedStuckAfter time.Duration
+	// how many times a stuck split request has its job queued up again before it's marked as errored
+	MaxRequeues int
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		// a long split only updates its progress every so often
+		StuckAfter:       time.Hour,
+		QueuedStuckAfter: 6 * time.Hour,
+		MaxRequeues:      1,
+	}
+}
+
+// Reaper looks for split requests that were left in progress after a worker crashed
+// or their job message got lost. Their job is queued up again, and if that doesn't
+// get them going either, they're marked as errored so that they can be retried
+type Reaper struct {
+	trackStore     trackentity.ConditionalTrackUpdater
+	activeSplits   trackentity.ActiveSplitFinder
+	publisher      rabbitmq.Publisher
+	eventPublisher trackentity.EventPublisher
+	pipeline       pipeline.Pipeline
+	policy         Policy
+}
+
+func NewReaper(trackStore trackentity.ConditionalTrackUpdater, activeSplits trackentity.ActiveSplitFinder, publisher rabbitmq.Publisher, eventPublisher trackentity.EventPublisher, jobPipeline pipeline.Pipeline, policy Policy) Reaper {
+	return Reaper{
+		trackStore:     trackStore,
+		activeSplits:   activeSplits,
+		publisher:      publisher,
+		eventPublisher: eventPublisher,
+		pipeline:       jobPipeline,
+		policy:         policy,
+	}
+}
+
+// Run sweeps for stuck split requests every interval until the context is done
+func (r Reaper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sweep(ctx); err != nil {
+			cerr.Log(cerr.Wrap(err).Error("Failed to sweep for stuck split requests"))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep goes through the split requests in progress once, dealing with the ones that are stuck
+func (r Reaper) Sweep(ctx context.Context) error {
+	tracklists, err := r.activeSplits.GetTrackListsWithActiveSplits(ctx)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to get tracklists with active splits")
+	}
+
+	for _, tracklist := range tracklists {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		r.sweepTrackList(ctx, tracklist)
+	}
+
+	return nil
+}
+
+func (r Reaper) sweepTrackList(ctx context.Context, tracklist trackentity.TrackList) {
+	tracklistID := tracklist.Defined.SongID
+	activeSplits := 0
+
+	for _, track := range tracklist.Defined.Tracks {
+		splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok || !splitRequest.IsInProgress() {
+			continue
+		}
+
+		activeSplits++
+
+		if !r.isStuck(*splitRequest) {
+			continue
+		}
+
+		if err := r.reap(ctx, tracklistID, splitRequest.ID); err != nil {
+			cerr.Log(cerr.Field("tracklist_id", tracklistID).
+				Field("track_id", splitRequest.ID).
+				Wrap(err).
+				Error("Failed to deal with stuck split request"))
+		}
+	}
+
+	if activeSplits == 0 {
+		if err := r.activeSplits.ClearActiveSplits(ctx, tracklistID); err != nil {
+			cerr.Log(cerr.Field("tracklist_id", tracklistID).Wrap(err).Error("Failed to clear active splits"))
+		}
+	}
+}
+
+// isStuck counts from when a worker picked up the stage. A split request that's waiting its
+// turn in the queue counts from when it last moved along, with the longer queued threshold
+func (r Reaper) isStuck(splitRequest trackentity.SplitRequestTrack) bool {
+	if !splitRequest.IsInProgress() {
+		return false
+	}
+
+	if splitRequest.StageStartedAt == nil {
+		// there's no telling how long a split request from before the time was kept has been waiting
+		if splitRequest.LastUpdatedAt == nil {
+			return false
+		}
+
+		return time.Since(*splitRequest.LastUpdatedAt) >= r.policy.QueuedStuckAfter
+	}
+
+	lastActiveAt := *splitRequest.StageStartedAt
+	if splitRequest.LastUpdatedAt != nil && splitRequest.LastUpdatedAt.After(lastActiveAt) {
+		lastActiveAt = *splitRequest.LastUpdatedAt
+	}
+
+	return time.Since(lastActiveAt) >= r.policy.StuckAfter
+}
+
+func (r Reaper) reap(ctx context.Context, tracklistID string, trackID string) error {
+	logger := log.WithFields(log.Fields{
+		"tracklist_id": tracklistID,
+		"track_id":     trackID,
+	})
+
+	var reaped trackentity.SplitRequestTrack
+	stillStuck := true
+	requeue := false
+
+	updater := func(track trackentity.Track) (trackentity.Track, error) {
+		splitRequest, ok := track.(*trackentity.SplitRequestTrack)
+		if !ok {
+			return nil, cerr.Error("Track from DB is not a split stem track")
+		}
+
+		// it could have moved along since the sweep started
+		if !r.isStuck(*splitRequest) {
+			stillStuck = false
+			return nil, cerr.Error("Split request is no longer stuck")
+		}
+
+		if splitRequest.ReapCount < r.policy.MaxRequeues {
+			requeue = true
+			splitRequest.ReapCount++
+			// the requeued job is back in the queue, and gets the full time to make progress once it's picked up.
+			// If it's lost as well, the queued threshold catches it again
+			splitRequest.StageStartedAt = nil
+			splitRequest.Touch(time.Now())
+		} else {
+			splitRequest.Status = trackentity.ErrorStatus
+			splitRequest.StatusMessage = StuckStatusMessage
+			splitRequest.StatusDebugLog = fmt.Sprintf("No progress since %s, after being requeued %d times",
+				splitRequest.LastUpdatedAt.Format(time.RFC3339), splitRequest.ReapCount)
+		}
+
+		reaped = *splitRequest
+		return splitRequest, nil
+	}
+
+	// every worker sweeps, so the update only goes through if no one else has reaped it in the meantime
+	err := r.trackStore.UpdateTrackIfUnchanged(ctx, tracklistID, trackID, updater)
+	if !stillStuck || errors.Is(err, trackentity.ErrTrackChanged) {
+		return nil
+	}
+
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to update stuck split request")
+	}
+
+	if !requeue {
+		logger.Warn("Split request is stuck, marked it as errored")
+		r.publishErrorEvent(tracklistID, trackID)
+		return nil
+	}
+
+	jobMsg, err := r.pipeline.ResumeJobMessage(tracklistID, reaped)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to create job message for stuck split request")
+	}
+
+	if err := r.publisher.Publish(jobMsg); err != nil {
+		return cerr.Field("job_type", jobMsg.Type).Wrap(err).Error("Failed to requeue job for stuck split request")
+	}
+
+	logger.WithField("job_type", jobMsg.Type).Warn("Split request is stuck, requeued its job")
+	return nil
+}
+
+// the track is already up to date, so failing to publish isn't a failure of the sweep
+func (r Reaper) publishErrorEvent(tracklistID string, trackID string) {
+	event := trackentity.TrackEvent{
+		TrackListID:   tracklistID,
+		TrackID:       trackID,
+		Type:          trackentity.ErrorEventType,
+		Status:        trackentity.ErrorStatus,
+		StatusMessage: StuckStatusMessage,
+	}
+
+	if err := r.eventPublisher.PublishTrackEvent(context.Background(), event); err != nil {
+		log.WithError(err).WithField("event", event).Error("Failed to publish track event")
+	}
+}
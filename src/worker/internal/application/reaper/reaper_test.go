@@ -0,0 +1,253 @@
+package reaper_test
+
+import (
+	"context"
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	trackevents "github.com/veedubyou/chord-paper-be/src/shared/track/events"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/pipeline"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/reaper"
+	"time"
+)
+
+var _ = Describe("Reaper", func() {
+	const (
+		tracklistID      = "tracklist-id"
+		trackID          = "track-id"
+		savedOriginalURL = "https://cloud-storage/original.mp3"
+	)
+
+	var (
+		dummyTrackStore *dummy.TrackStore
+		rabbitMQ        *dummy.RabbitMQ
+		eventBus        *trackevents.Bus
+		events          <-chan trackentity.TrackEvent
+		unsubscribe     func()
+
+		splitRequest *trackentity.SplitRequestTrack
+
+		stuckJobReaper   reaper.Reaper
+		storeUnavailable bool
+		sweepErr         error
+	)
+
+	var timeAgo = func(duration time.Duration) *time.Time {
+		t := time.Now().Add(-duration)
+		return &t
+	}
+
+	var getStoredSplitRequest = func() *trackentity.SplitRequestTrack {
+		tracklist, err := dummyTrackStore.GetTrackList(context.Background(), tracklistID)
+		Expect(err).NotTo(HaveOccurred())
+		track, err := tracklist.GetTrack(trackID)
+		Expect(err).NotTo(HaveOccurred())
+		stored, ok := track.(*trackentity.SplitRequestTrack)
+		Expect(ok).To(BeTrue())
+		return stored
+	}
+
+	BeforeEach(func() {
+		dummyTrackStore = dummy.NewDummyTrackStore()
+		storeUnavailable = false
+		rabbitMQ = dummy.NewRabbitMQ()
+		eventBus = trackevents.NewBus()
+		events, unsubscribe = eventBus.Subscribe(tracklistID)
+
+		splitRequest = &trackentity.SplitRequestTrack{
+			TrackFields:      trackentity.TrackFields{ID: trackID},
+			TrackType:        trackentity.SplitFourStemsType,
+			OriginalURL:      "https://www.youtube.com/watch?v=jenWdylTtzs",
+			Status:           trackentity.ProcessingStatus,
+			StatusMessage:    "Splitting the track into stems",
+			Progress:         30,
+			CompletedStage:   trackentity.TransferStage,
+			SavedOriginalURL: savedOriginalURL,
+		}
+
+		stuckJobReaper = reaper.NewReaper(dummyTrackStore, dummyTrackStore, rabbitMQ, eventBus, pipeline.NewSplitPipeline(pipeline.SplitHandlers{}), reaper.Policy{
+			StuckAfter:       time.Hour,
+			QueuedStuckAfter: 3 * time.Hour,
+			MaxRequeues:      1,
+		})
+	})
+
+	JustBeforeEach(func() {
+		tracklist := trackentity.TrackList{}
+		tracklist.Defined.SongID = tracklistID
+		tracklist.Defined.Tracks = trackentity.Tracks{splitRequest}
+		Expect(dummyTrackStore.SetTrackList(context.Background(), tracklist)).To(Succeed())
+
+		dummyTrackStore.Unavailable = storeUnavailable
+		sweepErr = stuckJobReaper.Sweep(context.Background())
+	})
+
+	AfterEach(func() {
+		unsubscribe()
+	})
+
+	var ItLeavesTheJobAlone = func() {
+		It("doesn't queue up any job", func() {
+			Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+		})
+
+		It("doesn't error the track", func() {
+			Expect(getStoredSplitRequest().Status).To(Equal(trackentity.ProcessingStatus))
+		})
+	}
+
+	Describe("A split request that's still making progress", func() {
+		BeforeEach(func() {
+			splitRequest.StageStartedAt = timeAgo(2 * time.Hour)
+			splitRequest.LastUpdatedAt = timeAgo(time.Minute)
+		})
+
+		It("succeeds", func() {
+			Expect(sweepErr).NotTo(HaveOccurred())
+		})
+
+		ItLeavesTheJobAlone()
+	})
+
+	Describe("A split request that's waiting its turn in the queue", func() {
+		BeforeEach(func() {
+			splitRequest.StageStartedAt = nil
+			splitRequest.LastUpdatedAt = timeAgo(2 * time.Hour)
+		})
+
+		It("succeeds", func() {
+			Expect(sweepErr).NotTo(HaveOccurred())
+		})
+
+		ItLeavesTheJobAlone()
+	})
+
+	Describe("A split request whose job was lost before a worker picked it up", func() {
+		BeforeEach(func() {
+			splitRequest.StageStartedAt = nil
+			splitRequest.LastUpdatedAt = timeAgo(4 * time.Hour)
+		})
+
+		It("succeeds", func() {
+			Expect(sweepErr).NotTo(HaveOccurred())
+		})
+
+		It("queues up the job for the stage it's on", func() {
+			Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
+
+			job := <-rabbitMQ.MessageChannel
+			Expect(job.Type).To(Equal(split.JobType))
+		})
+
+		Describe("After it's already been requeued", func() {
+			BeforeEach(func() {
+				splitRequest.ReapCount = 1
+			})
+
+			It("doesn't queue up the job again", func() {
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+			})
+
+			It("marks the track as errored", func() {
+				Expect(getStoredSplitRequest().Status).To(Equal(trackentity.ErrorStatus))
+			})
+		})
+	})
+
+	Describe("A split request that has never been updated", func() {
+		BeforeEach(func() {
+			splitRequest.LastUpdatedAt = nil
+		})
+
+		ItLeavesTheJobAlone()
+	})
+
+	Describe("A split request that has stopped", func() {
+		BeforeEach(func() {
+			splitRequest.Status = trackentity.ErrorStatus
+			splitRequest.LastUpdatedAt = timeAgo(2 * time.Hour)
+		})
+
+		It("doesn't queue up any job", func() {
+			Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+		})
+
+		It("leaves the track as it was", func() {
+			Expect(getStoredSplitRequest().ReapCount).To(BeZero())
+		})
+	})
+
+	Describe("A stuck split request", func() {
+		BeforeEach(func() {
+			splitRequest.StageStartedAt = timeAgo(3 * time.Hour)
+			splitRequest.LastUpdatedAt = timeAgo(2 * time.Hour)
+		})
+
+		It("succeeds", func() {
+			Expect(sweepErr).NotTo(HaveOccurred())
+		})
+
+		It("queues up the job for the stage it's on", func() {
+			Expect(rabbitMQ.MessageChannel).To(HaveLen(1))
+			message := <-rabbitMQ.MessageChannel
+			Expect(message.Type).To(Equal(split.JobType))
+
+			var jobParams split.JobParams
+			Expect(json.Unmarshal(message.Body, &jobParams)).To(Succeed())
+			Expect(jobParams.TrackListID).To(Equal(tracklistID))
+			Expect(jobParams.TrackID).To(Equal(trackID))
+			Expect(jobParams.SavedOriginalURL).To(Equal(savedOriginalURL))
+		})
+
+		It("counts the requeue and gives the job a fresh start", func() {
+			stored := getStoredSplitRequest()
+			Expect(stored.ReapCount).To(Equal(1))
+			Expect(stored.Status).To(Equal(trackentity.ProcessingStatus))
+			Expect(*stored.LastUpdatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("puts the job back to waiting in the queue", func() {
+			Expect(getStoredSplitRequest().StageStartedAt).To(BeNil())
+		})
+
+		Describe("After it's already been requeued", func() {
+			BeforeEach(func() {
+				splitRequest.ReapCount = 1
+			})
+
+			It("doesn't queue up the job again", func() {
+				Expect(rabbitMQ.MessageChannel).To(BeEmpty())
+			})
+
+			It("marks the track as errored", func() {
+				stored := getStoredSplitRequest()
+				Expect(stored.Status).To(Equal(trackentity.ErrorStatus))
+				Expect(stored.StatusMessage).To(Equal(reaper.StuckStatusMessage))
+				Expect(stored.StatusDebugLog).NotTo(BeEmpty())
+			})
+
+			It("publishes an error event", func() {
+				Eventually(events).Should(Receive(Equal(trackentity.TrackEvent{
+					TrackListID:   tracklistID,
+					TrackID:       trackID,
+					Type:          trackentity.ErrorEventType,
+					Status:        trackentity.ErrorStatus,
+					StatusMessage: reaper.StuckStatusMessage,
+				})))
+			})
+		})
+
+		Describe("When the track store is unavailable", func() {
+			BeforeEach(func() {
+				storeUnavailable = true
+			})
+
+			It("returns an error", func() {
+				Expect(sweepErr).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,336 @@
+package split_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var _ splitter.FileSplitter = Client{}
+
+var errServiceBusy = errors.New("The split service is already running as many splits as it can")
+
+const (
+	// the longest the client waits between tries while the service is busy or can't be reached
+	maxBusyRetryInterval = time.Minute
+
+	// statusRequestTimeout covers the requests that only send or get back a status
+	statusRequestTimeout = 30 * time.Second
+	// transferTimeout covers uploading the original and downloading a stem, which can be large
+	transferTimeout = 30 * time.Minute
+	// responseHeaderTimeout is how long the service gets to start responding once it has the request
+	responseHeaderTimeout = time.Minute
+)
+
+// NewClient takes how long the service can be unavailable while a split is running on it,
+// before the client gives up on the split
+func NewClient(baseURL string, secret string, pollInterval time.Duration, unavailableTimeout time.Duration) Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+
+	return Client{
+		baseURL:            strings.TrimSuffix(baseURL, "/"),
+		secret:             secret,
+		httpClient:         &http.Client{Transport: transport},
+		pollInterval:       pollInterval,
+		unavailableTimeout: unavailableTimeout,
+	}
+}
+
+// Client splits files by sending them off to the split service,
+// so that the splitting can run on other machines than the worker's
+type Client struct {
+	baseURL            string
+	secret             string
+	httpClient         *http.Client
+	pollInterval       time.Duration
+	unavailableTimeout time.Duration
+}
+
+func (c Client) SplitFile(ctx context.Context, originalFilePath string, stemOutputDir string, splitType splitter.SplitType, engineType trackentity.SplitEngineType, reportProgress splitter.ProgressReporter) (splitter.StemFilePaths, error) {
+	errctx := cerr.Field("split_service_url", c.baseURL).Field("original_filepath", originalFilePath)
+
+	status, err := c.startSplit(ctx, originalFilePath, splitType, engineType)
+	if err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to start the split on the split service")
+	}
+
+	errctx = errctx.Field("split_id", status.ID)
+
+	// the service is done with the split either way, once the stems are downloaded or the job gives up on them
+	defer c.deleteSplit(status.ID)
+
+	status, err = c.waitForSplit(ctx, status, reportProgress)
+	if err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to wait for the split service to split the file")
+	}
+
+	stemFilePaths := splitter.StemFilePaths{}
+	for stemName, fileName := range status.Stems {
+		stemFilePath, err := filepath.Abs(filepath.Join(stemOutputDir, fileName))
+		if err != nil {
+			return nil, errctx.Wrap(err).Error("Failed to convert stem path to absolute format")
+		}
+
+		if err := c.downloadStem(ctx, status.ID, stemName, stemFilePath); err != nil {
+			return nil, errctx.Field("stem", stemName).Wrap(err).Error("Failed to download stem from the split service")
+		}
+
+		stemFilePaths[stemName] = stemFilePath
+	}
+
+	return stemFilePaths, nil
+}
+
+// startSplit waits for its turn while the service is busy. There can be more workers splitting
+// than the service has room for, and failing the job would use up its retries for nothing
+func (c Client) startSplit(ctx context.Context, originalFilePath string, splitType splitter.SplitType, engineType trackentity.SplitEngineType) (SplitStatusResponse, error) {
+	retryInterval := c.pollInterval
+
+	for {
+		status, err := c.uploadOriginal(ctx, originalFilePath, splitType, engineType)
+		if !errors.Is(err, errServiceBusy) {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return SplitStatusResponse{}, cerr.Wrap(ctx.Err()).Error("The split was stopped while waiting for the split service to free up")
+		case <-time.After(retryInterval):
+		}
+
+		retryInterval = nextRetryInterval(retryInterval)
+	}
+}
+
+func nextRetryInterval(retryInterval time.Duration) time.Duration {
+	retryInterval *= 2
+	if retryInterval > maxBusyRetryInterval {
+		return maxBusyRetryInterval
+	}
+
+	return retryInterval
+}
+
+func (c Client) uploadOriginal(ctx context.Context, originalFilePath string, splitType splitter.SplitType, engineType trackentity.SplitEngineType) (SplitStatusResponse, error) {
+	file, err := os.Open(originalFilePath)
+	if err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to open the original file")
+	}
+
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to get the size of the original file")
+	}
+
+	query := url.Values{}
+	query.Set(splitTypeParam, string(splitType))
+	query.Set(engineTypeParam, string(engineType))
+
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+
+	request, err := c.newRequest(ctx, http.MethodPost, splitsPath+"?"+query.Encode(), file)
+	if err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to create the request")
+	}
+
+	// lets the service turn away a file that's too large before it's uploaded
+	request.ContentLength = fileInfo.Size()
+	request.Header.Set("Content-Type", "application/octet-stream")
+
+	return c.doStatusRequest(request, http.StatusAccepted)
+}
+
+// waitForSplit keeps polling through the service being busy or unreachable for a while,
+// since the split carries on regardless and failing the job would throw that work away
+func (c Client) waitForSplit(ctx context.Context, status SplitStatusResponse, reportProgress splitter.ProgressReporter) (SplitStatusResponse, error) {
+	reportedProgress := 0
+	wait := c.pollInterval
+	var unavailableSince *time.Time
+
+	for {
+		if status.Progress > reportedProgress {
+			reportProgress(status.Progress)
+			reportedProgress = status.Progress
+		}
+
+		switch status.Status {
+		case DoneStatus:
+			return status, nil
+		case FailedStatus:
+			err := cerr.Field("split_service_error", status.Error).Error("The split service failed to split the file")
+			if status.Permanent {
+				return SplitStatusResponse{}, cerr.Permanent(err)
+			}
+
+			return SplitStatusResponse{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return SplitStatusResponse{}, cerr.Wrap(ctx.Err()).Error("The split was stopped before it could finish")
+		case <-time.After(wait):
+		}
+
+		polledStatus, err := c.pollSplit(ctx, status.ID)
+		if err == nil {
+			status = polledStatus
+			wait = c.pollInterval
+			unavailableSince = nil
+			continue
+		}
+
+		if cerr.IsPermanent(err) || ctx.Err() != nil {
+			return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to get the split's status")
+		}
+
+		if unavailableSince == nil {
+			now := time.Now()
+			unavailableSince = &now
+		} else if time.Since(*unavailableSince) >= c.unavailableTimeout {
+			return SplitStatusResponse{}, cerr.Field("unavailable_since", *unavailableSince).
+				Wrap(err).Error("Gave up on getting the split's status")
+		}
+
+		cerr.Log(cerr.Wrap(err).Error("Failed to get the split's status, trying again"))
+		wait = nextRetryInterval(wait)
+	}
+}
+
+func (c Client) pollSplit(ctx context.Context, splitID string) (SplitStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusRequestTimeout)
+	defer cancel()
+
+	request, err := c.newRequest(ctx, http.MethodGet, splitPath(splitID), nil)
+	if err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to create the request")
+	}
+
+	return c.doStatusRequest(request, http.StatusOK)
+}
+
+func (c Client) doStatusRequest(request *http.Request, expectedStatusCode int) (SplitStatusResponse, error) {
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to reach the split service")
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusServiceUnavailable {
+		return SplitStatusResponse{}, errServiceBusy
+	}
+
+	if response.StatusCode != expectedStatusCode {
+		return SplitStatusResponse{}, responseError(response)
+	}
+
+	status := SplitStatusResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		return SplitStatusResponse{}, cerr.Wrap(err).Error("Failed to decode the split's status")
+	}
+
+	return status, nil
+}
+
+func (c Client) downloadStem(ctx context.Context, splitID string, stemName string, stemFilePath string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+
+	request, err := c.newRequest(ctx, http.MethodGet, stemPath(splitID, stemName), nil)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to create the request")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to reach the split service")
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(response)
+	}
+
+	file, err := os.Create(stemFilePath)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to create local file")
+	}
+
+	// a failed close can mean the file wasn't fully written
+	defer func() {
+		closeErr := file.Close()
+		if err == nil && closeErr != nil {
+			err = cerr.Wrap(closeErr).Error("Failed to finish writing the stem to disk")
+		}
+	}()
+
+	if _, err := io.Copy(file, response.Body); err != nil {
+		return cerr.Wrap(err).Error("Failed to copy the stem to disk")
+	}
+
+	return nil
+}
+
+// deleteSplit is best effort, as the service removes what's left behind on its own after a while
+func (c Client) deleteSplit(splitID string) {
+	logger := log.WithField("split_id", splitID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusRequestTimeout)
+	defer cancel()
+
+	request, err := c.newRequest(ctx, http.MethodDelete, splitPath(splitID), nil)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create the request to delete the split")
+		return
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		logger.WithError(err).Error("Failed to delete the split from the split service")
+		return
+	}
+
+	_ = response.Body.Close()
+}
+
+func (c Client) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(secretHeader, c.secret)
+	return request, nil
+}
+
+func responseError(response *http.Response) error {
+	errResponse := ErrorResponse{}
+	_ = json.NewDecoder(response.Body).Decode(&errResponse)
+
+	err := cerr.Field("status_code", response.StatusCode).
+		Field("split_service_error", errResponse.Error).
+		Error(fmt.Sprintf("The split service responded with %s", response.Status))
+
+	if errResponse.Permanent {
+		return cerr.Permanent(err)
+	}
+
+	return err
+}
@@ -0,0 +1,52 @@
+package split_service
+
+import "fmt"
+
+// The protocol between the worker and the split service:
+//
+//	POST   /splits?split_type=&engine_type=  the original file as the body, answers with the split's status
+//	GET    /splits/{id}                      the split's status, to poll for progress
+//	GET    /splits/{id}/stems/{stem}         a stem file, once the split is done
+//	DELETE /splits/{id}                      stops the split if it's running, and removes its files
+//
+// Every request carries the secret that the worker and the service share in the secret header
+const splitsPath = "/splits"
+
+const secretHeader = "X-Split-Service-Secret"
+
+const (
+	splitTypeParam  = "split_type"
+	engineTypeParam = "engine_type"
+)
+
+type SplitStatus string
+
+const (
+	RunningStatus SplitStatus = "running"
+	DoneStatus    SplitStatus = "done"
+	FailedStatus  SplitStatus = "failed"
+)
+
+type SplitStatusResponse struct {
+	ID       string      `json:"id"`
+	Status   SplitStatus `json:"status"`
+	Progress int         `json:"progress"`
+	Error    string      `json:"error,omitempty"`
+	// Permanent is whether trying the split again would fail the same way
+	Permanent bool `json:"permanent,omitempty"`
+	// Stems are the file names of the stems by stem name, once the split is done
+	Stems map[string]string `json:"stems,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Permanent bool   `json:"permanent"`
+}
+
+func splitPath(splitID string) string {
+	return fmt.Sprintf("%s/%s", splitsPath, splitID)
+}
+
+func stemPath(splitID string, stemName string) string {
+	return fmt.Sprintf("%s/%s/stems/%s", splitsPath, splitID, stemName)
+}
@@ -0,0 +1,312 @@
+package split_service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/google/uuid"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/working_dir"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ http.Handler = &Server{}
+
+// how long a finished split's files are kept around for a worker that never comes back for them
+const finishedSplitRetention = time.Hour
+
+// maxOriginalBytes is far more than the mp3 of any song, it's only there so an upload can't fill the disk
+const maxOriginalBytes = 500 << 20
+
+func NewServer(fileSplitter splitter.FileSplitter, workingDirStr string, concurrency int, secret string) (*Server, error) {
+	workingDir, err := working_dir.NewWorkingDir(workingDirStr)
+	if err != nil {
+		return nil, cerr.Wrap(err).Error("Failed to convert working dir to absolute format")
+	}
+
+	if concurrency < 1 {
+		return nil, cerr.Field("concurrency", concurrency).Error("The split service has to run at least one split at a time")
+	}
+
+	if secret == "" {
+		return nil, cerr.Error("The split service needs a secret to check the workers' requests against")
+	}
+
+	return &Server{
+		secret:       []byte(secret),
+		fileSplitter: fileSplitter,
+		workingDir:   workingDir,
+		slots:        make(chan struct{}, concurrency),
+		splits:       map[string]*splitRun{},
+	}, nil
+}
+
+// Server runs splits for workers over HTTP, on whatever machine has the hardware for it.
+// The splits run in the background, the workers poll for them to finish
+type Server struct {
+	secret       []byte
+	fileSplitter splitter.FileSplitter
+	workingDir   working_dir.WorkingDir
+	// a split takes up a slot while it runs, the service is busy when they're all taken
+	slots chan struct{}
+
+	mu     sync.Mutex
+	splits map[string]*splitRun
+}
+
+type splitRun struct {
+	dir    string
+	cancel context.CancelFunc
+	// closed once the split has stopped running
+	done chan struct{}
+
+	status    SplitStatus
+	progress  int
+	err       error
+	stemPaths splitter.StemFilePaths
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), s.secret) != 1 {
+		// the worker won't get the secret right by trying again, its config has to change
+		writeError(w, http.StatusUnauthorized, cerr.Error("The request doesn't have the split service's secret"), true)
+		return
+	}
+
+	// /splits, /splits/{id} or /splits/{id}/stems/{stem}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(segments) == 1 && segments[0] == "splits" && r.Method == http.MethodPost:
+		s.startSplit(w, r)
+	case len(segments) == 2 && segments[0] == "splits" && r.Method == http.MethodGet:
+		s.getSplit(w, segments[1])
+	case len(segments) == 2 && segments[0] == "splits" && r.Method == http.MethodDelete:
+		s.deleteSplit(w, segments[1])
+	case len(segments) == 4 && segments[0] == "splits" && segments[2] == "stems" && r.Method == http.MethodGet:
+		s.getStem(w, r, segments[1], segments[3])
+	default:
+		writeError(w, http.StatusNotFound, cerr.Field("path", r.URL.Path).Error("No such route"), true)
+	}
+}
+
+func (s *Server) startSplit(w http.ResponseWriter, r *http.Request) {
+	splitType := splitter.SplitType(r.URL.Query().Get(splitTypeParam))
+	engineType := trackentity.SplitEngineType(r.URL.Query().Get(engineTypeParam))
+	if splitType == splitter.InvalidSplitType || engineType == "" {
+		writeError(w, http.StatusBadRequest, cerr.Error("The split type and engine type are both needed"), true)
+		return
+	}
+
+	if r.ContentLength > maxOriginalBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, cerr.Field("content_length", r.ContentLength).Error("The original file is too large to split"), true)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		// the worker tries again later, by which time a split might have finished
+		writeError(w, http.StatusServiceUnavailable, cerr.Error("The split service is already running as many splits as it can"), false)
+		return
+	}
+
+	splitID := uuid.New().String()
+	logger := log.WithFields(log.Fields{
+		"split_id":    splitID,
+		"split_type":  splitType,
+		"engine_type": engineType,
+	})
+
+	dir := filepath.Join(s.workingDir.TempDir(), "split-"+splitID)
+	originalFilePath := filepath.Join(dir, "original.mp3")
+	if err := saveOriginal(http.MaxBytesReader(w, r.Body, maxOriginalBytes), originalFilePath); err != nil {
+		<-s.slots
+		_ = os.RemoveAll(dir)
+		writeError(w, http.StatusInternalServerError, cerr.Wrap(err).Error("Failed to save the original file"), false)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &splitRun{
+		dir:    dir,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: RunningStatus,
+	}
+
+	s.mu.Lock()
+	s.splits[splitID] = run
+	s.mu.Unlock()
+
+	logger.Info("Starting split")
+
+	go func() {
+		defer func() { <-s.slots }()
+		defer close(run.done)
+
+		reportProgress := func(percent int) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			run.progress = percent
+		}
+
+		stemPaths, err := s.fileSplitter.SplitFile(ctx, originalFilePath, filepath.Join(dir, "stems"), splitType, engineType, reportProgress)
+
+		s.mu.Lock()
+		if err != nil {
+			run.status = FailedStatus
+			run.err = err
+		} else {
+			run.status = DoneStatus
+			run.stemPaths = stemPaths
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			logger.WithError(err).Error("Split failed")
+		} else {
+			logger.Info("Split finished")
+		}
+
+		time.AfterFunc(finishedSplitRetention, func() {
+			s.removeSplit(splitID)
+		})
+	}()
+
+	s.writeStatus(w, http.StatusAccepted, splitID)
+}
+
+func saveOriginal(body io.Reader, originalFilePath string) (err error) {
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(originalFilePath), "stems"), os.ModePerm); err != nil {
+		return cerr.Wrap(err).Error("Failed to create the split's directories")
+	}
+
+	file, err := os.Create(originalFilePath)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to create the original file")
+	}
+
+	// a failed close can mean the file wasn't fully written
+	defer func() {
+		closeErr := file.Close()
+		if err == nil && closeErr != nil {
+			err = cerr.Wrap(closeErr).Error("Failed to finish writing the upload to disk")
+		}
+	}()
+
+	if _, err := io.Copy(file, body); err != nil {
+		return cerr.Wrap(err).Error("Failed to copy the upload to disk")
+	}
+
+	return nil
+}
+
+func (s *Server) getSplit(w http.ResponseWriter, splitID string) {
+	s.writeStatus(w, http.StatusOK, splitID)
+}
+
+func (s *Server) writeStatus(w http.ResponseWriter, statusCode int, splitID string) {
+	s.mu.Lock()
+	run, ok := s.splits[splitID]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, cerr.Field("split_id", splitID).Error("No such split"), true)
+		return
+	}
+
+	response := SplitStatusResponse{
+		ID:       splitID,
+		Status:   run.status,
+		Progress: run.progress,
+	}
+
+	if run.err != nil {
+		response.Error = run.err.Error()
+		response.Permanent = cerr.IsPermanent(run.err)
+	}
+
+	if run.stemPaths != nil {
+		response.Stems = map[string]string{}
+		for stemName, stemFilePath := range run.stemPaths {
+			response.Stems[stemName] = filepath.Base(stemFilePath)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, statusCode, response)
+}
+
+func (s *Server) getStem(w http.ResponseWriter, r *http.Request, splitID string, stemName string) {
+	s.mu.Lock()
+	run, ok := s.splits[splitID]
+	var stemFilePath string
+	if ok {
+		stemFilePath = run.stemPaths[stemName]
+	}
+	s.mu.Unlock()
+
+	if stemFilePath == "" {
+		writeError(w, http.StatusNotFound, cerr.Field("split_id", splitID).Field("stem", stemName).Error("No such stem"), true)
+		return
+	}
+
+	http.ServeFile(w, r, stemFilePath)
+}
+
+func (s *Server) deleteSplit(w http.ResponseWriter, splitID string) {
+	if !s.removeSplit(splitID) {
+		writeError(w, http.StatusNotFound, cerr.Field("split_id", splitID).Error("No such split"), true)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeSplit stops the split if it's still running, and removes its files
+func (s *Server) removeSplit(splitID string) bool {
+	s.mu.Lock()
+	run, ok := s.splits[splitID]
+	delete(s.splits, splitID)
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	run.cancel()
+
+	// the files go once a running split has stopped writing to them
+	go func() {
+		<-run.done
+		if err := os.RemoveAll(run.dir); err != nil {
+			log.WithField("split_id", splitID).WithError(err).Error("Failed to remove split files")
+		}
+	}()
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.WithError(err).Error("Failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error, permanent bool) {
+	writeJSON(w, statusCode, ErrorResponse{
+		Error:     err.Error(),
+		Permanent: permanent,
+	})
+}
@@ -0,0 +1,26 @@
+package split_service_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSplitService(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Split Service Suite")
+}
+
+var workingDir string
+
+var _ = BeforeSuite(func() {
+	workingDir = "./unit_test_wd"
+	err := os.MkdirAll(workingDir, os.ModePerm)
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	_ = os.RemoveAll(workingDir)
+})
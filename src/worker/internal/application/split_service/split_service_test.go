@@ -0,0 +1,331 @@
+package split_service_test
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/executor"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/integration_test/dummy"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split/splitter/file_splitter"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/split_service"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	testSecret             = "test-split-service-secret"
+	testPollInterval       = 10 * time.Millisecond
+	testUnavailableTimeout = 200 * time.Millisecond
+)
+
+var _ = Describe("Split service", func() {
+	var (
+		spleeterExecutor *dummy.SpleeterExecutor
+		demucsExecutor   *dummy.DemucsExecutor
+		server           *split_service.Server
+		service          *httptest.Server
+		serviceDir       string
+		client           split_service.Client
+
+		// failingPolls is how many of the next status polls the service turns away
+		failingPolls     int
+		failingPollsLock sync.Mutex
+
+		originalFilePath string
+		stemOutputDir    string
+		splitType        splitter.SplitType
+		engineType       trackentity.SplitEngineType
+
+		progressLock   sync.Mutex
+		progresses     []int
+		reportProgress = func(percent int) {
+			progressLock.Lock()
+			defer progressLock.Unlock()
+			progresses = append(progresses, percent)
+		}
+	)
+
+	var serviceTempDirEntries = func() []os.DirEntry {
+		entries, err := os.ReadDir(filepath.Join(serviceDir, "tmp"))
+		Expect(err).NotTo(HaveOccurred())
+		return entries
+	}
+
+	BeforeEach(func() {
+		spleeterExecutor = dummy.NewDummySpleeterExecutor()
+		demucsExecutor = dummy.NewDummyDemucsExecutor()
+		progresses = nil
+		failingPolls = 0
+		splitType = splitter.SplitFourStemsType
+		engineType = trackentity.SpleeterType
+
+		By("Starting the service with the fake executor", func() {
+			spleeter, err := file_splitter.NewSpleeterEngine(workingDir, "/somewhere/spleeter")
+			Expect(err).NotTo(HaveOccurred())
+			demucs, err := file_splitter.NewDemucsEngine(workingDir, "/somewhere/demucs")
+			Expect(err).NotTo(HaveOccurred())
+
+			engines, err := file_splitter.NewEngineRegistry(spleeter, demucs)
+			Expect(err).NotTo(HaveOccurred())
+
+			splitExecutor := dummy.NewDummySplitExecutor(map[string]executor.Executor{
+				"/somewhere/spleeter": spleeterExecutor,
+				"/somewhere/demucs":   demucsExecutor,
+			})
+
+			serviceDir = filepath.Join(workingDir, "service")
+			server, err = split_service.NewServer(file_splitter.NewLocalFileSplitter(engines, splitExecutor), serviceDir, 1, testSecret)
+			Expect(err).NotTo(HaveOccurred())
+
+			service = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				isStatusPoll := r.Method == http.MethodGet && !strings.Contains(r.URL.Path, "/stems/")
+
+				failingPollsLock.Lock()
+				failPoll := isStatusPoll && failingPolls > 0
+				if failPoll {
+					failingPolls--
+				}
+				failingPollsLock.Unlock()
+
+				if failPoll {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				server.ServeHTTP(w, r)
+			}))
+			client = split_service.NewClient(service.URL, testSecret, testPollInterval, testUnavailableTimeout)
+		})
+
+		By("Setting up the worker's side of the files", func() {
+			workerDir := filepath.Join(workingDir, "worker")
+			stemOutputDir = filepath.Join(workerDir, "stems")
+			Expect(os.MkdirAll(stemOutputDir, os.ModePerm)).To(Succeed())
+
+			originalFilePath = filepath.Join(workerDir, "original.mp3")
+			Expect(os.WriteFile(originalFilePath, []byte("cool_jamz"), os.ModePerm)).To(Succeed())
+		})
+	})
+
+	AfterEach(func() {
+		service.Close()
+		Expect(os.RemoveAll(filepath.Join(workingDir, "worker"))).To(Succeed())
+	})
+
+	Describe("A split that goes through", func() {
+		var (
+			stemFilePaths splitter.StemFilePaths
+			err           error
+		)
+
+		JustBeforeEach(func() {
+			stemFilePaths, err = client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+		})
+
+		It("succeeds", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("downloads the stems into the output dir", func() {
+			Expect(stemFilePaths).To(HaveLen(4))
+			for _, stemName := range []string{"vocals", "other", "bass", "drums"} {
+				stemFilePath := stemFilePaths[stemName]
+				Expect(filepath.Dir(stemFilePath)).To(Equal(testAbs(stemOutputDir)))
+
+				contents, err := os.ReadFile(stemFilePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("cool_jamz-" + stemName))
+			}
+		})
+
+		It("cleans up after itself on the service", func() {
+			Eventually(serviceTempDirEntries).Should(BeEmpty())
+		})
+
+		Describe("With an engine that reports its progress", func() {
+			BeforeEach(func() {
+				engineType = trackentity.DemucsType
+				demucsExecutor.OutputLines = []string{
+					" 50%|█████     | 29.25/58.5 [00:12<00:12,  2.41seconds/s]",
+					"100%|██████████| 58.5/58.5 [00:24<00:00,  2.40seconds/s]",
+				}
+			})
+
+			It("passes the progress on", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(progresses).NotTo(BeEmpty())
+				Expect(progresses[len(progresses)-1]).To(Equal(100))
+			})
+		})
+	})
+
+	Describe("A split the service can't do", func() {
+		BeforeEach(func() {
+			engineType = trackentity.DemucsV3Type
+		})
+
+		It("fails without retrying", func() {
+			_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeTrue())
+		})
+	})
+
+	Describe("A split that fails along the way", func() {
+		BeforeEach(func() {
+			spleeterExecutor.Unavailable = true
+		})
+
+		It("fails so that it can be retried", func() {
+			_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeFalse())
+		})
+	})
+
+	Describe("When the service can't be reached", func() {
+		BeforeEach(func() {
+			client = split_service.NewClient("http://127.0.0.1:1", testSecret, testPollInterval, testUnavailableTimeout)
+		})
+
+		It("fails so that it can be retried", func() {
+			_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeFalse())
+		})
+	})
+
+	Describe("When checking on the split fails for a little while", func() {
+		BeforeEach(func() {
+			failingPolls = 3
+		})
+
+		It("keeps checking until the split goes through", func() {
+			stemFilePaths, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stemFilePaths).To(HaveLen(4))
+		})
+	})
+
+	Describe("When checking on the split keeps failing", func() {
+		BeforeEach(func() {
+			failingPolls = 1000
+		})
+
+		It("gives up so that it can be retried", func() {
+			_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeFalse())
+		})
+
+		It("cleans up after itself on the service", func() {
+			_, _ = client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Eventually(serviceTempDirEntries).Should(BeEmpty())
+		})
+	})
+
+	Describe("A split that's stopped before it finishes", func() {
+		BeforeEach(func() {
+			spleeterExecutor.Hang = true
+		})
+
+		It("stops the split on the service", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			_, err := client.SplitFile(ctx, originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Eventually(serviceTempDirEntries).Should(BeEmpty())
+		})
+	})
+
+	Describe("Without the service's secret", func() {
+		BeforeEach(func() {
+			client = split_service.NewClient(service.URL, "not-the-secret", testPollInterval, testUnavailableTimeout)
+		})
+
+		It("fails without retrying", func() {
+			_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeTrue())
+		})
+
+		It("doesn't take the upload", func() {
+			_, _ = client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(serviceTempDirEntries()).To(BeEmpty())
+		})
+	})
+
+	Describe("An original that's too large", func() {
+		It("is turned away before it's uploaded", func() {
+			request := httptest.NewRequest(http.MethodPost, "/splits?split_type=4stems&engine_type=spleeter", strings.NewReader("cool_jamz"))
+			request.Header.Set("X-Split-Service-Secret", testSecret)
+			request.ContentLength = 1 << 40
+
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+
+			Expect(response.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(serviceTempDirEntries()).To(BeEmpty())
+		})
+	})
+
+	Describe("When the service is already busy", func() {
+		var stopFirstSplit context.CancelFunc
+
+		BeforeEach(func() {
+			demucsExecutor.Hang = true
+
+			var ctx context.Context
+			ctx, stopFirstSplit = context.WithCancel(context.Background())
+			go func() {
+				defer GinkgoRecover()
+				_, _ = client.SplitFile(ctx, originalFilePath, stemOutputDir, splitType, trackentity.DemucsType, reportProgress)
+			}()
+
+			Eventually(serviceTempDirEntries).Should(HaveLen(1))
+		})
+
+		AfterEach(func() {
+			stopFirstSplit()
+			Eventually(serviceTempDirEntries).Should(BeEmpty())
+		})
+
+		It("waits for the service to free up rather than failing", func() {
+			splitErrs := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := client.SplitFile(context.Background(), originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+				splitErrs <- err
+			}()
+
+			Consistently(splitErrs, 100*time.Millisecond).ShouldNot(Receive())
+
+			stopFirstSplit()
+			Eventually(splitErrs, 2*time.Second).Should(Receive(BeNil()))
+		})
+
+		It("stops waiting once the split is stopped, so that it can be retried", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := client.SplitFile(ctx, originalFilePath, stemOutputDir, splitType, engineType, reportProgress)
+			Expect(err).To(HaveOccurred())
+			Expect(cerr.IsPermanent(err)).To(BeFalse())
+		})
+	})
+})
+
+func testAbs(path string) string {
+	absPath, err := filepath.Abs(path)
+	Expect(err).NotTo(HaveOccurred())
+	return absPath
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"time"
+)
+
+// confirmTimeout is how long a publish waits on the broker before it's treated as lost
+const confirmTimeout = 30 * time.Second
+
+var _ MessageChannel = &confirmingChannel{}
+
+// confirmingChannel puts the channel in confirm mode, so a publish only succeeds once the
+// broker has the message on a queue. Callers are about to ack the delivery the message
+// came from, and a message that's dropped on the way would take the job with it
+type confirmingChannel struct {
+	*amqp091.Channel
+	returns chan amqp091.Return
+}
+
+func newConfirmingChannel(channel *amqp091.Channel) (*confirmingChannel, error) {
+	if err := channel.Confirm(false); err != nil {
+		return nil, cerr.Wrap(err).Error("Failed to put the channel in confirm mode")
+	}
+
+	// the worker publishes one message at a time and checks for its return right
+	// after the confirm, so there's never more than one waiting here
+	returns := channel.NotifyReturn(make(chan amqp091.Return, 1))
+
+	return &confirmingChannel{
+		Channel: channel,
+		returns: returns,
+	}, nil
+}
+
+// PublishConfirmed isn't safe to call concurrently, since a return can't be told apart
+// from another publish's
+func (c *confirmingChannel) PublishConfirmed(ctx context.Context, queueName string, msg amqp091.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	confirmation, err := c.PublishWithDeferredConfirmWithContext(ctx, "", queueName, true, false, msg)
+	if err != nil {
+		return err
+	}
+
+	if !confirmation.Wait() {
+		return cerr.Error("The broker didn't confirm the message")
+	}
+
+	// the broker sends back an unroutable message before it confirms it
+	select {
+	case returned := <-c.returns:
+		return cerr.Field("reply_text", returned.ReplyText).Error("The message couldn't be routed to a queue")
+	default:
+		return nil
+	}
+}
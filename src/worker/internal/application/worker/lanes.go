@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	trackentity "github.com/veedubyou/chord-paper-be/src/shared/track/entity"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+const SplitQueueSuffix = ".split"
+
+// ConcurrencyLimits caps how many jobs the worker runs at the same time.
+// The heavy stages (the split) are limited separately from the light ones
+// (start, transfer, save), which then don't have to wait behind a long split.
+// Which stages are heavy comes from trackentity.SplitJobStages
+type ConcurrencyLimits struct {
+	SplitJobs int
+	LightJobs int
+}
+
+func DefaultConcurrencyLimits() ConcurrencyLimits {
+	return ConcurrencyLimits{
+		SplitJobs: 1,
+		LightJobs: 4,
+	}
+}
+
+// validate makes sure that every lane gets a handler. A prefetch limit of 0
+// would also tell rabbit to send as many messages as it has
+func (c ConcurrencyLimits) validate() error {
+	if c.SplitJobs < 1 || c.LightJobs < 1 {
+		return cerr.Fields(cerr.F{
+			"split_jobs": c.SplitJobs,
+			"light_jobs": c.LightJobs,
+		}).Error("Each kind of job needs a concurrency of at least 1")
+	}
+
+	return nil
+}
+
+// lane is a queue along with how many of its jobs can run at once
+type lane struct {
+	queueName   string
+	concurrency int
+}
+
+func (q *QueueWorker) lanes() []lane {
+	return []lane{
+		{queueName: q.queueName, concurrency: q.concurrencyLimits.LightJobs},
+		{queueName: SplitQueueName(q.queueName), concurrency: q.concurrencyLimits.SplitJobs},
+	}
+}
+
+// consumerTag names the lane's consumer, so that it can be cancelled
+func (l lane) consumerTag() string {
+	return l.queueName + "-consumer"
+}
+
+// jobQueueName is the queue of the lane that runs the job type
+func (q *QueueWorker) jobQueueName(jobType string) string {
+	if trackentity.SplitJobStage(jobType).IsHeavy() {
+		return SplitQueueName(q.queueName)
+	}
+
+	return q.queueName
+}
+
+// SplitQueueName is where the heavy jobs wait, apart from the job queue
+// that everything else goes through
+func SplitQueueName(queueName string) string {
+	return queueName + SplitQueueSuffix
+}
+
+var _ rabbitmq.Publisher = JobPublisher{}
+
+// JobPublisher sends each job to the queue of the lane that runs it
+type JobPublisher struct {
+	lightJobs rabbitmq.Publisher
+	splitJobs rabbitmq.Publisher
+}
+
+func NewJobPublisher(lightJobs rabbitmq.Publisher, splitJobs rabbitmq.Publisher) JobPublisher {
+	return JobPublisher{
+		lightJobs: lightJobs,
+		splitJobs: splitJobs,
+	}
+}
+
+func NewJobPublisherFromURL(rabbitMQURL string, queueName string) JobPublisher {
+	return NewJobPublisher(
+		rabbitmq.NewQueuePublisher(rabbitMQURL, queueName),
+		rabbitmq.NewQueuePublisher(rabbitMQURL, SplitQueueName(queueName)),
+	)
+}
+
+func (j JobPublisher) Publish(msg amqp091.Publishing) error {
+	if trackentity.SplitJobStage(msg.Type).IsHeavy() {
+		return j.splitJobs.Publish(msg)
+	}
+
+	return j.lightJobs.Publish(msg)
+}
@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"fmt"
+	"github.com/rabbitmq/amqp091-go"
+	"time"
+)
+
+const (
+	RetryCountHeader   = "x-retry-count"
+	LastErrorHeader    = "x-last-error"
+	ErrorHistoryHeader = "x-error-history"
+
+	DeadLetterQueueSuffix = ".dead-letter"
+
+	// keeps the header well under the frame size limit
+	maxLastErrorLength = 1000
+)
+
+// RetryPolicy decides how often a failed job is run again, and how long
+// to wait in between. The wait doubles with every attempt, up to MaxDelay
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    10 * time.Minute,
+	}
+}
+
+// Delay is how long to wait before the given retry, counting from 1
+func (r RetryPolicy) Delay(retry int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if delay >= r.MaxDelay {
+			return r.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+// Delays are all the different waits the policy can come up with,
+// each of them gets its own delay queue
+func (r RetryPolicy) Delays() []time.Duration {
+	delays := []time.Duration{}
+	for retry := 1; retry < r.MaxAttempts; retry++ {
+		delay := r.Delay(retry)
+		if len(delays) > 0 && delays[len(delays)-1] == delay {
+			continue
+		}
+
+		delays = append(delays, delay)
+	}
+
+	return delays
+}
+
+// RetryQueueName is named after the delay rather than the attempt, since
+// RabbitMQ won't allow redeclaring a queue with a different TTL
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queueName, delay.Milliseconds())
+}
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + DeadLetterQueueSuffix
+}
+
+// RetryCount is how many times the message has been retried so far
+func RetryCount(headers amqp091.Table) int {
+	return countHeader(headers, RetryCountHeader)
+}
+
+func countHeader(headers amqp091.Table, key string) int {
+	switch count := headers[key].(type) {
+	case int:
+		return count
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	default:
+		return 0
+	}
+}
+
+// ErrorHistory is the error from every failed attempt at the job, oldest first
+func ErrorHistory(headers amqp091.Table) []string {
+	history, ok := headers[ErrorHistoryHeader].([]any)
+	if !ok {
+		return nil
+	}
+
+	errMsgs := []string{}
+	for _, entry := range history {
+		if errMsg, ok := entry.(string); ok {
+			errMsgs = append(errMsgs, errMsg)
+		}
+	}
+
+	return errMsgs
+}
+
+func appendErrorHistory(headers amqp091.Table, err error) []any {
+	history := []any{}
+	for _, errMsg := range ErrorHistory(headers) {
+		history = append(history, errMsg)
+	}
+
+	return append(history, truncateError(err))
+}
+
+// republishing builds a copy of the message with the given headers added to its own
+func republishing(message amqp091.Delivery, headers amqp091.Table) amqp091.Publishing {
+	mergedHeaders := amqp091.Table{}
+	for key, value := range message.Headers {
+		mergedHeaders[key] = value
+	}
+
+	for key, value := range headers {
+		mergedHeaders[key] = value
+	}
+
+	return amqp091.Publishing{
+		MessageId:    message.MessageId,
+		Headers:      mergedHeaders,
+		ContentType:  message.ContentType,
+		DeliveryMode: amqp091.Persistent,
+		Type:         message.Type,
+		Body:         message.Body,
+	}
+}
+
+func truncateError(err error) string {
+	errMsg := err.Error()
+	if len(errMsg) > maxLastErrorLength {
+		return errMsg[:maxLastErrorLength]
+	}
+
+	return errMsg
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"github.com/apex/log"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"time"
+)
+
+const InterruptedCountHeader = "x-interrupted-count"
+
+// Shutdown stops taking new jobs and gives the running ones until the timeout to finish.
+// Jobs that are still running by then are cancelled and put back on their queue,
+// for the next worker to pick up. Start returns once everything has stopped
+func (q *QueueWorker) Shutdown(timeout time.Duration) {
+	q.channelLock.Lock()
+	if q.channel == nil || q.isDraining() {
+		q.channelLock.Unlock()
+		return
+	}
+
+	close(q.draining)
+
+	if !q.consuming {
+		_ = q.channel.Close()
+		q.channel = nil
+		q.channelLock.Unlock()
+		return
+	}
+
+	for _, lane := range q.lanes() {
+		if err := q.channel.Cancel(lane.consumerTag(), false); err != nil {
+			cerr.Log(cerr.Field("queue_name", lane.queueName).Wrap(err).Error("Failed to cancel consumer"))
+		}
+	}
+	q.channelLock.Unlock()
+
+	log.WithField("timeout", timeout).Info("Waiting for running jobs to finish")
+
+	select {
+	case <-q.stopped:
+		log.Info("Running jobs have finished")
+
+	case <-time.After(timeout):
+		log.Warn("Running jobs didn't finish in time, cancelling them")
+		q.cancelJobs()
+		<-q.stopped
+	}
+}
+
+func (q *QueueWorker) isDraining() bool {
+	select {
+	case <-q.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// InterruptedCount is how many times a shutdown has cut the job short
+func InterruptedCount(headers amqp091.Table) int {
+	return countHeader(headers, InterruptedCountHeader)
+}
+
+// requeueInterruptedMessage puts the job back on its queue, marking down the interruption
+// so it doesn't go by unnoticed. It doesn't use up any of the job's retries
+func (q *QueueWorker) requeueInterruptedMessage(lane lane, message amqp091.Delivery) {
+	interrupted := InterruptedCount(message.Headers) + 1
+	logger := log.WithFields(log.Fields{
+		"message_type": message.Type,
+		"interrupted":  interrupted,
+	})
+
+	msg := republishing(message, amqp091.Table{
+		InterruptedCountHeader: int32(interrupted),
+		rabbitmq.AttemptHeader: rabbitmq.NextAttempt(message.Headers),
+	})
+
+	if err := q.publish(lane.queueName, msg); err != nil {
+		cerr.Log(cerr.Wrap(err).Error("Failed to requeue interrupted job, leaving it to the broker"))
+		if err = message.Nack(false, true); err != nil {
+			logger.Error("Failed to nack message")
+		}
+
+		return
+	}
+
+	logger.Info("Requeued the job that was interrupted by the shutdown")
+	if err := message.Ack(false); err != nil {
+		logger.Error("Failed to ack message")
+	}
+}
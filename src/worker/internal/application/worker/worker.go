@@ -1,49 +1,139 @@
 package worker
 
 import (
+	"context"
 	"github.com/apex/log"
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_router"
 	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
 	"sync"
 )
 
 type MessageChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
 	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)
+	// PublishConfirmed returns once the broker has put the message on the queue
+	PublishConfirmed(ctx context.Context, queueName string, msg amqp091.Publishing) error
+	Cancel(consumer string, noWait bool) error
 	Close() error
 }
 
 type QueueWorker struct {
-	channel     MessageChannel
-	channelLock sync.Mutex
-	jobRouter   job_router.JobRouter
-	queueName   string
+	channel           MessageChannel
+	channelLock       sync.Mutex
+	jobRouter         job_router.JobRouter
+	queueName         string
+	retryPolicy       RetryPolicy
+	concurrencyLimits ConcurrencyLimits
+
+	// jobCtx is cancelled when a shutdown runs out of time waiting on the running jobs
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+	consuming  bool
+	draining   chan struct{}
+	stopped    chan struct{}
 }
 
-func NewQueueWorker(channel MessageChannel, queueName string, jobRouter job_router.JobRouter) QueueWorker {
-	return QueueWorker{
-		channel:   channel,
-		queueName: queueName,
-		jobRouter: jobRouter,
+func NewQueueWorker(channel MessageChannel, queueName string, jobRouter job_router.JobRouter, retryPolicy RetryPolicy, concurrencyLimits ConcurrencyLimits) (QueueWorker, error) {
+	if err := concurrencyLimits.validate(); err != nil {
+		return QueueWorker{}, err
 	}
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+
+	return QueueWorker{
+		channel:           channel,
+		queueName:         queueName,
+		jobRouter:         jobRouter,
+		retryPolicy:       retryPolicy,
+		concurrencyLimits: concurrencyLimits,
+		jobCtx:            jobCtx,
+		cancelJobs:        cancelJobs,
+		draining:          make(chan struct{}),
+		stopped:           make(chan struct{}),
+	}, nil
 }
 
-func NewQueueWorkerFromConnection(conn *amqp091.Connection, queueName string, jobRouter job_router.JobRouter) (QueueWorker, error) {
+func NewQueueWorkerFromConnection(conn *amqp091.Connection, queueName string, jobRouter job_router.JobRouter, retryPolicy RetryPolicy, concurrencyLimits ConcurrencyLimits) (QueueWorker, error) {
+	if err := concurrencyLimits.validate(); err != nil {
+		_ = conn.Close()
+		return QueueWorker{}, err
+	}
+
 	rabbitChannel, err := conn.Channel()
 	if err != nil {
 		_ = conn.Close()
 		return QueueWorker{}, cerr.Wrap(err).Error("Failed to get channel")
 	}
 
-	// limit concurrent processing to prevent resource blow up during
-	// multiple split jobs happening at the same time
-	err = rabbitChannel.Qos(1, 0, true)
+	for _, laneQueueName := range []string{queueName, SplitQueueName(queueName)} {
+		_, err := rabbitChannel.QueueDeclare(
+			laneQueueName,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+
+		if err != nil {
+			_ = rabbitChannel.Close()
+			return QueueWorker{}, cerr.Field("queue_name", laneQueueName).Wrap(err).Error("Failed to declare queue")
+		}
+
+		if err := declareRetryQueues(rabbitChannel, laneQueueName, retryPolicy); err != nil {
+			_ = rabbitChannel.Close()
+			return QueueWorker{}, cerr.Wrap(err).Error("Failed to declare retry queues")
+		}
+	}
+
+	if err := declareDeadLetterQueue(rabbitChannel, queueName); err != nil {
+		_ = rabbitChannel.Close()
+		return QueueWorker{}, cerr.Wrap(err).Error("Failed to declare dead letter queue")
+	}
+
+	confirmingChannel, err := newConfirmingChannel(rabbitChannel)
 	if err != nil {
-		panic(err)
+		_ = rabbitChannel.Close()
+		return QueueWorker{}, err
+	}
+
+	return NewQueueWorker(confirmingChannel, queueName, jobRouter, retryPolicy, concurrencyLimits)
+}
+
+// declareRetryQueues sets up a queue for each delay, where messages sit until their
+// TTL runs out and they get dead lettered back onto the job queue they came from
+func declareRetryQueues(rabbitChannel *amqp091.Channel, queueName string, retryPolicy RetryPolicy) error {
+	for _, delay := range retryPolicy.Delays() {
+		retryQueueName := RetryQueueName(queueName, delay)
+		_, err := rabbitChannel.QueueDeclare(
+			retryQueueName,
+			true,
+			false,
+			false,
+			false,
+			amqp091.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+
+		if err != nil {
+			return cerr.Field("queue_name", retryQueueName).Wrap(err).Error("Failed to declare retry queue")
+		}
 	}
 
-	queue, err := rabbitChannel.QueueDeclare(
-		queueName,
+	return nil
+}
+
+// declareDeadLetterQueue sets up the one queue, shared by all the lanes, where messages
+// that have run out of attempts are kept for a human to look at
+func declareDeadLetterQueue(rabbitChannel *amqp091.Channel, queueName string) error {
+	deadLetterQueueName := DeadLetterQueueName(queueName)
+	_, err := rabbitChannel.QueueDeclare(
+		deadLetterQueueName,
 		true,
 		false,
 		false,
@@ -52,11 +142,10 @@ func NewQueueWorkerFromConnection(conn *amqp091.Connection, queueName string, jo
 	)
 
 	if err != nil {
-		_ = rabbitChannel.Close()
-		return QueueWorker{}, cerr.Wrap(err).Error("Failed to declare queue")
+		return cerr.Field("queue_name", deadLetterQueueName).Wrap(err).Error("Failed to declare dead letter queue")
 	}
 
-	return NewQueueWorker(rabbitChannel, queue.Name, jobRouter), nil
+	return nil
 }
 
 func (q *QueueWorker) Start() error {
@@ -70,49 +159,186 @@ func (q *QueueWorker) Start() error {
 
 	defer q.channel.Close()
 
+	lanes := q.lanes()
+	messageStreams := make([]<-chan amqp091.Delivery, len(lanes))
+	for i, lane := range lanes {
+		messageStream, err := q.consume(lane)
+		if err != nil {
+			q.channelLock.Unlock()
+			return err
+		}
+
+		messageStreams[i] = messageStream
+	}
+	q.consuming = true
+	q.channelLock.Unlock()
+
+	// each lane gets as many handlers as it has prefetched messages,
+	// so a message never waits on a free handler once it's delivered
+	handlers := sync.WaitGroup{}
+	for i, lane := range lanes {
+		for n := 0; n < lane.concurrency; n++ {
+			handlers.Add(1)
+			go q.handleMessages(lane, messageStreams[i], &handlers)
+		}
+	}
+
+	handlers.Wait()
+	close(q.stopped)
+	log.Info("Worker has stopped")
+	return nil
+}
+
+func (q *QueueWorker) handleMessages(lane lane, messageStream <-chan amqp091.Delivery, handlers *sync.WaitGroup) {
+	defer handlers.Done()
+	for message := range messageStream {
+		if q.isDraining() {
+			// delivered before the consumer was cancelled, but not started yet
+			if err := message.Nack(false, true); err != nil {
+				log.WithField("message_type", message.Type).Error("Failed to requeue message")
+			}
+
+			continue
+		}
+
+		q.handleMessage(lane, message)
+	}
+}
+
+func (q *QueueWorker) consume(lane lane) (<-chan amqp091.Delivery, error) {
+	errctx := cerr.Field("queue_name", lane.queueName)
+
+	// a non global prefetch limit only applies to the consumers started
+	// after it, which lets each lane on the channel have its own
+	if err := q.channel.Qos(lane.concurrency, 0, false); err != nil {
+		return nil, errctx.Wrap(err).Error("Failed to set the prefetch limit")
+	}
+
 	messageStream, err := q.channel.Consume(
-		q.queueName,
-		"",
+		lane.queueName,
+		lane.consumerTag(),
 		false,
 		false,
 		false,
 		false,
 		nil,
 	)
-	q.channelLock.Unlock()
 
 	if err != nil {
-		return cerr.Field("queue_name", q.queueName).
-			Wrap(err).Error("Failed to start consuming from channel")
+		return nil, errctx.Wrap(err).Error("Failed to start consuming from channel")
 	}
 
-	for message := range messageStream {
-		logger := log.WithField("message_type", message.Type)
-		logger.Info("Handling message")
-		err := q.jobRouter.HandleMessage(message)
-		if err != nil {
-			err = cerr.Field("message_type", message.Type).
-				Wrap(err).Error("Failed to process message")
+	return messageStream, nil
+}
 
-			cerr.Log(err)
+func (q *QueueWorker) handleMessage(lane lane, message amqp091.Delivery) {
+	logger := log.WithField("message_type", message.Type)
 
-			if err = message.Nack(false, false); err != nil {
-				logger.Error("Failed to nack message")
+	// jobs can be published without knowing about the lanes, e.g. by the server
+	// resuming a split, so they're passed along to the lane that runs them
+	if jobQueueName := q.jobQueueName(message.Type); jobQueueName != lane.queueName {
+		err := q.publish(jobQueueName, republishing(message, nil))
+		if err == nil {
+			if err = message.Ack(false); err != nil {
+				logger.Error("Failed to ack message")
 			}
-		} else {
-			logger.Info("Successfully processed message")
+
+			return
+		}
+
+		cerr.Log(cerr.Wrap(err).Error("Failed to forward message to its lane, handling it here instead"))
+	}
+
+	logger.Info("Handling message")
+	err := q.jobRouter.HandleMessage(q.jobCtx, message)
+	if err != nil && q.jobCtx.Err() != nil {
+		q.requeueInterruptedMessage(lane, message)
+	} else if err != nil {
+		err = cerr.Field("message_type", message.Type).
+			Wrap(err).Error("Failed to process message")
+
+		cerr.Log(err)
+		q.handleFailedMessage(message, err)
+	} else {
+		logger.Info("Successfully processed message")
+		if err = message.Ack(false); err != nil {
+			logger.Error("Failed to ack message")
+		}
+	}
+}
+
+// handleFailedMessage retries the job after a delay, unless the error is one that
+// retrying can't fix or it's out of attempts. Only then is the track marked as errored,
+// once the message is safely on the dead letter queue
+func (q *QueueWorker) handleFailedMessage(message amqp091.Delivery, jobErr error) {
+	logger := log.WithField("message_type", message.Type)
+	retry := RetryCount(message.Headers) + 1
+
+	if !cerr.IsPermanent(jobErr) && retry < q.retryPolicy.MaxAttempts {
+		err := q.scheduleRetry(message, retry, jobErr)
+		if err == nil {
+			logger.WithField("retry", retry).Info("Scheduled the job to be retried")
 			if err = message.Ack(false); err != nil {
 				logger.Error("Failed to ack message")
 			}
+
+			return
 		}
+
+		cerr.Log(cerr.Wrap(err).Error("Failed to schedule retry, giving up on the job"))
 	}
 
-	return nil
+	if err := q.deadLetter(message, jobErr); err != nil {
+		// dropping it would lose the job without a trace, so it goes back on the queue
+		cerr.Log(cerr.Wrap(err).Error("Failed to move the message to the dead letter queue, requeueing it"))
+		if err := message.Nack(false, true); err != nil {
+			logger.Error("Failed to requeue message")
+		}
+
+		return
+	}
+
+	if err := q.jobRouter.HandleFailedJob(message, jobErr); err != nil {
+		cerr.Log(cerr.Wrap(err).Error("Failed to report the failed job"))
+	}
+
+	if err := message.Nack(false, false); err != nil {
+		logger.Error("Failed to nack message")
+	}
+}
+
+func (q *QueueWorker) scheduleRetry(message amqp091.Delivery, retry int, jobErr error) error {
+	retryQueueName := RetryQueueName(q.jobQueueName(message.Type), q.retryPolicy.Delay(retry))
+	msg := republishing(message, amqp091.Table{
+		RetryCountHeader:       int32(retry),
+		ErrorHistoryHeader:     appendErrorHistory(message.Headers, jobErr),
+		rabbitmq.AttemptHeader: rabbitmq.NextAttempt(message.Headers),
+	})
+
+	return q.publish(retryQueueName, msg)
 }
 
-func (q *QueueWorker) Stop() {
+func (q *QueueWorker) deadLetter(message amqp091.Delivery, jobErr error) error {
+	msg := republishing(message, amqp091.Table{
+		LastErrorHeader:    truncateError(jobErr),
+		ErrorHistoryHeader: appendErrorHistory(message.Headers, jobErr),
+	})
+
+	return q.publish(DeadLetterQueueName(q.queueName), msg)
+}
+
+func (q *QueueWorker) publish(queueName string, msg amqp091.Publishing) error {
 	q.channelLock.Lock()
 	defer q.channelLock.Unlock()
-	_ = q.channel.Close()
-	q.channel = nil
+
+	if q.channel == nil {
+		return cerr.Error("Worker has been stopped")
+	}
+
+	err := q.channel.PublishConfirmed(context.Background(), queueName, msg)
+	if err != nil {
+		return cerr.Field("queue_name", queueName).Wrap(err).Error("Failed to publish message")
+	}
+
+	return nil
 }
@@ -0,0 +1,22 @@
+package cerr
+
+import "errors"
+
+type permanentError struct {
+	error
+}
+
+func (p permanentError) Unwrap() error {
+	return p.error
+}
+
+// Permanent marks an error that retrying the job won't get past,
+// e.g. a malformed message, or a track that isn't a split request
+func Permanent(err error) error {
+	return permanentError{error: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanentErr permanentError
+	return errors.As(err, &permanentErr)
+}
@@ -1,12 +1,13 @@
 package storagepath
 
-import "fmt"
+import (
+	"fmt"
+)
 
-type Generator struct {
-	Host   string
-	Bucket string
-}
+// Generator lays out where a track's files go within the bucket.
+// The paths it makes are relative to the bucket, so they work with any file store
+type Generator struct{}
 
 func (g Generator) GeneratePath(tracklistID string, trackID string, leafPath string) string {
-	return fmt.Sprintf("%s/%s/%s/%s/%s", g.Host, g.Bucket, tracklistID, trackID, leafPath)
+	return fmt.Sprintf("%s/%s/%s", tracklistID, trackID, leafPath)
 }
@@ -31,3 +31,21 @@ func (w WorkingDir) Root() string {
 func (w WorkingDir) TempDir() string {
 	return filepath.Join(w.root, "tmp")
 }
+
+// ClearTempDir removes whatever jobs left behind in the temp dir,
+// only safe to call while no job is running
+func (w WorkingDir) ClearTempDir() error {
+	entries, err := os.ReadDir(w.TempDir())
+	if err != nil {
+		return cerr.Field("temp_dir", w.TempDir()).Wrap(err).Error("Failed to read temp dir")
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(w.TempDir(), entry.Name())
+		if err := os.RemoveAll(entryPath); err != nil {
+			return cerr.Field("path", entryPath).Wrap(err).Error("Failed to remove temp dir entry")
+		}
+	}
+
+	return nil
+}
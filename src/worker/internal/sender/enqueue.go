@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/save_stems_to_db"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/split"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/transfer"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+func runEnqueue(args []string) error {
+	flags := flag.NewFlagSet("enqueue", flag.ExitOnError)
+	connFlags := addConnectionFlags(flags)
+	jobType := flags.String("type", start.JobType, fmt.Sprintf("job type, one of %s, %s, %s, %s",
+		start.JobType, transfer.JobType, split.JobType, save_stems_to_db.JobType))
+	tracklistID := flags.String("tracklist", "", "tracklist ID")
+	trackID := flags.String("track", "", "track ID")
+	originalURL := flags.String("original-url", "", "URL of the saved original, for "+split.JobType)
+	stemURLs := flags.String("stem-urls", "", "JSON object of stem name to URL, for "+save_stems_to_db.JobType)
+	_ = flags.Parse(args)
+
+	if *tracklistID == "" || *trackID == "" {
+		return cerr.Error("Both -tracklist and -track are required")
+	}
+
+	trackParams := job_message.TrackIdentifier{
+		TrackListID: *tracklistID,
+		TrackID:     *trackID,
+	}
+
+	jobParams, err := makeJobParams(*jobType, trackParams, *originalURL, *stemURLs)
+	if err != nil {
+		return err
+	}
+
+	jobBody, err := json.Marshal(jobParams)
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to marshal job params")
+	}
+
+	publisher := worker.NewJobPublisherFromURL(connFlags.rabbitURL, connFlags.queueName)
+	err = publisher.Publish(rabbitmq.NewJobPublishing(*jobType, jobBody))
+	if err != nil {
+		return cerr.Wrap(err).Error("Failed to publish job")
+	}
+
+	fmt.Printf("Enqueued %s for track %s\n", *jobType, *trackID)
+	return nil
+}
+
+func makeJobParams(jobType string, trackParams job_message.TrackIdentifier, originalURL string, stemURLs string) (any, error) {
+	errctx := cerr.Field("job_type", jobType)
+
+	switch jobType {
+	case start.JobType:
+		return start.JobParams{TrackIdentifier: trackParams}, nil
+
+	case transfer.JobType:
+		return transfer.JobParams{TrackIdentifier: trackParams}, nil
+
+	case split.JobType:
+		if originalURL == "" {
+			return nil, errctx.Error("-original-url is required for this job type")
+		}
+
+		return split.JobParams{
+			TrackIdentifier:  trackParams,
+			SavedOriginalURL: originalURL,
+		}, nil
+
+	case save_stems_to_db.JobType:
+		stems := map[string]string{}
+		if err := json.Unmarshal([]byte(stemURLs), &stems); err != nil {
+			return nil, errctx.Wrap(err).Error("-stem-urls must be a JSON object of stem name to URL")
+		}
+
+		return save_stems_to_db.JobParams{
+			TrackIdentifier: trackParams,
+			StemURLS:        stems,
+		}, nil
+
+	default:
+		return nil, errctx.Error("Unknown job type")
+	}
+}
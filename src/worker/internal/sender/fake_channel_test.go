@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+var _ queueChannel = &fakeChannel{}
+var _ amqp091.Acknowledger = &fakeChannel{}
+
+var errNoQueue = errors.New("NOT_FOUND - no queue")
+
+// fakeChannel keeps its queues in memory. Messages that have been gotten but not
+// acked go back to the front of their queue when the channel is closed, like rabbit does
+type fakeChannel struct {
+	queues    map[string][]amqp091.Delivery
+	consumers map[string]int
+	unacked   map[uint64]unackedMessage
+	nextTag   uint64
+	acked     int
+}
+
+type unackedMessage struct {
+	queueName string
+	message   amqp091.Delivery
+}
+
+func newFakeChannel(queueNames ...string) *fakeChannel {
+	f := &fakeChannel{
+		queues:    map[string][]amqp091.Delivery{},
+		consumers: map[string]int{},
+		unacked:   map[uint64]unackedMessage{},
+	}
+
+	for _, queueName := range queueNames {
+		f.queues[queueName] = []amqp091.Delivery{}
+	}
+
+	return f
+}
+
+func (f *fakeChannel) push(queueName string, message amqp091.Delivery) {
+	f.queues[queueName] = append(f.queues[queueName], message)
+}
+
+func (f *fakeChannel) QueueDeclarePassive(name string, _, _, _, _ bool, _ amqp091.Table) (amqp091.Queue, error) {
+	messages, ok := f.queues[name]
+	if !ok {
+		return amqp091.Queue{}, errNoQueue
+	}
+
+	return amqp091.Queue{Name: name, Messages: len(messages), Consumers: f.consumers[name]}, nil
+}
+
+func (f *fakeChannel) Get(queueName string, _ bool) (amqp091.Delivery, bool, error) {
+	messages, ok := f.queues[queueName]
+	if !ok {
+		return amqp091.Delivery{}, false, errNoQueue
+	}
+
+	if len(messages) == 0 {
+		return amqp091.Delivery{}, false, nil
+	}
+
+	f.nextTag++
+	message := messages[0]
+	message.Acknowledger = f
+	message.DeliveryTag = f.nextTag
+
+	f.queues[queueName] = messages[1:]
+	f.unacked[f.nextTag] = unackedMessage{queueName: queueName, message: message}
+	return message, true, nil
+}
+
+func (f *fakeChannel) QueuePurge(name string, _ bool) (int, error) {
+	messages, ok := f.queues[name]
+	if !ok {
+		return 0, errNoQueue
+	}
+
+	f.queues[name] = []amqp091.Delivery{}
+	return len(messages), nil
+}
+
+func (f *fakeChannel) Close() error {
+	for tag := f.nextTag; tag > 0; tag-- {
+		unacked, ok := f.unacked[tag]
+		if !ok {
+			continue
+		}
+
+		unacked.message.Redelivered = true
+		f.queues[unacked.queueName] = append([]amqp091.Delivery{unacked.message}, f.queues[unacked.queueName]...)
+		delete(f.unacked, tag)
+	}
+
+	return nil
+}
+
+func (f *fakeChannel) Ack(tag uint64, _ bool) error {
+	delete(f.unacked, tag)
+	f.acked++
+	return nil
+}
+
+func (f *fakeChannel) Nack(_ uint64, _ bool, _ bool) error {
+	return nil
+}
+
+func (f *fakeChannel) Reject(_ uint64, _ bool) error {
+	return nil
+}
+
+type fakePublisher struct {
+	published []amqp091.Publishing
+	err       error
+}
+
+func (f *fakePublisher) Publish(msg amqp091.Publishing) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.published = append(f.published, msg)
+	return nil
+}
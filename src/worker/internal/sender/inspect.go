@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// keeps each message on one line of the listing
+const maxListedErrorLength = 80
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	connFlags := addConnectionFlags(flags)
+	limit := flags.Int("limit", 0, "the most dead lettered messages to list, 0 for all of them")
+	_ = flags.Parse(args)
+
+	conn, err := dial(connFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channel, err := openChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	return listQueues(channel, connFlags, *limit)
+}
+
+// listQueues only counts the messages on the job queues, the workers are consuming
+// from them. The messages on the dead letter queue are listed one by one
+func listQueues(channel queueChannel, connFlags *connectionFlags, limit int) error {
+	for _, queue := range []string{jobQueue, splitQueue} {
+		queueName, err := resolveQueueName(connFlags, queue)
+		if err != nil {
+			return err
+		}
+
+		summary, err := inspectQueue(channel, queueName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%d messages, %d consumers)\n\n", queueName, summary.Messages, summary.Consumers)
+	}
+
+	peeked, err := peekDeadLetters(channel, connFlags, limit)
+	if err != nil {
+		return err
+	}
+	defer peeked.release()
+
+	printMessageList(worker.DeadLetterQueueName(connFlags.queueName), peeked.messages)
+	return nil
+}
+
+func printMessageList(queueName string, messages []amqp091.Delivery) {
+	fmt.Printf("%s (%d messages)\n", queueName, len(messages))
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "INDEX\tTYPE\tTRACKLIST ID\tTRACK ID\tRETRIES\tLAST ERROR")
+	for i, message := range messages {
+		trackParams := trackIdentifier(message)
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%d\t%s\n",
+			i,
+			message.Type,
+			trackParams.TrackListID,
+			trackParams.TrackID,
+			worker.RetryCount(message.Headers),
+			lastError(message))
+	}
+
+	_ = writer.Flush()
+	fmt.Println()
+}
+
+func lastError(message amqp091.Delivery) string {
+	history := worker.ErrorHistory(message.Headers)
+	if len(history) == 0 {
+		return ""
+	}
+
+	errMsg := strings.ReplaceAll(history[len(history)-1], "\n", " ")
+	if len(errMsg) > maxListedErrorLength {
+		return errMsg[:maxListedErrorLength] + "..."
+	}
+
+	return errMsg
+}
+
+func runShow(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	connFlags := addConnectionFlags(flags)
+	index := flags.Int("index", 0, "position of the message in the dead letter queue, as shown by list")
+	_ = flags.Parse(args)
+
+	conn, err := dial(connFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channel, err := openChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	peeked, err := peekDeadLetters(channel, connFlags, *index+1)
+	if err != nil {
+		return err
+	}
+	defer peeked.release()
+
+	message, err := peeked.message(*index)
+	if err != nil {
+		return err
+	}
+
+	printMessage(message)
+	return nil
+}
+
+func printMessage(message amqp091.Delivery) {
+	fmt.Printf("Type:        %s\n", message.Type)
+	fmt.Printf("Retries:     %d\n", worker.RetryCount(message.Headers))
+	fmt.Printf("Interrupted: %d\n", worker.InterruptedCount(message.Headers))
+
+	fmt.Println("\nBody:")
+	body := bytes.Buffer{}
+	if err := json.Indent(&body, message.Body, "", "  "); err != nil {
+		// not JSON, show it as it came
+		body.Reset()
+		body.Write(message.Body)
+	}
+	fmt.Println(body.String())
+
+	fmt.Println("\nError history:")
+	history := worker.ErrorHistory(message.Headers)
+	if len(history) == 0 {
+		fmt.Println("  (none)")
+	}
+
+	for i, errMsg := range history {
+		fmt.Printf("  attempt %d: %s\n", i+1, errMsg)
+	}
+}
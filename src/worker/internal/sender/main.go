@@ -1,62 +1,69 @@
 package main
 
 import (
-	"encoding/json"
-	"github.com/rabbitmq/amqp091-go"
+	"flag"
+	"fmt"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/dev"
 	"github.com/veedubyou/chord-paper-be/src/shared/config/envvar"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
-	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/start"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+	"os"
 )
 
-func main() {
-	rabbitURL := envvar.MustGet(envvar.RABBITMQ_URL)
+const usage = `Usage: sender <command> [flags]
 
-	conn, err := amqp091.Dial(rabbitURL)
-	if err != nil {
-		panic(err)
-	}
-	defer conn.Close()
+Commands:
+  list      count the messages on the job queues and list the dead letter queue
+  show      show a dead lettered message's body and error history
+  replay    move dead lettered messages back onto the job queue
+  purge     delete every message on a queue
+  enqueue   publish a job for a track
 
-	rabbitChannel, err := conn.Channel()
-	if err != nil {
-		panic(err)
-	}
-	defer rabbitChannel.Close()
-
-	queue, err := rabbitChannel.QueueDeclare(
-		"chord-paper-dev",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-
-	if err != nil {
-		panic(err)
-	}
+Run "sender <command> -h" for the flags of a command
+`
 
-	startJobParams := start.JobParams{
-		TrackIdentifier: job_message.TrackIdentifier{
-			TrackListID: "ad2fca6d-8c32-4030-86c0-8b5339347253",
-			TrackID:     "440a7737-bcda-4761-ae89-d85880f4bce3",
-		},
-	}
+type command func(args []string) error
 
-	jobBody, err := json.Marshal(startJobParams)
+var commands = map[string]command{
+	"list":    runList,
+	"show":    runShow,
+	"replay":  runReplay,
+	"purge":   runPurge,
+	"enqueue": runEnqueue,
+}
 
-	if err != nil {
-		panic(err)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
 
-	job := amqp091.Publishing{Type: start.JobType, Body: jobBody}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := cmd(os.Args[2:]); err != nil {
+		cerr.Log(err)
+		os.Exit(1)
+	}
+}
 
-	job.DeliveryMode = amqp091.Persistent
-	job.ContentType = "application/json"
+// connectionFlags are the flags every command needs to find the job queue,
+// defaulting to the same env variables the worker reads, then the dev values
+type connectionFlags struct {
+	rabbitURL string
+	queueName string
+}
 
-	err = rabbitChannel.Publish("", queue.Name, true, false, job)
+func addConnectionFlags(flags *flag.FlagSet) *connectionFlags {
+	connFlags := &connectionFlags{}
+	flags.StringVar(&connFlags.rabbitURL, "url",
+		envvar.GetOrDefault(envvar.RABBITMQ_URL, dev.RabbitMQHost),
+		"RabbitMQ URL")
+	flags.StringVar(&connFlags.queueName, "queue-name",
+		envvar.GetOrDefault(envvar.RABBITMQ_QUEUE_NAME, dev.RabbitMQQueueName),
+		"name of the job queue the worker consumes from")
 
-	if err != nil {
-		panic(err)
-	}
+	return connFlags
 }
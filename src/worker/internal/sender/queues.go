@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/jobs/job_message"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+const (
+	jobQueue        = "jobs"
+	splitQueue      = "split"
+	deadLetterQueue = "dead-letter"
+)
+
+func addQueueFlag(flags *flag.FlagSet, defaultQueue string) *string {
+	return flags.String("queue", defaultQueue,
+		"which queue to use, one of \""+jobQueue+"\", \""+splitQueue+"\" or \""+deadLetterQueue+"\"")
+}
+
+func resolveQueueName(connFlags *connectionFlags, queue string) (string, error) {
+	switch queue {
+	case jobQueue:
+		return connFlags.queueName, nil
+	case splitQueue:
+		return worker.SplitQueueName(connFlags.queueName), nil
+	case deadLetterQueue:
+		return worker.DeadLetterQueueName(connFlags.queueName), nil
+	default:
+		return "", cerr.Field("queue", queue).Error("Unknown queue")
+	}
+}
+
+func dial(connFlags *connectionFlags) (*amqp091.Connection, error) {
+	conn, err := amqp091.Dial(connFlags.rabbitURL)
+	if err != nil {
+		return nil, cerr.Field("url", connFlags.rabbitURL).Wrap(err).Error("Failed to dial RabbitMQ")
+	}
+
+	return conn, nil
+}
+
+// queueChannel is the part of a rabbit channel that the commands use
+type queueChannel interface {
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp091.Table) (amqp091.Queue, error)
+	Get(queue string, autoAck bool) (amqp091.Delivery, bool, error)
+	QueuePurge(name string, noWait bool) (int, error)
+	Close() error
+}
+
+func openChannel(conn *amqp091.Connection) (queueChannel, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, cerr.Wrap(err).Error("Failed to create rabbit channel")
+	}
+
+	return channel, nil
+}
+
+// inspectQueue looks up the queue, failing on one that doesn't exist rather than making an empty one
+func inspectQueue(channel queueChannel, queueName string) (amqp091.Queue, error) {
+	queue, err := channel.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return amqp091.Queue{}, cerr.Field("queue_name", queueName).Wrap(err).Error("Failed to find queue")
+	}
+
+	return queue, nil
+}
+
+// peekedQueue holds on to messages without acking them. They go back onto the
+// queue, in their original order, once release closes the channel, unless they
+// have been acked in the meantime
+type peekedQueue struct {
+	channel  queueChannel
+	messages []amqp091.Delivery
+}
+
+// peekDeadLetters takes up to limit messages off the dead letter queue, or all of them if limit is 0.
+// Only the dead letter queue can be peeked at, since no worker consumes from it. Taking messages
+// off the job queues would hold them back from the workers, and mark them as redelivered
+func peekDeadLetters(channel queueChannel, connFlags *connectionFlags, limit int) (*peekedQueue, error) {
+	queueName := worker.DeadLetterQueueName(connFlags.queueName)
+	errctx := cerr.Field("queue_name", queueName)
+
+	if _, err := inspectQueue(channel, queueName); err != nil {
+		return nil, err
+	}
+
+	peeked := &peekedQueue{channel: channel}
+	for limit == 0 || len(peeked.messages) < limit {
+		message, ok, err := channel.Get(queueName, false)
+		if err != nil {
+			peeked.release()
+			return nil, errctx.Wrap(err).Error("Failed to get message from queue")
+		}
+
+		if !ok {
+			break
+		}
+
+		peeked.messages = append(peeked.messages, message)
+	}
+
+	return peeked, nil
+}
+
+func (p *peekedQueue) message(index int) (amqp091.Delivery, error) {
+	if index < 0 || index >= len(p.messages) {
+		return amqp091.Delivery{}, cerr.Fields(cerr.F{
+			"index":         index,
+			"message_count": len(p.messages),
+		}).Error("No message at index")
+	}
+
+	return p.messages[index], nil
+}
+
+func (p *peekedQueue) release() {
+	_ = p.channel.Close()
+}
+
+func trackIdentifier(message amqp091.Delivery) job_message.TrackIdentifier {
+	var trackParams job_message.TrackIdentifier
+	// a malformed message just shows up without track IDs
+	_ = json.Unmarshal(message.Body, &trackParams)
+	return trackParams
+}
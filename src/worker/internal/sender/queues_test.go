@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+)
+
+var _ = Describe("Sender", func() {
+	var (
+		connFlags       *connectionFlags
+		channel         *fakeChannel
+		jobQueueName    string
+		splitQueueName  string
+		deadLetterQueue string
+	)
+
+	var deadLetter = func(jobType string, body string) amqp091.Delivery {
+		return amqp091.Delivery{
+			MessageId: jobType + "-id",
+			Type:      jobType,
+			Body:      []byte(body),
+			Headers: amqp091.Table{
+				worker.RetryCountHeader:   int32(3),
+				worker.LastErrorHeader:    "it broke",
+				rabbitmq.AttemptHeader:    int32(4),
+				worker.ErrorHistoryHeader: []any{"it broke"},
+			},
+		}
+	}
+
+	var messageTypes = func(queueName string) []string {
+		types := []string{}
+		for _, message := range channel.queues[queueName] {
+			types = append(types, message.Type)
+		}
+
+		return types
+	}
+
+	BeforeEach(func() {
+		connFlags = &connectionFlags{rabbitURL: "amqp://nowhere", queueName: "jobs-test"}
+		jobQueueName = connFlags.queueName
+		splitQueueName = worker.SplitQueueName(connFlags.queueName)
+		deadLetterQueue = worker.DeadLetterQueueName(connFlags.queueName)
+
+		channel = newFakeChannel(jobQueueName, splitQueueName, deadLetterQueue)
+		channel.push(deadLetterQueue, deadLetter("start_job", `{"track_id":"first"}`))
+		channel.push(deadLetterQueue, deadLetter("split_track", `{"track_id":"second"}`))
+		channel.push(deadLetterQueue, deadLetter("save_stems_to_db", `{"track_id":"third"}`))
+	})
+
+	Describe("Replay", func() {
+		var publisher *fakePublisher
+
+		BeforeEach(func() {
+			publisher = &fakePublisher{}
+		})
+
+		Describe("One message", func() {
+			var replayed int
+
+			BeforeEach(func() {
+				var err error
+				replayed, err = replayDeadLetters(channel, publisher, connFlags, 1, false)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("publishes the message at the index", func() {
+				Expect(replayed).To(Equal(1))
+				Expect(publisher.published).To(HaveLen(1))
+				Expect(publisher.published[0].Type).To(Equal("split_track"))
+				Expect(publisher.published[0].MessageId).To(Equal("split_track-id"))
+			})
+
+			It("gives the job a fresh set of retries, keeping its error history", func() {
+				headers := publisher.published[0].Headers
+				Expect(headers).NotTo(HaveKey(worker.RetryCountHeader))
+				Expect(headers).NotTo(HaveKey(worker.LastErrorHeader))
+				Expect(headers).To(HaveKeyWithValue(worker.ErrorHistoryHeader, []any{"it broke"}))
+				Expect(headers).To(HaveKeyWithValue(rabbitmq.AttemptHeader, int32(5)))
+			})
+
+			It("only takes the replayed message off the dead letter queue, in the same order", func() {
+				Expect(messageTypes(deadLetterQueue)).To(Equal([]string{"start_job", "save_stems_to_db"}))
+			})
+		})
+
+		Describe("Every message", func() {
+			It("empties the dead letter queue", func() {
+				replayed, err := replayDeadLetters(channel, publisher, connFlags, -1, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(replayed).To(Equal(3))
+				Expect(publisher.published).To(HaveLen(3))
+				Expect(channel.queues[deadLetterQueue]).To(BeEmpty())
+			})
+		})
+
+		Describe("An index past the end", func() {
+			It("fails without losing any messages", func() {
+				_, err := replayDeadLetters(channel, publisher, connFlags, 5, false)
+				Expect(err).To(HaveOccurred())
+				Expect(publisher.published).To(BeEmpty())
+				Expect(messageTypes(deadLetterQueue)).To(Equal([]string{"start_job", "split_track", "save_stems_to_db"}))
+			})
+		})
+
+		Describe("When publishing fails", func() {
+			BeforeEach(func() {
+				publisher.err = errors.New("rabbit is down")
+			})
+
+			It("keeps the message on the dead letter queue", func() {
+				_, err := replayDeadLetters(channel, publisher, connFlags, 0, false)
+				Expect(err).To(HaveOccurred())
+				Expect(channel.acked).To(BeZero())
+				Expect(messageTypes(deadLetterQueue)).To(Equal([]string{"start_job", "split_track", "save_stems_to_db"}))
+			})
+		})
+	})
+
+	Describe("Purge", func() {
+		It("deletes every message on the queue", func() {
+			purged, err := purgeQueue(channel, deadLetterQueue)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(purged).To(Equal(3))
+			Expect(channel.queues[deadLetterQueue]).To(BeEmpty())
+		})
+
+		It("leaves the other queues alone", func() {
+			channel.push(jobQueueName, deadLetter("start_job", `{}`))
+
+			_, err := purgeQueue(channel, deadLetterQueue)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messageTypes(jobQueueName)).To(Equal([]string{"start_job"}))
+		})
+
+		It("fails on a queue that doesn't exist", func() {
+			_, err := purgeQueue(channel, "not-a-queue")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("List", func() {
+		It("doesn't take any messages off the job queues", func() {
+			channel.push(jobQueueName, deadLetter("start_job", `{}`))
+			channel.push(splitQueueName, deadLetter("split_track", `{}`))
+
+			Expect(listQueues(channel, connFlags, 0)).To(Succeed())
+			Expect(channel.nextTag).To(BeEquivalentTo(3))
+
+			for _, queueName := range []string{jobQueueName, splitQueueName} {
+				Expect(channel.queues[queueName]).To(HaveLen(1))
+				Expect(channel.queues[queueName][0].Redelivered).To(BeFalse())
+			}
+		})
+
+		It("puts the dead lettered messages back", func() {
+			Expect(listQueues(channel, connFlags, 0)).To(Succeed())
+			Expect(messageTypes(deadLetterQueue)).To(Equal([]string{"start_job", "split_track", "save_stems_to_db"}))
+		})
+	})
+})
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/rabbitmq"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/lib/cerr"
+)
+
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	connFlags := addConnectionFlags(flags)
+	index := flags.Int("index", -1, "position of the message in the dead letter queue, as shown by list")
+	all := flags.Bool("all", false, "replay every message on the dead letter queue")
+	_ = flags.Parse(args)
+
+	if *all == (*index >= 0) {
+		return cerr.Error("Pass exactly one of -index or -all")
+	}
+
+	conn, err := dial(connFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	publisher, err := newJobPublisher(connFlags, conn)
+	if err != nil {
+		return err
+	}
+
+	channel, err := openChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	replayed, err := replayDeadLetters(channel, publisher, connFlags, *index, *all)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Replayed %d messages\n", replayed)
+	return nil
+}
+
+// replayDeadLetters replays the dead lettered message at the index, or all of them
+func replayDeadLetters(channel queueChannel, publisher rabbitmq.Publisher, connFlags *connectionFlags, index int, all bool) (int, error) {
+	limit := 0
+	if !all {
+		limit = index + 1
+	}
+
+	peeked, err := peekDeadLetters(channel, connFlags, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer peeked.release()
+
+	toReplay := peeked.messages
+	if !all {
+		message, err := peeked.message(index)
+		if err != nil {
+			return 0, err
+		}
+
+		toReplay = []amqp091.Delivery{message}
+	}
+
+	for i, message := range toReplay {
+		if err := replay(publisher, message); err != nil {
+			return i, err
+		}
+	}
+
+	return len(toReplay), nil
+}
+
+// newJobPublisher publishes over the connection that's already open,
+// sending each job to the queue of the lane that runs it
+func newJobPublisher(connFlags *connectionFlags, conn *amqp091.Connection) (worker.JobPublisher, error) {
+	lightJobs, err := rabbitmq.NewQueuePublisherWithConnection(connFlags.rabbitURL, conn, connFlags.queueName)
+	if err != nil {
+		return worker.JobPublisher{}, cerr.Wrap(err).Error("Failed to create publisher for the job queue")
+	}
+
+	splitJobs, err := rabbitmq.NewQueuePublisherWithConnection(connFlags.rabbitURL, conn, worker.SplitQueueName(connFlags.queueName))
+	if err != nil {
+		return worker.JobPublisher{}, cerr.Wrap(err).Error("Failed to create publisher for the split queue")
+	}
+
+	return worker.NewJobPublisher(lightJobs, splitJobs), nil
+}
+
+// replay puts the message back on the job queue with a fresh set of attempts.
+// The error history is kept, so it's still there if the job fails again
+func replay(publisher rabbitmq.Publisher, message amqp091.Delivery) error {
+	errctx := cerr.Field("message_type", message.Type)
+
+	headers := amqp091.Table{}
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+	delete(headers, worker.RetryCountHeader)
+	delete(headers, worker.LastErrorHeader)
+	headers[rabbitmq.AttemptHeader] = rabbitmq.NextAttempt(message.Headers)
+
+	err := publisher.Publish(amqp091.Publishing{
+		MessageId: message.MessageId,
+		Headers:   headers,
+		Type:      message.Type,
+		Body:      message.Body,
+	})
+	if err != nil {
+		return errctx.Wrap(err).Error("Failed to publish message to the job queue")
+	}
+
+	// only taken off the dead letter queue once it's safely on the job queue
+	if err := message.Ack(false); err != nil {
+		return errctx.Wrap(err).Error("Failed to ack dead lettered message")
+	}
+
+	return nil
+}
+
+func runPurge(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	connFlags := addConnectionFlags(flags)
+	queue := addQueueFlag(flags, deadLetterQueue)
+	_ = flags.Parse(args)
+
+	queueName, err := resolveQueueName(connFlags, *queue)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dial(connFlags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channel, err := openChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	purged, err := purgeQueue(channel, queueName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Purged %d messages from %s\n", purged, queueName)
+	return nil
+}
+
+func purgeQueue(channel queueChannel, queueName string) (int, error) {
+	if _, err := inspectQueue(channel, queueName); err != nil {
+		return 0, err
+	}
+
+	purged, err := channel.QueuePurge(queueName, false)
+	if err != nil {
+		return 0, cerr.Field("queue_name", queueName).Wrap(err).Error("Failed to purge queue")
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/apex/log"
+	"github.com/veedubyou/chord-paper-be/src/shared/config"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/dev"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/envvar"
+	"github.com/veedubyou/chord-paper-be/src/shared/config/local"
+	"github.com/veedubyou/chord-paper-be/src/shared/lib/env"
+	"github.com/veedubyou/chord-paper-be/src/worker/application"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+)
+
+const (
+	port                   = ":5001"
+	defaultConcurrency     = 1
+	shutdownTimeoutSeconds = 25
+)
+
+func main() {
+	var appConfig application.SplitServiceConfig
+	concurrency := envvar.GetIntOrDefault(envvar.SPLIT_SERVICE_CONCURRENCY, defaultConcurrency)
+	shutdownTimeout := time.Duration(envvar.GetIntOrDefault(envvar.SHUTDOWN_TIMEOUT_SECONDS, shutdownTimeoutSeconds)) * time.Second
+
+	switch env.Get() {
+	case env.Production:
+		appConfig = application.SplitServiceConfig{
+			SplitEngineConfig: application.SplitEngineConfig{
+				SpleeterBinPath:         config.SpleeterPath(),
+				SpleeterWorkingDirPath:  envvar.MustGet(envvar.SPLEETER_WORKING_DIR_PATH),
+				DemucsBinPath:           config.DemucsPath(),
+				DemucsWorkingDirPath:    envvar.MustGet(envvar.DEMUCS_WORKING_DIR_PATH),
+				OpenUnmixBinPath:        envvar.GetOrDefault(envvar.OPEN_UNMIX_BIN_PATH, ""),
+				OpenUnmixWorkingDirPath: envvar.GetOrDefault(envvar.OPEN_UNMIX_WORKING_DIR_PATH, "/open-unmix-scratch"),
+			},
+			Port:            port,
+			WorkingDirPath:  envvar.MustGet(envvar.SPLIT_SERVICE_WORKING_DIR_PATH),
+			Concurrency:     concurrency,
+			Secret:          envvar.MustGet(envvar.SPLIT_SERVICE_SECRET),
+			ShutdownTimeout: shutdownTimeout,
+		}
+
+	case env.Development:
+		appConfig = application.SplitServiceConfig{
+			SplitEngineConfig: application.SplitEngineConfig{
+				SpleeterBinPath:         config.SpleeterPath(),
+				SpleeterWorkingDirPath:  path.Join(local.ProjectRoot(), "/src/worker/wd/spleeter"),
+				DemucsBinPath:           config.DemucsPath(),
+				DemucsWorkingDirPath:    path.Join(local.ProjectRoot(), "/src/worker/wd/demucs"),
+				OpenUnmixBinPath:        envvar.GetOrDefault(envvar.OPEN_UNMIX_BIN_PATH, ""),
+				OpenUnmixWorkingDirPath: path.Join(local.ProjectRoot(), "/src/worker/wd/open-unmix"),
+			},
+			Port:            port,
+			WorkingDirPath:  path.Join(local.ProjectRoot(), "/src/worker/wd/split-service"),
+			Concurrency:     concurrency,
+			Secret:          dev.SplitServiceSecret,
+			ShutdownTimeout: shutdownTimeout,
+		}
+
+	default:
+		panic("Unexpected environment")
+	}
+
+	app := application.NewSplitServiceApp(appConfig)
+	stopping := make(chan struct{})
+	stopped := make(chan struct{})
+	go stopOnSignal(&app, stopping, stopped)
+
+	if err := app.Start(); err != nil {
+		panic(err)
+	}
+
+	// ListenAndServe returns as soon as Shutdown starts, but Stop still has to finish cleaning up
+	select {
+	case <-stopping:
+		<-stopped
+	default:
+	}
+}
+
+func stopOnSignal(app *application.SplitServiceApp, stopping chan<- struct{}, stopped chan<- struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	sig := <-signals
+	log.WithField("signal", sig.String()).Info("Shutting down the split service")
+	close(stopping)
+	app.Stop()
+	close(stopped)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/apex/log"
 	"github.com/veedubyou/chord-paper-be/src/shared/config"
 	"github.com/veedubyou/chord-paper-be/src/shared/config/dev"
 	"github.com/veedubyou/chord-paper-be/src/shared/config/envvar"
@@ -8,11 +9,27 @@ import (
 	"github.com/veedubyou/chord-paper-be/src/shared/config/prod"
 	"github.com/veedubyou/chord-paper-be/src/shared/lib/env"
 	"github.com/veedubyou/chord-paper-be/src/worker/application"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/reaper"
+	"github.com/veedubyou/chord-paper-be/src/worker/internal/application/worker"
+	"os"
+	"os/signal"
 	"path"
+	"syscall"
+	"time"
 )
 
+// should be well under the time the deployment gives the worker to stop
+const defaultShutdownTimeoutSeconds = 25
+
 func main() {
 	var appConfig application.Config
+	defaultConcurrency := worker.DefaultConcurrencyLimits()
+	shutdownTimeout := time.Duration(envvar.GetIntOrDefault(envvar.SHUTDOWN_TIMEOUT_SECONDS, defaultShutdownTimeoutSeconds)) * time.Second
+	defaultStuckJobThresholdMinutes := int(reaper.DefaultPolicy().StuckAfter / time.Minute)
+	stuckJobThreshold := time.Duration(envvar.GetIntOrDefault(envvar.STUCK_JOB_THRESHOLD_MINUTES, defaultStuckJobThresholdMinutes)) * time.Minute
+	defaultQueuedJobThresholdMinutes := int(reaper.DefaultPolicy().QueuedStuckAfter / time.Minute)
+	queuedJobThreshold := time.Duration(envvar.GetIntOrDefault(envvar.QUEUED_JOB_THRESHOLD_MINUTES, defaultQueuedJobThresholdMinutes)) * time.Minute
+	splitServiceURL := envvar.GetOrDefault(envvar.SPLIT_SERVICE_URL, "")
 
 	switch env.Get() {
 	case env.Production:
@@ -22,45 +39,100 @@ func main() {
 				SecretAccessKey: envvar.MustGet(envvar.AWS_SECRET_ACCESS_KEY),
 				Region:          prod.DynamoDBRegion,
 			},
-			CloudStorageConfig: config.ProdCloudStorage{
-				StorageHost: prod.GOOGLE_STORAGE_HOST,
-				SecretKey:   envvar.MustGet(envvar.GOOGLE_CLOUD_KEY),
-				BucketName:  envvar.MustGet(envvar.GOOGLE_CLOUD_STORAGE_BUCKET_NAME),
-			},
+			CloudStorageConfig:      config.CloudStorageFromEnv(),
 			RabbitMQURL:             envvar.MustGet(envvar.RABBITMQ_URL),
 			RabbitMQQueueName:       envvar.MustGet(envvar.RABBITMQ_QUEUE_NAME),
 			YoutubeDLBinPath:        config.YoutubeDLPath(),
 			YoutubeDLWorkingDirPath: envvar.MustGet(envvar.YOUTUBEDL_WORKING_DIR_PATH),
-			SpleeterBinPath:         config.SpleeterPath(),
-			SpleeterWorkingDirPath:  envvar.MustGet(envvar.SPLEETER_WORKING_DIR_PATH),
-			DemucsBinPath:           config.DemucsPath(),
-			DemucsWorkingDirPath:    envvar.MustGet(envvar.DEMUCS_WORKING_DIR_PATH),
+			SplitEngineConfig: application.SplitEngineConfig{
+				SpleeterBinPath:         engineBinPath(splitServiceURL, config.SpleeterPath),
+				SpleeterWorkingDirPath:  envvar.MustGet(envvar.SPLEETER_WORKING_DIR_PATH),
+				DemucsBinPath:           engineBinPath(splitServiceURL, config.DemucsPath),
+				DemucsWorkingDirPath:    envvar.MustGet(envvar.DEMUCS_WORKING_DIR_PATH),
+				OpenUnmixBinPath:        envvar.GetOrDefault(envvar.OPEN_UNMIX_BIN_PATH, ""),
+				OpenUnmixWorkingDirPath: envvar.GetOrDefault(envvar.OPEN_UNMIX_WORKING_DIR_PATH, "/open-unmix-scratch"),
+			},
+			SplitServiceURL:     splitServiceURL,
+			SplitServiceSecret:  splitServiceSecret(splitServiceURL),
+			SplitJobConcurrency: envvar.GetIntOrDefault(envvar.SPLIT_JOB_CONCURRENCY, defaultConcurrency.SplitJobs),
+			LightJobConcurrency: envvar.GetIntOrDefault(envvar.LIGHT_JOB_CONCURRENCY, defaultConcurrency.LightJobs),
+			ShutdownTimeout:     shutdownTimeout,
+			StuckJobThreshold:   stuckJobThreshold,
+			QueuedJobThreshold:  queuedJobThreshold,
 		}
 
 	case env.Development:
 		appConfig = application.Config{
 			DynamoConfig: dev.DynamoConfig,
-			// using prod GCS creds for now because the local fake GCS doesn't persist
-			CloudStorageConfig: config.ProdCloudStorage{
-				StorageHost: prod.GOOGLE_STORAGE_HOST,
-				SecretKey:   envvar.MustGet(envvar.GOOGLE_CLOUD_KEY),
-				BucketName:  envvar.MustGet(envvar.GOOGLE_CLOUD_STORAGE_BUCKET_NAME),
-			},
+			// defaults to prod GCS creds because the local fake GCS doesn't persist,
+			// FILE_STORE_TYPE=local_disk keeps the files on this machine instead
+			CloudStorageConfig:      config.CloudStorageFromEnv(),
 			RabbitMQURL:             dev.RabbitMQHost,
 			RabbitMQQueueName:       dev.RabbitMQQueueName,
 			YoutubeDLBinPath:        config.YoutubeDLPath(),
 			YoutubeDLWorkingDirPath: path.Join(local.ProjectRoot(), "/src/worker/wd/youtube-dl"),
-			SpleeterBinPath:         config.SpleeterPath(),
-			SpleeterWorkingDirPath:  path.Join(local.ProjectRoot(), "/src/worker/wd/spleeter"),
-			DemucsBinPath:           config.DemucsPath(),
-			DemucsWorkingDirPath:    path.Join(local.ProjectRoot(), "/src/worker/wd/demucs"),
+			SplitEngineConfig: application.SplitEngineConfig{
+				SpleeterBinPath:         engineBinPath(splitServiceURL, config.SpleeterPath),
+				SpleeterWorkingDirPath:  path.Join(local.ProjectRoot(), "/src/worker/wd/spleeter"),
+				DemucsBinPath:           engineBinPath(splitServiceURL, config.DemucsPath),
+				DemucsWorkingDirPath:    path.Join(local.ProjectRoot(), "/src/worker/wd/demucs"),
+				OpenUnmixBinPath:        envvar.GetOrDefault(envvar.OPEN_UNMIX_BIN_PATH, ""),
+				OpenUnmixWorkingDirPath: path.Join(local.ProjectRoot(), "/src/worker/wd/open-unmix"),
+			},
+			SplitServiceURL:     splitServiceURL,
+			SplitServiceSecret:  dev.SplitServiceSecret,
+			SplitJobConcurrency: envvar.GetIntOrDefault(envvar.SPLIT_JOB_CONCURRENCY, defaultConcurrency.SplitJobs),
+			LightJobConcurrency: envvar.GetIntOrDefault(envvar.LIGHT_JOB_CONCURRENCY, defaultConcurrency.LightJobs),
+			ShutdownTimeout:     shutdownTimeout,
+			StuckJobThreshold:   stuckJobThreshold,
+			QueuedJobThreshold:  queuedJobThreshold,
 		}
 	default:
 		panic("Unexpected environment")
 	}
 
 	app := application.NewApp(appConfig)
+	stopping := make(chan struct{})
+	stopped := make(chan struct{})
+	go stopOnSignal(&app, stopping, stopped)
+
 	if err := app.Start(); err != nil {
 		panic(err)
 	}
+
+	// Start returns as soon as the consumers stop, but Stop still has to finish cleaning up
+	select {
+	case <-stopping:
+		<-stopped
+	default:
+	}
+}
+
+// the split engines only have to be installed when the worker runs the splits itself
+func engineBinPath(splitServiceURL string, findBin func() string) string {
+	if splitServiceURL != "" {
+		return ""
+	}
+
+	return findBin()
+}
+
+// the secret is only needed when there's a split service to send it to
+func splitServiceSecret(splitServiceURL string) string {
+	if splitServiceURL == "" {
+		return ""
+	}
+
+	return envvar.MustGet(envvar.SPLIT_SERVICE_SECRET)
+}
+
+func stopOnSignal(app *application.App, stopping chan<- struct{}, stopped chan<- struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	sig := <-signals
+	log.WithField("signal", sig.String()).Info("Shutting down the worker")
+	close(stopping)
+	app.Stop()
+	close(stopped)
 }